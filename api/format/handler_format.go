@@ -7,3 +7,9 @@ import (
 
 type JSONHandler func(*gin.Context) (interface{}, *err.APIErr)
 type NormalHandler func(*gin.Context)
+
+// StreamHandler 以Server-Sent Events返回的handler，执行过程中通过emit推送事件，返回值作为最后一个事件推送。
+type StreamHandler func(*gin.Context, StreamEmitter) (interface{}, *err.APIErr)
+
+// StreamEmitter 推送一个名为event的事件，data会被编码为JSON。可以在多个goroutine中同时调用。
+type StreamEmitter func(event string, data interface{})
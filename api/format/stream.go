@@ -0,0 +1,84 @@
+package format
+
+import (
+	SErr "ServerServing/err"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// StreamEventOutput 命令输出的一行。
+	StreamEventOutput = "output"
+	// StreamEventExit 最后一个事件，内容与普通接口的响应格式相同，其中包含命令的退出码等执行结果。
+	StreamEventExit = "exit"
+
+	// streamHeartbeatInterval 长时间没有输出时，定期发送注释行，避免连接被代理或者浏览器当作空闲连接断开。
+	streamHeartbeatInterval = 15 * time.Second
+	streamEventBuffer       = 256
+)
+
+type streamEvent struct {
+	event string
+	data  interface{}
+}
+
+// wrapStreamHandler 以text/event-stream返回，handler推送的事件按顺序发送，handler返回后发送exit事件并结束响应。
+// 客户端断开连接时请求的ctx被取消，之后推送的事件被丢弃，但仍然等待handler返回，因为gin会在请求结束后复用gin.Context。
+func wrapStreamHandler(handler StreamHandler) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		events := make(chan *streamEvent, streamEventBuffer)
+		emit := func(event string, data interface{}) {
+			select {
+			case events <- &streamEvent{event: event, data: data}:
+			case <-ctx.Done():
+			}
+		}
+		done := make(chan *JSONRespFormat, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("wrapStreamHandler handler panic, path=[%s], panic=[%v]", c.Request.URL.Path, r)
+					done <- NewJSONResp(SErr.InternalErr.Code, fmt.Sprintf("%v", r), nil)
+				}
+			}()
+			resp, e := handler(c, emit)
+			if e != nil {
+				done <- NewJSONResp(e.Code, e.Message, nil)
+				return
+			}
+			done <- SimpleOKResp(resp)
+		}()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		// 避免nginx等反向代理缓冲响应。
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event := <-events:
+				c.SSEvent(event.event, event.data)
+			case <-heartbeat.C:
+				_, _ = io.WriteString(c.Writer, ": heartbeat\n\n")
+			case final := <-done:
+				// handler返回之前推送的事件都已经在events中，先把它们发送完。
+				for len(events) > 0 {
+					event := <-events
+					c.SSEvent(event.event, event.data)
+				}
+				c.SSEvent(StreamEventExit, final)
+				c.Writer.Flush()
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package format
+
+import (
+	SErr "ServerServing/err"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveStream(t *testing.T, handler StreamHandler) string {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/stream", Wrap(handler))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/stream", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	return w.Body.String()
+}
+
+func TestWrapStreamHandler(t *testing.T) {
+	body := serveStream(t, func(c *gin.Context, emit StreamEmitter) (interface{}, *SErr.APIErr) {
+		for _, line := range []string{"copying 1/2", "copying 2/2"} {
+			emit(StreamEventOutput, map[string]string{"line": line})
+		}
+		return map[string]int{"exit_status": 0}, nil
+	})
+	expected := "event:output\ndata:{\"line\":\"copying 1/2\"}\n\n" +
+		"event:output\ndata:{\"line\":\"copying 2/2\"}\n\n" +
+		"event:exit\ndata:{\"code\":20000,\"message\":\"success\",\"data\":{\"exit_status\":0}}\n\n"
+	if body != expected {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	// 出错时同样以exit事件结束，内容为错误码与错误信息。
+	body = serveStream(t, func(c *gin.Context, emit StreamEmitter) (interface{}, *SErr.APIErr) {
+		return nil, SErr.NeedLoginErr
+	})
+	if !strings.HasPrefix(body, "event:exit\n") || !strings.Contains(body, SErr.NeedLoginErr.Message) {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
@@ -15,6 +15,9 @@ func Wrap(handler interface{}) func(c *gin.Context) {
 	case NormalHandler:
 		target = wrapNormalHandler(handler.(NormalHandler))
 		break
+	case StreamHandler:
+		target = wrapStreamHandler(handler.(StreamHandler))
+		break
 	default:
 		panic("Unsupported SearchSourceCode API type")
 	}
@@ -18,6 +18,11 @@ func Register(r *gin.Engine) {
 	sessionsRouter := rg.Group(prefixSession)
 	serversRouter := rg.Group(prefixServer)
 	serversAccountsRouter := rg.Group(prefixServerAccounts)
+	cmdScriptsRouter := rg.Group(prefixCmdScripts)
+	scriptsRouter := rg.Group(prefixScripts)
+	scriptRunsRouter := rg.Group(prefixScriptRuns)
+	terminalSessionsRouter := rg.Group(prefixTerminalSessions)
+	commandAuditsRouter := rg.Group(prefixCommandAudits)
 
 	testAPI := testAPI{}
 	testRouter.GET("error_handler", format.Wrap(testAPI.testErrorHandler()))
@@ -41,20 +46,65 @@ func Register(r *gin.Engine) {
 	serversRouter.PUT(":host/:port", format.Wrap(serversAPI.update()))
 	serversRouter.GET("", format.Wrap(serversAPI.infos()))
 	serversRouter.GET("connections/:host/:port", format.Wrap(serversAPI.connectionTest()))
+	serversRouter.GET("host_keys/:host/:port", format.Wrap(serversAPI.hostKeyInfo()))
+	serversRouter.PUT("host_keys/:host/:port", format.Wrap(serversAPI.acceptHostKey()))
+	serversRouter.POST("fan_out", format.Wrap(serversAPI.fanOut()))
+	serversRouter.POST("fan_out/stream", format.Wrap(serversAPI.fanOutStream()))
+	serversRouter.PUT("agent_tokens/:host/:port", format.Wrap(serversAPI.rotateAgentToken()))
+	serversRouter.DELETE("agent_tokens/:host/:port", format.Wrap(serversAPI.revokeAgentToken()))
+	serversRouter.POST("agent_reports/:host/:port", format.Wrap(serversAPI.agentReport()))
+
+	terminalsAPI := terminalsAPI{}
+	serversRouter.GET("terminals/:host/:port", format.Wrap(terminalsAPI.open()))
+	terminalSessionsRouter.GET("", format.Wrap(terminalsAPI.sessions()))
+	terminalSessionsRouter.GET(":id", format.Wrap(terminalsAPI.sessionInfo()))
+	terminalSessionsRouter.GET(":id/recording", format.Wrap(terminalsAPI.recording()))
+
+	serverFilesAPI := serverFilesAPI{}
+	serversRouter.GET("files/:host/:port", format.Wrap(serverFilesAPI.infos()))
+	serversRouter.DELETE("files/:host/:port", format.Wrap(serverFilesAPI.delete()))
+	serversRouter.GET("files/:host/:port/stat", format.Wrap(serverFilesAPI.stat()))
+	serversRouter.GET("files/:host/:port/content", format.Wrap(serverFilesAPI.download()))
+	serversRouter.PUT("files/:host/:port/content", format.Wrap(serverFilesAPI.upload()))
+	serversRouter.PUT("files/:host/:port/perm", format.Wrap(serverFilesAPI.chmod()))
+	serversRouter.PUT("files/:host/:port/owner", format.Wrap(serverFilesAPI.chown()))
 
 	serversAccountsAPI := serversAccountsAPI{}
 	serversAccountsRouter.POST("", format.Wrap(serversAccountsAPI.create()))
 	serversAccountsRouter.GET("/backupDir", format.Wrap(serversAccountsAPI.backupDir()))
 	serversAccountsRouter.DELETE("", format.Wrap(serversAccountsAPI.delete()))
+	serversAccountsRouter.DELETE("stream", format.Wrap(serversAccountsAPI.deleteStream()))
 	serversAccountsRouter.PUT("", format.Wrap(serversAccountsAPI.update()))
+
+	cmdScriptsAPI := cmdScriptsAPI{}
+	cmdScriptsRouter.GET("", format.Wrap(cmdScriptsAPI.list()))
+
+	scriptsAPI := scriptsAPI{}
+	scriptsRouter.POST("", format.Wrap(scriptsAPI.create()))
+	scriptsRouter.GET("", format.Wrap(scriptsAPI.infos()))
+	scriptsRouter.GET(":id", format.Wrap(scriptsAPI.info()))
+	scriptsRouter.PUT(":id", format.Wrap(scriptsAPI.update()))
+	scriptsRouter.DELETE(":id", format.Wrap(scriptsAPI.delete()))
+	scriptsRouter.POST(":id/runs", format.Wrap(scriptsAPI.run()))
+	scriptsRouter.POST(":id/runs/stream", format.Wrap(scriptsAPI.runStream()))
+	scriptRunsRouter.GET("", format.Wrap(scriptsAPI.runInfos()))
+	scriptRunsRouter.GET(":id", format.Wrap(scriptsAPI.runInfo()))
+
+	commandAuditsAPI := commandAuditsAPI{}
+	commandAuditsRouter.GET("", format.Wrap(commandAuditsAPI.infos()))
 }
 
 const (
-	prefixTest           = "test"
-	prefixUser           = "users"
-	prefixSession        = "sessions"
-	prefixServer         = "servers"
-	prefixServerAccounts = "servers/accounts"
+	prefixTest             = "test"
+	prefixUser             = "users"
+	prefixSession          = "sessions"
+	prefixServer           = "servers"
+	prefixServerAccounts   = "servers/accounts"
+	prefixCmdScripts       = "cmd_scripts"
+	prefixScripts          = "scripts"
+	prefixScriptRuns       = "scripts/runs"
+	prefixTerminalSessions = "terminal_sessions"
+	prefixCommandAudits    = "command_audits"
 )
 
 //type sourceCodeAPI struct{}
@@ -155,6 +205,48 @@ func (serversAPI) connectionTest() format.JSONHandler {
 	}
 }
 
+func (serversAPI) hostKeyInfo() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().HostKeyInfo(c)
+	}
+}
+
+func (serversAPI) acceptHostKey() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().AcceptHostKey(c)
+	}
+}
+
+func (serversAPI) fanOut() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().FanOut(c)
+	}
+}
+
+func (serversAPI) fanOutStream() format.StreamHandler {
+	return func(c *gin.Context, emit format.StreamEmitter) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().FanOutStream(c, emit)
+	}
+}
+
+func (serversAPI) rotateAgentToken() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().RotateAgentToken(c)
+	}
+}
+
+func (serversAPI) revokeAgentToken() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().RevokeAgentToken(c)
+	}
+}
+
+func (serversAPI) agentReport() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerHandler().AgentReport(c)
+	}
+}
+
 type serversAccountsAPI struct{}
 
 func (serversAccountsAPI) create() format.JSONHandler {
@@ -175,12 +267,160 @@ func (serversAccountsAPI) delete() format.JSONHandler {
 	}
 }
 
+func (serversAccountsAPI) deleteStream() format.StreamHandler {
+	return func(c *gin.Context, emit format.StreamEmitter) (interface{}, *err.APIErr) {
+		return handler.GetServerAccountsHandler().DeleteStream(c, emit)
+	}
+}
+
 func (serversAccountsAPI) update() format.JSONHandler {
 	return func(c *gin.Context) (interface{}, *err.APIErr) {
 		return handler.GetServerAccountsHandler().Update(c)
 	}
 }
 
+type cmdScriptsAPI struct{}
+
+func (cmdScriptsAPI) list() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetCmdScriptsHandler().List(c)
+	}
+}
+
+type scriptsAPI struct{}
+
+func (scriptsAPI) create() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().Create(c)
+	}
+}
+
+func (scriptsAPI) update() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().Update(c)
+	}
+}
+
+func (scriptsAPI) delete() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().Delete(c)
+	}
+}
+
+func (scriptsAPI) info() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().Info(c)
+	}
+}
+
+func (scriptsAPI) infos() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().Infos(c)
+	}
+}
+
+func (scriptsAPI) run() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().Run(c)
+	}
+}
+
+func (scriptsAPI) runStream() format.StreamHandler {
+	return func(c *gin.Context, emit format.StreamEmitter) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().RunStream(c, emit)
+	}
+}
+
+func (scriptsAPI) runInfos() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().RunInfos(c)
+	}
+}
+
+func (scriptsAPI) runInfo() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetScriptsHandler().RunInfo(c)
+	}
+}
+
+type terminalsAPI struct{}
+
+func (terminalsAPI) open() format.NormalHandler {
+	return func(c *gin.Context) {
+		handler.GetTerminalsHandler().Open(c)
+	}
+}
+
+func (terminalsAPI) sessions() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetTerminalsHandler().Sessions(c)
+	}
+}
+
+func (terminalsAPI) sessionInfo() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetTerminalsHandler().SessionInfo(c)
+	}
+}
+
+func (terminalsAPI) recording() format.NormalHandler {
+	return func(c *gin.Context) {
+		handler.GetTerminalsHandler().Recording(c)
+	}
+}
+
+type serverFilesAPI struct{}
+
+func (serverFilesAPI) infos() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerFilesHandler().Infos(c)
+	}
+}
+
+func (serverFilesAPI) stat() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerFilesHandler().Stat(c)
+	}
+}
+
+func (serverFilesAPI) download() format.NormalHandler {
+	return func(c *gin.Context) {
+		handler.GetServerFilesHandler().Download(c)
+	}
+}
+
+func (serverFilesAPI) upload() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerFilesHandler().Upload(c)
+	}
+}
+
+func (serverFilesAPI) chmod() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerFilesHandler().Chmod(c)
+	}
+}
+
+func (serverFilesAPI) chown() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerFilesHandler().Chown(c)
+	}
+}
+
+func (serverFilesAPI) delete() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetServerFilesHandler().Delete(c)
+	}
+}
+
+type commandAuditsAPI struct{}
+
+func (commandAuditsAPI) infos() format.JSONHandler {
+	return func(c *gin.Context) (interface{}, *err.APIErr) {
+		return handler.GetCommandAuditsHandler().Infos(c)
+	}
+}
+
 type testAPI struct{}
 
 // Ping
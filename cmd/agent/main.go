@@ -0,0 +1,127 @@
+// agent 运行在被管理的服务器上，定期在本机采集硬件信息以及CPU、内存、进程、GPU的使用情况，推送到ServerServing。
+// 采集使用与SSH相同的cmds_scripts以及解析逻辑，所以推送的数据与ServerServing通过SSH获取的数据相同。
+//
+// 使用方法：管理员先通过 PUT /api/v1/servers/agent_tokens/{host}/{port} 为服务器生成agent token，之后在该服务器上运行：
+//
+//	SERVER_SERVING_AGENT_TOKEN=<token> agent -server http://<ServerServing地址>:4400 -host <注册的host> -port <注册的port>
+//
+// host与port为该服务器在ServerServing中注册的Host与Port。运行agent的用户需要具有sudo权限，需要密码时通过SERVER_SERVING_AGENT_SUDO_PWD传入。
+package main
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/service/server_executor"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	tokenEnv   = "SERVER_SERVING_AGENT_TOKEN"
+	sudoPwdEnv = "SERVER_SERVING_AGENT_SUDO_PWD"
+)
+
+func main() {
+	serverAddr := flag.String("server", "", "ServerServing的地址，例如 http://127.0.0.1:4400")
+	host := flag.String("host", "", "本服务器在ServerServing中注册的Host")
+	port := flag.Uint("port", 22, "本服务器在ServerServing中注册的Port")
+	interval := flag.Duration("interval", 30*time.Second, "推送数据的间隔")
+	flag.Parse()
+	token := os.Getenv(tokenEnv)
+	if *serverAddr == "" || *host == "" || token == "" {
+		log.Fatalf("agent需要指定-server与-host，并通过环境变量%s传入agent token", tokenEnv)
+	}
+	if *interval < time.Second {
+		log.Fatalf("推送数据的间隔不能小于1s，interval=[%s]", *interval)
+	}
+	reportURL := fmt.Sprintf("%s/api/v1/servers/agent_reports/%s/%d", *serverAddr, url.PathEscape(*host), *port)
+
+	if err := server_executor.InitCmdScripts(); err != nil {
+		log.Fatalf("InitCmdScripts failed, err=[%s]", err.Message)
+	}
+	es, err := server_executor.OpenExecutorService(&server_executor.OpenExecutorServiceParam{
+		Host:            *host,
+		Port:            *port,
+		OSType:          daModels.OSTypeLinuxLocal,
+		AdminAccountPwd: os.Getenv(sudoPwdEnv),
+	})
+	if err != nil {
+		log.Fatalf("打开本机的Executor失败！err=[%s]", err.Message)
+	}
+	defer es.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	client := &http.Client{Timeout: *interval}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		report(ctx, client, es, reportURL, token, *interval)
+		select {
+		case <-ctx.Done():
+			log.Printf("agent退出")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// report 采集并推送一次数据，失败时只打印log，等待下一次推送。
+func report(ctx context.Context, client *http.Client, es server_executor.ExecutorService, reportURL, token string, interval time.Duration) {
+	collectCtx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+	r, sErr := server_executor.CollectAgentReport(collectCtx, es, interval)
+	if sErr != nil {
+		log.Printf("agent采集数据失败！err=[%s]", sErr.Message)
+		return
+	}
+	if err := push(ctx, client, reportURL, token, r); err != nil {
+		log.Printf("agent推送数据失败！url=[%s], err=[%s]", reportURL, err)
+	}
+}
+
+func push(ctx context.Context, client *http.Client, reportURL, token string, r *server_executor.AgentReport) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reportURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(server_executor.AgentTokenHeader, token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status=[%s], body=[%s]", resp.Status, respBody)
+	}
+	// 与api/format一样，出错时的状态码也为200，错误码在响应体中。
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析响应失败，body=[%s], err=[%s]", respBody, err)
+	}
+	if result.Code != SErr.CodeOK {
+		return fmt.Errorf("code=[%d], message=[%s]", result.Code, result.Message)
+	}
+	return nil
+}
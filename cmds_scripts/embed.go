@@ -0,0 +1,11 @@
+// Package cmds_scripts 内嵌了在服务器上执行的命令脚本，作为默认的脚本集合。
+// 每个子目录对应一个发行版或者发行版家族（linux_common为所有Linux发行版通用），目录下的每个文件为一个命令模板。
+// 查找脚本时依次使用/etc/os-release中的ID、ID_LIKE中的每一项、发行版类型对应的目录，最后为linux_common。
+package cmds_scripts
+
+import "embed"
+
+// FS 内嵌的全部脚本。
+//
+//go:embed linux_common suse
+var FS embed.FS
@@ -27,6 +27,8 @@ func convert2Env(env string) (ConfigurationEnv, error) {
 
 type Configuration map[ConfigurationEnv]*EachConfig
 
+// EachConfig 某个环境的配置。
+// CmdsScriptsPath 为命令脚本的覆盖目录，可以不配置。目录结构与cmds_scripts相同，其中的脚本会覆盖内嵌的同名脚本。
 type EachConfig struct {
 	AppName         string       `yaml:"app_name"`
 	Host            string       `yaml:"host"`
@@ -34,6 +36,20 @@ type EachConfig struct {
 	CmdsScriptsPath string       `yaml:"cmds_scripts_path"`
 	MySqlConfig     *MySqlConfig `yaml:"mysql_config"`
 	RedisConfig     *RedisConfig `yaml:"redis_config"`
+	// SSHPoolConfig 可以不配置，不配置时使用默认值。
+	SSHPoolConfig *SSHPoolConfig `yaml:"ssh_pool_config"`
+	// CommandTimeoutConfig 可以不配置，不配置时使用默认值。
+	CommandTimeoutConfig *CommandTimeoutConfig `yaml:"command_timeout_config"`
+	// FanOutConfig 可以不配置，不配置时使用默认值。
+	FanOutConfig *FanOutConfig `yaml:"fan_out_config"`
+	// AgentConfig 可以不配置，不配置时使用默认值。
+	AgentConfig *AgentConfig `yaml:"agent_config"`
+	// TranscriptRecordDir 可以不配置。配置后，在服务器上执行的每个脚本的名称、参数与输出都会被录制到该目录下，用于离线测试时回放。
+	TranscriptRecordDir string `yaml:"transcript_record_dir"`
+	// TerminalRecordingDir 网页终端的录像（asciicast格式）保存的目录，可以不配置，不配置时为工作目录下的terminal_recordings。
+	TerminalRecordingDir string `yaml:"terminal_recording_dir"`
+	// BackupRoot 服务器上备份用户home目录的文件夹，必须是绝对路径，可以不配置，不配置时为/backup。
+	BackupRoot string `yaml:"backup_root"`
 
 	Env ConfigurationEnv
 }
@@ -49,6 +65,50 @@ type RedisConfig struct {
 	Addr string `yaml:"addr"`
 }
 
+// SSHPoolConfig SSH连接池的配置，值为0时使用默认值。
+type SSHPoolConfig struct {
+	// MaxSessionsPerHost 每个服务器同时打开的session数量上限。
+	MaxSessionsPerHost int `yaml:"max_sessions_per_host"`
+	// IdleTimeoutSeconds 连接空闲多久后被关闭。
+	IdleTimeoutSeconds int `yaml:"idle_timeout_seconds"`
+	// KeepaliveIntervalSeconds 对空闲连接做keepalive检查的间隔。
+	KeepaliveIntervalSeconds int `yaml:"keepalive_interval_seconds"`
+	// UnreachableFailureThreshold 连续建连失败多少次之后开始冷却，冷却期内不再尝试连接该服务器。
+	UnreachableFailureThreshold int `yaml:"unreachable_failure_threshold"`
+	// UnreachableBaseCooldownSeconds 第一次冷却的时长，之后每多失败一次翻倍。
+	UnreachableBaseCooldownSeconds int `yaml:"unreachable_base_cooldown_seconds"`
+	// UnreachableMaxCooldownSeconds 冷却时长的上限。
+	UnreachableMaxCooldownSeconds int `yaml:"unreachable_max_cooldown_seconds"`
+}
+
+// CommandTimeoutConfig 在服务器上执行命令的超时时间配置。
+type CommandTimeoutConfig struct {
+	// DefaultSeconds 没有单独配置的脚本使用的超时时间。
+	DefaultSeconds int `yaml:"default_seconds"`
+	// ScriptSeconds 按脚本名单独配置的超时时间，例如nvidia_gpu_query。
+	ScriptSeconds map[string]int `yaml:"script_seconds"`
+}
+
+// FanOutConfig 在多台服务器上同时执行命令的配置，值为0时使用默认值。
+type FanOutConfig struct {
+	// DefaultParallelism 请求中没有指定时，同时执行的服务器数量。
+	DefaultParallelism int `yaml:"default_parallelism"`
+	// MaxParallelism 请求中可以指定的同时执行的服务器数量上限。
+	MaxParallelism int `yaml:"max_parallelism"`
+	// DefaultHostTimeoutSeconds 请求中没有指定时，每台服务器上执行命令的超时时间。
+	DefaultHostTimeoutSeconds int `yaml:"default_host_timeout_seconds"`
+	// MaxHostTimeoutSeconds 请求中可以指定的每台服务器的超时时间上限。
+	MaxHostTimeoutSeconds int `yaml:"max_host_timeout_seconds"`
+}
+
+// AgentConfig 服务器上运行的agent推送数据的配置，值为0时使用默认值。
+type AgentConfig struct {
+	// StaleAfterSeconds 收到agent的数据多久之后视为过期，过期后重新通过SSH获取。默认为agent推送间隔的3倍。
+	StaleAfterSeconds int `yaml:"stale_after_seconds"`
+	// MaxReportBytes agent一次推送的数据大小上限。
+	MaxReportBytes int64 `yaml:"max_report_bytes"`
+}
+
 type args struct {
 	ConfigPath string
 	Env        ConfigurationEnv
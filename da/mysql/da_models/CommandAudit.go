@@ -0,0 +1,29 @@
+package da_models
+
+import (
+	"time"
+)
+
+// CommandAudit 平台在服务器上执行的一条命令的审计记录，只会新增，不会被修改或者删除，所以没有使用gorm.Model。
+// UserID 为触发该命令的平台用户，不是由用户的请求触发时为0。
+// Args 为JSON格式的参数，其中敏感参数（secret类型）的值已经被隐藏；输出只保存摘要（SHA-256）与长度。
+// ExitStatus 为命令的退出码，没有正常退出时为-1；ErrCode 与 Error 为执行失败时的错误码与错误信息。
+type CommandAudit struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+
+	UserID uint `gorm:"index:idx_command_audits_user_id"`
+
+	Host string `gorm:"index:idx_command_audits_host_port,priority:1;size:20"`
+	Port uint   `gorm:"index:idx_command_audits_host_port,priority:2"`
+
+	Script       string `gorm:"index:idx_command_audits_script;size:100"`
+	Args         string `gorm:"type:text"`
+	ExitStatus   int
+	ErrCode      int
+	Error        string    `gorm:"type:text"`
+	StartedAt    time.Time `gorm:"index:idx_command_audits_started_at"`
+	DurationMs   int64
+	OutputDigest string `gorm:"size:64"`
+	OutputSize   int
+}
@@ -0,0 +1,57 @@
+package da_models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// ScriptRole 执行脚本目录中的脚本所需的角色。
+type ScriptRole string
+
+const (
+	// ScriptRoleUser 任何登录的用户都可以执行。
+	ScriptRoleUser ScriptRole = "script_role_user"
+	// ScriptRoleAdmin 只有管理员可以执行。
+	ScriptRoleAdmin ScriptRole = "script_role_admin"
+)
+
+// ScriptAllServers 出现在AllowedServers中时，该脚本可以在任意服务器上执行。
+const ScriptAllServers = "*"
+
+// Script 脚本目录中由管理员定义的一个脚本。
+// Body 使用与cmds_scripts相同的命令模板格式，开头的# @param注释声明它的参数，正文中使用{{name}}引用参数。
+type Script struct {
+	gorm.Model
+
+	// Name 删除后可以重新使用，所以不在数据库中加唯一约束，而是在创建与更新时检查。
+	Name        string `gorm:"index:idx_scripts_name;not null;size:50"`
+	Description string `gorm:"size:140"`
+	Body        string `gorm:"type:text;not null"`
+	// AllowedServers 允许执行该脚本的服务器，格式为以逗号分隔的host:port，为*时允许所有服务器。
+	AllowedServers string     `gorm:"type:text"`
+	RequiredRole   ScriptRole `gorm:"not null;size:30" sql:"type:ENUM('script_role_user', 'script_role_admin')"`
+	CreatorID      uint
+}
+
+// ScriptRun 一次脚本的执行记录，无论成功与否都会保存。
+// 脚本在之后可能被修改或者删除，所以记录中同时保存了执行时的脚本名与脚本内容。
+// Args 为JSON格式的参数，其中敏感参数（secret类型）的值已经被隐藏；Error 为执行失败时的错误信息。
+type ScriptRun struct {
+	gorm.Model
+
+	ScriptID   uint   `gorm:"index:idx_script_runs_script_id"`
+	ScriptName string `gorm:"size:50"`
+	ScriptBody string `gorm:"type:text"`
+	UserID     uint   `gorm:"index:idx_script_runs_user_id"`
+
+	Host string `gorm:"index:idx_script_runs_host_port,priority:1;size:20"`
+	Port uint   `gorm:"index:idx_script_runs_host_port,priority:2"`
+
+	Args       string `gorm:"type:text"`
+	Stdout     string `gorm:"type:mediumtext"`
+	Stderr     string `gorm:"type:mediumtext"`
+	ExitStatus int
+	Error      string `gorm:"type:text"`
+	StartedAt  time.Time
+	DurationMs int64
+}
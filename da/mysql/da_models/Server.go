@@ -8,10 +8,24 @@ import (
 type OSType string
 
 const (
-	OSTypeLinux         OSType = "os_type_linux"
+	OSTypeLinux OSType = "os_type_linux"
+	// OSTypeLinuxLocal 运行ServerServing的这台Linux机器本身，命令直接在本机执行，不经过SSH。
+	OSTypeLinuxLocal    OSType = "os_type_linux_local"
 	OSTypeWindowsServer OSType = "os_type_windows_server"
 )
 
+// SSHAuthType 描述管理员账户登录服务器时使用的认证方式。
+type SSHAuthType string
+
+const (
+	// SSHAuthTypePassword 使用密码登录。为空时也视为密码登录，以兼容旧数据。
+	SSHAuthTypePassword SSHAuthType = "ssh_auth_type_password"
+	// SSHAuthTypePrivateKey 使用存储的私钥登录，私钥可以带有passphrase。
+	SSHAuthTypePrivateKey SSHAuthType = "ssh_auth_type_private_key"
+	// SSHAuthTypeAgent 使用转发过来的ssh-agent socket登录。
+	SSHAuthTypeAgent SSHAuthType = "ssh_auth_type_agent"
+)
+
 type Server struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -22,8 +36,43 @@ type Server struct {
 	Host             string `gorm:"primaryKey;index:idx_servers_host_port,priority:1;size:20"`
 	Port             uint   `gorm:"primaryKey;index:idx_servers_host_port,priority:2"`
 	AdminAccountName string `gorm:"index;not null;size:50"`
-	AdminAccountPwd  string `gorm:"not null;size:50"`
-	OSType           OSType `json:"os_type" gorm:"not null" sql:"type:ENUM('os_type_linux', 'os_type_windows_server')"`
+	// AdminAccountPwd 在密码登录时为登录密码，在私钥或agent登录时作为sudo密码使用（NOPASSWD的sudo可以为空）。
+	AdminAccountPwd string `gorm:"not null;size:50"`
+	OSType          OSType `json:"os_type" gorm:"not null" sql:"type:ENUM('os_type_linux', 'os_type_linux_local', 'os_type_windows_server')"`
+
+	AuthType                  SSHAuthType `gorm:"size:30"`
+	AdminPrivateKey           string      `gorm:"type:text"`
+	AdminPrivateKeyPassphrase string      `gorm:"size:100"`
+	AdminAgentSocket          string      `gorm:"size:255"`
+
+	// HostKeyFingerprint 创建服务器时记录的host key指纹（SHA256），之后的每次连接都需要与之一致。
+	HostKeyFingerprint string `gorm:"size:100"`
+
+	// OSReleaseID、OSReleaseIDLike、OSVersionID、OSPrettyName 最近一次连接时从/etc/os-release中解析的发行版信息。
+	// OSReleaseIDLike 与os-release中的ID_LIKE格式相同，以空格分隔。
+	OSReleaseID     string `gorm:"size:50"`
+	OSReleaseIDLike string `gorm:"size:100"`
+	OSVersionID     string `gorm:"size:30"`
+	OSPrettyName    string `gorm:"size:100"`
+
+	// JumpServerHost、JumpServerPort 引用另一个已注册的服务器作为跳板机，连接跳板机时使用它自己的认证信息与host key指纹。
+	// 跳板机自己也可以再配置跳板机，从而组成多跳的链路。
+	JumpServerHost string `gorm:"size:20"`
+	JumpServerPort uint
+	// ProxyJump 临时指定的跳板机，格式与OpenSSH的ProxyJump相同，例如 admin@gateway:2222,inner-gateway。
+	// 这些跳板机使用本服务器的认证信息，在JumpServer之后连接。
+	ProxyJump string `gorm:"size:255"`
+
+	// AgentTokenHash 推送数据的agent使用的token的SHA256（十六进制），为空时不接受该服务器的agent推送的数据。
+	AgentTokenHash string `gorm:"size:64"`
 
 	Accounts []Account `gorm:"foreignKey:Host,Port"`
 }
+
+// GetAuthType 返回该服务器的认证方式，兼容AuthType为空的旧数据。
+func (s *Server) GetAuthType() SSHAuthType {
+	if s.AuthType == "" {
+		return SSHAuthTypePassword
+	}
+	return s.AuthType
+}
@@ -0,0 +1,30 @@
+package da_models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// TerminalSession 管理员通过网页终端在服务器上打开的一次终端会话。
+// 终端的输出以asciicast v2格式录制在RecordingPath中，用户的输入不会被录制（其中可能有密码）。
+// 会话结束之前EndedAt为nil；ExitStatus 为shell的退出码，没有正常退出时为-1，原因在Error中。
+type TerminalSession struct {
+	gorm.Model
+
+	UserID uint `gorm:"index:idx_terminal_sessions_user_id"`
+
+	Host    string `gorm:"index:idx_terminal_sessions_host_port,priority:1;size:20"`
+	Port    uint   `gorm:"index:idx_terminal_sessions_host_port,priority:2"`
+	Account string `gorm:"size:50"`
+
+	Cols          int
+	Rows          int
+	StartedAt     time.Time
+	EndedAt       *time.Time
+	DurationMs    int64
+	ExitStatus    int
+	Error         string `gorm:"type:text"`
+	RecordingPath string `gorm:"size:255"`
+	// RecordingSize 录像文件的大小，单位为字节。
+	RecordingSize int64
+}
@@ -13,10 +13,15 @@ var db *gorm.DB
 
 func InitMySQL() {
 	confParam := config.GetConfig().MySqlConfig
-	var err error
 	DSN := fmt.Sprintf("%s:%s@tcp(%s)/%s?parseTime=true", confParam.UserName, confParam.Pwd, confParam.Addr, confParam.DBName)
 	log.Printf("MySQL Connection Establishing... DSN=[%s]", DSN)
-	db, err = gorm.Open(mysql.Open(DSN))
+	InitWithDialector(mysql.Open(DSN))
+}
+
+// InitWithDialector 使用指定的数据库驱动建立连接并迁移表结构。测试中可以使用它连接sqlite之类的本地数据库。
+func InitWithDialector(dialector gorm.Dialector) {
+	var err error
+	db, err = gorm.Open(dialector)
 	if err != nil {
 		panic(err)
 	}
@@ -32,6 +37,22 @@ func InitMySQL() {
 	if err != nil {
 		panic(err)
 	}
+	err = db.AutoMigrate(&da_models.Script{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&da_models.ScriptRun{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&da_models.TerminalSession{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&da_models.CommandAudit{})
+	if err != nil {
+		panic(err)
+	}
 }
 
 func GetDB() *gorm.DB {
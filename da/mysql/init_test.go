@@ -4,12 +4,14 @@ import (
 	"ServerServing/config"
 	"ServerServing/da/mysql/da_models"
 	"ServerServing/util"
+	"ServerServing/util/testutil"
 	"strconv"
 	"testing"
 )
 
 func TestInitMySQL(t *testing.T) {
-	config.InitConfigWithFile("/Users/purchaser/go/src/ServerServing/config.yml", "dev")
+	configPath := testutil.LiveConfigPath(t, "连接真实MySQL")
+	config.InitConfigWithFile(configPath, "dev")
 	InitMySQL()
 	selectServerAndAccounts(t)
 	// initServer(t)
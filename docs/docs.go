@@ -31,15 +31,43 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/servers/": {
+        "/api/v1/cmd_scripts/": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server"
+                    "cmd_script"
                 ],
-                "summary": "查询多个server信息。",
+                "summary": "列出生效中的命令脚本，以及每个脚本的来源。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.CmdScriptsListResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/command_audits/": {
+            "get": {
+                "description": "可以按服务器（host与port）、触发命令的用户、脚本名以及开始执行的时间范围（Unix时间戳，包含两端）筛选。\n参数中的密码等敏感信息已经被隐藏，命令的输出只保存了摘要与长度。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "command_audit"
+                ],
+                "summary": "查询平台在服务器上执行过的命令的审计记录，按开始执行的时间倒序排列，只有管理员可以查询。",
                 "parameters": [
                     {
                         "type": "integer",
@@ -48,77 +76,75 @@ var doc = `{
                     },
                     {
                         "type": "string",
-                        "name": "keyword",
+                        "name": "host",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "name": "size",
+                        "name": "port",
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "WithAccounts 加载账户信息的参数，为nil则不加载",
-                        "name": "with_accounts",
+                        "type": "string",
+                        "name": "script",
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "WithBackupDirInfo 指定是否加载用户备份文件夹的信息。",
-                        "name": "with_backup_dir_info",
+                        "type": "integer",
+                        "name": "size",
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "WithCPUMemProcessesUsage 指定是否加载CPU，内存，进程的使用信息。",
-                        "name": "with_cmp_usages",
+                        "type": "integer",
+                        "name": "started_from",
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "WithGPUUsages 指定是否加载GPU的使用信息。",
-                        "name": "with_gpu_usages",
+                        "type": "integer",
+                        "name": "started_to",
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "WithHardwareInfo 指定是否加载硬件的元信息",
-                        "name": "with_hardware_info",
+                        "type": "integer",
+                        "name": "user_id",
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "WithRemoteAccessUsages 指定是否加载正在远程登录这台服务器的用户信息。",
-                        "name": "with_remote_access_usages",
-                        "in": "query"
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerInfosResponse"
+                            "$ref": "#/definitions/internal_models.CommandAuditsInfosResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/v1/scripts/": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server"
+                    "script"
                 ],
-                "summary": "创建server。",
+                "summary": "查询脚本目录中的脚本列表。",
                 "parameters": [
                     {
-                        "description": "serverCreateRequest",
-                        "name": "serverCreateRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_models.ServerCreateRequest"
-                        }
+                        "type": "integer",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
                     },
                     {
                         "type": "string",
@@ -131,27 +157,27 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerCreateResponse"
+                            "$ref": "#/definitions/internal_models.ScriptsInfosResponse"
                         }
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server"
+                    "script"
                 ],
-                "summary": "删除server。",
+                "summary": "在脚本目录中创建一个脚本。脚本使用命令模板格式，开头的# @param注释声明参数，正文中使用{{name}}引用参数，其它的{{}}原样保留。",
                 "parameters": [
                     {
-                        "description": "serverDeleteRequest",
-                        "name": "serverDeleteRequest",
+                        "description": "scriptsCreateRequest",
+                        "name": "scriptsCreateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerDeleteRequest"
+                            "$ref": "#/definitions/internal_models.ScriptsCreateRequest"
                         }
                     },
                     {
@@ -165,86 +191,114 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerDeleteResponse"
+                            "$ref": "#/definitions/internal_models.ScriptsCreateResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/servers/accounts": {
-            "put": {
+        "/api/v1/scripts/runs": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server_account"
+                    "script"
                 ],
-                "summary": "更新，恢复一个服务器的账号。",
+                "summary": "查询脚本的执行记录，非管理员只能看到自己的执行记录。",
                 "parameters": [
                     {
-                        "description": "serverAccountUpdateRequest",
-                        "name": "serverAccountUpdateRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountUpdateRequest"
-                        }
+                        "type": "integer",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "port",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "script_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountUpdateResponse"
+                            "$ref": "#/definitions/internal_models.ScriptRunsInfosResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/v1/scripts/runs/{id}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server_account"
+                    "script"
                 ],
-                "summary": "创建一个服务器的sudo账号。",
+                "summary": "查询一条脚本的执行记录，非管理员只能查看自己的执行记录。",
                 "parameters": [
                     {
-                        "description": "serverAccountCreateRequest",
-                        "name": "serverAccountCreateRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountCreateRequest"
-                        }
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountCreateResponse"
+                            "$ref": "#/definitions/internal_models.ScriptRunsInfoResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/scripts/{id}": {
+            "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server_account"
+                    "script"
                 ],
-                "summary": "删除一个服务器的账号。",
+                "summary": "查询脚本目录中的一个脚本。",
                 "parameters": [
                     {
-                        "description": "serverAccountDeleteRequest",
-                        "name": "serverAccountDeleteRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountDeleteRequest"
-                        }
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
@@ -257,122 +311,198 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountDeleteResponse"
+                            "$ref": "#/definitions/internal_models.ScriptsInfoResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/servers/accounts/backupDir": {
-            "get": {
+            },
+            "put": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server_account"
+                    "script"
                 ],
-                "summary": "获取一个账户的backup文件夹的相关信息",
+                "summary": "修改脚本目录中的一个脚本。",
                 "parameters": [
                     {
-                        "type": "string",
-                        "name": "account_name",
-                        "in": "query"
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "string",
-                        "name": "host",
-                        "in": "query"
+                        "description": "scriptsUpdateRequest",
+                        "name": "scriptsUpdateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ScriptsUpdateRequest"
+                        }
                     },
                     {
-                        "type": "integer",
-                        "name": "port",
-                        "in": "query"
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerAccountBackupDirResponse"
+                            "$ref": "#/definitions/internal_models.ScriptsUpdateResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/servers/connections/{host}/{port}": {
-            "get": {
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server"
+                    "script"
                 ],
-                "summary": "测试连通性",
+                "summary": "删除脚本目录中的一个脚本，它的执行记录仍然保留。",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "host",
-                        "name": "host",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "integer",
-                        "description": "port",
-                        "name": "port",
+                        "description": "id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "name": "account_name",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "name": "account_pwd",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "name": "os_type",
-                        "in": "query"
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerConnectionTestResponse"
+                            "$ref": "#/definitions/internal_models.ScriptsDeleteResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/servers/{host}/{port}": {
-            "get": {
+        "/api/v1/scripts/{id}/runs": {
+            "post": {
+                "description": "每次执行都会保存执行记录。执行失败时（包括参数有误、连接服务器失败、命令返回非0的退出码）同样返回执行记录，失败原因在result.error中。",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "server"
+                    "script"
                 ],
-                "summary": "查询server信息。",
+                "summary": "在服务器上执行脚本目录中的一个脚本，需要脚本要求的角色，并且该服务器在脚本允许的服务器中。",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "host",
-                        "name": "host",
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "port",
-                        "name": "port",
+                        "description": "scriptsRunRequest",
+                        "name": "scriptsRunRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ScriptsRunRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ScriptsRunResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/scripts/{id}/runs/stream": {
+            "post": {
+                "description": "output事件的内容为internal_models.CommandOutputLine。最后推送exit事件，内容与执行脚本接口的响应相同，其中result.exit_status为脚本的退出码。\n长时间没有输出时会定期发送以冒号开头的注释行。",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "script"
+                ],
+                "summary": "与执行脚本相同，但以Server-Sent Events流式返回，脚本每输出一行就推送一个output事件。",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "description": "scriptsRunRequest",
+                        "name": "scriptsRunRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ScriptsRunRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ScriptsRunResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "查询多个server信息。",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "keyword",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
                     {
                         "type": "boolean",
                         "description": "WithAccounts 加载账户信息的参数，为nil则不加载",
@@ -391,6 +521,12 @@ var doc = `{
                         "name": "with_cmp_usages",
                         "in": "query"
                     },
+                    {
+                        "type": "boolean",
+                        "description": "WithFilesystemUsages 指定是否加载已挂载的文件系统的空间与inode使用信息。",
+                        "name": "with_fs_usages",
+                        "in": "query"
+                    },
                     {
                         "type": "boolean",
                         "description": "WithGPUUsages 指定是否加载GPU的使用信息。",
@@ -414,41 +550,27 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerInfoResponse"
+                            "$ref": "#/definitions/internal_models.ServerInfosResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "server"
                 ],
-                "summary": "更新服务器数据",
+                "summary": "创建server。",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "host",
-                        "name": "host",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "port",
-                        "name": "port",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "serverUpdateRequest",
-                        "name": "serverUpdateRequest",
+                        "description": "serverCreateRequest",
+                        "name": "serverCreateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerUpdateRequest"
+                            "$ref": "#/definitions/internal_models.ServerCreateRequest"
                         }
                     },
                     {
@@ -462,46 +584,63 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.ServerUpdateResponse"
+                            "$ref": "#/definitions/internal_models.ServerCreateResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/sessions/": {
-            "get": {
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "session"
+                    "server"
+                ],
+                "summary": "删除server。",
+                "parameters": [
+                    {
+                        "description": "serverDeleteRequest",
+                        "name": "serverDeleteRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerDeleteRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
                 ],
-                "summary": "检查登录状态。",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.SessionsCheckResponse"
+                            "$ref": "#/definitions/internal_models.ServerDeleteResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/v1/servers/accounts": {
+            "put": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "session"
+                    "server_account"
                 ],
-                "summary": "创建session。（登录）",
+                "summary": "更新，恢复一个服务器的账号。",
                 "parameters": [
                     {
-                        "description": "createRequest",
-                        "name": "sessionsCreateRequest",
+                        "description": "serverAccountUpdateRequest",
+                        "name": "serverAccountUpdateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_models.SessionsCreateRequest"
+                            "$ref": "#/definitions/internal_models.ServerAccountUpdateRequest"
                         }
                     }
                 ],
@@ -509,27 +648,27 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.SessionsCreateResponse"
+                            "$ref": "#/definitions/internal_models.ServerAccountUpdateResponse"
                         }
                     }
                 }
             },
-            "delete": {
+            "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "session"
+                    "server_account"
                 ],
-                "summary": "退出session。（退出登录）",
+                "summary": "创建一个服务器的sudo账号。",
                 "parameters": [
                     {
-                        "description": "destroyRequest",
-                        "name": "sessionsDestroyRequest",
+                        "description": "serverAccountCreateRequest",
+                        "name": "serverAccountCreateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_models.SessionsDestroyRequest"
+                            "$ref": "#/definitions/internal_models.ServerAccountCreateRequest"
                         }
                     }
                 ],
@@ -537,67 +676,69 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.SessionsDestroyResponse"
+                            "$ref": "#/definitions/internal_models.ServerAccountCreateResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/test/error_handler": {
-            "get": {
+            },
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "test"
+                    "server_account"
                 ],
-                "summary": "test error handler",
-                "responses": {
-                    "200": {
-                        "description": ""
+                "summary": "删除一个服务器的账号。",
+                "parameters": [
+                    {
+                        "description": "serverAccountDeleteRequest",
+                        "name": "serverAccountDeleteRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerAccountDeleteRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
-                }
-            }
-        },
-        "/api/v1/test/ping": {
-            "get": {
-                "produces": [
-                    "application/json"
                 ],
-                "tags": [
-                    "test"
-                ],
-                "summary": "ping",
                 "responses": {
                     "200": {
-                        "description": ""
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerAccountDeleteResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/users/": {
+        "/api/v1/servers/accounts/backupDir": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "server_account"
                 ],
-                "summary": "获取多个用户信息，可以添加关键字对姓名搜索。",
+                "summary": "获取一个账户的backup文件夹的相关信息",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "name": "from",
+                        "type": "string",
+                        "name": "account_name",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "name": "searchKeyword",
+                        "name": "host",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "name": "size",
+                        "name": "port",
                         "in": "query"
                     }
                 ],
@@ -605,54 +746,121 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.UsersInfosResponse"
+                            "$ref": "#/definitions/internal_models.ServerAccountBackupDirResponse"
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/v1/servers/accounts/stream": {
+            "delete": {
+                "description": "备份很大的home目录可能需要很长时间，期间会定期发送以冒号开头的注释行。最后推送exit事件，内容与删除账号的接口的响应相同。",
                 "produces": [
-                    "application/json"
+                    "text/event-stream"
                 ],
                 "tags": [
-                    "user"
+                    "server_account"
                 ],
-                "summary": "注册用户",
+                "summary": "与删除一个服务器的账号相同，但以Server-Sent Events流式返回，执行的命令每输出一行就推送一个output事件。",
                 "parameters": [
                     {
-                        "description": "userCreateRequest",
-                        "name": "userCreateRequest",
+                        "description": "serverAccountDeleteRequest",
+                        "name": "serverAccountDeleteRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_models.UsersCreateRequest"
+                            "$ref": "#/definitions/internal_models.ServerAccountDeleteRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.UsersCreateResponse"
+                            "$ref": "#/definitions/internal_models.ServerAccountDeleteResponse"
                         }
                     }
                 }
             }
         },
-        "/api/v1/users/{id}": {
-            "get": {
+        "/api/v1/servers/agent_reports/{host}/{port}": {
+            "post": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "server"
                 ],
-                "summary": "获取单个用户信息",
+                "summary": "服务器上运行的agent推送采集到的数据，使用agent token认证。",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "integer",
-                        "description": "id",
-                        "name": "id",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "agentReport",
+                        "name": "agentReport",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server_executor.AgentReport"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "agent token",
+                        "name": "X-Agent-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerAgentReportResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/agent_tokens/{host}/{port}": {
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "为服务器生成新的agent token，之前的token立即失效。token只在本次返回，之后无法再查看。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
                         "in": "path",
                         "required": true
                     },
@@ -667,175 +875,2371 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.UsersInfoResponse"
+                            "$ref": "#/definitions/internal_models.ServerAgentTokenRotateResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "delete": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "server"
                 ],
-                "summary": "修改用户信息",
+                "summary": "不再接受服务器上的agent推送的数据。",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "integer",
-                        "description": "id",
-                        "name": "id",
+                        "description": "port",
+                        "name": "port",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "updateRequest",
-                        "name": "updateRequest",
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerAgentTokenRevokeResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/connections/{host}/{port}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "测试连通性",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "account_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "account_pwd",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "admin_agent_socket",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "admin_private_key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "admin_private_key_passphrase",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "auth_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "jump_server_host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "jump_server_port",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "os_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "proxy_jump",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerConnectionTestResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/fan_out": {
+            "post": {
+                "description": "服务器通过servers明确指定，或者通过keyword搜索（为空字符串时为全部服务器）。每台服务器的结果互不影响，响应中按服务器以及按相同的结果汇总。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "在多台服务器上同时执行同一个命令或者脚本目录中的脚本。直接执行命令需要管理员权限。",
+                "parameters": [
+                    {
+                        "description": "serverFanOutRequest",
+                        "name": "serverFanOutRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_models.UsersUpdateRequest"
+                            "$ref": "#/definitions/internal_models.ServerFanOutRequest"
                         }
                     },
                     {
                         "type": "string",
                         "description": "x-token",
                         "name": "x-token",
-                        "in": "header",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFanOutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/fan_out/stream": {
+            "post": {
+                "description": "output事件的内容为internal_models.CommandOutputLine，其中的host与port表示是哪台服务器的输出。最后推送exit事件，内容与在多台服务器上同时执行的接口的响应相同，其中包含每台服务器的退出码。",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "与在多台服务器上同时执行相同，但以Server-Sent Events流式返回，每台服务器每输出一行就推送一个output事件。",
+                "parameters": [
+                    {
+                        "description": "serverFanOutRequest",
+                        "name": "serverFanOutRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFanOutRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFanOutResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/files/{host}/{port}": {
+            "get": {
+                "description": "文件操作通过以root身份运行的sftp-server完成，可以查看任意目录，例如/backup或者某个用户的home目录。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "列出服务器上某个文件夹中的文件，包括大小、所有者、权限与修改时间，只有管理员可以查看。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_models.UsersUpdateResponse"
+                            "$ref": "#/definitions/internal_models.ServerFilesInfosResponse"
                         }
                     }
                 }
+            },
+            "delete": {
+                "description": "recursive为false时只能删除文件与空文件夹；为true时删除整个文件夹，其中的符号链接不会被跟随。/、/etc等系统目录不允许删除。",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "删除服务器上的文件或文件夹，只有管理员可以删除。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "recursive",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesDeleteResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/files/{host}/{port}/content": {
+            "get": {
+                "description": "开始传输之前出错时返回JSON格式的错误；传输过程中出错时连接会被中断，响应的长度小于Content-Length。",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "下载服务器上的文件，文件内容以流的形式直接写入响应，只能下载普通文件，只有管理员可以下载。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "file content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "已经存在的文件会被替换。内容先写入同一文件夹下的临时文件，上传完成后再替换目标文件。\n新文件的所有者为root，权限为perm（默认为0644），需要时可以再修改所有者。",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "上传文件到服务器，请求体即为文件内容，以流的形式写入服务器，只有管理员可以上传。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "perm",
+                        "in": "query"
+                    },
+                    {
+                        "description": "file content",
+                        "name": "content",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesUploadResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/files/{host}/{port}/owner": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "修改服务器上文件或文件夹的所有者，组为该账户的主组，不会递归修改，只有管理员可以修改。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "serverFilesChownRequest",
+                        "name": "serverFilesChownRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesChownRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesChownResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/files/{host}/{port}/perm": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "修改服务器上文件或文件夹的权限，不会递归修改，只有管理员可以修改。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "serverFilesChmodRequest",
+                        "name": "serverFilesChmodRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesChmodRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesChmodResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/files/{host}/{port}/stat": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server_file"
+                ],
+                "summary": "获取服务器上某个文件或文件夹的信息，符号链接返回链接本身的信息，只有管理员可以查看。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "name": "path",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerFilesStatResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/host_keys/{host}/{port}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "查看服务器记录的host key指纹，以及服务器当前提供的指纹。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerHostKeyInfoResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "重新接受服务器当前提供的host key。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "serverHostKeyAcceptRequest",
+                        "name": "serverHostKeyAcceptRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerHostKeyAcceptRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerHostKeyAcceptResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/servers/terminals/{host}/{port}": {
+            "get": {
+                "description": "浏览器的WebSocket无法设置请求头，可以通过x_token查询参数传递token。\n前端发送JSON格式的文本消息：{\"type\":\"input\",\"data\":\"ls\\r\"}为输入，{\"type\":\"resize\",\"cols\":120,\"rows\":40}为修改终端大小。\n服务器以二进制消息原样发送终端的输出，可以直接写入xterm.js。终端结束时发送一条{\"type\":\"exit\",\"session_id\":1,\"exit_status\":0,\"error\":\"\"}文本消息后关闭连接。\n终端的输出会被录制下来，可以通过终端会话的接口回放。",
+                "tags": [
+                    "terminal"
+                ],
+                "summary": "通过WebSocket打开服务器上的一个终端，使用服务器的管理员账户登录，只有管理员可以使用。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "name": "cols",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "rows",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x_token",
+                        "name": "x_token",
+                        "in": "query"
+                    }
+                ]
+            }
+        },
+        "/api/v1/servers/{host}/{port}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "查询server信息。",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithAccounts 加载账户信息的参数，为nil则不加载",
+                        "name": "with_accounts",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithBackupDirInfo 指定是否加载用户备份文件夹的信息。",
+                        "name": "with_backup_dir_info",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithCPUMemProcessesUsage 指定是否加载CPU，内存，进程的使用信息。",
+                        "name": "with_cmp_usages",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithFilesystemUsages 指定是否加载已挂载的文件系统的空间与inode使用信息。",
+                        "name": "with_fs_usages",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithGPUUsages 指定是否加载GPU的使用信息。",
+                        "name": "with_gpu_usages",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithHardwareInfo 指定是否加载硬件的元信息",
+                        "name": "with_hardware_info",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "WithRemoteAccessUsages 指定是否加载正在远程登录这台服务器的用户信息。",
+                        "name": "with_remote_access_usages",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerInfoResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "更新服务器数据",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "host",
+                        "name": "host",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "port",
+                        "name": "port",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "serverUpdateRequest",
+                        "name": "serverUpdateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.ServerUpdateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sessions/": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "检查登录状态。",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.SessionsCheckResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "创建session。（登录）",
+                "parameters": [
+                    {
+                        "description": "createRequest",
+                        "name": "sessionsCreateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.SessionsCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.SessionsCreateResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "退出session。（退出登录）",
+                "parameters": [
+                    {
+                        "description": "destroyRequest",
+                        "name": "sessionsDestroyRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.SessionsDestroyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.SessionsDestroyResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/terminal_sessions/": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "terminal"
+                ],
+                "summary": "查询终端会话列表，只有管理员可以查询。",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "port",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.TerminalSessionsInfosResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/terminal_sessions/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "terminal"
+                ],
+                "summary": "查询一个终端会话，只有管理员可以查询。",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.TerminalSessionsInfoResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/terminal_sessions/{id}/recording": {
+            "get": {
+                "produces": [
+                    "application/x-asciicast"
+                ],
+                "tags": [
+                    "terminal"
+                ],
+                "summary": "下载终端会话的录像，格式为asciicast v2，可以使用asciinema-player回放，只有管理员可以下载。",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "asciicast v2",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/test/error_handler": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "test"
+                ],
+                "summary": "test error handler",
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/api/v1/test/ping": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "test"
+                ],
+                "summary": "ping",
+                "responses": {
+                    "200": {
+                        "description": ""
+                    }
+                }
+            }
+        },
+        "/api/v1/users/": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "获取多个用户信息，可以添加关键字对姓名搜索。",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "searchKeyword",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.UsersInfosResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "注册用户",
+                "parameters": [
+                    {
+                        "description": "userCreateRequest",
+                        "name": "userCreateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.UsersCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.UsersCreateResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "获取单个用户信息",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.UsersInfoResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "修改用户信息",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "updateRequest",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.UsersUpdateRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "x-token",
+                        "name": "x-token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_models.UsersUpdateResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "err.APIErr": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "stable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "gorm.DeletedAt": {
+            "type": "object",
+            "properties": {
+                "time": {
+                    "type": "string"
+                },
+                "valid": {
+                    "description": "Valid is true if Time is not NULL",
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_models.CmdScript": {
+            "type": "object",
+            "properties": {
+                "dir": {
+                    "type": "string"
+                },
+                "location": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CmdScriptParam"
+                    }
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.CmdScriptParam": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.CmdScriptsListResponse": {
+            "type": "object",
+            "properties": {
+                "scripts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CmdScript"
+                    }
+                }
+            }
+        },
+        "internal_models.CommandAudit": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "err_code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "exit_status": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "output_digest": {
+                    "type": "string"
+                },
+                "output_size": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "script": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.CommandAuditsInfosResponse": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandAudit"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.CommandResult": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "description": "执行耗时，单位为毫秒。",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "执行失败时的原因，成功时为空。",
+                    "type": "string"
+                },
+                "exit_status": {
+                    "description": "退出码。命令没有正常退出时（例如超时被终止、连接断开）为-1。",
+                    "type": "integer"
+                },
+                "script": {
+                    "description": "脚本名，直接执行的命令则为命令本身。",
+                    "type": "string"
+                },
+                "started_at": {
+                    "description": "开始执行的时间。",
+                    "type": "string"
+                },
+                "stderr": {
+                    "description": "标准错误输出。",
+                    "type": "string"
+                },
+                "stdout": {
+                    "description": "标准输出。",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.Script": {
+            "type": "object",
+            "properties": {
+                "allowed_servers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "creator_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CmdScriptParam"
+                    }
+                },
+                "required_role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptRun": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "result": {
+                    "$ref": "#/definitions/internal_models.CommandResult"
+                },
+                "script_body": {
+                    "type": "string"
+                },
+                "script_id": {
+                    "type": "integer"
+                },
+                "script_name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptRunsInfoResponse": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "result": {
+                    "$ref": "#/definitions/internal_models.CommandResult"
+                },
+                "script_body": {
+                    "type": "string"
+                },
+                "script_id": {
+                    "type": "integer"
+                },
+                "script_name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptRunsInfosResponse": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ScriptRun"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptsCreateRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_servers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "body": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required_role": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ScriptsCreateResponse": {
+            "type": "object",
+            "properties": {
+                "allowed_servers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "creator_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CmdScriptParam"
+                    }
+                },
+                "required_role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptsDeleteResponse": {
+            "type": "object"
+        },
+        "internal_models.ScriptsInfoResponse": {
+            "type": "object",
+            "properties": {
+                "allowed_servers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "creator_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CmdScriptParam"
+                    }
+                },
+                "required_role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptsInfosResponse": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.Script"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptsRunRequest": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "host": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptsRunResponse": {
+            "type": "object",
+            "properties": {
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "result": {
+                    "$ref": "#/definitions/internal_models.CommandResult"
+                },
+                "script_body": {
+                    "type": "string"
+                },
+                "script_id": {
+                    "type": "integer"
+                },
+                "script_name": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ScriptsUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_servers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "body": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required_role": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ScriptsUpdateResponse": {
+            "type": "object",
+            "properties": {
+                "allowed_servers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "creator_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CmdScriptParam"
+                    }
+                },
+                "required_role": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ServerAccount": {
+            "type": "object",
+            "properties": {
+                "backup_dir_info": {
+                    "$ref": "#/definitions/internal_models.ServerAccountBackupDirInfo"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "gid": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "not_exists_in_server": {
+                    "type": "boolean"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "pwd": {
+                    "type": "string"
+                },
+                "uid": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerAccountBackupDirInfo": {
+            "type": "object",
+            "properties": {
+                "backup_dir": {
+                    "type": "string"
+                },
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
+                "dir_exists": {
+                    "type": "boolean"
+                },
+                "failed_info": {
+                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "path_exists": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_models.ServerAccountBackupDirResponse": {
+            "type": "object",
+            "properties": {
+                "backup_dir": {
+                    "type": "string"
+                },
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
+                "dir_exists": {
+                    "type": "boolean"
+                },
+                "failed_info": {
+                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "path_exists": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_models.ServerAccountCreateRequest": {
+            "type": "object",
+            "properties": {
+                "account_name": {
+                    "type": "string"
+                },
+                "account_pwd": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ServerAccountCreateResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerAccountDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "account_name": {
+                    "type": "string"
+                },
+                "backup": {
+                    "type": "boolean"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ServerAccountDeleteResponse": {
+            "type": "object",
+            "properties": {
+                "backup_dir": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerAccountInfos": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerAccount"
+                    }
+                },
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
+                "failed_info": {
+                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                },
+                "output": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerAccountUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "account_name": {
+                    "type": "string"
+                },
+                "account_pwd": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "recover": {
+                    "description": "Recover 该账户是从删除的账户中恢复",
+                    "type": "boolean"
+                },
+                "recover_backup": {
+                    "description": "RecoverBackup 指定是否要恢复backup的用户目录文件夹。",
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_models.ServerAccountUpdateResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerAccountUsage": {
+            "type": "object",
+            "properties": {
+                "account_name": {
+                    "type": "string"
+                },
+                "cpu_usage": {
+                    "description": "CPUUsage 与MemUsage为该账户全部进程的CPU利用率与内存利用率之和，单位为%。",
+                    "type": "number"
+                },
+                "gpu_indexes": {
+                    "description": "GPUIndexes 该账户的进程正在使用的GPU编号，从小到大排列。",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "gpu_memory_mib": {
+                    "description": "GPUMemoryMiB 该账户的进程在全部GPU上占用的显存，单位为MiB。",
+                    "type": "number"
+                },
+                "mem_usage": {
+                    "type": "number"
+                },
+                "processes": {
+                    "description": "Processes 该账户的进程数。",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ServerAgentInfo": {
+            "type": "object",
+            "properties": {
+                "collected_at": {
+                    "type": "integer"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "interval_seconds": {
+                    "type": "integer"
+                },
+                "received_at": {
+                    "type": "integer"
+                },
+                "sections": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_models.ServerAgentReportResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerAgentTokenRevokeResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerAgentTokenRotateResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerBasic": {
+            "type": "object",
+            "properties": {
+                "admin_account_name": {
+                    "type": "string"
+                },
+                "admin_account_pwd": {
+                    "type": "string"
+                },
+                "admin_agent_socket": {
+                    "type": "string"
+                },
+                "auth_type": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "host_key_fingerprint": {
+                    "type": "string"
+                },
+                "jump_server_host": {
+                    "type": "string"
+                },
+                "jump_server_port": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "os_pretty_name": {
+                    "type": "string"
+                },
+                "os_release_id": {
+                    "description": "最近一次连接时从/etc/os-release中解析的发行版信息，还没有成功连接过时为空。",
+                    "type": "string"
+                },
+                "os_release_id_like": {
+                    "type": "string"
+                },
+                "os_type": {
+                    "type": "string"
+                },
+                "os_version_id": {
+                    "type": "string"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "proxy_jump": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerCPUHardwareInfo": {
+            "type": "object",
+            "properties": {
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
+                "failed_info": {
+                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                },
+                "info": {
+                    "$ref": "#/definitions/internal_models.ServerCPUs"
+                },
+                "output": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerCPUMemProcessesUsageInfo": {
+            "type": "object",
+            "properties": {
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
+                "cpu_mem_usage": {
+                    "description": "CPUMemUsage 服务器总的CPU，内存使用率。",
+                    "$ref": "#/definitions/internal_models.ServerCPUMemUsage"
+                },
+                "failed_info": {
+                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "process_infos": {
+                    "description": "ProcessInfos 全部进程信息。",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerProcessInfo"
+                    }
+                }
+            }
+        },
+        "internal_models.ServerCPUMemUsage": {
+            "type": "object",
+            "properties": {
+                "cpu_stat": {
+                    "description": "CPUStat 两次采样/proc/stat之间全部核的CPU使用率，CPUStats为每个核的使用率。采样失败时为nil。",
+                    "$ref": "#/definitions/internal_models.ServerCPUStat"
+                },
+                "cpu_stats": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerCPUStat"
+                    }
+                },
+                "load_average": {
+                    "description": "LoadAverage 来自/proc/loadavg的1、5、15分钟平均负载。",
+                    "$ref": "#/definitions/internal_models.ServerLoadAverage"
+                },
+                "mem_total": {
+                    "description": "MemTotal 内存总量，使用字符串固定死",
+                    "type": "string"
+                },
+                "mem_usage": {
+                    "description": "MemUsage 总内存使用（比例：如3600MB/8000MB）",
+                    "type": "number"
+                },
+                "uptime_seconds": {
+                    "description": "UptimeSeconds 来自/proc/uptime的开机时长，单位为秒。",
+                    "type": "number"
+                },
+                "user_cpu_usage": {
+                    "description": "UserProcCPUUsage 记录用户进程的CPU使用率。（总比例）能够采样/proc/stat时与CPUStat.User相同，否则来自top。",
+                    "type": "number"
+                }
             }
-        }
-    },
-    "definitions": {
-        "gorm.DeletedAt": {
+        },
+        "internal_models.ServerCPUStat": {
             "type": "object",
             "properties": {
-                "time": {
+                "cpu": {
+                    "description": "CPU /proc/stat中的名称，全部核为cpu，每个核为cpu0、cpu1等。",
                     "type": "string"
                 },
-                "valid": {
-                    "description": "Valid is true if Time is not NULL",
+                "iowait": {
+                    "type": "number"
+                },
+                "steal": {
+                    "description": "Steal 虚拟机中被宿主机上其它虚拟机占用的时间。",
+                    "type": "number"
+                },
+                "system": {
+                    "description": "System 包含irq与softirq。",
+                    "type": "number"
+                },
+                "total": {
+                    "description": "Total 除idle与iowait之外的全部时间。",
+                    "type": "number"
+                },
+                "user": {
+                    "description": "User 包含nice。",
+                    "type": "number"
+                }
+            }
+        },
+        "internal_models.ServerCPUs": {
+            "type": "object",
+            "properties": {
+                "architecture": {
+                    "description": "Architecture 架构",
+                    "type": "string"
+                },
+                "cores": {
+                    "description": "Cores CPU核数",
+                    "type": "integer"
+                },
+                "model_name": {
+                    "description": "ModelName 如：Intel(R) Xeon(R) CPU E5-2682 v4 @ 2.50GHz",
+                    "type": "string"
+                },
+                "threads_per_core": {
+                    "description": "ThreadsPerCore 每个核心可以跑几个线程",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ServerConnectionTestResponse": {
+            "type": "object",
+            "properties": {
+                "cause": {
+                    "type": "string"
+                },
+                "connected": {
                     "type": "boolean"
                 }
             }
         },
-        "internal_models.ServerAccount": {
+        "internal_models.ServerCreateRequest": {
             "type": "object",
             "properties": {
-                "backup_dir_info": {
-                    "$ref": "#/definitions/internal_models.ServerAccountBackupDirInfo"
+                "admin_account_name": {
+                    "type": "string"
                 },
-                "created_at": {
+                "admin_account_pwd": {
                     "type": "string"
                 },
-                "deleted_at": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                "admin_agent_socket": {
+                    "type": "string"
                 },
-                "gid": {
-                    "type": "integer"
+                "admin_private_key": {
+                    "type": "string"
+                },
+                "admin_private_key_passphrase": {
+                    "type": "string"
+                },
+                "auth_type": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
                 },
                 "host": {
                     "type": "string"
                 },
+                "jump_server_host": {
+                    "type": "string"
+                },
+                "jump_server_port": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
-                "not_exists_in_server": {
-                    "type": "boolean"
+                "os_type": {
+                    "type": "string"
                 },
                 "port": {
                     "type": "integer"
                 },
-                "pwd": {
+                "proxy_jump": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerCreateResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "host": {
                     "type": "string"
                 },
-                "uid": {
+                "port": {
                     "type": "integer"
-                },
-                "updated_at": {
-                    "type": "string"
                 }
             }
         },
-        "internal_models.ServerAccountBackupDirInfo": {
+        "internal_models.ServerDeleteResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerFanOutGroup": {
             "type": "object",
             "properties": {
-                "backup_dir": {
+                "error": {
                     "type": "string"
                 },
-                "dir_exists": {
-                    "type": "boolean"
+                "exit_status": {
+                    "type": "integer"
                 },
-                "failed_info": {
-                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                "hosts": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "output": {
+                "stderr": {
                     "type": "string"
                 },
-                "path_exists": {
+                "stdout": {
+                    "type": "string"
+                },
+                "succeeded": {
                     "type": "boolean"
                 }
             }
         },
-        "internal_models.ServerAccountBackupDirResponse": {
+        "internal_models.ServerFanOutHostResult": {
             "type": "object",
             "properties": {
-                "backup_dir": {
+                "host": {
                     "type": "string"
                 },
-                "dir_exists": {
-                    "type": "boolean"
+                "name": {
+                    "type": "string"
                 },
-                "failed_info": {
-                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                "port": {
+                    "type": "integer"
                 },
-                "output": {
-                    "type": "string"
+                "result": {
+                    "$ref": "#/definitions/internal_models.CommandResult"
                 },
-                "path_exists": {
+                "script_run_id": {
+                    "type": "integer"
+                },
+                "succeeded": {
                     "type": "boolean"
                 }
             }
         },
-        "internal_models.ServerAccountCreateRequest": {
+        "internal_models.ServerFanOutRequest": {
             "type": "object",
             "properties": {
-                "account_name": {
-                    "type": "string"
+                "args": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "account_pwd": {
+                "command": {
                     "type": "string"
                 },
-                "host": {
+                "keyword": {
                     "type": "string"
                 },
-                "port": {
+                "parallelism": {
+                    "type": "integer"
+                },
+                "script_id": {
+                    "type": "integer"
+                },
+                "servers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerFanOutTarget"
+                    }
+                },
+                "timeout_seconds": {
                     "type": "integer"
                 }
             }
         },
-        "internal_models.ServerAccountCreateResponse": {
-            "type": "object"
-        },
-        "internal_models.ServerAccountDeleteRequest": {
+        "internal_models.ServerFanOutResponse": {
             "type": "object",
             "properties": {
-                "account_name": {
-                    "type": "string"
+                "failed": {
+                    "type": "integer"
                 },
-                "backup": {
-                    "type": "boolean"
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerFanOutGroup"
+                    }
+                },
+                "hosts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerFanOutHostResult"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
                 },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.ServerFanOutTarget": {
+            "type": "object",
+            "properties": {
                 "host": {
                     "type": "string"
                 },
@@ -844,272 +3248,372 @@ var doc = `{
                 }
             }
         },
-        "internal_models.ServerAccountDeleteResponse": {
+        "internal_models.ServerFileInfo": {
             "type": "object",
             "properties": {
-                "backup_dir": {
+                "gid": {
+                    "type": "integer"
+                },
+                "is_dir": {
+                    "type": "boolean"
+                },
+                "is_symlink": {
+                    "type": "boolean"
+                },
+                "mod_time": {
+                    "type": "integer"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "name": {
                     "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "perm": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "uid": {
+                    "type": "integer"
                 }
             }
         },
-        "internal_models.ServerAccountInfos": {
+        "internal_models.ServerFilesChmodRequest": {
             "type": "object",
             "properties": {
-                "accounts": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/internal_models.ServerAccount"
-                    }
-                },
-                "failed_info": {
-                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                "path": {
+                    "type": "string"
                 },
-                "output": {
+                "perm": {
                     "type": "string"
                 }
             }
         },
-        "internal_models.ServerAccountUpdateRequest": {
+        "internal_models.ServerFilesChmodResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerFilesChownRequest": {
             "type": "object",
             "properties": {
-                "account_name": {
+                "owner": {
                     "type": "string"
                 },
-                "account_pwd": {
+                "path": {
                     "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerFilesChownResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerFilesDeleteResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerFilesInfosResponse": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerFileInfo"
+                    }
                 },
-                "host": {
+                "path": {
                     "type": "string"
-                },
-                "port": {
-                    "type": "integer"
-                },
-                "recover": {
-                    "description": "Recover 该账户是从删除的账户中恢复",
-                    "type": "boolean"
-                },
-                "recover_backup": {
-                    "description": "RecoverBackup 指定是否要恢复backup的用户目录文件夹。",
-                    "type": "boolean"
                 }
             }
         },
-        "internal_models.ServerAccountUpdateResponse": {
-            "type": "object"
+        "internal_models.ServerFilesStatResponse": {
+            "type": "object",
+            "properties": {
+                "file": {
+                    "$ref": "#/definitions/internal_models.ServerFileInfo"
+                }
+            }
+        },
+        "internal_models.ServerFilesUploadResponse": {
+            "type": "object",
+            "properties": {
+                "file": {
+                    "$ref": "#/definitions/internal_models.ServerFileInfo"
+                }
+            }
         },
-        "internal_models.ServerBasic": {
+        "internal_models.ServerFilesystem": {
             "type": "object",
             "properties": {
-                "admin_account_name": {
-                    "type": "string"
+                "available_bytes": {
+                    "type": "integer"
                 },
-                "admin_account_pwd": {
+                "device": {
                     "type": "string"
                 },
-                "created_at": {
+                "fs_type": {
                     "type": "string"
                 },
-                "deleted_at": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                "highlights": {
+                    "description": "Highlights 该文件系统存放了/、/home或者备份文件夹时，为对应的ServerFilesystemHighlightRoot等值，否则为空。",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "description": {
-                    "type": "string"
+                "inode_use_percent": {
+                    "description": "InodeUsePercent inode使用率，单位为%。",
+                    "type": "number"
                 },
-                "host": {
-                    "type": "string"
+                "inodes_free": {
+                    "type": "integer"
                 },
-                "name": {
-                    "type": "string"
+                "inodes_total": {
+                    "description": "InodesTotal 与InodesUsed、InodesFree为inode的数量，不支持inode统计的文件系统（例如vfat）为nil。",
+                    "type": "integer"
                 },
-                "os_type": {
+                "inodes_used": {
+                    "type": "integer"
+                },
+                "mount_point": {
                     "type": "string"
                 },
-                "port": {
+                "size_bytes": {
+                    "description": "SizeBytes 总空间，UsedBytes与AvailableBytes为已使用与普通用户可用的空间，单位为字节。",
                     "type": "integer"
                 },
-                "updated_at": {
-                    "type": "string"
+                "use_percent": {
+                    "description": "UsePercent 空间使用率，单位为%，与df的Use%相同。",
+                    "type": "number"
+                },
+                "used_bytes": {
+                    "type": "integer"
                 }
             }
         },
-        "internal_models.ServerCPUHardwareInfo": {
+        "internal_models.ServerFilesystemUsageInfo": {
             "type": "object",
             "properties": {
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
                 "failed_info": {
                     "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
                 },
-                "info": {
-                    "$ref": "#/definitions/internal_models.ServerCPUs"
+                "filesystems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerFilesystem"
+                    }
                 },
                 "output": {
                     "type": "string"
                 }
             }
         },
-        "internal_models.ServerCPUMemProcessesUsageInfo": {
+        "internal_models.ServerGPU": {
             "type": "object",
             "properties": {
-                "cpu_mem_usage": {
-                    "description": "CPUMemUsage 服务器总的CPU，内存使用率。",
-                    "$ref": "#/definitions/internal_models.ServerCPUMemUsage"
-                },
-                "failed_info": {
-                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                "bus_id": {
+                    "description": "BusID lspci输出的PCI总线地址，例如17:00.0。",
+                    "type": "string"
                 },
-                "output": {
+                "product": {
+                    "description": "Product 产品名。",
                     "type": "string"
                 },
-                "process_infos": {
-                    "description": "ProcessInfos 全部进程信息。",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/internal_models.ServerProcessInfo"
-                    }
+                "usage": {
+                    "description": "Usage 该GPU当前的使用情况，同时加载了GPU的使用情况并且能够按BusID对应上时才有。",
+                    "$ref": "#/definitions/internal_models.ServerGPUUsage"
                 }
             }
         },
-        "internal_models.ServerCPUMemUsage": {
+        "internal_models.ServerGPUHardwareInfos": {
             "type": "object",
             "properties": {
-                "mem_total": {
-                    "description": "MemTotal 内存总量，使用字符串固定死",
-                    "type": "string"
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
                 },
-                "mem_usage": {
-                    "description": "MemUsage 总内存使用（比例：如3600MB/8000MB）",
-                    "type": "number"
+                "failed_info": {
+                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
                 },
-                "user_cpu_usage": {
-                    "description": "UserProcCPUUsage 记录用户进程的CPU使用率。（总比例）",
-                    "type": "number"
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerGPU"
+                    }
+                },
+                "output": {
+                    "type": "string"
                 }
             }
         },
-        "internal_models.ServerCPUs": {
+        "internal_models.ServerGPUProcess": {
             "type": "object",
             "properties": {
-                "architecture": {
-                    "description": "Architecture 架构",
+                "command_line": {
                     "type": "string"
                 },
-                "cores": {
-                    "description": "Cores CPU核数",
+                "gpu_index": {
+                    "description": "GPUIndex 与ServerGPUUsage.Index一致，按GPUUUID找不到对应的GPU时为nil。",
                     "type": "integer"
                 },
-                "model_name": {
-                    "description": "ModelName 如：Intel(R) Xeon(R) CPU E5-2682 v4 @ 2.50GHz",
+                "gpu_uuid": {
                     "type": "string"
                 },
-                "threads_per_core": {
-                    "description": "ThreadsPerCore 每个核心可以跑几个线程",
-                    "type": "integer"
-                }
-            }
-        },
-        "internal_models.ServerConnectionTestResponse": {
-            "type": "object",
-            "properties": {
-                "cause": {
+                "owner_account_name": {
+                    "description": "OwnerAccountName 与CommandLine来自ps，查询之后进程已经退出时为nil。",
                     "type": "string"
                 },
-                "connected": {
-                    "type": "boolean"
+                "pid": {
+                    "type": "integer"
+                },
+                "used_memory_mib": {
+                    "description": "UsedMemoryMiB 占用的显存，单位为MiB。",
+                    "type": "number"
                 }
             }
         },
-        "internal_models.ServerCreateRequest": {
+        "internal_models.ServerGPUUsage": {
             "type": "object",
             "properties": {
-                "admin_account_name": {
+                "bus_id": {
+                    "description": "BusID nvidia-smi输出的PCI总线地址，例如00000000:17:00.0。",
                     "type": "string"
                 },
-                "admin_account_pwd": {
-                    "type": "string"
+                "fan_speed_percent": {
+                    "description": "FanSpeedPercent 风扇转速，单位为%。被动散热的GPU没有风扇。",
+                    "type": "number"
                 },
-                "description": {
-                    "type": "string"
+                "index": {
+                    "description": "Index nvidia-smi中的GPU编号，即CUDA_VISIBLE_DEVICES使用的编号（默认顺序下）。",
+                    "type": "integer"
                 },
-                "host": {
-                    "type": "string"
+                "memory_total_mib": {
+                    "description": "MemoryTotalMiB 总显存，单位为MiB。",
+                    "type": "number"
+                },
+                "memory_used_mib": {
+                    "description": "MemoryUsedMiB 已使用的显存，单位为MiB。",
+                    "type": "number"
                 },
                 "name": {
                     "type": "string"
                 },
-                "os_type": {
-                    "type": "string"
+                "persistence_mode": {
+                    "description": "PersistenceMode 是否开启了持久模式。",
+                    "type": "boolean"
                 },
-                "port": {
-                    "type": "integer"
-                }
-            }
-        },
-        "internal_models.ServerCreateResponse": {
-            "type": "object"
-        },
-        "internal_models.ServerDeleteRequest": {
-            "type": "object",
-            "properties": {
-                "host": {
-                    "type": "string"
+                "power_draw_w": {
+                    "description": "PowerDrawW 当前功耗，单位为W。",
+                    "type": "number"
                 },
-                "port": {
-                    "type": "integer"
-                }
-            }
-        },
-        "internal_models.ServerDeleteResponse": {
-            "type": "object"
-        },
-        "internal_models.ServerGPU": {
-            "type": "object",
-            "properties": {
-                "product": {
-                    "description": "Product 产品名。",
+                "power_limit_w": {
+                    "description": "PowerLimitW 功耗上限，单位为W。",
+                    "type": "number"
+                },
+                "temperature_c": {
+                    "description": "TemperatureC 温度，单位为摄氏度。",
+                    "type": "number"
+                },
+                "utilization_percent": {
+                    "description": "UtilizationPercent GPU利用率，单位为%。",
+                    "type": "number"
+                },
+                "uuid": {
                     "type": "string"
                 }
             }
         },
-        "internal_models.ServerGPUHardwareInfos": {
+        "internal_models.ServerGPUUsageInfo": {
             "type": "object",
             "properties": {
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
                 "failed_info": {
                     "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
                 },
-                "infos": {
+                "gpus": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/internal_models.ServerGPU"
+                        "$ref": "#/definitions/internal_models.ServerGPUUsage"
                     }
                 },
                 "output": {
                     "type": "string"
+                },
+                "processes": {
+                    "description": "Processes 正在使用GPU的进程，来自nvidia-smi --query-compute-apps。",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerGPUProcess"
+                    }
                 }
             }
         },
-        "internal_models.ServerGPUUsageInfo": {
+        "internal_models.ServerHardwareInfo": {
             "type": "object",
             "properties": {
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
+                "cpu_hardware_info": {
+                    "$ref": "#/definitions/internal_models.ServerCPUHardwareInfo"
+                },
                 "failed_info": {
                     "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
                 },
+                "gpu_hardware_infos": {
+                    "$ref": "#/definitions/internal_models.ServerGPUHardwareInfos"
+                },
                 "output": {
                     "type": "string"
                 }
             }
         },
-        "internal_models.ServerHardwareInfo": {
+        "internal_models.ServerHostKeyAcceptRequest": {
             "type": "object",
             "properties": {
-                "cpu_hardware_info": {
-                    "$ref": "#/definitions/internal_models.ServerCPUHardwareInfo"
+                "fingerprint": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerHostKeyAcceptResponse": {
+            "type": "object"
+        },
+        "internal_models.ServerHostKeyInfoResponse": {
+            "type": "object",
+            "properties": {
+                "matched": {
+                    "type": "boolean"
                 },
-                "failed_info": {
-                    "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
+                "presented_fingerprint": {
+                    "type": "string"
                 },
-                "gpu_hardware_infos": {
-                    "$ref": "#/definitions/internal_models.ServerGPUHardwareInfos"
+                "scan_failed_cause": {
+                    "description": "ScanFailedCause 获取服务器当前的host key失败时的原因。",
+                    "type": "string"
                 },
-                "output": {
+                "stored_fingerprint": {
                     "type": "string"
                 }
             }
@@ -1125,6 +3629,17 @@ var doc = `{
                     "description": "AccountInfos 记录服务器账户信息。",
                     "$ref": "#/definitions/internal_models.ServerAccountInfos"
                 },
+                "account_usages": {
+                    "description": "AccountUsages 按账户汇总的资源占用，由进程信息以及正在使用GPU的进程得到，两者都没有加载时为nil。",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerAccountUsage"
+                    }
+                },
+                "agent": {
+                    "description": "Agent 使用了agent推送的数据时，记录该数据的信息。",
+                    "$ref": "#/definitions/internal_models.ServerAgentInfo"
+                },
                 "basic": {
                     "description": "Basic 基本的Server目录信息",
                     "$ref": "#/definitions/internal_models.ServerBasic"
@@ -1133,6 +3648,10 @@ var doc = `{
                     "description": "CPUMemProcessesUsageInfo CPU，内存，进程的使用资源信息。（Top指令）",
                     "$ref": "#/definitions/internal_models.ServerCPUMemProcessesUsageInfo"
                 },
+                "filesystem_usage_info": {
+                    "description": "FilesystemUsageInfo 已挂载的文件系统的使用情况。",
+                    "$ref": "#/definitions/internal_models.ServerFilesystemUsageInfo"
+                },
                 "hardware_info": {
                     "description": "ServerHardwareInfo 硬件元信息",
                     "$ref": "#/definitions/internal_models.ServerHardwareInfo"
@@ -1142,8 +3661,12 @@ var doc = `{
                     "$ref": "#/definitions/internal_models.ServerRemoteAccessingUsagesInfo"
                 },
                 "server_gpu_usage_info": {
-                    "description": "GPUUsageInfo 当前该Server总的GPU利用率信息。（当前为string，具体待定）",
+                    "description": "GPUUsageInfo 当前该Server每块GPU的使用情况，以及正在使用GPU的进程。",
                     "$ref": "#/definitions/internal_models.ServerGPUUsageInfo"
+                },
+                "status": {
+                    "description": "Status 服务器的连接状态，总是不为空。",
+                    "$ref": "#/definitions/internal_models.ServerStatus"
                 }
             }
         },
@@ -1153,6 +3676,10 @@ var doc = `{
                 "cause_description": {
                     "description": "描述具体原因。",
                     "type": "string"
+                },
+                "failed_command": {
+                    "description": "执行失败的那条命令的结果，包括退出码与标准错误输出。不是因为命令执行失败时为空。",
+                    "$ref": "#/definitions/internal_models.CommandResult"
                 }
             }
         },
@@ -1167,6 +3694,17 @@ var doc = `{
                     "description": "AccountInfos 记录服务器账户信息。",
                     "$ref": "#/definitions/internal_models.ServerAccountInfos"
                 },
+                "account_usages": {
+                    "description": "AccountUsages 按账户汇总的资源占用，由进程信息以及正在使用GPU的进程得到，两者都没有加载时为nil。",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerAccountUsage"
+                    }
+                },
+                "agent": {
+                    "description": "Agent 使用了agent推送的数据时，记录该数据的信息。",
+                    "$ref": "#/definitions/internal_models.ServerAgentInfo"
+                },
                 "basic": {
                     "description": "Basic 基本的Server目录信息",
                     "$ref": "#/definitions/internal_models.ServerBasic"
@@ -1175,6 +3713,10 @@ var doc = `{
                     "description": "CPUMemProcessesUsageInfo CPU，内存，进程的使用资源信息。（Top指令）",
                     "$ref": "#/definitions/internal_models.ServerCPUMemProcessesUsageInfo"
                 },
+                "filesystem_usage_info": {
+                    "description": "FilesystemUsageInfo 已挂载的文件系统的使用情况。",
+                    "$ref": "#/definitions/internal_models.ServerFilesystemUsageInfo"
+                },
                 "hardware_info": {
                     "description": "ServerHardwareInfo 硬件元信息",
                     "$ref": "#/definitions/internal_models.ServerHardwareInfo"
@@ -1184,8 +3726,12 @@ var doc = `{
                     "$ref": "#/definitions/internal_models.ServerRemoteAccessingUsagesInfo"
                 },
                 "server_gpu_usage_info": {
-                    "description": "GPUUsageInfo 当前该Server总的GPU利用率信息。（当前为string，具体待定）",
+                    "description": "GPUUsageInfo 当前该Server每块GPU的使用情况，以及正在使用GPU的进程。",
                     "$ref": "#/definitions/internal_models.ServerGPUUsageInfo"
+                },
+                "status": {
+                    "description": "Status 服务器的连接状态，总是不为空。",
+                    "$ref": "#/definitions/internal_models.ServerStatus"
                 }
             }
         },
@@ -1203,6 +3749,20 @@ var doc = `{
                 }
             }
         },
+        "internal_models.ServerLoadAverage": {
+            "type": "object",
+            "properties": {
+                "load1": {
+                    "type": "number"
+                },
+                "load15": {
+                    "type": "number"
+                },
+                "load5": {
+                    "type": "number"
+                }
+            }
+        },
         "internal_models.ServerProcessInfo": {
             "type": "object",
             "properties": {
@@ -1210,6 +3770,16 @@ var doc = `{
                     "description": "Command 命令",
                     "type": "string"
                 },
+                "command_line": {
+                    "description": "CommandLine 完整的命令行。只有使用GPU的进程才会查询，其它进程为nil。",
+                    "type": "string"
+                },
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
                 "cpu_usage": {
                     "description": "CPU利用率。",
                     "type": "number"
@@ -1217,9 +3787,16 @@ var doc = `{
                 "failed_info": {
                     "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
                 },
-                "gpu_usage": {
-                    "description": "GPU利用率（不一定能查到）",
-                    "type": "string"
+                "gpu_indexes": {
+                    "description": "GPUIndexes 该进程正在使用的GPU编号，没有使用GPU时为空。",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "gpu_memory_mib": {
+                    "description": "GPUMemoryMiB 该进程在全部GPU上占用的显存，单位为MiB，没有使用GPU时为nil。",
+                    "type": "number"
                 },
                 "mem_usage": {
                     "description": "内存利用率",
@@ -1253,6 +3830,12 @@ var doc = `{
         "internal_models.ServerRemoteAccessingUsagesInfo": {
             "type": "object",
             "properties": {
+                "command_results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                },
                 "failed_info": {
                     "$ref": "#/definitions/internal_models.ServerInfoLoadingFailedInfo"
                 },
@@ -1262,7 +3845,27 @@ var doc = `{
                         "$ref": "#/definitions/internal_models.ServerRemoteAccessingAccount"
                     }
                 },
-                "output": {
+                "output": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_models.ServerStatus": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "retry_at": {
+                    "type": "integer"
+                },
+                "since": {
+                    "type": "integer"
+                },
+                "status": {
                     "type": "string"
                 }
             }
@@ -1276,11 +3879,32 @@ var doc = `{
                 "admin_account_pwd": {
                     "type": "string"
                 },
+                "admin_agent_socket": {
+                    "type": "string"
+                },
+                "admin_private_key": {
+                    "type": "string"
+                },
+                "admin_private_key_passphrase": {
+                    "type": "string"
+                },
+                "auth_type": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
+                "jump_server_host": {
+                    "type": "string"
+                },
+                "jump_server_port": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
+                },
+                "proxy_jump": {
+                    "type": "string"
                 }
             }
         },
@@ -1320,6 +3944,108 @@ var doc = `{
         "internal_models.SessionsDestroyResponse": {
             "type": "object"
         },
+        "internal_models.TerminalSession": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string"
+                },
+                "cols": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "ended_at": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "exit_status": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "recording_size": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.TerminalSessionsInfoResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string"
+                },
+                "cols": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "ended_at": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "exit_status": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "recording_size": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_models.TerminalSessionsInfosResponse": {
+            "type": "object",
+            "properties": {
+                "infos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.TerminalSession"
+                    }
+                },
+                "total_count": {
+                    "type": "integer"
+                }
+            }
+        },
         "internal_models.User": {
             "type": "object",
             "properties": {
@@ -1415,6 +4141,248 @@ var doc = `{
         },
         "internal_models.UsersUpdateResponse": {
             "type": "object"
+        },
+        "server_executor.AgentReport": {
+            "type": "object",
+            "properties": {
+                "collected_at": {
+                    "description": "CollectedAt 采集数据的时间（agent所在服务器的时间），Unix时间戳，单位为秒。",
+                    "type": "integer"
+                },
+                "hostname": {
+                    "description": "Hostname agent所在服务器的主机名，只用于展示。",
+                    "type": "string"
+                },
+                "info": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceCollectInfoResp"
+                },
+                "interval_seconds": {
+                    "description": "IntervalSeconds agent推送数据的间隔，用于判断数据是否过期。",
+                    "type": "integer"
+                }
+            }
+        },
+        "server_executor.ExecutorServiceBackupDirsResp": {
+            "type": "object",
+            "properties": {
+                "backupRoot": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "output": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceCPUHardwareResp": {
+            "type": "object",
+            "properties": {
+                "cpu": {
+                    "$ref": "#/definitions/internal_models.ServerCPUs"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceCPUMemProcessesUsagesResp": {
+            "type": "object",
+            "properties": {
+                "cpumemUsage": {
+                    "$ref": "#/definitions/internal_models.ServerCPUMemUsage"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "processInfos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerProcessInfo"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceCollectInfoResp": {
+            "type": "object",
+            "properties": {
+                "accountList": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceGetAccountListResp"
+                },
+                "accountListErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "backupDirs": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceBackupDirsResp"
+                },
+                "backupDirsErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "cpuhardware": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceCPUHardwareResp"
+                },
+                "cpuhardwareErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "cpumemProcessesUsages": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceCPUMemProcessesUsagesResp"
+                },
+                "cpumemProcessesUsagesErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "filesystemUsages": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceFilesystemUsagesResp"
+                },
+                "filesystemUsagesErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "gpuhardware": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceGPUHardwareResp"
+                },
+                "gpuhardwareErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "gpuusages": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceGPUUsagesResp"
+                },
+                "gpuusagesErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                },
+                "remoteAccessInfos": {
+                    "$ref": "#/definitions/server_executor.ExecutorServiceRemoteAccessResp"
+                },
+                "remoteAccessInfosErr": {
+                    "$ref": "#/definitions/err.APIErr"
+                }
+            }
+        },
+        "server_executor.ExecutorServiceFilesystemUsagesResp": {
+            "type": "object",
+            "properties": {
+                "filesystems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerFilesystem"
+                    }
+                },
+                "output": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceGPUHardwareResp": {
+            "type": "object",
+            "properties": {
+                "gpus": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerGPU"
+                    }
+                },
+                "output": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceGPUUsagesResp": {
+            "type": "object",
+            "properties": {
+                "gpus": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerGPUUsage"
+                    }
+                },
+                "output": {
+                    "type": "string"
+                },
+                "processes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerGPUProcess"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceGetAccountListResp": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerAccount"
+                    }
+                },
+                "output": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
+        },
+        "server_executor.ExecutorServiceRemoteAccessResp": {
+            "type": "object",
+            "properties": {
+                "output": {
+                    "type": "string"
+                },
+                "remoteAccessingAccountInfos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.ServerRemoteAccessingAccount"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_models.CommandResult"
+                    }
+                }
+            }
         }
     }
 }`
@@ -5,12 +5,18 @@ import (
 )
 
 const (
-	CodeOK            = 20000
-	CodeStableError   = 20001
-	CodeNotFound      = 40004
-	CodeBadRequest    = 40000
-	CodeForbidden     = 40003
-	CodeInternalError = 50000
+	CodeOK                = 20000
+	CodeStableError       = 20001
+	CodeHostKeyMismatch   = 20002
+	CodeCommandTimeout    = 20003
+	CodeCommandCanceled   = 20004
+	CodeCommandFailed     = 20005
+	CodeSSHAuthFailed     = 20006
+	CodeServerUnreachable = 20007
+	CodeNotFound          = 40004
+	CodeBadRequest        = 40000
+	CodeForbidden         = 40003
+	CodeInternalError     = 50000
 )
 
 var (
@@ -59,6 +65,37 @@ var (
 		Code:    CodeStableError,
 		Stable:  true,
 	}
+	SSHAuthFailedErr = &APIErr{
+		Message: "SSH认证失败！",
+		Code:    CodeSSHAuthFailed,
+		Stable:  true,
+	}
+	// ServerUnreachableErr 网络不通、连接超时，或者服务器处于连续连接失败之后的冷却期。
+	ServerUnreachableErr = &APIErr{
+		Message: "无法连接到该服务器！",
+		Code:    CodeServerUnreachable,
+		Stable:  true,
+	}
+	HostKeyMismatchErr = &APIErr{
+		Message: "服务器的host key与记录的指纹不一致！可能存在中间人攻击，如果确认服务器重装过，请管理员重新接受新的host key。",
+		Code:    CodeHostKeyMismatch,
+		Stable:  true,
+	}
+	CommandTimeoutErr = &APIErr{
+		Message: "在服务器上执行命令超时！",
+		Code:    CodeCommandTimeout,
+		Stable:  true,
+	}
+	CommandCanceledErr = &APIErr{
+		Message: "请求已被取消，在服务器上执行的命令已被终止！",
+		Code:    CodeCommandCanceled,
+		Stable:  true,
+	}
+	CommandFailedErr = &APIErr{
+		Message: "在服务器上执行的命令返回了非0的退出码！",
+		Code:    CodeCommandFailed,
+		Stable:  true,
+	}
 	BackupDirNotExists = &APIErr{
 		Message: "备份用户文件夹时，该用户的home目录文件夹不存在！",
 		Code:    CodeStableError,
@@ -89,6 +126,16 @@ var (
 		Code:    CodeStableError,
 		Stable:  true,
 	}
+	FileNotExistsErr = &APIErr{
+		Message: "服务器上的文件或文件夹不存在！",
+		Code:    CodeStableError,
+		Stable:  true,
+	}
+	FileOperationErr = &APIErr{
+		Message: "在服务器上操作文件失败！",
+		Code:    CodeStableError,
+		Stable:  true,
+	}
 )
 
 type APIErr struct {
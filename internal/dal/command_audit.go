@@ -0,0 +1,62 @@
+package dal
+
+import (
+	"ServerServing/da/mysql"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+type CommandAuditDal struct{}
+
+func GetCommandAuditDal() CommandAuditDal {
+	return CommandAuditDal{}
+}
+
+// CommandAuditCond 查询审计记录的条件。cond中不为零值的字段（UserID、Host、Port、Script）需要相等，
+// StartedFrom 与 StartedTo 不为nil时，按命令开始执行的时间筛选（包含两端）。
+type CommandAuditCond struct {
+	Cond        *daModels.CommandAudit
+	StartedFrom *time.Time
+	StartedTo   *time.Time
+}
+
+// Create 保存一条审计记录。
+func (s CommandAuditDal) Create(audit *daModels.CommandAudit) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(&daModels.CommandAudit{}).Create(audit)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("保存命令审计记录失败！出错信息=[%v]", res.Error)
+	}
+	return nil
+}
+
+// List 按cond筛选审计记录，按命令开始执行的时间倒序排列。
+func (s CommandAuditDal) List(cond *CommandAuditCond, from, size uint) ([]*daModels.CommandAudit, uint, *SErr.APIErr) {
+	log.Printf("CommandAudits List, UserID=[%d], Host=[%s], Port=[%d], Script=[%s], StartedFrom=[%v], StartedTo=[%v], from=[%d], size=[%d]",
+		cond.Cond.UserID, cond.Cond.Host, cond.Cond.Port, cond.Cond.Script, cond.StartedFrom, cond.StartedTo, from, size)
+	var audits []*daModels.CommandAudit
+	var count int64
+	db := mysql.GetDB()
+	query := func() *gorm.DB {
+		q := db.Model(&daModels.CommandAudit{}).Where(cond.Cond)
+		if cond.StartedFrom != nil {
+			q = q.Where("started_at >= ?", *cond.StartedFrom)
+		}
+		if cond.StartedTo != nil {
+			q = q.Where("started_at <= ?", *cond.StartedTo)
+		}
+		return q
+	}
+	res := query().Count(&count)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessage(res.Error.Error())
+	}
+	res = query().Order("started_at desc").Order("id desc").Offset(int(from)).Limit(int(size)).Find(&audits)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessageF("查询命令审计记录时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return audits, uint(count), nil
+}
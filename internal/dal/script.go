@@ -0,0 +1,158 @@
+package dal
+
+import (
+	"ServerServing/da/mysql"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+)
+
+type ScriptDal struct{}
+
+func GetScriptDal() ScriptDal {
+	return ScriptDal{}
+}
+
+// Create 创建一个脚本，脚本名不能与未删除的脚本重复。
+func (s ScriptDal) Create(script *daModels.Script) *SErr.APIErr {
+	db := mysql.GetDB()
+	var sErr *SErr.APIErr
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		if sErr = s.checkNameUnused(tx, script.Name, 0); sErr != nil {
+			return sErr
+		}
+		res := tx.Model(&daModels.Script{}).Create(script)
+		if res.Error != nil {
+			sErr = SErr.InternalErr.CustomMessageF("创建脚本失败！出错信息=[%v]", res.Error)
+			return res.Error
+		}
+		return nil
+	})
+	return sErr
+}
+
+// checkNameUnused 检查脚本名是否已经被除了excludeID以外的其它脚本使用。
+func (s ScriptDal) checkNameUnused(tx *gorm.DB, name string, excludeID uint) *SErr.APIErr {
+	tmpScript := &daModels.Script{}
+	res := tx.Model(&daModels.Script{}).Where("name = ? and id <> ?", name, excludeID).First(tmpScript)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("查询已有的脚本时出错！数据库出错=[%v]", res.Error)
+	}
+	return SErr.InvalidParamErr.CustomMessageF("脚本名%s已存在！", name)
+}
+
+// Update 更新脚本的全部可修改字段，调用者需要保证该脚本存在。
+func (s ScriptDal) Update(ID uint, script *daModels.Script) *SErr.APIErr {
+	db := mysql.GetDB()
+	var sErr *SErr.APIErr
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		if sErr = s.checkNameUnused(tx, script.Name, ID); sErr != nil {
+			return sErr
+		}
+		res := tx.Model(&daModels.Script{}).Where("id = ?", ID).
+			Select("name", "description", "body", "allowed_servers", "required_role", "updated_at").
+			Updates(script)
+		if res.Error != nil {
+			sErr = SErr.InternalErr.CustomMessageF("更新脚本失败！出错信息=[%v]", res.Error)
+			return res.Error
+		}
+		return nil
+	})
+	return sErr
+}
+
+// Delete 删除一个脚本，它的执行记录仍然保留。
+func (s ScriptDal) Delete(ID uint) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Delete(&daModels.Script{}, ID)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessage(res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return SErr.InvalidParamErr.CustomMessageF("要删除的脚本ID=[%d]不存在！", ID)
+	}
+	return nil
+}
+
+// Get 获取一个脚本。
+func (s ScriptDal) Get(ID uint) (*daModels.Script, *SErr.APIErr) {
+	db := mysql.GetDB()
+	script := &daModels.Script{}
+	res := db.Model(&daModels.Script{}).Where("id = ?", ID).First(script)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, SErr.InvalidParamErr.CustomMessageF("找不到该脚本，ID=[%d]", ID)
+	}
+	if res.Error != nil {
+		return nil, SErr.InternalErr.CustomMessageF("查询脚本时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return script, nil
+}
+
+// List 获取脚本列表。
+func (s ScriptDal) List(from, size uint) ([]*daModels.Script, uint, *SErr.APIErr) {
+	log.Printf("Scripts List, from=[%d], size=[%d]", from, size)
+	var scripts []*daModels.Script
+	var count int64
+	db := mysql.GetDB()
+	res := db.Model(&daModels.Script{}).Count(&count)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessage(res.Error.Error())
+	}
+	res = db.Model(&daModels.Script{}).Order("created_at desc").Offset(int(from)).Limit(int(size)).Find(&scripts)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessageF("查询脚本列表时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return scripts, uint(count), nil
+}
+
+type ScriptRunDal struct{}
+
+func GetScriptRunDal() ScriptRunDal {
+	return ScriptRunDal{}
+}
+
+// Create 保存一次脚本的执行记录。
+func (s ScriptRunDal) Create(run *daModels.ScriptRun) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(&daModels.ScriptRun{}).Create(run)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("保存脚本的执行记录失败！出错信息=[%v]", res.Error)
+	}
+	return nil
+}
+
+// Get 获取一条执行记录。
+func (s ScriptRunDal) Get(ID uint) (*daModels.ScriptRun, *SErr.APIErr) {
+	db := mysql.GetDB()
+	run := &daModels.ScriptRun{}
+	res := db.Model(&daModels.ScriptRun{}).Where("id = ?", ID).First(run)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, SErr.InvalidParamErr.CustomMessageF("找不到该执行记录，ID=[%d]", ID)
+	}
+	if res.Error != nil {
+		return nil, SErr.InternalErr.CustomMessageF("查询执行记录时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return run, nil
+}
+
+// List 按cond中不为零值的字段（ScriptID、UserID、Host、Port）筛选执行记录，按时间倒序排列。
+func (s ScriptRunDal) List(cond *daModels.ScriptRun, from, size uint) ([]*daModels.ScriptRun, uint, *SErr.APIErr) {
+	log.Printf("ScriptRuns List, ScriptID=[%d], UserID=[%d], Host=[%s], Port=[%d], from=[%d], size=[%d]", cond.ScriptID, cond.UserID, cond.Host, cond.Port, from, size)
+	var runs []*daModels.ScriptRun
+	var count int64
+	db := mysql.GetDB()
+	res := db.Model(&daModels.ScriptRun{}).Where(cond).Count(&count)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessage(res.Error.Error())
+	}
+	res = db.Model(&daModels.ScriptRun{}).Where(cond).Order("created_at desc").Offset(int(from)).Limit(int(size)).Find(&runs)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessageF("查询执行记录列表时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return runs, uint(count), nil
+}
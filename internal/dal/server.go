@@ -22,7 +22,37 @@ func (s ServerDal) validServerInfo(server *daModels.Server) bool {
 		server.Port != 0 &&
 		len(server.Accounts) == 0 &&
 		server.AdminAccountName != "" &&
-		server.AdminAccountPwd != ""
+		s.validServerAuth(server) &&
+		s.validServerJump(server)
+}
+
+// validServerJump 引用的跳板机需要同时指定Host与Port，并且不能是自己。本机服务器不能配置跳板机。
+func (s ServerDal) validServerJump(server *daModels.Server) bool {
+	if server.OSType == daModels.OSTypeLinuxLocal {
+		return server.JumpServerHost == "" && server.ProxyJump == ""
+	}
+	if server.JumpServerHost == "" {
+		return server.JumpServerPort == 0
+	}
+	return server.JumpServerPort != 0 && !(server.JumpServerHost == server.Host && server.JumpServerPort == server.Port)
+}
+
+// validServerAuth 根据认证方式检查所需的认证信息是否齐全。本机服务器不经过SSH，不需要认证信息。
+func (s ServerDal) validServerAuth(server *daModels.Server) bool {
+	if server.OSType == daModels.OSTypeLinuxLocal {
+		return true
+	}
+	switch server.GetAuthType() {
+	case daModels.SSHAuthTypePassword:
+		return server.AdminAccountPwd != ""
+	case daModels.SSHAuthTypePrivateKey:
+		return server.AdminPrivateKey != ""
+	case daModels.SSHAuthTypeAgent:
+		// agent socket为空时，使用ServerServing进程自身的SSH_AUTH_SOCK。
+		return true
+	default:
+		return false
+	}
 }
 
 // Create 创建一个新的Server。
@@ -161,6 +191,12 @@ func (s ServerDal) Search(from, size uint, keyword string, withAccounts bool) ([
 
 // Update 更新数据库信息，无法保证更新的目标是否相同。可以在上层通过redis做分布式锁做并发更新保障。（在mysql做并发控制需要使用事务，代价比较高）
 func (s ServerDal) Update(Host string, Port uint, server *daModels.Server) *SErr.APIErr {
+	if !s.validServerAuth(server) {
+		return SErr.InvalidParamErr.CustomMessageF("更新Server的认证信息不合法！AuthType=[%s]", server.AuthType)
+	}
+	if !s.validServerJump(&daModels.Server{Host: Host, Port: Port, JumpServerHost: server.JumpServerHost, JumpServerPort: server.JumpServerPort}) {
+		return SErr.InvalidParamErr.CustomMessageF("更新Server的跳板机不合法！JumpServerHost=[%s]，JumpServerPort=[%d]", server.JumpServerHost, server.JumpServerPort)
+	}
 	db := mysql.GetDB()
 	var sErr *SErr.APIErr
 	_ = db.Transaction(func(tx *gorm.DB) error {
@@ -175,7 +211,9 @@ func (s ServerDal) Update(Host string, Port uint, server *daModels.Server) *SErr
 			sErr = SErr.InvalidParamErr.CustomMessage("更新Server数据时，服务器名称重复！")
 			return errors.New("服务器名称重复")
 		}
-		res = db.Model(&daModels.Server{}).Where(&daModels.Server{Host: Host, Port: Port}).Select("name", "description", "admin_account_name", "admin_account_pwd").Updates(server)
+		res = db.Model(&daModels.Server{}).Where(&daModels.Server{Host: Host, Port: Port}).Select("name", "description", "admin_account_name", "admin_account_pwd",
+			"auth_type", "admin_private_key", "admin_private_key_passphrase", "admin_agent_socket",
+			"jump_server_host", "jump_server_port", "proxy_jump").Updates(server)
 		if res.Error != nil {
 			sErr = SErr.InternalErr.CustomMessageF("更新Server数据出错，出错信息为：[%s]", res.Error.Error())
 			return res.Error
@@ -184,3 +222,43 @@ func (s ServerDal) Update(Host string, Port uint, server *daModels.Server) *SErr
 	})
 	return sErr
 }
+
+// UpdateOSRelease 更新服务器记录的发行版信息，即server中os_release_id、os_release_id_like、os_version_id与os_pretty_name四个字段。
+func (s ServerDal) UpdateOSRelease(Host string, Port uint, server *daModels.Server) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(&daModels.Server{}).Where(&daModels.Server{Host: Host, Port: Port}).
+		Select("os_release_id", "os_release_id_like", "os_version_id", "os_pretty_name").Updates(server)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("更新Server的发行版信息出错，出错信息为：[%s]", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return SErr.InvalidParamErr.CustomMessageF("要更新的Server Host=[%s] Port=[%d] 不存在，请检查参数！", Host, Port)
+	}
+	return nil
+}
+
+// UpdateHostKeyFingerprint 更新服务器记录的host key指纹。
+func (s ServerDal) UpdateHostKeyFingerprint(Host string, Port uint, fingerprint string) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(&daModels.Server{}).Where(&daModels.Server{Host: Host, Port: Port}).Update("host_key_fingerprint", fingerprint)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("更新Server的host key指纹出错，出错信息为：[%s]", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return SErr.InvalidParamErr.CustomMessageF("要更新的Server Host=[%s] Port=[%d] 不存在，请检查参数！", Host, Port)
+	}
+	return nil
+}
+
+// UpdateAgentTokenHash 更新服务器的agent token的SHA256，为空时表示不再接受agent推送的数据。
+func (s ServerDal) UpdateAgentTokenHash(Host string, Port uint, tokenHash string) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(&daModels.Server{}).Where(&daModels.Server{Host: Host, Port: Port}).Update("agent_token_hash", tokenHash)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("更新Server的agent token出错，出错信息为：[%s]", res.Error.Error())
+	}
+	if res.RowsAffected == 0 {
+		return SErr.InvalidParamErr.CustomMessageF("要更新的Server Host=[%s] Port=[%d] 不存在，请检查参数！", Host, Port)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package dal
+
+import (
+	"ServerServing/da/mysql"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"errors"
+	"gorm.io/gorm"
+	"log"
+)
+
+type TerminalSessionDal struct{}
+
+func GetTerminalSessionDal() TerminalSessionDal {
+	return TerminalSessionDal{}
+}
+
+// Create 在终端会话开始时保存它。
+func (s TerminalSessionDal) Create(session *daModels.TerminalSession) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(&daModels.TerminalSession{}).Create(session)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("保存终端会话失败！出错信息=[%v]", res.Error)
+	}
+	return nil
+}
+
+// Finish 在终端会话结束时更新它的结束时间、退出码以及录像大小等信息。
+func (s TerminalSessionDal) Finish(session *daModels.TerminalSession) *SErr.APIErr {
+	db := mysql.GetDB()
+	res := db.Model(session).Select("ended_at", "duration_ms", "exit_status", "error", "recording_size", "updated_at").Updates(session)
+	if res.Error != nil {
+		return SErr.InternalErr.CustomMessageF("更新终端会话失败！出错信息=[%v]", res.Error)
+	}
+	return nil
+}
+
+// Get 获取一个终端会话。
+func (s TerminalSessionDal) Get(ID uint) (*daModels.TerminalSession, *SErr.APIErr) {
+	db := mysql.GetDB()
+	session := &daModels.TerminalSession{}
+	res := db.Model(&daModels.TerminalSession{}).Where("id = ?", ID).First(session)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return nil, SErr.InvalidParamErr.CustomMessageF("找不到该终端会话，ID=[%d]", ID)
+	}
+	if res.Error != nil {
+		return nil, SErr.InternalErr.CustomMessageF("查询终端会话时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return session, nil
+}
+
+// List 按cond中不为零值的字段（UserID、Host、Port）筛选终端会话，按时间倒序排列。
+func (s TerminalSessionDal) List(cond *daModels.TerminalSession, from, size uint) ([]*daModels.TerminalSession, uint, *SErr.APIErr) {
+	log.Printf("TerminalSessions List, UserID=[%d], Host=[%s], Port=[%d], from=[%d], size=[%d]", cond.UserID, cond.Host, cond.Port, from, size)
+	var sessions []*daModels.TerminalSession
+	var count int64
+	db := mysql.GetDB()
+	res := db.Model(&daModels.TerminalSession{}).Where(cond).Count(&count)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessage(res.Error.Error())
+	}
+	res = db.Model(&daModels.TerminalSession{}).Where(cond).Order("created_at desc").Offset(int(from)).Limit(int(size)).Find(&sessions)
+	if res.Error != nil {
+		return nil, 0, SErr.InternalErr.CustomMessageF("查询终端会话列表时出错！出错信息为：[%s]", res.Error.Error())
+	}
+	return sessions, uint(count), nil
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	SErr "ServerServing/err"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CmdScriptsHandler struct{}
+
+func GetCmdScriptsHandler() *CmdScriptsHandler {
+	return &CmdScriptsHandler{}
+}
+
+// List
+// @Summary 列出生效中的命令脚本，以及每个脚本的来源。
+// @Tags cmd_script
+// @Produce json
+// @Router /api/v1/cmd_scripts/ [get]
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.CmdScriptsListResponse
+func (CmdScriptsHandler) List(c *gin.Context) (interface{}, *SErr.APIErr) {
+	sessionsSvc := service.GetSessionsService()
+	_, err := sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdScriptsSvc := service.GetCmdScriptsService()
+	scripts, err := cmdScriptsSvc.List(c)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CmdScriptsListResponse{Scripts: scripts}, nil
+}
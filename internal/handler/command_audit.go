@@ -0,0 +1,44 @@
+package handler
+
+import (
+	SErr "ServerServing/err"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CommandAuditsHandler struct{}
+
+func GetCommandAuditsHandler() *CommandAuditsHandler {
+	return &CommandAuditsHandler{}
+}
+
+// Infos
+// @Summary 查询平台在服务器上执行过的命令的审计记录，按开始执行的时间倒序排列，只有管理员可以查询。
+// @Description 可以按服务器（host与port）、触发命令的用户、脚本名以及开始执行的时间范围（Unix时间戳，包含两端）筛选。
+// @Description 参数中的密码等敏感信息已经被隐藏，命令的输出只保存了摘要与长度。
+// @Tags command_audit
+// @Produce json
+// @Router /api/v1/command_audits/ [get]
+// @Param commandAuditsInfosRequest query internal_models.CommandAuditsInfosRequest true "commandAuditsInfosRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.CommandAuditsInfosResponse
+func (CommandAuditsHandler) Infos(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.CommandAuditsInfosRequest{}
+	e := c.ShouldBindQuery(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	audits, totalCount, err := service.GetCommandAuditsService().Audits(c, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.CommandAuditsInfosResponse{
+		Infos:      audits,
+		TotalCount: totalCount,
+	}, nil
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"ServerServing/api/format"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// streamOutput 将之后在c的请求中执行的命令的每一行输出作为output事件推送。
+func streamOutput(c *gin.Context, emit format.StreamEmitter) {
+	service.StreamOutput(c, func(line *models.CommandOutputLine) {
+		emit(format.StreamEventOutput, line)
+	})
+}
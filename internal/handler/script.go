@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"ServerServing/api/format"
+	SErr "ServerServing/err"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"ServerServing/util"
+	"github.com/gin-gonic/gin"
+)
+
+type ScriptsHandler struct{}
+
+func GetScriptsHandler() *ScriptsHandler {
+	return &ScriptsHandler{}
+}
+
+func (ScriptsHandler) parseID(c *gin.Context) (uint, *SErr.APIErr) {
+	ID, err := util.ParseInt(c.Param("id"))
+	if err != nil || ID <= 0 {
+		return 0, SErr.BadRequestErr.CustomMessageF("请求的ID不为整数！或者ID <= 0")
+	}
+	return uint(ID), nil
+}
+
+// Create
+// @Summary 在脚本目录中创建一个脚本。脚本使用命令模板格式，开头的# @param注释声明参数，正文中使用{{name}}引用参数，其它的{{}}原样保留。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/ [post]
+// @Param scriptsCreateRequest body internal_models.ScriptsCreateRequest true "scriptsCreateRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsCreateResponse
+func (ScriptsHandler) Create(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.ScriptsCreateRequest{}
+	e := c.ShouldBind(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	userID, err := sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := service.GetScriptsService().Create(c, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptsCreateResponse{Script: script}, nil
+}
+
+// Update
+// @Summary 修改脚本目录中的一个脚本。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/{id} [put]
+// @param id path uint true "id"
+// @Param scriptsUpdateRequest body internal_models.ScriptsUpdateRequest true "scriptsUpdateRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsUpdateResponse
+func (h ScriptsHandler) Update(c *gin.Context) (interface{}, *SErr.APIErr) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ScriptsUpdateRequest{}
+	e := c.ShouldBind(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	_, err = sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := service.GetScriptsService().Update(c, ID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptsUpdateResponse{Script: script}, nil
+}
+
+// Delete
+// @Summary 删除脚本目录中的一个脚本，它的执行记录仍然保留。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/{id} [delete]
+// @param id path uint true "id"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsDeleteResponse
+func (h ScriptsHandler) Delete(c *gin.Context) (interface{}, *SErr.APIErr) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	_, err = sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = service.GetScriptsService().Delete(c, ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptsDeleteResponse{}, nil
+}
+
+// Info
+// @Summary 查询脚本目录中的一个脚本。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/{id} [get]
+// @param id path uint true "id"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsInfoResponse
+func (h ScriptsHandler) Info(c *gin.Context) (interface{}, *SErr.APIErr) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := service.GetSessionsService().GetUserID(c); err != nil {
+		return nil, SErr.NeedLoginErr
+	}
+
+	script, err := service.GetScriptsService().Info(c, ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptsInfoResponse{Script: script}, nil
+}
+
+// Infos
+// @Summary 查询脚本目录中的脚本列表。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/ [get]
+// @Param scriptsInfosRequest query internal_models.ScriptsInfosRequest true "scriptsInfosRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsInfosResponse
+func (ScriptsHandler) Infos(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.ScriptsInfosRequest{}
+	e := c.ShouldBindQuery(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().GetUserID(c); err != nil {
+		return nil, SErr.NeedLoginErr
+	}
+
+	scripts, totalCount, err := service.GetScriptsService().Infos(c, req.From, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptsInfosResponse{
+		Infos:      scripts,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// Run
+// @Summary 在服务器上执行脚本目录中的一个脚本，需要脚本要求的角色，并且该服务器在脚本允许的服务器中。
+// @Description 每次执行都会保存执行记录。执行失败时（包括参数有误、连接服务器失败、命令返回非0的退出码）同样返回执行记录，失败原因在result.error中。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/{id}/runs [post]
+// @param id path uint true "id"
+// @Param scriptsRunRequest body internal_models.ScriptsRunRequest true "scriptsRunRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsRunResponse
+func (h ScriptsHandler) Run(c *gin.Context) (interface{}, *SErr.APIErr) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ScriptsRunRequest{}
+	e := c.ShouldBind(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	userID, isAdmin, err := service.GetSessionsService().LoggedInUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := service.GetScriptsService().Run(c, userID, isAdmin, ID, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptsRunResponse{ScriptRun: run}, nil
+}
+
+// RunStream
+// @Summary 与执行脚本相同，但以Server-Sent Events流式返回，脚本每输出一行就推送一个output事件。
+// @Description output事件的内容为internal_models.CommandOutputLine。最后推送exit事件，内容与执行脚本接口的响应相同，其中result.exit_status为脚本的退出码。
+// @Description 长时间没有输出时会定期发送以冒号开头的注释行。
+// @Tags script
+// @Produce text/event-stream
+// @Router /api/v1/scripts/{id}/runs/stream [post]
+// @param id path uint true "id"
+// @Param scriptsRunRequest body internal_models.ScriptsRunRequest true "scriptsRunRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptsRunResponse
+func (h ScriptsHandler) RunStream(c *gin.Context, emit format.StreamEmitter) (interface{}, *SErr.APIErr) {
+	streamOutput(c, emit)
+	return h.Run(c)
+}
+
+// RunInfos
+// @Summary 查询脚本的执行记录，非管理员只能看到自己的执行记录。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/runs [get]
+// @Param scriptRunsInfosRequest query internal_models.ScriptRunsInfosRequest true "scriptRunsInfosRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptRunsInfosResponse
+func (h ScriptsHandler) RunInfos(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.ScriptRunsInfosRequest{}
+	e := c.ShouldBindQuery(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	userID, isAdmin, err := service.GetSessionsService().LoggedInUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, totalCount, err := service.GetScriptsService().Runs(c, userID, isAdmin, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptRunsInfosResponse{
+		Infos:      runs,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// RunInfo
+// @Summary 查询一条脚本的执行记录，非管理员只能查看自己的执行记录。
+// @Tags script
+// @Produce json
+// @Router /api/v1/scripts/runs/{id} [get]
+// @param id path uint true "id"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ScriptRunsInfoResponse
+func (h ScriptsHandler) RunInfo(c *gin.Context) (interface{}, *SErr.APIErr) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	userID, isAdmin, err := service.GetSessionsService().LoggedInUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := service.GetScriptsService().RunInfo(c, userID, isAdmin, ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScriptRunsInfoResponse{ScriptRun: run}, nil
+}
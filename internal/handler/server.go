@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"ServerServing/api/format"
 	SErr "ServerServing/err"
 	models "ServerServing/internal/internal_models"
 	"ServerServing/internal/service"
@@ -39,7 +40,7 @@ func (s ServerHandler) ConnectionTest(c *gin.Context) (interface{}, *SErr.APIErr
 	}
 
 	serversSvc := service.GetServersService()
-	sErr := serversSvc.ConnectionTest(c, host, uint(port), req.OSType, req.AccountName, req.AccountPwd)
+	sErr := serversSvc.ConnectionTest(c, host, uint(port), req.OSType, req.AccountName, req.AccountPwd, &req.ServerAuthParam, &req.ServerJumpParam)
 	if sErr != nil {
 		return &models.ServerConnectionTestResponse{
 			Connected: false,
@@ -211,3 +212,109 @@ func (ServerHandler) Infos(c *gin.Context) (interface{}, *SErr.APIErr) {
 		TotalCount: totalCount,
 	}, nil
 }
+
+// HostKeyInfo
+// @Summary 查看服务器记录的host key指纹，以及服务器当前提供的指纹。
+// @Tags server
+// @Produce json
+// @Router /api/v1/servers/host_keys/{host}/{port} [get]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerHostKeyInfoResponse
+func (s ServerHandler) HostKeyInfo(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := s.parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	_, err = sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	serversSvc := service.GetServersService()
+	res, err := serversSvc.HostKeyInfo(c, host, port)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AcceptHostKey
+// @Summary 重新接受服务器当前提供的host key。
+// @Tags server
+// @Produce json
+// @Router /api/v1/servers/host_keys/{host}/{port} [put]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverHostKeyAcceptRequest body internal_models.ServerHostKeyAcceptRequest true "serverHostKeyAcceptRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerHostKeyAcceptResponse
+func (s ServerHandler) AcceptHostKey(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.ServerHostKeyAcceptRequest{}
+	e := c.ShouldBind(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	host, port, err := s.parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	_, err = sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	serversSvc := service.GetServersService()
+	err = serversSvc.AcceptHostKey(c, host, port, req.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerHostKeyAcceptResponse{}, nil
+}
+
+// FanOut
+// @Summary 在多台服务器上同时执行同一个命令或者脚本目录中的脚本。直接执行命令需要管理员权限。
+// @Description 服务器通过servers明确指定，或者通过keyword搜索（为空字符串时为全部服务器）。每台服务器的结果互不影响，响应中按服务器以及按相同的结果汇总。
+// @Tags server
+// @Produce json
+// @Router /api/v1/servers/fan_out [post]
+// @Param serverFanOutRequest body internal_models.ServerFanOutRequest true "serverFanOutRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFanOutResponse
+func (ServerHandler) FanOut(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.ServerFanOutRequest{}
+	e := c.ShouldBind(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	userID, isAdmin, err := service.GetSessionsService().LoggedInUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	serversSvc := service.GetServersService()
+	resp, err := serversSvc.FanOut(c, userID, isAdmin, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FanOutStream
+// @Summary 与在多台服务器上同时执行相同，但以Server-Sent Events流式返回，每台服务器每输出一行就推送一个output事件。
+// @Description output事件的内容为internal_models.CommandOutputLine，其中的host与port表示是哪台服务器的输出。最后推送exit事件，内容与在多台服务器上同时执行的接口的响应相同，其中包含每台服务器的退出码。
+// @Tags server
+// @Produce text/event-stream
+// @Router /api/v1/servers/fan_out/stream [post]
+// @Param serverFanOutRequest body internal_models.ServerFanOutRequest true "serverFanOutRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFanOutResponse
+func (h ServerHandler) FanOutStream(c *gin.Context, emit format.StreamEmitter) (interface{}, *SErr.APIErr) {
+	streamOutput(c, emit)
+	return h.FanOut(c)
+}
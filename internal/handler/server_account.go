@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"ServerServing/api/format"
 	SErr "ServerServing/err"
 	models "ServerServing/internal/internal_models"
 	"ServerServing/internal/service"
@@ -90,6 +91,20 @@ func (ServerAccountsHandler) Delete(c *gin.Context) (interface{}, *SErr.APIErr)
 	}, nil
 }
 
+// DeleteStream
+// @Summary 与删除一个服务器的账号相同，但以Server-Sent Events流式返回，执行的命令每输出一行就推送一个output事件。
+// @Description 备份很大的home目录可能需要很长时间，期间会定期发送以冒号开头的注释行。最后推送exit事件，内容与删除账号的接口的响应相同。
+// @Tags server_account
+// @Produce text/event-stream
+// @Router /api/v1/servers/accounts/stream [delete]
+// @Param serverAccountDeleteRequest body internal_models.ServerAccountDeleteRequest true "serverAccountDeleteRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerAccountDeleteResponse
+func (h ServerAccountsHandler) DeleteStream(c *gin.Context, emit format.StreamEmitter) (interface{}, *SErr.APIErr) {
+	streamOutput(c, emit)
+	return h.Delete(c)
+}
+
 // Update
 // @Summary 更新，恢复一个服务器的账号。
 // @Tags server_account
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	SErr "ServerServing/err"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"ServerServing/internal/service/server_executor"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RotateAgentToken
+// @Summary 为服务器生成新的agent token，之前的token立即失效。token只在本次返回，之后无法再查看。
+// @Tags server
+// @Produce json
+// @Router /api/v1/servers/agent_tokens/{host}/{port} [put]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerAgentTokenRotateResponse
+func (s ServerHandler) RotateAgentToken(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := s.parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	_, err = sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	serversSvc := service.GetServersService()
+	token, err := serversSvc.RotateAgentToken(c, host, port)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerAgentTokenRotateResponse{Token: token}, nil
+}
+
+// RevokeAgentToken
+// @Summary 不再接受服务器上的agent推送的数据。
+// @Tags server
+// @Produce json
+// @Router /api/v1/servers/agent_tokens/{host}/{port} [delete]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerAgentTokenRevokeResponse
+func (s ServerHandler) RevokeAgentToken(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := s.parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsSvc := service.GetSessionsService()
+	_, err = sessionsSvc.LoggedInAndIsAdmin(c)
+	if err != nil {
+		return nil, err
+	}
+
+	serversSvc := service.GetServersService()
+	err = serversSvc.RevokeAgentToken(c, host, port)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerAgentTokenRevokeResponse{}, nil
+}
+
+// AgentReport
+// @Summary 服务器上运行的agent推送采集到的数据，使用agent token认证。
+// @Tags server
+// @Produce json
+// @Router /api/v1/servers/agent_reports/{host}/{port} [post]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param agentReport body server_executor.AgentReport true "agentReport"
+// @Param X-Agent-Token header string true "agent token"
+// @Success 200 {object} internal_models.ServerAgentReportResponse
+func (s ServerHandler) AgentReport(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := s.parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.AgentMaxReportBytes())
+	report := &server_executor.AgentReport{}
+	e := c.ShouldBindJSON(report)
+	if e != nil {
+		return nil, SErr.BadRequestErr.CustomMessageF("解析agent推送的数据失败！err=[%s]", e)
+	}
+
+	serversSvc := service.GetServersService()
+	err = serversSvc.IngestAgentReport(c, host, port, c.GetHeader(server_executor.AgentTokenHeader), report)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerAgentReportResponse{}, nil
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"ServerServing/api/format"
+	SErr "ServerServing/err"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"mime"
+	"strconv"
+)
+
+type ServerFilesHandler struct{}
+
+func GetServerFilesHandler() *ServerFilesHandler {
+	return &ServerFilesHandler{}
+}
+
+// Infos
+// @Summary 列出服务器上某个文件夹中的文件，包括大小、所有者、权限与修改时间，只有管理员可以查看。
+// @Description 文件操作通过以root身份运行的sftp-server完成，可以查看任意目录，例如/backup或者某个用户的home目录。
+// @Tags server_file
+// @Produce json
+// @Router /api/v1/servers/files/{host}/{port} [get]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesInfosRequest query internal_models.ServerFilesInfosRequest true "serverFilesInfosRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFilesInfosResponse
+func (ServerFilesHandler) Infos(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ServerFilesInfosRequest{}
+	if e := c.ShouldBindQuery(req); e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	files, err := service.GetServerFilesService().ListDir(c, host, port, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerFilesInfosResponse{
+		Path:  req.Path,
+		Files: files,
+	}, nil
+}
+
+// Stat
+// @Summary 获取服务器上某个文件或文件夹的信息，符号链接返回链接本身的信息，只有管理员可以查看。
+// @Tags server_file
+// @Produce json
+// @Router /api/v1/servers/files/{host}/{port}/stat [get]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesStatRequest query internal_models.ServerFilesStatRequest true "serverFilesStatRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFilesStatResponse
+func (ServerFilesHandler) Stat(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ServerFilesStatRequest{}
+	if e := c.ShouldBindQuery(req); e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	file, err := service.GetServerFilesService().Stat(c, host, port, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerFilesStatResponse{File: file}, nil
+}
+
+// Download
+// @Summary 下载服务器上的文件，文件内容以流的形式直接写入响应，只能下载普通文件，只有管理员可以下载。
+// @Description 开始传输之前出错时返回JSON格式的错误；传输过程中出错时连接会被中断，响应的长度小于Content-Length。
+// @Tags server_file
+// @Produce application/octet-stream
+// @Router /api/v1/servers/files/{host}/{port}/content [get]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesDownloadRequest query internal_models.ServerFilesDownloadRequest true "serverFilesDownloadRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {string} string "file content"
+func (ServerFilesHandler) Download(c *gin.Context) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		format.Err(c, err)
+		return
+	}
+	req := &models.ServerFilesDownloadRequest{}
+	if e := c.ShouldBindQuery(req); e != nil {
+		format.Err(c, SErr.BadRequestErr)
+		return
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		format.Err(c, err)
+		return
+	}
+
+	err = service.GetServerFilesService().Download(c, host, port, req.Path, func(info *models.ServerFileInfo) io.Writer {
+		c.Header("Content-Type", "application/octet-stream")
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+		return c.Writer
+	})
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// 已经开始传输，无法再返回JSON格式的错误。
+		log.Printf("ServerFilesHandler Download failed after the transfer started, path=[%s], err=[%s]", req.Path, err)
+		return
+	}
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.Header().Del("Content-Disposition")
+	format.Err(c, err)
+}
+
+// Upload
+// @Summary 上传文件到服务器，请求体即为文件内容，以流的形式写入服务器，只有管理员可以上传。
+// @Description 已经存在的文件会被替换。内容先写入同一文件夹下的临时文件，上传完成后再替换目标文件。
+// @Description 新文件的所有者为root，权限为perm（默认为0644），需要时可以再修改所有者。
+// @Tags server_file
+// @Accept application/octet-stream
+// @Produce json
+// @Router /api/v1/servers/files/{host}/{port}/content [put]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesUploadRequest query internal_models.ServerFilesUploadRequest true "serverFilesUploadRequest"
+// @Param content body string true "file content"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFilesUploadResponse
+func (ServerFilesHandler) Upload(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ServerFilesUploadRequest{}
+	if e := c.ShouldBindQuery(req); e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	file, err := service.GetServerFilesService().Upload(c, host, port, req.Path, req.Perm, c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ServerFilesUploadResponse{File: file}, nil
+}
+
+// Chmod
+// @Summary 修改服务器上文件或文件夹的权限，不会递归修改，只有管理员可以修改。
+// @Tags server_file
+// @Accept json
+// @Produce json
+// @Router /api/v1/servers/files/{host}/{port}/perm [put]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesChmodRequest body internal_models.ServerFilesChmodRequest true "serverFilesChmodRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFilesChmodResponse
+func (ServerFilesHandler) Chmod(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ServerFilesChmodRequest{}
+	if e := c.ShouldBindJSON(req); e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	if err := service.GetServerFilesService().Chmod(c, host, port, req.Path, req.Perm); err != nil {
+		return nil, err
+	}
+	return &models.ServerFilesChmodResponse{}, nil
+}
+
+// Chown
+// @Summary 修改服务器上文件或文件夹的所有者，组为该账户的主组，不会递归修改，只有管理员可以修改。
+// @Tags server_file
+// @Accept json
+// @Produce json
+// @Router /api/v1/servers/files/{host}/{port}/owner [put]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesChownRequest body internal_models.ServerFilesChownRequest true "serverFilesChownRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFilesChownResponse
+func (ServerFilesHandler) Chown(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ServerFilesChownRequest{}
+	if e := c.ShouldBindJSON(req); e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	if err := service.GetServerFilesService().Chown(c, host, port, req.Path, req.Owner); err != nil {
+		return nil, err
+	}
+	return &models.ServerFilesChownResponse{}, nil
+}
+
+// Delete
+// @Summary 删除服务器上的文件或文件夹，只有管理员可以删除。
+// @Description recursive为false时只能删除文件与空文件夹；为true时删除整个文件夹，其中的符号链接不会被跟随。/、/etc等系统目录不允许删除。
+// @Tags server_file
+// @Produce json
+// @Router /api/v1/servers/files/{host}/{port} [delete]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param serverFilesDeleteRequest query internal_models.ServerFilesDeleteRequest true "serverFilesDeleteRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.ServerFilesDeleteResponse
+func (ServerFilesHandler) Delete(c *gin.Context) (interface{}, *SErr.APIErr) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		return nil, err
+	}
+	req := &models.ServerFilesDeleteRequest{}
+	if e := c.ShouldBindQuery(req); e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	if err := service.GetServerFilesService().Remove(c, host, port, req.Path, req.Recursive); err != nil {
+		return nil, err
+	}
+	return &models.ServerFilesDeleteResponse{}, nil
+}
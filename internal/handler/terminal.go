@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"ServerServing/api/format"
+	SErr "ServerServing/err"
+	models "ServerServing/internal/internal_models"
+	"ServerServing/internal/service"
+	"ServerServing/util"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	terminalReadBufferSize = 32 * 1024
+	terminalCloseTimeout   = time.Second
+)
+
+// terminalUpgrader 认证使用请求中显式传递的token而不是cookie，与CORS的配置一致，允许任意来源。
+var terminalUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: terminalReadBufferSize,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+type TerminalsHandler struct{}
+
+func GetTerminalsHandler() *TerminalsHandler {
+	return &TerminalsHandler{}
+}
+
+func (TerminalsHandler) parseID(c *gin.Context) (uint, *SErr.APIErr) {
+	ID, err := util.ParseInt(c.Param("id"))
+	if err != nil || ID <= 0 {
+		return 0, SErr.BadRequestErr.CustomMessageF("请求的ID不为整数！或者ID <= 0")
+	}
+	return uint(ID), nil
+}
+
+// Open
+// @Summary 通过WebSocket打开服务器上的一个终端，使用服务器的管理员账户登录，只有管理员可以使用。
+// @Description 浏览器的WebSocket无法设置请求头，可以通过x_token查询参数传递token。
+// @Description 前端发送JSON格式的文本消息：{"type":"input","data":"ls\r"}为输入，{"type":"resize","cols":120,"rows":40}为修改终端大小。
+// @Description 服务器以二进制消息原样发送终端的输出，可以直接写入xterm.js。终端结束时发送一条{"type":"exit","session_id":1,"exit_status":0,"error":""}文本消息后关闭连接。
+// @Description 终端的输出会被录制下来，可以通过终端会话的接口回放。
+// @Tags terminal
+// @Router /api/v1/servers/terminals/{host}/{port} [get]
+// @param host path string true "host"
+// @param port path uint true "port"
+// @Param terminalOpenRequest query internal_models.TerminalOpenRequest false "terminalOpenRequest"
+// @Param x-token header string false "x-token"
+// @Param x_token query string false "x_token"
+func (h TerminalsHandler) Open(c *gin.Context) {
+	host, port, err := GetServerHandler().parseHostPort(c)
+	if err != nil {
+		format.Err(c, err)
+		return
+	}
+	req := &models.TerminalOpenRequest{}
+	if e := c.ShouldBindQuery(req); e != nil {
+		format.Err(c, SErr.BadRequestErr)
+		return
+	}
+	userID, err := service.GetSessionsService().LoggedInAndIsAdmin(c)
+	if err != nil {
+		format.Err(c, err)
+		return
+	}
+	if !c.IsWebsocket() {
+		format.Err(c, SErr.BadRequestErr.CustomMessage("需要使用WebSocket打开终端！"))
+		return
+	}
+
+	ws, e := terminalUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if e != nil {
+		// Upgrade失败时已经返回了HTTP错误。
+		log.Printf("TerminalsHandler Open upgrade failed, err=[%v]", e)
+		return
+	}
+	defer ws.Close()
+	session, err := service.GetTerminalsService().Open(c, userID, host, port, req.Cols, req.Rows)
+	if err != nil {
+		// 连接服务器失败的原因通过exit消息告诉前端，而不是让WebSocket直接断开。
+		h.closeWith(ws, &models.TerminalExitMessage{
+			Type:       models.TerminalMessageExit,
+			ExitStatus: -1,
+			Error:      err.Message,
+		})
+		return
+	}
+	h.bridge(ws, session)
+}
+
+// bridge 在WebSocket与终端之间转发，直到shell退出或者前端断开，之后发送exit消息并关闭WebSocket。
+func (h TerminalsHandler) bridge(ws *websocket.Conn, session *service.TerminalSession) {
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		buf := make([]byte, terminalReadBufferSize)
+		for {
+			n, err := session.Read(buf)
+			if n > 0 {
+				if err := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			msg := &models.TerminalClientMessage{}
+			if err := json.Unmarshal(data, msg); err != nil {
+				continue
+			}
+			switch msg.Type {
+			case models.TerminalMessageInput:
+				if _, err := session.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case models.TerminalMessageResize:
+				if err := session.Resize(msg.Cols, msg.Rows); err != nil {
+					log.Printf("TerminalsHandler resize failed, session=[%d], err=[%s]", session.ID, err.Message)
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-outputDone:
+	case <-inputDone:
+		// 前端断开或者无法再写入终端，直接关闭终端。
+		session.Close()
+		<-outputDone
+	}
+	h.closeWith(ws, session.Finish())
+	_ = ws.Close()
+	<-inputDone
+}
+
+// closeWith 发送exit消息，然后正常关闭WebSocket。
+func (h TerminalsHandler) closeWith(ws *websocket.Conn, exit *models.TerminalExitMessage) {
+	deadline := time.Now().Add(terminalCloseTimeout)
+	_ = ws.SetWriteDeadline(deadline)
+	_ = ws.WriteJSON(exit)
+	_ = ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+}
+
+// Sessions
+// @Summary 查询终端会话列表，只有管理员可以查询。
+// @Tags terminal
+// @Produce json
+// @Router /api/v1/terminal_sessions/ [get]
+// @Param terminalSessionsInfosRequest query internal_models.TerminalSessionsInfosRequest true "terminalSessionsInfosRequest"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.TerminalSessionsInfosResponse
+func (TerminalsHandler) Sessions(c *gin.Context) (interface{}, *SErr.APIErr) {
+	req := &models.TerminalSessionsInfosRequest{}
+	e := c.ShouldBindQuery(req)
+	if e != nil {
+		return nil, SErr.BadRequestErr
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	sessions, totalCount, err := service.GetTerminalsService().Sessions(c, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TerminalSessionsInfosResponse{
+		Infos:      sessions,
+		TotalCount: totalCount,
+	}, nil
+}
+
+// SessionInfo
+// @Summary 查询一个终端会话，只有管理员可以查询。
+// @Tags terminal
+// @Produce json
+// @Router /api/v1/terminal_sessions/{id} [get]
+// @param id path uint true "id"
+// @Param x-token header string false "x-token"
+// @Success 200 {object} internal_models.TerminalSessionsInfoResponse
+func (h TerminalsHandler) SessionInfo(c *gin.Context) (interface{}, *SErr.APIErr) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		return nil, err
+	}
+
+	session, err := service.GetTerminalsService().SessionInfo(c, ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TerminalSessionsInfoResponse{TerminalSession: session}, nil
+}
+
+// Recording
+// @Summary 下载终端会话的录像，格式为asciicast v2，可以使用asciinema-player回放，只有管理员可以下载。
+// @Tags terminal
+// @Produce application/x-asciicast
+// @Router /api/v1/terminal_sessions/{id}/recording [get]
+// @param id path uint true "id"
+// @Param x-token header string false "x-token"
+// @Success 200 {string} string "asciicast v2"
+func (h TerminalsHandler) Recording(c *gin.Context) {
+	ID, err := h.parseID(c)
+	if err != nil {
+		format.Err(c, err)
+		return
+	}
+	if _, err := service.GetSessionsService().LoggedInAndIsAdmin(c); err != nil {
+		format.Err(c, err)
+		return
+	}
+
+	path, err := service.GetTerminalsService().RecordingPath(c, ID)
+	if err != nil {
+		format.Err(c, err)
+		return
+	}
+	c.Header("Content-Type", "application/x-asciicast")
+	c.File(path)
+}
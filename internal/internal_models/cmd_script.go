@@ -0,0 +1,23 @@
+package internal_models
+
+type CmdScriptParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// CmdScript 一个生效中的命令脚本。
+// Source 为embedded时表示随程序内嵌的默认脚本，为override时表示从覆盖目录中加载的脚本，Location为它的路径。
+type CmdScript struct {
+	Dir      string            `json:"dir"`
+	Name     string            `json:"name"`
+	Source   string            `json:"source"`
+	Location string            `json:"location"`
+	Params   []*CmdScriptParam `json:"params"`
+}
+
+type CmdScriptsListRequest struct {
+}
+
+type CmdScriptsListResponse struct {
+	Scripts []*CmdScript `json:"scripts"`
+}
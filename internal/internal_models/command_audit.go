@@ -0,0 +1,37 @@
+package internal_models
+
+// CommandAudit 平台在服务器上执行的一条命令的审计记录。UserID 为触发该命令的用户，不是由用户的请求触发时为0。
+// Args 中敏感参数的值已经被隐藏；输出只保存了摘要（stdout与stderr以\x00分隔后的SHA-256）与长度。
+// ExitStatus 没有正常退出时为-1，ErrCode 与 Error 为执行失败时的错误码与错误信息。
+type CommandAudit struct {
+	ID           uint              `json:"id"`
+	UserID       uint              `json:"user_id"`
+	Host         string            `json:"host"`
+	Port         uint              `json:"port"`
+	Script       string            `json:"script"`
+	Args         map[string]string `json:"args"`
+	ExitStatus   int               `json:"exit_status"`
+	ErrCode      int               `json:"err_code"`
+	Error        string            `json:"error"`
+	StartedAt    int64             `json:"started_at"`
+	DurationMs   int64             `json:"duration_ms"`
+	OutputDigest string            `json:"output_digest"`
+	OutputSize   int               `json:"output_size"`
+}
+
+// CommandAuditsInfosRequest 各个筛选条件为空时不筛选。StartedFrom 与 StartedTo 为Unix时间戳（秒），包含两端。
+type CommandAuditsInfosRequest struct {
+	UserID      *uint   `form:"user_id" json:"user_id"`
+	Host        *string `form:"host" json:"host"`
+	Port        *uint   `form:"port" json:"port"`
+	Script      *string `form:"script" json:"script"`
+	StartedFrom *int64  `form:"started_from" json:"started_from"`
+	StartedTo   *int64  `form:"started_to" json:"started_to"`
+	From        uint    `form:"from" json:"from"`
+	Size        uint    `form:"size" json:"size"`
+}
+
+type CommandAuditsInfosResponse struct {
+	Infos      []*CommandAudit `json:"infos"`
+	TotalCount uint            `json:"total_count"`
+}
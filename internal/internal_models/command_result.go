@@ -0,0 +1,33 @@
+package internal_models
+
+import "time"
+
+// CommandResult 在服务器上执行一条命令（脚本）的结果。
+type CommandResult struct {
+	Script     string    `json:"script"`      // 脚本名，直接执行的命令则为命令本身。
+	Stdout     string    `json:"stdout"`      // 标准输出。
+	Stderr     string    `json:"stderr"`      // 标准错误输出。
+	ExitStatus int       `json:"exit_status"` // 退出码。命令没有正常退出时（例如超时被终止、连接断开）为-1。
+	StartedAt  time.Time `json:"started_at"`  // 开始执行的时间。
+	DurationMs int64     `json:"duration_ms"` // 执行耗时，单位为毫秒。
+	Error      string    `json:"error"`       // 执行失败时的原因，成功时为空。
+}
+
+// Succeeded 命令是否正常退出并且退出码为0。
+func (r *CommandResult) Succeeded() bool {
+	return r != nil && r.ExitStatus == 0 && r.Error == ""
+}
+
+const (
+	OutputStreamStdout = "stdout"
+	OutputStreamStderr = "stderr"
+)
+
+// CommandOutputLine 命令执行过程中输出的一行（不包含结尾的换行符），用于在命令结束之前推送执行进度。
+type CommandOutputLine struct {
+	Host   string `json:"host"`   // 执行命令的服务器。
+	Port   uint   `json:"port"`   // 执行命令的服务器的端口。
+	Script string `json:"script"` // 正在执行的脚本名。
+	Stream string `json:"stream"` // stdout或者stderr。
+	Line   string `json:"line"`   // 输出的内容。
+}
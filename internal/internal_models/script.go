@@ -0,0 +1,103 @@
+package internal_models
+
+import "ServerServing/da/mysql/da_models"
+
+// Script 脚本目录中的一个脚本。Params 为从Body开头的# @param注释中解析出的参数。
+// AllowedServers 为允许执行该脚本的服务器，格式为host:port，包含*时允许所有服务器。
+type Script struct {
+	ID             uint                 `json:"id"`
+	CreatedAt      int64                `json:"created_at"`
+	UpdatedAt      int64                `json:"updated_at"`
+	Name           string               `json:"name"`
+	Description    string               `json:"description"`
+	Body           string               `json:"body"`
+	Params         []*CmdScriptParam    `json:"params"`
+	AllowedServers []string             `json:"allowed_servers"`
+	RequiredRole   da_models.ScriptRole `json:"required_role"`
+	CreatorID      uint                 `json:"creator_id"`
+}
+
+// ScriptRun 一次脚本的执行记录。Args 中敏感参数的值已经被隐藏。
+// Result 为在服务器上执行的结果，没能连接到服务器或者参数有误时，其中只有Error。
+type ScriptRun struct {
+	ID         uint              `json:"id"`
+	CreatedAt  int64             `json:"created_at"`
+	ScriptID   uint              `json:"script_id"`
+	ScriptName string            `json:"script_name"`
+	ScriptBody string            `json:"script_body"`
+	UserID     uint              `json:"user_id"`
+	Host       string            `json:"host"`
+	Port       uint              `json:"port"`
+	Args       map[string]string `json:"args"`
+	Result     *CommandResult    `json:"result"`
+}
+
+type ScriptsCreateRequest struct {
+	Name           string               `json:"name"`
+	Description    string               `json:"description"`
+	Body           string               `json:"body"`
+	AllowedServers []string             `json:"allowed_servers"`
+	RequiredRole   da_models.ScriptRole `json:"required_role"`
+}
+
+type ScriptsCreateResponse struct {
+	*Script
+}
+
+type ScriptsUpdateRequest struct {
+	Name           string               `json:"name"`
+	Description    string               `json:"description"`
+	Body           string               `json:"body"`
+	AllowedServers []string             `json:"allowed_servers"`
+	RequiredRole   da_models.ScriptRole `json:"required_role"`
+}
+
+type ScriptsUpdateResponse struct {
+	*Script
+}
+
+type ScriptsDeleteResponse struct {
+}
+
+type ScriptsInfoResponse struct {
+	*Script
+}
+
+type ScriptsInfosRequest struct {
+	From uint `form:"from" json:"from"`
+	Size uint `form:"size" json:"size"`
+}
+
+type ScriptsInfosResponse struct {
+	Infos      []*Script `json:"infos"`
+	TotalCount uint      `json:"total_count"`
+}
+
+// ScriptsRunRequest Args 的key为脚本声明的参数名，需要与声明的参数完全一致。
+type ScriptsRunRequest struct {
+	Host string            `json:"host"`
+	Port uint              `json:"port"`
+	Args map[string]string `json:"args"`
+}
+
+type ScriptsRunResponse struct {
+	*ScriptRun
+}
+
+// ScriptRunsInfosRequest 各个筛选条件为空时不筛选。非管理员只能看到自己的执行记录。
+type ScriptRunsInfosRequest struct {
+	ScriptID *uint   `form:"script_id" json:"script_id"`
+	Host     *string `form:"host" json:"host"`
+	Port     *uint   `form:"port" json:"port"`
+	From     uint    `form:"from" json:"from"`
+	Size     uint    `form:"size" json:"size"`
+}
+
+type ScriptRunsInfosResponse struct {
+	Infos      []*ScriptRun `json:"infos"`
+	TotalCount uint         `json:"total_count"`
+}
+
+type ScriptRunsInfoResponse struct {
+	*ScriptRun
+}
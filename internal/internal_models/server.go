@@ -16,6 +16,26 @@ type ServerCreateRequest struct {
 	OSType           da_models.OSType `form:"os_type" json:"os_type"`
 	AdminAccountName string           `form:"admin_account_name" json:"admin_account_name"`
 	AdminAccountPwd  string           `form:"admin_account_pwd" json:"admin_account_pwd"`
+	ServerAuthParam
+	ServerJumpParam
+}
+
+// ServerJumpParam 描述连接服务器时经过的跳板机，都为空时直接连接。
+// JumpServerHost、JumpServerPort 引用另一个已注册的服务器作为跳板机。
+// ProxyJump 为临时指定的跳板机，格式与OpenSSH的ProxyJump相同，例如 admin@gateway:2222,inner-gateway，使用本服务器的认证信息。
+type ServerJumpParam struct {
+	JumpServerHost string `form:"jump_server_host" json:"jump_server_host"`
+	JumpServerPort uint   `form:"jump_server_port" json:"jump_server_port"`
+	ProxyJump      string `form:"proxy_jump" json:"proxy_jump"`
+}
+
+// ServerAuthParam 描述管理员账户的SSH认证方式。AuthType为空时使用密码认证。
+// 使用私钥或agent认证时，AdminAccountPwd作为sudo密码使用，若该账户是NOPASSWD的sudo用户，则可以为空。
+type ServerAuthParam struct {
+	AuthType                  da_models.SSHAuthType `form:"auth_type" json:"auth_type"`
+	AdminPrivateKey           string                `form:"admin_private_key" json:"admin_private_key"`
+	AdminPrivateKeyPassphrase string                `form:"admin_private_key_passphrase" json:"admin_private_key_passphrase"`
+	AdminAgentSocket          string                `form:"admin_agent_socket" json:"admin_agent_socket"`
 }
 
 type ServerCreateResponse struct {
@@ -26,6 +46,8 @@ type ServerUpdateRequest struct {
 	Description      string `form:"description" json:"description"`
 	AdminAccountName string `form:"admin_account_name" json:"admin_account_name"`
 	AdminAccountPwd  string `form:"admin_account_pwd" json:"admin_account_pwd"`
+	ServerAuthParam
+	ServerJumpParam
 }
 
 type ServerUpdateResponse struct {
@@ -74,6 +96,8 @@ type LoadServerDetailArg struct {
 	WithCPUMemProcessesUsage bool `form:"with_cmp_usages" json:"with_cmp_usages"`
 	// WithBackupDirInfo 指定是否加载用户备份文件夹的信息。
 	WithBackupDirInfo bool `form:"with_backup_dir_info" json:"with_backup_dir_info"`
+	// WithFilesystemUsages 指定是否加载已挂载的文件系统的空间与inode使用信息。
+	WithFilesystemUsages bool `form:"with_fs_usages" json:"with_fs_usages"`
 }
 
 // ServerInfo 包含了查询一个Server的详细信息结构体。包含可能的一切数据，从中选取子集展示。
@@ -84,6 +108,12 @@ type ServerInfo struct {
 	// AccessFailedInfo 指定了当该服务器连接失败时的信息。如果该字段不为空，那么其他字段才有意义。
 	AccessFailedInfo *ServerInfoLoadingFailedInfo `json:"access_failed_info"`
 
+	// Status 服务器的连接状态，总是不为空。
+	Status *ServerStatus `json:"status"`
+
+	// Agent 使用了agent推送的数据时，记录该数据的信息。
+	Agent *ServerAgentInfo `json:"agent"`
+
 	// AccountInfos 记录服务器账户信息。
 	AccountInfos *ServerAccountInfos `json:"account_infos"`
 
@@ -96,8 +126,39 @@ type ServerInfo struct {
 	// RemoteAccessingUsageInfo 正在从远端访问的用户的使用信息
 	RemoteAccessingUsageInfo *ServerRemoteAccessingUsagesInfo `json:"remote_accessing_usage_info"`
 
-	// GPUUsageInfo 当前该Server总的GPU利用率信息。（当前为string，具体待定）
+	// GPUUsageInfo 当前该Server每块GPU的使用情况，以及正在使用GPU的进程。
 	GPUUsageInfo *ServerGPUUsageInfo `json:"server_gpu_usage_info"`
+
+	// AccountUsages 按账户汇总的资源占用，由进程信息以及正在使用GPU的进程得到，两者都没有加载时为nil。
+	AccountUsages []*ServerAccountUsage `json:"account_usages"`
+
+	// FilesystemUsageInfo 已挂载的文件系统的使用情况。
+	FilesystemUsageInfo *ServerFilesystemUsageInfo `json:"filesystem_usage_info"`
+}
+
+// ServerAccountUsage 一个账户的全部进程占用的资源，按GPUMemoryMiB、CPUUsage从大到小排列。
+type ServerAccountUsage struct {
+	AccountName string `json:"account_name"`
+	// Processes 该账户的进程数。
+	Processes int `json:"processes"`
+	// CPUUsage 与MemUsage为该账户全部进程的CPU利用率与内存利用率之和，单位为%。
+	CPUUsage float64 `json:"cpu_usage"`
+	MemUsage float64 `json:"mem_usage"`
+	// GPUIndexes 该账户的进程正在使用的GPU编号，从小到大排列。
+	GPUIndexes []int `json:"gpu_indexes"`
+	// GPUMemoryMiB 该账户的进程在全部GPU上占用的显存，单位为MiB。
+	GPUMemoryMiB float64 `json:"gpu_memory_mib"`
+}
+
+// ServerStatus 服务器的连接状态。
+// Status 为online、unreachable（网络不通或者连接超时）、auth_failed（认证失败）或者error（能够连接但是无法使用，例如没有sudo权限）。
+// Since 为进入该状态的时间；连续失败之后进入冷却期，RetryAt之前不会再尝试连接该服务器，不在冷却期时为0。时间均为Unix时间戳（秒）。
+type ServerStatus struct {
+	Status              string `json:"status"`
+	Since               int64  `json:"since"`
+	LastError           string `json:"last_error"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	RetryAt             int64  `json:"retry_at"`
 }
 
 type ServerBasic struct {
@@ -112,6 +173,19 @@ type ServerBasic struct {
 	AdminAccountName string           `json:"admin_account_name"`
 	AdminAccountPwd  string           `json:"admin_account_pwd"`
 	OSType           da_models.OSType `json:"os_type"`
+
+	AuthType           da_models.SSHAuthType `json:"auth_type"`
+	AdminAgentSocket   string                `json:"admin_agent_socket"`
+	HostKeyFingerprint string                `json:"host_key_fingerprint"`
+	// 最近一次连接时从/etc/os-release中解析的发行版信息，还没有成功连接过时为空。
+	OSReleaseID     string `json:"os_release_id"`
+	OSReleaseIDLike string `json:"os_release_id_like"`
+	OSVersionID     string `json:"os_version_id"`
+	OSPrettyName    string `json:"os_pretty_name"`
+	ServerJumpParam
+	// 私钥不对外展示。
+	AdminPrivateKey           string `json:"-"`
+	AdminPrivateKeyPassphrase string `json:"-"`
 }
 
 type ServerAccount struct {
@@ -145,15 +219,18 @@ type ServerAccountBackupDirInfo struct {
 
 // ServerInfoLoadingFailedInfo 描述一个服务器的某部分内容加载失败的原因，以及当时服务器的原始输出。
 type ServerInfoLoadingFailedInfo struct {
-	CauseDescription string `json:"cause_description"` // 描述具体原因。
+	CauseDescription string         `json:"cause_description"` // 描述具体原因。
+	FailedCommand    *CommandResult `json:"failed_command"`    // 执行失败的那条命令的结果，包括退出码与标准错误输出。不是因为命令执行失败时为空。
 }
 
 // ServerInfoCommon 每个Server的信息都要包含的结构。它描述了获取该信息时是否失败，以及对应的服务器原始输出。
 // 如果Output为空，则代表该信息并不是独立访问得到的。
+// CommandResults 为获取该信息时执行的每条命令的结果。
 // FailedInfo 表示当该部分信息查询失败时的原因。
 type ServerInfoCommon struct {
-	Output     string                       `json:"output"`
-	FailedInfo *ServerInfoLoadingFailedInfo `json:"failed_info"`
+	Output         string                       `json:"output"`
+	CommandResults []*CommandResult             `json:"command_results"`
+	FailedInfo     *ServerInfoLoadingFailedInfo `json:"failed_info"`
 }
 
 // ServerHardwareInfo 硬件信息。
@@ -214,6 +291,10 @@ type ServerGPUHardwareInfos struct {
 type ServerGPU struct {
 	// Product 产品名。
 	Product *string `json:"product"`
+	// BusID lspci输出的PCI总线地址，例如17:00.0。
+	BusID *string `json:"bus_id"`
+	// Usage 该GPU当前的使用情况，同时加载了GPU的使用情况并且能够按BusID对应上时才有。
+	Usage *ServerGPUUsage `json:"usage"`
 }
 
 type ServerMemoryHardwareInfo struct {
@@ -263,7 +344,7 @@ type ServerCPUMemProcessesUsageInfo struct {
 }
 
 type ServerCPUMemUsage struct {
-	// UserProcCPUUsage 记录用户进程的CPU使用率。（总比例）
+	// UserProcCPUUsage 记录用户进程的CPU使用率。（总比例）能够采样/proc/stat时与CPUStat.User相同，否则来自top。
 	UserProcCPUUsage *float64 `json:"user_cpu_usage"`
 
 	// MemUsage 总内存使用（比例：如3600MB/8000MB）
@@ -271,6 +352,37 @@ type ServerCPUMemUsage struct {
 
 	// MemTotal 内存总量，使用字符串固定死
 	MemTotal *string `json:"mem_total"`
+
+	// CPUStat 两次采样/proc/stat之间全部核的CPU使用率，CPUStats为每个核的使用率。采样失败时为nil。
+	CPUStat  *ServerCPUStat   `json:"cpu_stat"`
+	CPUStats []*ServerCPUStat `json:"cpu_stats"`
+
+	// LoadAverage 来自/proc/loadavg的1、5、15分钟平均负载。
+	LoadAverage *ServerLoadAverage `json:"load_average"`
+
+	// UptimeSeconds 来自/proc/uptime的开机时长，单位为秒。
+	UptimeSeconds *float64 `json:"uptime_seconds"`
+}
+
+// ServerCPUStat 一个CPU（或者全部CPU）在采样间隔内各类时间的占比，单位为%。
+type ServerCPUStat struct {
+	// CPU /proc/stat中的名称，全部核为cpu，每个核为cpu0、cpu1等。
+	CPU string `json:"cpu"`
+	// Total 除idle与iowait之外的全部时间。
+	Total float64 `json:"total"`
+	// User 包含nice。
+	User float64 `json:"user"`
+	// System 包含irq与softirq。
+	System float64 `json:"system"`
+	IOWait float64 `json:"iowait"`
+	// Steal 虚拟机中被宿主机上其它虚拟机占用的时间。
+	Steal float64 `json:"steal"`
+}
+
+type ServerLoadAverage struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
 }
 
 // ServerProcessInfo 描述一个在Server上的进程信息。
@@ -287,23 +399,127 @@ type ServerProcessInfo struct {
 	CPUUsage *float64 `json:"cpu_usage"`
 	// 内存利用率
 	MemUsage *float64 `json:"mem_usage"`
-	// GPU利用率（不一定能查到）
-	GPUUsage *string `json:"gpu_usage"`
+	// CommandLine 完整的命令行。只有使用GPU的进程才会查询，其它进程为nil。
+	CommandLine *string `json:"command_line"`
+	// GPUIndexes 该进程正在使用的GPU编号，没有使用GPU时为空。
+	GPUIndexes []int `json:"gpu_indexes"`
+	// GPUMemoryMiB 该进程在全部GPU上占用的显存，单位为MiB，没有使用GPU时为nil。
+	GPUMemoryMiB *float64 `json:"gpu_memory_mib"`
 }
 
-// ServerGPUUsageInfo 记录当前GPU使用情况。
-// GPU的使用率查询比较复杂，直接展示原输出。
+// ServerGPUUsageInfo 记录当前GPU使用情况。目前只支持NVIDIA的GPU，来自nvidia-smi --query-gpu。
 type ServerGPUUsageInfo struct {
 	*ServerInfoCommon
+
+	GPUs []*ServerGPUUsage `json:"gpus"`
+	// Processes 正在使用GPU的进程，来自nvidia-smi --query-compute-apps。
+	Processes []*ServerGPUProcess `json:"processes"`
+}
+
+// ServerGPUProcess 一个进程在一块GPU上的使用情况。使用多块GPU的进程在每块GPU上各有一条。
+type ServerGPUProcess struct {
+	PID uint `json:"pid"`
+	// GPUIndex 与ServerGPUUsage.Index一致，按GPUUUID找不到对应的GPU时为nil。
+	GPUIndex *int   `json:"gpu_index"`
+	GPUUUID  string `json:"gpu_uuid"`
+	// UsedMemoryMiB 占用的显存，单位为MiB。
+	UsedMemoryMiB *float64 `json:"used_memory_mib"`
+	// OwnerAccountName 与CommandLine来自ps，查询之后进程已经退出时为nil。
+	OwnerAccountName *string `json:"owner_account_name"`
+	CommandLine      *string `json:"command_line"`
+}
+
+// ServerGPUUsage 一块GPU的使用情况。nvidia-smi不支持或者查询不到的项（[N/A]、[Not Supported]）为nil。
+type ServerGPUUsage struct {
+	// Index nvidia-smi中的GPU编号，即CUDA_VISIBLE_DEVICES使用的编号（默认顺序下）。
+	Index int    `json:"index"`
+	UUID  string `json:"uuid"`
+	Name  string `json:"name"`
+	// BusID nvidia-smi输出的PCI总线地址，例如00000000:17:00.0。
+	BusID string `json:"bus_id"`
+	// UtilizationPercent GPU利用率，单位为%。
+	UtilizationPercent *float64 `json:"utilization_percent"`
+	// MemoryUsedMiB 已使用的显存，单位为MiB。
+	MemoryUsedMiB *float64 `json:"memory_used_mib"`
+	// MemoryTotalMiB 总显存，单位为MiB。
+	MemoryTotalMiB *float64 `json:"memory_total_mib"`
+	// TemperatureC 温度，单位为摄氏度。
+	TemperatureC *float64 `json:"temperature_c"`
+	// PowerDrawW 当前功耗，单位为W。
+	PowerDrawW *float64 `json:"power_draw_w"`
+	// PowerLimitW 功耗上限，单位为W。
+	PowerLimitW *float64 `json:"power_limit_w"`
+	// FanSpeedPercent 风扇转速，单位为%。被动散热的GPU没有风扇。
+	FanSpeedPercent *float64 `json:"fan_speed_percent"`
+	// PersistenceMode 是否开启了持久模式。
+	PersistenceMode *bool `json:"persistence_mode"`
+}
+
+// ServerFilesystemUsageInfo 记录已挂载的文件系统的使用情况，来自df，不包括tmpfs、proc等伪文件系统。
+type ServerFilesystemUsageInfo struct {
+	*ServerInfoCommon
+
+	Filesystems []*ServerFilesystem `json:"filesystems"`
+}
+
+// ServerFilesystem 的Highlights中的值，表示该文件系统存放了哪个重要的路径。
+const (
+	ServerFilesystemHighlightRoot       = "root"
+	ServerFilesystemHighlightHome       = "home"
+	ServerFilesystemHighlightBackupRoot = "backup_root"
+)
+
+// ServerFilesystem 一个已挂载的文件系统的空间与inode使用情况。
+type ServerFilesystem struct {
+	Device     string `json:"device"`
+	FSType     string `json:"fs_type"`
+	MountPoint string `json:"mount_point"`
+	// SizeBytes 总空间，UsedBytes与AvailableBytes为已使用与普通用户可用的空间，单位为字节。
+	SizeBytes      uint64 `json:"size_bytes"`
+	UsedBytes      uint64 `json:"used_bytes"`
+	AvailableBytes uint64 `json:"available_bytes"`
+	// UsePercent 空间使用率，单位为%，与df的Use%相同。
+	UsePercent *float64 `json:"use_percent"`
+	// InodesTotal 与InodesUsed、InodesFree为inode的数量，不支持inode统计的文件系统（例如vfat）为nil。
+	InodesTotal *uint64 `json:"inodes_total"`
+	InodesUsed  *uint64 `json:"inodes_used"`
+	InodesFree  *uint64 `json:"inodes_free"`
+	// InodeUsePercent inode使用率，单位为%。
+	InodeUsePercent *float64 `json:"inode_use_percent"`
+	// Highlights 该文件系统存放了/、/home或者备份文件夹时，为对应的ServerFilesystemHighlightRoot等值，否则为空。
+	Highlights []string `json:"highlights"`
 }
 
 type ServerConnectionTestRequest struct {
 	AccountName string           `form:"account_name" json:"account_name"`
 	AccountPwd  string           `form:"account_pwd" json:"account_pwd"`
 	OSType      da_models.OSType `form:"os_type" json:"os_type"`
+	ServerAuthParam
+	ServerJumpParam
 }
 
 type ServerConnectionTestResponse struct {
 	Connected bool   `json:"connected"`
 	Cause     string `json:"cause"`
 }
+
+type ServerHostKeyInfoRequest struct {
+}
+
+// ServerHostKeyInfoResponse 展示记录的host key指纹与服务器当前提供的指纹。
+type ServerHostKeyInfoResponse struct {
+	StoredFingerprint    string `json:"stored_fingerprint"`
+	PresentedFingerprint string `json:"presented_fingerprint"`
+	Matched              bool   `json:"matched"`
+	// ScanFailedCause 获取服务器当前的host key失败时的原因。
+	ScanFailedCause string `json:"scan_failed_cause"`
+}
+
+// ServerHostKeyAcceptRequest 管理员确认接受服务器当前的host key。
+// Fingerprint 必须与服务器当前提供的指纹一致，防止在查看与接受之间host key又发生了变化。
+type ServerHostKeyAcceptRequest struct {
+	Fingerprint string `form:"fingerprint" json:"fingerprint"`
+}
+
+type ServerHostKeyAcceptResponse struct {
+}
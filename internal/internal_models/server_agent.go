@@ -0,0 +1,22 @@
+package internal_models
+
+// ServerAgentInfo 服务器上运行的agent最近一次推送的数据的信息，只有使用了agent的数据时才不为空。
+// Sections 为使用agent数据的部分，其余部分仍然通过SSH获取。ReceivedAt 与 CollectedAt 均为Unix时间戳（秒）。
+type ServerAgentInfo struct {
+	Hostname        string   `json:"hostname"`
+	IntervalSeconds int      `json:"interval_seconds"`
+	CollectedAt     int64    `json:"collected_at"`
+	ReceivedAt      int64    `json:"received_at"`
+	Sections        []string `json:"sections"`
+}
+
+// ServerAgentTokenRotateResponse Token 只在生成时返回一次，服务器只保存它的SHA256。
+type ServerAgentTokenRotateResponse struct {
+	Token string `json:"token"`
+}
+
+type ServerAgentTokenRevokeResponse struct {
+}
+
+type ServerAgentReportResponse struct {
+}
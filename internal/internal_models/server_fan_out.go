@@ -0,0 +1,49 @@
+package internal_models
+
+type ServerFanOutTarget struct {
+	Host string `json:"host"`
+	Port uint   `json:"port"`
+}
+
+// ServerFanOutRequest 在多台服务器上同时执行同一个命令或者脚本目录中的脚本。
+// 服务器通过Servers明确指定，或者通过Keyword搜索（与服务器列表的搜索相同），二者只能使用一个。
+// Command 与 ScriptID 只能指定一个，直接执行命令需要管理员权限；执行脚本时Args为脚本的参数。
+// Parallelism 为同时执行的服务器数量，TimeoutSeconds 为每台服务器上执行的超时时间，为0时使用配置的默认值。
+type ServerFanOutRequest struct {
+	Servers        []*ServerFanOutTarget `json:"servers"`
+	Keyword        *string               `json:"keyword"`
+	Command        string                `json:"command"`
+	ScriptID       uint                  `json:"script_id"`
+	Args           map[string]string     `json:"args"`
+	Parallelism    int                   `json:"parallelism"`
+	TimeoutSeconds int                   `json:"timeout_seconds"`
+}
+
+// ServerFanOutHostResult 一台服务器上的执行结果。执行的是脚本目录中的脚本时，ScriptRunID 为保存的执行记录的ID。
+type ServerFanOutHostResult struct {
+	Host        string         `json:"host"`
+	Port        uint           `json:"port"`
+	Name        string         `json:"name"`
+	Succeeded   bool           `json:"succeeded"`
+	Result      *CommandResult `json:"result"`
+	ScriptRunID uint           `json:"script_run_id,omitempty"`
+}
+
+// ServerFanOutGroup 结果（退出码、标准输出、标准错误输出以及出错信息）完全相同的一组服务器，Hosts 的格式为host:port。
+type ServerFanOutGroup struct {
+	Succeeded  bool     `json:"succeeded"`
+	ExitStatus int      `json:"exit_status"`
+	Stdout     string   `json:"stdout"`
+	Stderr     string   `json:"stderr"`
+	Error      string   `json:"error"`
+	Hosts      []string `json:"hosts"`
+}
+
+// ServerFanOutResponse Hosts 按请求中服务器的顺序排列，Groups 按服务器数量从多到少排列。
+type ServerFanOutResponse struct {
+	Total     int                       `json:"total"`
+	Succeeded int                       `json:"succeeded"`
+	Failed    int                       `json:"failed"`
+	Hosts     []*ServerFanOutHostResult `json:"hosts"`
+	Groups    []*ServerFanOutGroup      `json:"groups"`
+}
@@ -0,0 +1,76 @@
+package internal_models
+
+// ServerFileInfo 服务器上的一个文件或文件夹。
+// Mode 为ls -l格式的权限，例如drwxr-xr-x；Perm 为八进制的权限位，例如0755。
+// Owner 为UID对应的账户名，找不到对应的账户时为空。ModTime 为Unix时间戳（秒）。
+type ServerFileInfo struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	IsDir     bool   `json:"is_dir"`
+	IsSymlink bool   `json:"is_symlink"`
+	Size      int64  `json:"size"`
+	Mode      string `json:"mode"`
+	Perm      string `json:"perm"`
+	UID       uint   `json:"uid"`
+	GID       uint   `json:"gid"`
+	Owner     string `json:"owner"`
+	ModTime   int64  `json:"mod_time"`
+}
+
+type ServerFilesInfosRequest struct {
+	Path string `form:"path" json:"path"`
+}
+
+type ServerFilesInfosResponse struct {
+	Path  string            `json:"path"`
+	Files []*ServerFileInfo `json:"files"`
+}
+
+type ServerFilesStatRequest struct {
+	Path string `form:"path" json:"path"`
+}
+
+type ServerFilesStatResponse struct {
+	File *ServerFileInfo `json:"file"`
+}
+
+type ServerFilesDownloadRequest struct {
+	Path string `form:"path" json:"path"`
+}
+
+// ServerFilesUploadRequest 文件内容为请求体本身。Perm 为八进制的权限位，为空时使用0644。
+type ServerFilesUploadRequest struct {
+	Path string `form:"path" json:"path"`
+	Perm string `form:"perm" json:"perm"`
+}
+
+type ServerFilesUploadResponse struct {
+	File *ServerFileInfo `json:"file"`
+}
+
+// ServerFilesChmodRequest Perm 为八进制的权限位，例如0755。
+type ServerFilesChmodRequest struct {
+	Path string `json:"path"`
+	Perm string `json:"perm"`
+}
+
+type ServerFilesChmodResponse struct {
+}
+
+// ServerFilesChownRequest Owner 为服务器上的账户名，文件的组为该账户的主组。
+type ServerFilesChownRequest struct {
+	Path  string `json:"path"`
+	Owner string `json:"owner"`
+}
+
+type ServerFilesChownResponse struct {
+}
+
+// ServerFilesDeleteRequest Recursive 为true时删除整个文件夹，否则只能删除文件与空文件夹。
+type ServerFilesDeleteRequest struct {
+	Path      string `form:"path" json:"path"`
+	Recursive bool   `form:"recursive" json:"recursive"`
+}
+
+type ServerFilesDeleteResponse struct {
+}
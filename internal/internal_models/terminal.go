@@ -0,0 +1,68 @@
+package internal_models
+
+// TerminalSession 管理员通过网页终端打开的一次终端会话。EndedAt 为0时会话仍在进行中。
+// ExitStatus 为shell的退出码，没有正常退出时（例如关闭了网页）为-1，原因在Error中。
+type TerminalSession struct {
+	ID            uint   `json:"id"`
+	UserID        uint   `json:"user_id"`
+	Host          string `json:"host"`
+	Port          uint   `json:"port"`
+	Account       string `json:"account"`
+	Cols          int    `json:"cols"`
+	Rows          int    `json:"rows"`
+	StartedAt     int64  `json:"started_at"`
+	EndedAt       int64  `json:"ended_at"`
+	DurationMs    int64  `json:"duration_ms"`
+	ExitStatus    int    `json:"exit_status"`
+	Error         string `json:"error"`
+	RecordingSize int64  `json:"recording_size"`
+}
+
+// TerminalOpenRequest 终端的初始大小，为0时为80列24行。
+type TerminalOpenRequest struct {
+	Cols int `form:"cols" json:"cols"`
+	Rows int `form:"rows" json:"rows"`
+}
+
+const (
+	// TerminalMessageInput 用户的输入，内容在Data中。
+	TerminalMessageInput = "input"
+	// TerminalMessageResize 修改终端的大小。
+	TerminalMessageResize = "resize"
+	// TerminalMessageExit 终端已经结束，这是服务器发送的最后一条消息。
+	TerminalMessageExit = "exit"
+)
+
+// TerminalClientMessage 前端通过WebSocket发送的文本消息。
+type TerminalClientMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// TerminalExitMessage 终端结束时服务器发送的文本消息。终端的输出则以二进制消息原样发送。
+type TerminalExitMessage struct {
+	Type       string `json:"type"`
+	SessionID  uint   `json:"session_id"`
+	ExitStatus int    `json:"exit_status"`
+	Error      string `json:"error"`
+}
+
+// TerminalSessionsInfosRequest 各个筛选条件为空时不筛选。
+type TerminalSessionsInfosRequest struct {
+	UserID *uint   `form:"user_id" json:"user_id"`
+	Host   *string `form:"host" json:"host"`
+	Port   *uint   `form:"port" json:"port"`
+	From   uint    `form:"from" json:"from"`
+	Size   uint    `form:"size" json:"size"`
+}
+
+type TerminalSessionsInfosResponse struct {
+	Infos      []*TerminalSession `json:"infos"`
+	TotalCount uint               `json:"total_count"`
+}
+
+type TerminalSessionsInfoResponse struct {
+	*TerminalSession
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicastHeader asciicast v2格式的第一行。
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+const (
+	asciicastEventOutput = "o"
+	asciicastEventResize = "r"
+)
+
+// asciicastRecorder 以asciicast v2格式录制终端：第一行为header，之后每行为一个[距开始的秒数, 事件类型, 内容]的事件。
+// 录像可以直接使用asciinema或者asciinema-player回放。写入失败后不再写入，错误通过Err获取。
+type asciicastRecorder struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	start time.Time
+	// pending 上一次输出末尾不完整的UTF-8字符，留到下一次输出时一起写入，避免被替换成U+FFFD。
+	pending []byte
+	err     error
+}
+
+// newAsciicastRecorder 写入header，之后的事件的时间都相对于start。
+func newAsciicastRecorder(w io.Writer, start time.Time, header *asciicastHeader) (*asciicastRecorder, error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	r := &asciicastRecorder{
+		enc:   enc,
+		start: start,
+	}
+	header.Version = 2
+	header.Timestamp = start.Unix()
+	if err := r.enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Output 录制终端的一段输出。
+func (r *asciicastRecorder) Output(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.pending, p...)
+	data, r.pending = splitIncompleteUTF8(data)
+	if len(data) > 0 {
+		r.event(asciicastEventOutput, string(data))
+	}
+}
+
+// Resize 录制终端大小的变化，内容为“列数x行数”。
+func (r *asciicastRecorder) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.event(asciicastEventResize, formatTerminalSize(cols, rows))
+}
+
+// Close 写入剩余的输出。
+func (r *asciicastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) > 0 {
+		r.event(asciicastEventOutput, string(r.pending))
+		r.pending = nil
+	}
+	return r.err
+}
+
+func (r *asciicastRecorder) event(typ, data string) {
+	if r.err != nil {
+		return
+	}
+	elapsed := float64(time.Since(r.start).Microseconds()) / 1e6
+	r.err = r.enc.Encode([]interface{}{elapsed, typ, data})
+}
+
+func formatTerminalSize(cols, rows int) string {
+	return fmt.Sprintf("%dx%d", cols, rows)
+}
+
+// splitIncompleteUTF8 将p分为以完整的UTF-8字符结尾的部分，以及末尾不完整的字符。
+func splitIncompleteUTF8(p []byte) ([]byte, []byte) {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(p[i]) {
+			continue
+		}
+		if !utf8.FullRune(p[i:]) {
+			return p[:i], append([]byte(nil), p[i:]...)
+		}
+		break
+	}
+	return p, nil
+}
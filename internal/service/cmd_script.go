@@ -0,0 +1,40 @@
+package service
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"github.com/gin-gonic/gin"
+)
+
+type CmdScriptsService struct{}
+
+func GetCmdScriptsService() *CmdScriptsService {
+	return &CmdScriptsService{}
+}
+
+// List 列出全部生效中的命令脚本以及它们的来源。
+func (s *CmdScriptsService) List(c *gin.Context) ([]*internal_models.CmdScript, *SErr.APIErr) {
+	infos, err := server_executor.ListCmdScripts()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*internal_models.CmdScript, 0, len(infos))
+	for _, info := range infos {
+		params := make([]*internal_models.CmdScriptParam, 0, len(info.Params))
+		for _, param := range info.Params {
+			params = append(params, &internal_models.CmdScriptParam{
+				Name: param.Name,
+				Type: string(param.Type),
+			})
+		}
+		res = append(res, &internal_models.CmdScript{
+			Dir:      info.Dir,
+			Name:     info.Name,
+			Source:   string(info.Source),
+			Location: info.Location,
+			Params:   params,
+		})
+	}
+	return res, nil
+}
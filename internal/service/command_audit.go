@@ -0,0 +1,102 @@
+package service
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/dal"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"log"
+	"time"
+)
+
+type CommandAuditsService struct{}
+
+func GetCommandAuditsService() *CommandAuditsService {
+	return &CommandAuditsService{}
+}
+
+// Audit 保存一条命令的审计记录，作为server_executor的CommandAuditor使用。保存失败不影响命令本身的结果，只打印日志。
+func (s *CommandAuditsService) Audit(entry *server_executor.CommandAuditEntry) {
+	audit := &daModels.CommandAudit{
+		UserID:       uint(entry.ActorUserID),
+		Host:         entry.Host,
+		Port:         entry.Port,
+		Script:       entry.Script,
+		ExitStatus:   entry.ExitStatus,
+		ErrCode:      entry.ErrCode,
+		Error:        entry.Error,
+		StartedAt:    entry.StartedAt,
+		DurationMs:   entry.Duration.Milliseconds(),
+		OutputDigest: entry.OutputDigest,
+		OutputSize:   entry.OutputSize,
+	}
+	if len(entry.Args) > 0 {
+		argsBytes, _ := json.Marshal(entry.Args)
+		audit.Args = string(argsBytes)
+	}
+	if err := dal.GetCommandAuditDal().Create(audit); err != nil {
+		log.Printf("CommandAuditsService Audit failed, userID=[%d], Host=[%s], Port=[%d], script=[%s], err=[%s]", entry.ActorUserID, entry.Host, entry.Port, entry.Script, err.Message)
+	}
+}
+
+// Audits 查询审计记录。
+func (s *CommandAuditsService) Audits(c *gin.Context, req *internal_models.CommandAuditsInfosRequest) ([]*internal_models.CommandAudit, uint, *SErr.APIErr) {
+	cond := &dal.CommandAuditCond{Cond: &daModels.CommandAudit{}}
+	if req.UserID != nil {
+		cond.Cond.UserID = *req.UserID
+	}
+	if req.Host != nil {
+		cond.Cond.Host = *req.Host
+	}
+	if req.Port != nil {
+		cond.Cond.Port = *req.Port
+	}
+	if req.Script != nil {
+		cond.Cond.Script = *req.Script
+	}
+	if req.StartedFrom != nil {
+		from := time.Unix(*req.StartedFrom, 0)
+		cond.StartedFrom = &from
+	}
+	if req.StartedTo != nil {
+		to := time.Unix(*req.StartedTo, 0)
+		cond.StartedTo = &to
+	}
+	if cond.StartedFrom != nil && cond.StartedTo != nil && cond.StartedFrom.After(*cond.StartedTo) {
+		return nil, 0, SErr.InvalidParamErr.CustomMessage("started_from不能晚于started_to！")
+	}
+	audits, totalCount, err := dal.GetCommandAuditDal().List(cond, req.From, req.Size)
+	if err != nil {
+		return nil, 0, err
+	}
+	res := make([]*internal_models.CommandAudit, 0, len(audits))
+	for _, audit := range audits {
+		res = append(res, s.packAudit(audit))
+	}
+	return res, totalCount, nil
+}
+
+func (s *CommandAuditsService) packAudit(audit *daModels.CommandAudit) *internal_models.CommandAudit {
+	var args map[string]string
+	if audit.Args != "" {
+		_ = json.Unmarshal([]byte(audit.Args), &args)
+	}
+	return &internal_models.CommandAudit{
+		ID:           audit.ID,
+		UserID:       audit.UserID,
+		Host:         audit.Host,
+		Port:         audit.Port,
+		Script:       audit.Script,
+		Args:         args,
+		ExitStatus:   audit.ExitStatus,
+		ErrCode:      audit.ErrCode,
+		Error:        audit.Error,
+		StartedAt:    audit.StartedAt.Unix(),
+		DurationMs:   audit.DurationMs,
+		OutputDigest: audit.OutputDigest,
+		OutputSize:   audit.OutputSize,
+	}
+}
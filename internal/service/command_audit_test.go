@@ -0,0 +1,105 @@
+package service
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCommandAudits(t *testing.T) {
+	initReplayEnv(t)
+	svc := GetCommandAuditsService()
+	start := time.Unix(1700000000, 0)
+	for i, entry := range []*server_executor.CommandAuditEntry{
+		{ActorUserID: 1, Host: "10.0.0.1", Port: 22, Script: "user_add_with_openssl_pwd", Args: server_executor.CmdArgs{"account_name": "bob", "pwd": "******"}, StartedAt: start, Duration: 1500 * time.Millisecond},
+		{ActorUserID: 2, Host: "10.0.0.1", Port: 22, Script: "get_account_list", StartedAt: start.Add(time.Hour), OutputDigest: server_executor.OutputDigest("root|0|0\n", ""), OutputSize: 9},
+		{Host: "10.0.0.2", Port: 2222, Script: "nvidia_gpu_usage", ExitStatus: 9, ErrCode: 40009, Error: "failed", StartedAt: start.Add(2 * time.Hour)},
+	} {
+		svc.Audit(entry)
+		if i == 0 && entry.Args["pwd"] != "******" {
+			t.Fatal("Audit should not modify the entry")
+		}
+	}
+
+	query := func(req *internal_models.CommandAuditsInfosRequest) []*internal_models.CommandAudit {
+		t.Helper()
+		req.Size = 10
+		audits, total, err := svc.Audits(nil, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(total) != len(audits) {
+			t.Fatalf("unexpected total %d, got %d audits", total, len(audits))
+		}
+		return audits
+	}
+	all := query(&internal_models.CommandAuditsInfosRequest{})
+	if len(all) != 3 || all[0].Script != "nvidia_gpu_usage" || all[2].Script != "user_add_with_openssl_pwd" {
+		t.Fatalf("unexpected audits %+v", all)
+	}
+	added := all[2]
+	if added.UserID != 1 || added.Args["pwd"] != "******" || added.DurationMs != 1500 || added.StartedAt != start.Unix() {
+		t.Fatalf("unexpected audit %+v", added)
+	}
+	if all[0].ExitStatus != 9 || all[0].ErrCode != 40009 || all[0].UserID != 0 || all[0].Args != nil {
+		t.Fatalf("unexpected audit %+v", all[0])
+	}
+
+	host, port, userID, script := "10.0.0.1", uint(22), uint(2), "user_add_with_openssl_pwd"
+	if audits := query(&internal_models.CommandAuditsInfosRequest{Host: &host, Port: &port}); len(audits) != 2 {
+		t.Fatalf("unexpected audits %+v", audits)
+	}
+	if audits := query(&internal_models.CommandAuditsInfosRequest{UserID: &userID}); len(audits) != 1 || audits[0].OutputSize != 9 {
+		t.Fatalf("unexpected audits %+v", audits)
+	}
+	if audits := query(&internal_models.CommandAuditsInfosRequest{Script: &script}); len(audits) != 1 || audits[0].ID != added.ID {
+		t.Fatalf("unexpected audits %+v", audits)
+	}
+	from, to := start.Add(time.Hour).Unix(), start.Add(2*time.Hour).Unix()
+	if audits := query(&internal_models.CommandAuditsInfosRequest{StartedFrom: &from, StartedTo: &to}); len(audits) != 2 || audits[1].Script != "get_account_list" {
+		t.Fatalf("unexpected audits %+v", audits)
+	}
+	if audits := query(&internal_models.CommandAuditsInfosRequest{StartedTo: &from}); len(audits) != 2 {
+		t.Fatalf("unexpected audits %+v", audits)
+	}
+	if _, _, err := svc.Audits(nil, &internal_models.CommandAuditsInfosRequest{StartedFrom: &to, StartedTo: &from}); err == nil {
+		t.Fatal("started_from after started_to should be rejected")
+	}
+}
+
+func TestRequestContextActor(t *testing.T) {
+	user := &daModels.User{}
+	user.ID = 5
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/servers", nil)
+	c.Request.Header.Set(tokenKey, GetSessionsService().GenToken(user))
+	var log []*server_executor.CommandAuditEntry
+	t.Cleanup(server_executor.UseCommandAuditor(func(entry *server_executor.CommandAuditEntry) {
+		log = append(log, entry)
+	}))
+	// 用一个NOPASSWD的假sudo在本机执行命令。
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "sudo"), []byte("#!/bin/sh\n[ \"$1\" = \"-n\" ] && shift\nexec \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	es, err := server_executor.OpenExecutorService(&server_executor.OpenExecutorServiceParam{OSType: daModels.OSTypeLinuxLocal})
+	if err != nil {
+		t.Skipf("this machine is not a supported Linux distribution: %s", err.Message)
+	}
+	defer es.Close()
+	if _, err := es.PathExists(requestContext(c), "/"); err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].ActorUserID != 5 || log[0].Script != "path_exists" {
+		t.Fatalf("unexpected audit log %+v", log)
+	}
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamOutput 使之后在c的请求中执行的命令每输出一行就交给listener，用于在命令结束之前向前端推送执行进度。
+// listener 会在多个goroutine中同时调用，例如在多台服务器上同时执行时。
+func StreamOutput(c *gin.Context, listener func(line *internal_models.CommandOutputLine)) {
+	c.Request = c.Request.WithContext(server_executor.WithOutputListener(c.Request.Context(), listener))
+}
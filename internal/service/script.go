@@ -0,0 +1,325 @@
+package service
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/dal"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScriptsService 管理员定义的脚本目录，以及在服务器上执行其中的脚本。
+type ScriptsService struct{}
+
+func GetScriptsService() *ScriptsService {
+	return &ScriptsService{}
+}
+
+// maxScriptRunOutputBytes 执行记录中保存的标准输出与标准错误输出的最大长度，超出的部分会被截断。
+const maxScriptRunOutputBytes = 1 << 20
+
+var scriptNameReg = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,50}$`)
+
+// scriptCmdName 在服务器上执行脚本时使用的脚本名，与cmds_scripts中的脚本区分开。
+// 可以在配置文件的command_timeout_config.script_seconds中使用该名字（例如script:cleanup_logs）为某个脚本单独配置超时时间。
+func scriptCmdName(name string) string {
+	return "script:" + name
+}
+
+// Create 创建一个脚本。
+func (s *ScriptsService) Create(c *gin.Context, creatorID int, req *internal_models.ScriptsCreateRequest) (*internal_models.Script, *SErr.APIErr) {
+	script, err := s.validScript(req.Name, req.Description, req.Body, req.AllowedServers, req.RequiredRole)
+	if err != nil {
+		return nil, err
+	}
+	script.CreatorID = uint(creatorID)
+	err = dal.GetScriptDal().Create(script)
+	if err != nil {
+		return nil, err
+	}
+	return s.packScript(script), nil
+}
+
+// Update 修改一个脚本。修改不会影响已有的执行记录，它们保存了执行时的脚本内容。
+func (s *ScriptsService) Update(c *gin.Context, ID uint, req *internal_models.ScriptsUpdateRequest) (*internal_models.Script, *SErr.APIErr) {
+	scriptDal := dal.GetScriptDal()
+	if _, err := scriptDal.Get(ID); err != nil {
+		return nil, err
+	}
+	script, err := s.validScript(req.Name, req.Description, req.Body, req.AllowedServers, req.RequiredRole)
+	if err != nil {
+		return nil, err
+	}
+	err = scriptDal.Update(ID, script)
+	if err != nil {
+		return nil, err
+	}
+	return s.Info(c, ID)
+}
+
+// Delete 删除一个脚本，它的执行记录仍然保留。
+func (s *ScriptsService) Delete(c *gin.Context, ID uint) *SErr.APIErr {
+	return dal.GetScriptDal().Delete(ID)
+}
+
+// Info 获取一个脚本。
+func (s *ScriptsService) Info(c *gin.Context, ID uint) (*internal_models.Script, *SErr.APIErr) {
+	script, err := dal.GetScriptDal().Get(ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.packScript(script), nil
+}
+
+// Infos 获取脚本列表。
+func (s *ScriptsService) Infos(c *gin.Context, from, size uint) ([]*internal_models.Script, uint, *SErr.APIErr) {
+	scripts, totalCount, err := dal.GetScriptDal().List(from, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	res := make([]*internal_models.Script, 0, len(scripts))
+	for _, script := range scripts {
+		res = append(res, s.packScript(script))
+	}
+	return res, totalCount, nil
+}
+
+// validScript 检查脚本的各个字段，脚本的正文需要是合法的命令模板。返回待保存的脚本。
+func (s *ScriptsService) validScript(name, description, body string, allowedServers []string, role daModels.ScriptRole) (*daModels.Script, *SErr.APIErr) {
+	if !scriptNameReg.MatchString(name) {
+		return nil, SErr.InvalidParamErr.CustomMessageF("脚本名[%s]不合法！只能包含字母、数字以及._-，长度为1到50。", name)
+	}
+	if len([]rune(description)) > 140 {
+		return nil, SErr.InvalidParamErr.CustomMessage("脚本的描述不能超过140个字符！")
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, SErr.InvalidParamErr.CustomMessage("脚本内容不能为空！")
+	}
+	if _, err := server_executor.ParseScriptParams(name, body); err != nil {
+		return nil, err
+	}
+	switch role {
+	case "":
+		// 没有指定时只允许管理员执行。
+		role = daModels.ScriptRoleAdmin
+	case daModels.ScriptRoleUser, daModels.ScriptRoleAdmin:
+	default:
+		return nil, SErr.InvalidParamErr.CustomMessageF("不支持的角色%s！", role)
+	}
+	servers := make([]string, 0, len(allowedServers))
+	for _, server := range allowedServers {
+		server = strings.TrimSpace(server)
+		if server == daModels.ScriptAllServers {
+			servers = append(servers, server)
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(server)
+		port, portErr := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || host == "" || portErr != nil || port == 0 {
+			return nil, SErr.InvalidParamErr.CustomMessageF("允许执行的服务器[%s]格式有误，应为host:port或者*！", server)
+		}
+		servers = append(servers, net.JoinHostPort(host, portStr))
+	}
+	if len(servers) == 0 {
+		return nil, SErr.InvalidParamErr.CustomMessage("至少需要允许一个服务器执行该脚本！")
+	}
+	return &daModels.Script{
+		Name:           name,
+		Description:    description,
+		Body:           body,
+		AllowedServers: strings.Join(servers, ","),
+		RequiredRole:   role,
+	}, nil
+}
+
+// allowedOn 脚本是否允许在该服务器上执行。
+func (s *ScriptsService) allowedOn(script *daModels.Script, Host string, Port uint) bool {
+	target := net.JoinHostPort(Host, strconv.Itoa(int(Port)))
+	for _, server := range strings.Split(script.AllowedServers, ",") {
+		if server == daModels.ScriptAllServers || server == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Run 在服务器上执行脚本目录中的脚本，并保存执行记录。
+// 检查通过后，无论执行成功与否（包括参数有误、连接服务器失败、命令返回非0的退出码）都会保存执行记录并返回它，失败原因在Result.Error中。
+func (s *ScriptsService) Run(c *gin.Context, userID int, isAdmin bool, ID uint, req *internal_models.ScriptsRunRequest) (*internal_models.ScriptRun, *SErr.APIErr) {
+	script, err := s.runnableScript(ID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !s.allowedOn(script, req.Host, req.Port) {
+		return nil, s.notAllowedErr(script, req.Host, req.Port)
+	}
+	serverBasic, _, err := GetServersService().basicInfo(c, req.Host, req.Port)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.runOn(requestContext(c), c, userID, script, serverBasic, server_executor.CmdArgs(req.Args))
+	if err != nil {
+		return nil, err
+	}
+	return s.packRun(run), nil
+}
+
+// runnableScript 获取脚本，并检查用户是否具有执行它所需的角色。
+func (s *ScriptsService) runnableScript(ID uint, isAdmin bool) (*daModels.Script, *SErr.APIErr) {
+	script, err := dal.GetScriptDal().Get(ID)
+	if err != nil {
+		return nil, err
+	}
+	if script.RequiredRole != daModels.ScriptRoleUser && !isAdmin {
+		return nil, SErr.AdminOnlyActionErr
+	}
+	return script, nil
+}
+
+func (s *ScriptsService) notAllowedErr(script *daModels.Script, Host string, Port uint) *SErr.APIErr {
+	return SErr.ForbiddenErr.CustomMessageF("脚本%s不允许在服务器Host=[%s], Port=[%d]上执行！", script.Name, Host, Port)
+}
+
+// runOn 在服务器上执行脚本并保存执行记录，ctx用于控制执行的超时与取消。只有保存执行记录失败时才返回错误。
+func (s *ScriptsService) runOn(ctx context.Context, c *gin.Context, userID int, script *daModels.Script, serverBasic *internal_models.ServerBasic, args server_executor.CmdArgs) (*daModels.ScriptRun, *SErr.APIErr) {
+	argsBytes, _ := json.Marshal(server_executor.RedactScriptArgs(script.Body, args))
+	run := &daModels.ScriptRun{
+		ScriptID:   script.ID,
+		ScriptName: script.Name,
+		ScriptBody: script.Body,
+		UserID:     uint(userID),
+		Host:       serverBasic.Host,
+		Port:       serverBasic.Port,
+		Args:       string(argsBytes),
+		ExitStatus: -1,
+		StartedAt:  time.Now(),
+	}
+	var resp *server_executor.ExecutorServiceRunScriptResp
+	runErr := GetServersService().withConnectionByServer(c, serverBasic, func(es server_executor.ExecutorService) *SErr.APIErr {
+		var err *SErr.APIErr
+		resp, err = es.RunScript(ctx, scriptCmdName(script.Name), script.Body, args)
+		return err
+	})
+	if resp != nil && len(resp.Results) > 0 {
+		result := resp.Results[len(resp.Results)-1]
+		run.Stdout = truncateScriptOutput(result.Stdout)
+		run.Stderr = truncateScriptOutput(result.Stderr)
+		run.ExitStatus = result.ExitStatus
+		run.StartedAt = result.StartedAt
+		run.DurationMs = result.DurationMs
+	}
+	if runErr != nil {
+		run.Error = runErr.Message
+	}
+	log.Printf("ScriptsService Run script=[%s], Host=[%s], Port=[%d], userID=[%d], exitStatus=[%d], err=[%v]", script.Name, run.Host, run.Port, userID, run.ExitStatus, runErr)
+	err := dal.GetScriptRunDal().Create(run)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// truncateScriptOutput 截断过长的输出，保留开头的部分。
+func truncateScriptOutput(output string) string {
+	if len(output) <= maxScriptRunOutputBytes {
+		return output
+	}
+	return strings.ToValidUTF8(output[:maxScriptRunOutputBytes], "") + "\n...（输出过长，已截断）"
+}
+
+// Runs 获取执行记录列表。非管理员只能看到自己的执行记录。
+func (s *ScriptsService) Runs(c *gin.Context, userID int, isAdmin bool, req *internal_models.ScriptRunsInfosRequest) ([]*internal_models.ScriptRun, uint, *SErr.APIErr) {
+	cond := &daModels.ScriptRun{}
+	if req.ScriptID != nil {
+		cond.ScriptID = *req.ScriptID
+	}
+	if req.Host != nil {
+		cond.Host = *req.Host
+	}
+	if req.Port != nil {
+		cond.Port = *req.Port
+	}
+	if !isAdmin {
+		cond.UserID = uint(userID)
+	}
+	runs, totalCount, err := dal.GetScriptRunDal().List(cond, req.From, req.Size)
+	if err != nil {
+		return nil, 0, err
+	}
+	res := make([]*internal_models.ScriptRun, 0, len(runs))
+	for _, run := range runs {
+		res = append(res, s.packRun(run))
+	}
+	return res, totalCount, nil
+}
+
+// RunInfo 获取一条执行记录。非管理员只能查看自己的执行记录。
+func (s *ScriptsService) RunInfo(c *gin.Context, userID int, isAdmin bool, runID uint) (*internal_models.ScriptRun, *SErr.APIErr) {
+	run, err := dal.GetScriptRunDal().Get(runID)
+	if err != nil {
+		return nil, err
+	}
+	if !isAdmin && run.UserID != uint(userID) {
+		return nil, SErr.ForbiddenErr.CustomMessage("只能查看自己的执行记录！")
+	}
+	return s.packRun(run), nil
+}
+
+func (s *ScriptsService) packScript(script *daModels.Script) *internal_models.Script {
+	params := make([]*internal_models.CmdScriptParam, 0)
+	// 保存前已经检查过，这里不会出错。
+	if parsed, err := server_executor.ParseScriptParams(script.Name, script.Body); err == nil {
+		for _, param := range parsed {
+			params = append(params, &internal_models.CmdScriptParam{
+				Name: param.Name,
+				Type: string(param.Type),
+			})
+		}
+	}
+	return &internal_models.Script{
+		ID:             script.ID,
+		CreatedAt:      script.CreatedAt.Unix(),
+		UpdatedAt:      script.UpdatedAt.Unix(),
+		Name:           script.Name,
+		Description:    script.Description,
+		Body:           script.Body,
+		Params:         params,
+		AllowedServers: strings.Split(script.AllowedServers, ","),
+		RequiredRole:   script.RequiredRole,
+		CreatorID:      script.CreatorID,
+	}
+}
+
+func (s *ScriptsService) packRun(run *daModels.ScriptRun) *internal_models.ScriptRun {
+	args := make(map[string]string)
+	_ = json.Unmarshal([]byte(run.Args), &args)
+	return &internal_models.ScriptRun{
+		ID:         run.ID,
+		CreatedAt:  run.CreatedAt.Unix(),
+		ScriptID:   run.ScriptID,
+		ScriptName: run.ScriptName,
+		ScriptBody: run.ScriptBody,
+		UserID:     run.UserID,
+		Host:       run.Host,
+		Port:       run.Port,
+		Args:       args,
+		Result: &internal_models.CommandResult{
+			Script:     scriptCmdName(run.ScriptName),
+			Stdout:     run.Stdout,
+			Stderr:     run.Stderr,
+			ExitStatus: run.ExitStatus,
+			StartedAt:  run.StartedAt,
+			DurationMs: run.DurationMs,
+			Error:      run.Error,
+		},
+	}
+}
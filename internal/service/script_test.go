@@ -0,0 +1,149 @@
+package service
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestScriptsService_RunReplay(t *testing.T) {
+	servers := initReplayEnv(t)
+	ubuntu, centos := servers[0].transcript, servers[1].transcript
+	// 回放时按脚本名与隐藏了敏感参数的参数查找结果。
+	ubuntu.Entries = append(ubuntu.Entries, &server_executor.TranscriptEntry{
+		Script: "script:disk_usage",
+		Args:   server_executor.CmdArgs{"dir": "/data", "token": "******"},
+		Result: &internal_models.CommandResult{Stdout: "12G\t/data\n"},
+	}, &server_executor.TranscriptEntry{
+		Script:  "script:disk_usage",
+		Args:    server_executor.CmdArgs{"dir": "/missing", "token": "******"},
+		Result:  &internal_models.CommandResult{Stderr: "du: cannot access '/missing': No such file or directory\n", ExitStatus: 1, Error: "exit status 1"},
+		ErrCode: SErr.CodeCommandFailed,
+	})
+
+	svc := GetScriptsService()
+	body := "# @param dir path\n# @param token secret\ndu -sh {{dir}} && curl -s -H {{token}} https://example.com/report\n"
+	if _, err := svc.Create(nil, 1, &internal_models.ScriptsCreateRequest{Name: "disk_usage", Body: "# @param dir path\ndu -sh /data", AllowedServers: []string{"*"}}); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unused param should be rejected, err=%v", err)
+	}
+	ubuntuAddr := net.JoinHostPort(ubuntu.Host, strconv.Itoa(int(ubuntu.Port)))
+	script, err := svc.Create(nil, 1, &internal_models.ScriptsCreateRequest{
+		Name:           "disk_usage",
+		Body:           body,
+		AllowedServers: []string{ubuntuAddr},
+		RequiredRole:   daModels.ScriptRoleUser,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script.Params) != 2 || script.Params[1].Type != "secret" || script.AllowedServers[0] != ubuntuAddr {
+		t.Fatalf("unexpected script %+v", script)
+	}
+	if _, err := svc.Create(nil, 1, &internal_models.ScriptsCreateRequest{Name: "disk_usage", Body: body, AllowedServers: []string{"*"}}); err == nil {
+		t.Fatal("duplicated name should be rejected")
+	}
+
+	run, err := svc.Run(nil, 2, false, script.ID, &internal_models.ScriptsRunRequest{
+		Host: ubuntu.Host, Port: ubuntu.Port, Args: map[string]string{"dir": "/data", "token": "s3cret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Result.Stdout != "12G\t/data\n" || run.Result.ExitStatus != 0 || run.Result.Error != "" || run.Args["token"] != "******" || run.UserID != 2 {
+		t.Fatalf("unexpected run %+v, result %+v", run, run.Result)
+	}
+	// 执行失败同样会保存执行记录。
+	run, err = svc.Run(nil, 2, false, script.ID, &internal_models.ScriptsRunRequest{
+		Host: ubuntu.Host, Port: ubuntu.Port, Args: map[string]string{"dir": "/missing", "token": "s3cret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Result.ExitStatus != 1 || run.Result.Error == "" {
+		t.Fatalf("unexpected result %+v", run.Result)
+	}
+	// 参数有误时不会在服务器上执行，但是仍然保存执行记录。
+	run, err = svc.Run(nil, 3, true, script.ID, &internal_models.ScriptsRunRequest{
+		Host: ubuntu.Host, Port: ubuntu.Port, Args: map[string]string{"dir": "../etc", "token": "s3cret"},
+	})
+	if err != nil || run.Result.ExitStatus != -1 || run.Result.Error == "" {
+		t.Fatalf("unexpected run %+v, err=%v", run, err)
+	}
+
+	// 不在允许的服务器中，或者没有要求的角色时，拒绝执行，也不保存执行记录。
+	if _, err := svc.Run(nil, 2, false, script.ID, &internal_models.ScriptsRunRequest{Host: centos.Host, Port: centos.Port}); err == nil || err.Code != SErr.ForbiddenErr.Code {
+		t.Fatalf("unexpected err %v", err)
+	}
+	_, err = svc.Update(nil, script.ID, &internal_models.ScriptsUpdateRequest{
+		Name:           "disk_usage",
+		Body:           body,
+		AllowedServers: []string{ubuntuAddr},
+		RequiredRole:   daModels.ScriptRoleAdmin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Run(nil, 2, false, script.ID, &internal_models.ScriptsRunRequest{Host: ubuntu.Host, Port: ubuntu.Port}); err != SErr.AdminOnlyActionErr {
+		t.Fatalf("unexpected err %v", err)
+	}
+
+	// 非管理员只能看到自己的执行记录。
+	runs, total, err := svc.Runs(nil, 2, false, &internal_models.ScriptRunsInfosRequest{Size: 10})
+	if err != nil || total != 2 || len(runs) != 2 {
+		t.Fatalf("unexpected runs %+v, total=%d, err=%v", runs, total, err)
+	}
+	if _, err := svc.RunInfo(nil, 2, false, run.ID); err == nil || err.Code != SErr.ForbiddenErr.Code {
+		t.Fatalf("unexpected err %v", err)
+	}
+	_, total, err = svc.Runs(nil, 3, true, &internal_models.ScriptRunsInfosRequest{ScriptID: &script.ID, Size: 10})
+	if err != nil || total != 3 {
+		t.Fatalf("unexpected total %d, err=%v", total, err)
+	}
+
+	// 删除脚本后执行记录仍然保留。
+	if err := svc.Delete(nil, script.ID); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := svc.RunInfo(nil, 3, true, run.ID); err != nil || info.ScriptBody != body {
+		t.Fatalf("unexpected run %+v, err=%v", info, err)
+	}
+}
+
+func TestScriptsService_RunGoTemplateBraces(t *testing.T) {
+	servers := initReplayEnv(t)
+	ubuntu := servers[0].transcript
+	ubuntu.Entries = append(ubuntu.Entries, &server_executor.TranscriptEntry{
+		Script: "script:docker_names",
+		Args:   server_executor.CmdArgs{"name": "web"},
+		Result: &internal_models.CommandResult{Stdout: "web-1\nweb-2\n"},
+	})
+
+	// docker以及kubectl的Go模板不是脚本的参数，保存与执行时原样保留。
+	svc := GetScriptsService()
+	body := "# @param name string\ndocker ps --filter name={{name}} --format '{{.Names}}'\n"
+	script, err := svc.Create(nil, 1, &internal_models.ScriptsCreateRequest{
+		Name:           "docker_names",
+		Body:           body,
+		AllowedServers: []string{"*"},
+		RequiredRole:   daModels.ScriptRoleUser,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script.Params) != 1 || script.Params[0].Name != "name" || script.Body != body {
+		t.Fatalf("unexpected script %+v", script)
+	}
+	run, err := svc.Run(nil, 2, false, script.ID, &internal_models.ScriptsRunRequest{
+		Host: ubuntu.Host, Port: ubuntu.Port, Args: map[string]string{"name": "web"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Result.Stdout != "web-1\nweb-2\n" || run.Result.ExitStatus != 0 || run.Result.Error != "" {
+		t.Fatalf("unexpected run %+v, result %+v", run, run.Result)
+	}
+}
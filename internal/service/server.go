@@ -7,10 +7,13 @@ import (
 	"ServerServing/internal/internal_models"
 	"ServerServing/internal/service/server_executor"
 	"ServerServing/util"
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"log"
+	"strings"
 	"sync"
+	"time"
 )
 
 type ServersService struct {
@@ -20,6 +23,39 @@ func GetServersService() *ServersService {
 	return &ServersService{}
 }
 
+// requestContext 获取http请求的context，客户端断开连接时它会被取消。c为nil时（例如在测试中）返回context.Background()。
+// 请求来自已登录的用户时，ctx中带有该用户的ID，使用它执行的命令在审计记录中会记录为由该用户触发。
+func requestContext(c *gin.Context) context.Context {
+	if c == nil || c.Request == nil {
+		return context.Background()
+	}
+	ctx := c.Request.Context()
+	if userID, err := GetSessionsService().GetUserID(c); err == nil {
+		ctx = server_executor.WithActor(ctx, userID)
+	}
+	return ctx
+}
+
+// fillServerInfoCommon 将executor返回的原始输出以及每条命令的结果填入common中。
+func fillServerInfoCommon(common *internal_models.ServerInfoCommon, rc server_executor.ExecutorServiceRespCommon) {
+	common.Output = rc.Output
+	common.CommandResults = rc.Results
+}
+
+// newLoadingFailedInfo 构造加载失败的信息。如果是某条命令执行失败导致的，则附带该命令的结果。
+func newLoadingFailedInfo(cause string, rc server_executor.ExecutorServiceRespCommon) *internal_models.ServerInfoLoadingFailedInfo {
+	info := &internal_models.ServerInfoLoadingFailedInfo{
+		CauseDescription: cause,
+	}
+	for i := len(rc.Results) - 1; i >= 0; i-- {
+		if !rc.Results[i].Succeeded() {
+			info.FailedCommand = rc.Results[i]
+			break
+		}
+	}
+	return info
+}
+
 type withESConnFunc func(es server_executor.ExecutorService) *SErr.APIErr
 
 func (s *ServersService) withConnectionByHostPort(c *gin.Context, Host string, Port uint, f withESConnFunc) *SErr.APIErr {
@@ -28,6 +64,17 @@ func (s *ServersService) withConnectionByHostPort(c *gin.Context, Host string, P
 	}, f)
 }
 
+// withConnectionByServer 使用已注册服务器的信息（包括它的跳板机）建立连接。
+func (s *ServersService) withConnectionByServer(c *gin.Context, serverBasic *internal_models.ServerBasic, f withESConnFunc) *SErr.APIErr {
+	return s.withConnection(c, func() (server_executor.ExecutorService, *SErr.APIErr) {
+		param, err := s.openParamOf(serverBasic)
+		if err != nil {
+			return nil, err
+		}
+		return s.openExecutorService(param)
+	}, f)
+}
+
 func (s *ServersService) withConnectionByParam(c *gin.Context, param *server_executor.OpenExecutorServiceParam, f withESConnFunc) *SErr.APIErr {
 	return s.withConnection(c, func() (server_executor.ExecutorService, *SErr.APIErr) {
 		return server_executor.OpenExecutorService(param)
@@ -48,14 +95,27 @@ func (s *ServersService) withConnection(c *gin.Context, openConn func() (server_
 	return f(es)
 }
 
-func (s *ServersService) ConnectionTest(c *gin.Context, Host string, Port uint, OSType daModels.OSType, accountName, accountPwd string) *SErr.APIErr {
-	es, err := s.openExecutorService(&server_executor.OpenExecutorServiceParam{
-		Host:             Host,
-		Port:             Port,
-		OSType:           OSType,
-		AdminAccountName: accountName,
-		AdminAccountPwd:  accountPwd,
-	})
+// ConnectionTest 测试能否连接到服务器。配置了跳板机时，出错信息中会指明是哪一跳连接失败。
+func (s *ServersService) ConnectionTest(c *gin.Context, Host string, Port uint, OSType daModels.OSType, accountName, accountPwd string, auth *internal_models.ServerAuthParam, jump *internal_models.ServerJumpParam) *SErr.APIErr {
+	param := &server_executor.OpenExecutorServiceParam{
+		Host:                      Host,
+		Port:                      Port,
+		OSType:                    OSType,
+		AdminAccountName:          accountName,
+		AdminAccountPwd:           accountPwd,
+		AuthType:                  auth.AuthType,
+		AdminPrivateKey:           auth.AdminPrivateKey,
+		AdminPrivateKeyPassphrase: auth.AdminPrivateKeyPassphrase,
+		AdminAgentSocket:          auth.AdminAgentSocket,
+		// 管理员主动测试连接时，即使该服务器处于冷却期也要真正尝试连接。
+		IgnoreBackoff: true,
+	}
+	jumpHops, err := s.jumpHops(Host, Port, jump, accountName, sshAuthOfParam(param))
+	if err != nil {
+		return err
+	}
+	param.JumpHops = jumpHops
+	es, err := s.openExecutorService(param)
 	if err != nil {
 		return err
 	}
@@ -64,24 +124,46 @@ func (s *ServersService) ConnectionTest(c *gin.Context, Host string, Port uint,
 }
 
 func (s *ServersService) Create(c *gin.Context, param *internal_models.ServerCreateRequest) *SErr.APIErr {
-	err := s.withConnectionByParam(c, &server_executor.OpenExecutorServiceParam{
-		Host:             param.Host,
-		Port:             param.Port,
-		OSType:           param.OSType,
-		AdminAccountName: param.AdminAccountName,
-		AdminAccountPwd:  param.AdminAccountPwd,
-	}, func(es server_executor.ExecutorService) *SErr.APIErr {
+	openParam := &server_executor.OpenExecutorServiceParam{
+		Host:                      param.Host,
+		Port:                      param.Port,
+		OSType:                    param.OSType,
+		AdminAccountName:          param.AdminAccountName,
+		AdminAccountPwd:           param.AdminAccountPwd,
+		AuthType:                  param.AuthType,
+		AdminPrivateKey:           param.AdminPrivateKey,
+		AdminPrivateKeyPassphrase: param.AdminPrivateKeyPassphrase,
+		AdminAgentSocket:          param.AdminAgentSocket,
+		IgnoreBackoff:             true,
+	}
+	jumpHops, err := s.jumpHops(param.Host, param.Port, &param.ServerJumpParam, param.AdminAccountName, sshAuthOfParam(openParam))
+	if err != nil {
+		return err
+	}
+	openParam.JumpHops = jumpHops
+	err = s.withConnectionByParam(c, openParam, func(es server_executor.ExecutorService) *SErr.APIErr {
 		// 能够联通该服务器，则调用MySQL创建。
 		serverDal := dal.GetServerDal()
-		err := serverDal.Create(&daModels.Server{
-			Name:             param.Name,
-			Description:      param.Description,
-			Host:             param.Host,
-			Port:             param.Port,
-			AdminAccountName: param.AdminAccountName,
-			AdminAccountPwd:  param.AdminAccountPwd,
-			OSType:           param.OSType,
-		})
+		server := &daModels.Server{
+			Name:                      param.Name,
+			Description:               param.Description,
+			Host:                      param.Host,
+			Port:                      param.Port,
+			AdminAccountName:          param.AdminAccountName,
+			AdminAccountPwd:           param.AdminAccountPwd,
+			OSType:                    param.OSType,
+			AuthType:                  param.AuthType,
+			AdminPrivateKey:           param.AdminPrivateKey,
+			AdminPrivateKeyPassphrase: param.AdminPrivateKeyPassphrase,
+			AdminAgentSocket:          param.AdminAgentSocket,
+			// 第一次连接时信任服务器提供的host key，之后的连接都需要与之一致。
+			HostKeyFingerprint: es.HostKeyFingerprint(),
+			JumpServerHost:     param.JumpServerHost,
+			JumpServerPort:     param.JumpServerPort,
+			ProxyJump:          param.ProxyJump,
+		}
+		setOSRelease(server, es.OSRelease())
+		err := serverDal.Create(server)
 		if err != nil {
 			log.Printf("ServersService serverDal.Create failed, err=[%v]", err)
 			return err
@@ -99,20 +181,38 @@ func (s *ServersService) Update(c *gin.Context, Host string, Port uint, param *i
 	if err != nil {
 		return err
 	}
-	err = s.withConnectionByParam(c, &server_executor.OpenExecutorServiceParam{
-		Host:             Host,
-		Port:             Port,
-		OSType:           basicInfo.OSType,
-		AdminAccountName: param.AdminAccountName,
-		AdminAccountPwd:  param.AdminAccountPwd,
-	}, func(es server_executor.ExecutorService) *SErr.APIErr {
+	openParam := &server_executor.OpenExecutorServiceParam{
+		Host:                      Host,
+		Port:                      Port,
+		OSType:                    basicInfo.OSType,
+		AdminAccountName:          param.AdminAccountName,
+		AdminAccountPwd:           param.AdminAccountPwd,
+		AuthType:                  param.AuthType,
+		AdminPrivateKey:           param.AdminPrivateKey,
+		AdminPrivateKeyPassphrase: param.AdminPrivateKeyPassphrase,
+		AdminAgentSocket:          param.AdminAgentSocket,
+		HostKeyFingerprint:        basicInfo.HostKeyFingerprint,
+	}
+	jumpHops, err := s.jumpHops(Host, Port, &param.ServerJumpParam, param.AdminAccountName, sshAuthOfParam(openParam))
+	if err != nil {
+		return err
+	}
+	openParam.JumpHops = jumpHops
+	err = s.withConnectionByParam(c, openParam, func(es server_executor.ExecutorService) *SErr.APIErr {
 		// 能够联通该服务器，则调用MySQL创建。
 		serverDal := dal.GetServerDal()
 		err := serverDal.Update(Host, Port, &daModels.Server{
-			Name:             param.Name,
-			Description:      param.Description,
-			AdminAccountName: param.AdminAccountName,
-			AdminAccountPwd:  param.AdminAccountPwd,
+			Name:                      param.Name,
+			Description:               param.Description,
+			AdminAccountName:          param.AdminAccountName,
+			AdminAccountPwd:           param.AdminAccountPwd,
+			AuthType:                  param.AuthType,
+			AdminPrivateKey:           param.AdminPrivateKey,
+			AdminPrivateKeyPassphrase: param.AdminPrivateKeyPassphrase,
+			AdminAgentSocket:          param.AdminAgentSocket,
+			JumpServerHost:            param.JumpServerHost,
+			JumpServerPort:            param.JumpServerPort,
+			ProxyJump:                 param.ProxyJump,
 		})
 		if err != nil {
 			log.Printf("ServersService serverDal.Update failed, err=[%v]", err)
@@ -123,6 +223,8 @@ func (s *ServersService) Update(c *gin.Context, Host string, Port uint, param *i
 	if err != nil {
 		return err
 	}
+	// 认证信息已经变化，连接池中旧的连接不应该再被使用。
+	server_executor.InvalidateConnections(Host, Port)
 	return nil
 }
 
@@ -134,6 +236,8 @@ func (s *ServersService) Delete(c *gin.Context, Host string, Port uint) *SErr.AP
 		log.Printf("ServersService serverDal.Create failed, err=[%v]", err)
 		return err
 	}
+	server_executor.InvalidateConnections(Host, Port)
+	agentReports.forget(Host, Port)
 	return nil
 }
 
@@ -163,17 +267,20 @@ func (s *ServersService) Info(c *gin.Context, Host string, Port uint, arg *inter
 	serverInfo.AccountInfos = &internal_models.ServerAccountInfos{
 		Accounts: accounts,
 	}
+	// 有没有过期的agent数据时，agent能够提供的部分使用它的数据；全部部分都能由agent提供时，不再连接服务器。
+	arg, usedAgent := s.loadInfoFromAgent(serverInfo, arg)
+	if usedAgent && !needsServer(arg) {
+		serverInfo.Status = s.statusOf(Host, Port, nil)
+		return serverInfo, nil
+	}
 	// 第二步，初始化到该服务器的连接，如果连接失败，则直接返回错误。
-	err = s.withConnectionByParam(c, &server_executor.OpenExecutorServiceParam{
-		Host:             serverBasic.Host,
-		Port:             serverBasic.Port,
-		OSType:           serverBasic.OSType,
-		AdminAccountName: serverBasic.AdminAccountName,
-		AdminAccountPwd:  serverBasic.AdminAccountPwd,
-	}, func(es server_executor.ExecutorService) *SErr.APIErr {
-		s.loadInfoFromServer(serverInfo, es, arg)
+	err = s.withConnectionByServer(c, serverBasic, func(es server_executor.ExecutorService) *SErr.APIErr {
+		s.pinHostKeyIfAbsent(serverBasic, es)
+		s.updateOSReleaseIfChanged(serverBasic, es)
+		s.loadInfoFromServer(requestContext(c), serverInfo, es, arg)
 		return nil
 	})
+	serverInfo.Status = s.statusOf(Host, Port, err)
 	if err != nil {
 		serverInfo.AccessFailedInfo = &internal_models.ServerInfoLoadingFailedInfo{
 			CauseDescription: err.Message,
@@ -183,12 +290,239 @@ func (s *ServersService) Info(c *gin.Context, Host string, Port uint, arg *inter
 	return serverInfo, nil
 }
 
+// statusOf 根据本次建立连接的结果以及连接池记录的连接状态，生成服务器的连接状态。
+func (s *ServersService) statusOf(Host string, Port uint, err *SErr.APIErr) *internal_models.ServerStatus {
+	reach := server_executor.ServerReachability(Host, Port)
+	if reach == nil || (err == nil) != (reach.Status == server_executor.ServerStatusOnline) {
+		// 没有经过连接池（例如回放录制记录），或者是建连之外的错误（例如跳板机的配置有误），以本次的结果为准。
+		reach = &server_executor.Reachability{
+			Status: server_executor.StatusOfDialErr(err),
+			Since:  time.Now(),
+		}
+		if err != nil {
+			reach.LastError = err.Message
+			reach.ConsecutiveFailures = 1
+		}
+	}
+	status := &internal_models.ServerStatus{
+		Status:              string(reach.Status),
+		Since:               reach.Since.Unix(),
+		LastError:           reach.LastError,
+		ConsecutiveFailures: reach.ConsecutiveFailures,
+	}
+	if !reach.RetryAt.IsZero() {
+		status.RetryAt = reach.RetryAt.Unix()
+	}
+	return status
+}
+
+// openParamOf 根据服务器的基本信息生成建立连接所需的参数，包括解析它的跳板机。
+func (s *ServersService) openParamOf(serverBasic *internal_models.ServerBasic) (*server_executor.OpenExecutorServiceParam, *SErr.APIErr) {
+	param := &server_executor.OpenExecutorServiceParam{
+		Host:                      serverBasic.Host,
+		Port:                      serverBasic.Port,
+		OSType:                    serverBasic.OSType,
+		AdminAccountName:          serverBasic.AdminAccountName,
+		AdminAccountPwd:           serverBasic.AdminAccountPwd,
+		AuthType:                  serverBasic.AuthType,
+		AdminPrivateKey:           serverBasic.AdminPrivateKey,
+		AdminPrivateKeyPassphrase: serverBasic.AdminPrivateKeyPassphrase,
+		AdminAgentSocket:          serverBasic.AdminAgentSocket,
+		HostKeyFingerprint:        serverBasic.HostKeyFingerprint,
+	}
+	jumpHops, err := s.jumpHops(serverBasic.Host, serverBasic.Port, &serverBasic.ServerJumpParam, serverBasic.AdminAccountName, sshAuthOfParam(param))
+	if err != nil {
+		return nil, err
+	}
+	param.JumpHops = jumpHops
+	return param, nil
+}
+
+// sshAuthOfParam 取出建连参数中的认证信息，用于临时指定的跳板机。
+func sshAuthOfParam(param *server_executor.OpenExecutorServiceParam) *server_executor.SSHAuth {
+	return &server_executor.SSHAuth{
+		Type:                 param.AuthType,
+		Password:             param.AdminAccountPwd,
+		PrivateKey:           param.AdminPrivateKey,
+		PrivateKeyPassphrase: param.AdminPrivateKeyPassphrase,
+		AgentSocket:          param.AdminAgentSocket,
+	}
+}
+
+// jumpHops 解析连接某服务器时依次经过的跳板机。
+// 先经过引用的已注册服务器（如果它自己也配置了跳板机，则先经过它的跳板机），再经过临时指定的ProxyJump跳板机。
+func (s *ServersService) jumpHops(Host string, Port uint, jump *internal_models.ServerJumpParam, account string, auth *server_executor.SSHAuth) ([]*server_executor.SSHJumpHop, *SErr.APIErr) {
+	hops, err := s.resolveJumpHops(Host, Port, jump, account, auth, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	if len(hops) > server_executor.MaxSSHJumpHops {
+		return nil, SErr.InvalidParamErr.CustomMessageF("连接服务器%s:%d需要经过%d个跳板机，超过了上限%d个！", Host, Port, len(hops), server_executor.MaxSSHJumpHops)
+	}
+	return hops, nil
+}
+
+func (s *ServersService) resolveJumpHops(Host string, Port uint, jump *internal_models.ServerJumpParam, account string, auth *server_executor.SSHAuth, visited map[string]bool) ([]*server_executor.SSHJumpHop, *SErr.APIErr) {
+	visited[fmt.Sprintf("%s:%d", Host, Port)] = true
+	hops := make([]*server_executor.SSHJumpHop, 0)
+	if jump.JumpServerHost != "" {
+		if visited[fmt.Sprintf("%s:%d", jump.JumpServerHost, jump.JumpServerPort)] {
+			return nil, SErr.InvalidParamErr.CustomMessageF("服务器%s:%d的跳板机配置形成了环，跳板机为%s:%d！", Host, Port, jump.JumpServerHost, jump.JumpServerPort)
+		}
+		if len(visited) > server_executor.MaxSSHJumpHops {
+			return nil, SErr.InvalidParamErr.CustomMessageF("跳板机数量超过了上限%d个！", server_executor.MaxSSHJumpHops)
+		}
+		daServer, err := dal.GetServerDal().Get(jump.JumpServerHost, jump.JumpServerPort, false)
+		if err != nil {
+			return nil, err.CustomMessageF("查询服务器%s:%d的跳板机失败！错误信息为：%s", Host, Port, err.Message)
+		}
+		jumpBasic := s.packServer(daServer)
+		jumpAuth := &server_executor.SSHAuth{
+			Type:                 jumpBasic.AuthType,
+			Password:             jumpBasic.AdminAccountPwd,
+			PrivateKey:           jumpBasic.AdminPrivateKey,
+			PrivateKeyPassphrase: jumpBasic.AdminPrivateKeyPassphrase,
+			AgentSocket:          jumpBasic.AdminAgentSocket,
+		}
+		parentHops, err := s.resolveJumpHops(jumpBasic.Host, jumpBasic.Port, &jumpBasic.ServerJumpParam, jumpBasic.AdminAccountName, jumpAuth, visited)
+		if err != nil {
+			return nil, err
+		}
+		hops = append(hops, parentHops...)
+		hops = append(hops, &server_executor.SSHJumpHop{
+			Host:               jumpBasic.Host,
+			Port:               jumpBasic.Port,
+			Account:            jumpBasic.AdminAccountName,
+			Auth:               jumpAuth,
+			HostKeyFingerprint: jumpBasic.HostKeyFingerprint,
+		})
+	}
+	if jump.ProxyJump != "" {
+		proxyHops, err := server_executor.ParseProxyJump(jump.ProxyJump, account, auth)
+		if err != nil {
+			return nil, err
+		}
+		hops = append(hops, proxyHops...)
+	}
+	return hops, nil
+}
+
+// hostKeyFingerprinter 能够返回本次连接中服务器提供的host key指纹，例如ExecutorService与Terminal。
+type hostKeyFingerprinter interface {
+	HostKeyFingerprint() string
+}
+
+// pinHostKeyIfAbsent 对于还没有记录host key指纹的旧服务器数据，在第一次成功连接后记录它的指纹。
+func (s *ServersService) pinHostKeyIfAbsent(serverBasic *internal_models.ServerBasic, es hostKeyFingerprinter) {
+	if serverBasic.HostKeyFingerprint != "" || es.HostKeyFingerprint() == "" {
+		return
+	}
+	err := dal.GetServerDal().UpdateHostKeyFingerprint(serverBasic.Host, serverBasic.Port, es.HostKeyFingerprint())
+	if err != nil {
+		// 非关键错误，下次连接时会再次尝试记录。
+		log.Printf("ServersService pinHostKeyIfAbsent failed, Host=[%s], Port=[%d], err=[%v]", serverBasic.Host, serverBasic.Port, err)
+		return
+	}
+	serverBasic.HostKeyFingerprint = es.HostKeyFingerprint()
+}
+
+// setOSRelease 将解析的/etc/os-release记录到服务器数据中，release为nil时不做修改。
+func setOSRelease(server *daModels.Server, release *server_executor.OSRelease) {
+	if release == nil {
+		return
+	}
+	server.OSReleaseID = release.ID
+	server.OSReleaseIDLike = strings.Join(release.IDLike, " ")
+	server.OSVersionID = release.VersionID
+	server.OSPrettyName = release.PrettyName
+}
+
+// updateOSReleaseIfChanged 服务器的发行版信息与记录的不同时（例如旧数据，或者服务器升级了系统）更新记录。
+func (s *ServersService) updateOSReleaseIfChanged(serverBasic *internal_models.ServerBasic, es server_executor.ExecutorService) {
+	server := &daModels.Server{}
+	setOSRelease(server, es.OSRelease())
+	if server.OSReleaseID == "" || (server.OSReleaseID == serverBasic.OSReleaseID && server.OSReleaseIDLike == serverBasic.OSReleaseIDLike &&
+		server.OSVersionID == serverBasic.OSVersionID && server.OSPrettyName == serverBasic.OSPrettyName) {
+		return
+	}
+	err := dal.GetServerDal().UpdateOSRelease(serverBasic.Host, serverBasic.Port, server)
+	if err != nil {
+		// 非关键错误，下次连接时会再次尝试更新。
+		log.Printf("ServersService updateOSReleaseIfChanged failed, Host=[%s], Port=[%d], err=[%v]", serverBasic.Host, serverBasic.Port, err)
+		return
+	}
+	serverBasic.OSReleaseID = server.OSReleaseID
+	serverBasic.OSReleaseIDLike = server.OSReleaseIDLike
+	serverBasic.OSVersionID = server.OSVersionID
+	serverBasic.OSPrettyName = server.OSPrettyName
+}
+
+// HostKeyInfo 查看服务器记录的host key指纹，以及服务器当前提供的指纹。
+func (s *ServersService) HostKeyInfo(c *gin.Context, Host string, Port uint) (*internal_models.ServerHostKeyInfoResponse, *SErr.APIErr) {
+	serverBasic, _, err := s.basicInfo(c, Host, Port)
+	if err != nil {
+		return nil, err
+	}
+	if serverBasic.OSType == daModels.OSTypeLinuxLocal {
+		return nil, SErr.InvalidParamErr.CustomMessage("本机服务器不通过SSH连接，没有host key！")
+	}
+	res := &internal_models.ServerHostKeyInfoResponse{
+		StoredFingerprint: serverBasic.HostKeyFingerprint,
+	}
+	openParam, err := s.openParamOf(serverBasic)
+	if err != nil {
+		return nil, err
+	}
+	presented, err := server_executor.ScanHostKeyFingerprint(Host, Port, openParam.JumpHops)
+	if err != nil {
+		res.ScanFailedCause = err.Message
+		return res, nil
+	}
+	res.PresentedFingerprint = presented
+	res.Matched = presented == serverBasic.HostKeyFingerprint
+	return res, nil
+}
+
+// AcceptHostKey 管理员确认接受服务器当前提供的host key。
+// fingerprint 为管理员确认过的指纹，只有它与服务器当前提供的指纹一致时才会更新记录。
+func (s *ServersService) AcceptHostKey(c *gin.Context, Host string, Port uint, fingerprint string) *SErr.APIErr {
+	if fingerprint == "" {
+		return SErr.InvalidParamErr.CustomMessage("请指定要接受的host key指纹！")
+	}
+	serverBasic, _, err := s.basicInfo(c, Host, Port)
+	if err != nil {
+		return err
+	}
+	if serverBasic.OSType == daModels.OSTypeLinuxLocal {
+		return SErr.InvalidParamErr.CustomMessage("本机服务器不通过SSH连接，没有host key！")
+	}
+	openParam, err := s.openParamOf(serverBasic)
+	if err != nil {
+		return err
+	}
+	presented, err := server_executor.ScanHostKeyFingerprint(Host, Port, openParam.JumpHops)
+	if err != nil {
+		return err
+	}
+	if presented != fingerprint {
+		return SErr.HostKeyMismatchErr.CustomMessageF("要接受的指纹与服务器当前提供的指纹不一致！要接受的指纹为：%s，服务器当前提供的指纹为：%s", fingerprint, presented)
+	}
+	log.Printf("ServersService AcceptHostKey, Host=[%s], Port=[%d], fingerprint=[%s]", Host, Port, fingerprint)
+	err = dal.GetServerDal().UpdateHostKeyFingerprint(Host, Port, fingerprint)
+	if err != nil {
+		return err
+	}
+	server_executor.InvalidateConnections(Host, Port)
+	return nil
+}
+
 func (s *ServersService) openExecutorService(param *server_executor.OpenExecutorServiceParam) (server_executor.ExecutorService, *SErr.APIErr) {
 	es, err := server_executor.OpenExecutorService(param)
 	if err != nil {
 		causeDescription := fmt.Sprintf("在尝试使用管理员账户与该服务器建连时失败！请检查该账户的配置以及网络状况！内嵌的出错信息为：[%s]", err.Message)
 		log.Printf("ServersService Info，causeDescription=[%s]", causeDescription)
-		return nil, SErr.SSHConnectionErr.CustomMessage(causeDescription)
+		// 保留内嵌错误的错误码，例如host key不一致。
+		return nil, err.CustomMessage(causeDescription)
 	}
 	return es, nil
 }
@@ -198,17 +532,33 @@ func (s *ServersService) openExecutorServiceByHostPort(c *gin.Context, Host stri
 	if err != nil {
 		return nil, err
 	}
-	return s.openExecutorService(
-		&server_executor.OpenExecutorServiceParam{
-			Host:             serverBasic.Host,
-			Port:             serverBasic.Port,
-			OSType:           serverBasic.OSType,
-			AdminAccountName: serverBasic.AdminAccountName,
-			AdminAccountPwd:  serverBasic.AdminAccountPwd,
-		})
+	openParam, err := s.openParamOf(serverBasic)
+	if err != nil {
+		return nil, err
+	}
+	es, err := s.openExecutorService(openParam)
+	if err != nil {
+		return nil, err
+	}
+	s.pinHostKeyIfAbsent(serverBasic, es)
+	return es, nil
 }
 
-func (s *ServersService) loadInfoFromServer(targetServerInfo *internal_models.ServerInfo, es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg) {
+func (s *ServersService) loadInfoFromServer(ctx context.Context, targetServerInfo *internal_models.ServerInfo, es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg) {
+	// 首先，在一个session中执行全部需要的命令，而不是每个可选项各自打开session。
+	// 生成命令失败时，每个可选项都会以该错误失败，所以这里只需要打印log。
+	collected, err := es.CollectInfo(ctx, &server_executor.CollectInfoArg{
+		AccountList:           arg.WithAccounts,
+		BackupDirs:            arg.WithAccounts && arg.WithBackupDirInfo,
+		HardwareInfo:          arg.WithHardwareInfo,
+		RemoteAccessInfos:     arg.WithRemoteAccessUsages,
+		CPUMemProcessesUsages: arg.WithCPUMemProcessesUsage,
+		GPUUsages:             arg.WithGPUUsages,
+		FilesystemUsages:      arg.WithFilesystemUsages,
+	})
+	if err != nil {
+		log.Printf("ServersService loadInfoFromServer CollectInfo failed, es=[%s], err=[%s]", es, err)
+	}
 	// 接下来，分别对LoadServerDetailArg中的每个可选项进行针对性的load
 	// 对WithAccountArg做load：
 	// 账户信息在MySQL中存储一份，但是不一定准确（因为Server可能随时被人修改）
@@ -217,17 +567,21 @@ func (s *ServersService) loadInfoFromServer(targetServerInfo *internal_models.Se
 	// 如果MySQL中，没有存储该账户的信息，则使用从Server查询的最新数据插入该用户的数据。
 	// 如果MySQL存储了，并且从Server能够查询到该用户（一致的），则将它的信息进行补全。
 	// 如果MYSQL存储了，但是从Server中查不到该用户（可能被删掉了），那么就把他的数据过滤掉（不在MySQL中删除）
-	s.loadAccounts(es, arg, targetServerInfo)
+	s.loadAccounts(es, collected, arg.WithAccountsIgnoreDBAccounts, targetServerInfo)
 	// 对WithHardwareInfo做load：
 	// 目前包含CPU和GPU的硬件数据。
-	s.loadHardwareInfo(es, arg, targetServerInfo)
+	s.loadHardwareInfo(es, collected, targetServerInfo)
 	// 对WithRemoteAccessUsages做load
 	// 包含了当前正在使用远程访问该服务器的用户信息
-	s.loadRemoteAccessUsages(es, arg, targetServerInfo)
+	s.loadRemoteAccessUsages(es, collected, targetServerInfo)
 	// 对WithCPUMemProcessesUsageInfo做load
-	s.loadCPUMemProcessesUsageInfo(es, arg, targetServerInfo)
+	s.loadCPUMemProcessesUsageInfo(es, collected, targetServerInfo)
 	// 对WithGPUUsages做load
-	s.loadGPUUsages(es, arg, targetServerInfo)
+	s.loadGPUUsages(es, collected, targetServerInfo)
+	// 对WithFilesystemUsages做load
+	s.loadFilesystemUsages(es, collected, targetServerInfo)
+	// 最后，将进程的GPU使用情况按账户汇总
+	s.loadAccountUsages(targetServerInfo)
 }
 
 // Infos 获取一批Server数据。目前所有Server使用同一个arg参数指定它对应的Detail信息量。
@@ -244,31 +598,28 @@ func (s *ServersService) Infos(c *gin.Context, from, size uint, arg *internal_mo
 	if err != nil {
 		return nil, 0, err
 	}
-	resultServerInfos := make([]*internal_models.ServerInfo, 0, len(servers))
+	// 每个服务器的结果写入自己的位置，保持与MySQL中相同的顺序。
+	resultServerInfos := make([]*internal_models.ServerInfo, len(servers))
 	wg := &sync.WaitGroup{}
-	mu := &sync.Mutex{}
-	for _, daServer := range servers {
-		daServer := daServer
+	for i, daServer := range servers {
+		serverInfo := &internal_models.ServerInfo{}
+		resultServerInfos[i] = serverInfo
 		serverBasic, accounts := s.packServer(daServer), s.packAccounts(daServer.Accounts)
 		util.GoWithWG(wg, func() {
-			serverInfo := &internal_models.ServerInfo{}
 			serverInfo.Basic = serverBasic
 			// 从服务器中能够获取到一份Account数据，但是并不一定是最新的服务器中的用户数据。
 			serverInfo.AccountInfos = &internal_models.ServerAccountInfos{
 				Accounts: accounts,
 			}
-			// 第二步，初始化到该服务器的连接，如果连接失败，则直接返回错误。
-			err := s.withConnectionByParam(c, &server_executor.OpenExecutorServiceParam{
-				Host:             serverBasic.Host,
-				Port:             serverBasic.Port,
-				OSType:           serverBasic.OSType,
-				AdminAccountName: serverBasic.AdminAccountName,
-				AdminAccountPwd:  serverBasic.AdminAccountPwd,
-			}, func(es server_executor.ExecutorService) *SErr.APIErr {
-				s.loadInfoFromServer(serverInfo, es, arg)
-				mu.Lock()
-				defer mu.Unlock()
-				resultServerInfos = append(resultServerInfos, serverInfo)
+			serverArg, usedAgent := s.loadInfoFromAgent(serverInfo, arg)
+			if usedAgent && !needsServer(serverArg) {
+				serverInfo.Status = s.statusOf(serverBasic.Host, serverBasic.Port, nil)
+				return
+			}
+			// 第二步，初始化到该服务器的连接。连接失败的服务器同样会被返回，并带有它的连接状态。
+			err := s.withConnectionByServer(c, serverBasic, func(es server_executor.ExecutorService) *SErr.APIErr {
+				s.pinHostKeyIfAbsent(serverBasic, es)
+				s.loadInfoFromServer(requestContext(c), serverInfo, es, serverArg)
 				return nil
 			})
 			if err != nil {
@@ -276,29 +627,28 @@ func (s *ServersService) Infos(c *gin.Context, from, size uint, arg *internal_mo
 					CauseDescription: err.Message,
 				}
 			}
+			serverInfo.Status = s.statusOf(serverBasic.Host, serverBasic.Port, err)
 		})
 	}
 	wg.Wait()
 	return resultServerInfos, total, nil
 }
 
-func (s *ServersService) loadAccounts(es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg, serverInfo *internal_models.ServerInfo) {
-	if arg.WithAccounts == false {
+func (s *ServersService) loadAccounts(es server_executor.ExecutorService, collected *server_executor.ExecutorServiceCollectInfoResp, ignoreDBAccounts bool, serverInfo *internal_models.ServerInfo) {
+	if collected.AccountList == nil {
 		return
 	}
-	if serverInfo.AccountInfos == nil || arg.WithAccountsIgnoreDBAccounts {
+	if serverInfo.AccountInfos == nil || ignoreDBAccounts {
 		serverInfo.AccountInfos = &internal_models.ServerAccountInfos{
 			Accounts: make([]*internal_models.ServerAccount, 0, 4),
 		}
 	}
 	serverInfo.AccountInfos.ServerInfoCommon = &internal_models.ServerInfoCommon{}
-	getAccountListResp, err := es.GetAccountList()
-	serverInfo.AccountInfos.Output = getAccountListResp.Output
+	getAccountListResp, err := collected.AccountList, collected.AccountListErr
+	fillServerInfoCommon(serverInfo.AccountInfos.ServerInfoCommon, getAccountListResp.ExecutorServiceRespCommon)
 	if err != nil {
 		serverInfo.AccountInfos.Accounts = nil
-		serverInfo.AccountInfos.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{
-			CauseDescription: fmt.Sprintf("向服务器查询用户列表时出错，es=[%s], 出错信息为：[%s]", es, err.Error()),
-		}
+		serverInfo.AccountInfos.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("向服务器查询用户列表时出错，es=[%s], 出错信息为：[%s]", es, err.Error()), getAccountListResp.ExecutorServiceRespCommon)
 		return
 	}
 	accountsInServerMap := make(map[string]*internal_models.ServerAccount)
@@ -307,18 +657,18 @@ func (s *ServersService) loadAccounts(es server_executor.ExecutorService, arg *i
 	}
 
 	// 如果忽略DB的账户，需要将server中得到数据赋值到serverInfo中
-	if arg.WithAccountsIgnoreDBAccounts {
+	if ignoreDBAccounts {
 		for _, accInServer := range accountsInServerMap {
 			serverInfo.AccountInfos.Accounts = append(serverInfo.AccountInfos.Accounts, accInServer)
 		}
 	}
 
 	// 如果不忽略DB账户，需要将DB得到的数据与server得到的数据进行合并
-	if !arg.WithAccountsIgnoreDBAccounts {
+	if !ignoreDBAccounts {
 		s.combineDBAccounts(es, serverInfo, accountsInServerMap)
 	}
 
-	s.loadAccountBackupDirInfos(es, arg, serverInfo)
+	s.loadAccountBackupDirInfos(collected, serverInfo)
 }
 
 func (s *ServersService) combineDBAccounts(es server_executor.ExecutorService, serverInfo *internal_models.ServerInfo, originalAccountsInServerMap map[string]*internal_models.ServerAccount) {
@@ -368,40 +718,29 @@ func (s *ServersService) combineDBAccounts(es server_executor.ExecutorService, s
 	}
 }
 
-func (s *ServersService) loadAccountBackupDirInfos(es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg, serverInfo *internal_models.ServerInfo) {
-	if !arg.WithBackupDirInfo {
+func (s *ServersService) loadAccountBackupDirInfos(collected *server_executor.ExecutorServiceCollectInfoResp, serverInfo *internal_models.ServerInfo) {
+	resp, err := collected.BackupDirs, collected.BackupDirsErr
+	if resp == nil {
 		return
 	}
-	wg := &sync.WaitGroup{}
-	batch := 5
-	for i, account := range serverInfo.AccountInfos.Accounts {
-		account := account
-		util.GoWithWG(wg, func() {
-			account.BackupDirInfo = &internal_models.ServerAccountBackupDirInfo{
-				ServerInfoCommon: &internal_models.ServerInfoCommon{
-					Output:     "",
-					FailedInfo: nil,
-				},
-			}
-			resp, err := es.GetBackupDir(account.Name)
-			if err != nil {
-				account.BackupDirInfo.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{CauseDescription: err.Error()}
-				return
-			}
-			account.BackupDirInfo.Output = resp.Output
-			account.BackupDirInfo.DirExists = resp.DirExists
-			account.BackupDirInfo.PathExists = resp.PathExists
-			account.BackupDirInfo.BackupDir = resp.BackupDir
-		})
-		if i%batch == 0 || i == len(serverInfo.AccountInfos.Accounts)-1 {
-			wg.Wait()
+	// 所有账户的备份文件夹由同一条命令列出，每个账户的Output与Results只保留它自己的条目。
+	for _, account := range serverInfo.AccountInfos.Accounts {
+		account.BackupDirInfo = &internal_models.ServerAccountBackupDirInfo{
+			ServerInfoCommon: &internal_models.ServerInfoCommon{},
+		}
+		accountRespCommon := resp.RespCommonOf(account.Name)
+		fillServerInfoCommon(account.BackupDirInfo.ServerInfoCommon, accountRespCommon)
+		if err != nil {
+			account.BackupDirInfo.FailedInfo = newLoadingFailedInfo(err.Error(), accountRespCommon)
+			continue
 		}
+		account.BackupDirInfo.BackupDir, account.BackupDirInfo.PathExists, account.BackupDirInfo.DirExists = resp.BackupDirOf(account.Name)
 	}
 }
 
 // loadHardwareInfo 加载硬件相关信息
-func (s *ServersService) loadHardwareInfo(es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg, serverInfo *internal_models.ServerInfo) {
-	if !arg.WithHardwareInfo {
+func (s *ServersService) loadHardwareInfo(es fmt.Stringer, collected *server_executor.ExecutorServiceCollectInfoResp, serverInfo *internal_models.ServerInfo) {
+	if collected.CPUHardware == nil {
 		return
 	}
 	serverInfo.HardwareInfo = &internal_models.ServerHardwareInfo{
@@ -421,28 +760,24 @@ func (s *ServersService) loadHardwareInfo(es server_executor.ExecutorService, ar
 		},
 	}
 	// CPU
-	cpuResp, err := es.GetCPUHardware()
-	serverInfo.HardwareInfo.CPUHardwareInfo.Output = cpuResp.Output
+	cpuResp, err := collected.CPUHardware, collected.CPUHardwareErr
+	fillServerInfoCommon(serverInfo.HardwareInfo.CPUHardwareInfo.ServerInfoCommon, cpuResp.ExecutorServiceRespCommon)
 	if err != nil {
-		serverInfo.HardwareInfo.CPUHardwareInfo.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{
-			CauseDescription: fmt.Sprintf("向服务器查询cpu数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()),
-		}
+		serverInfo.HardwareInfo.CPUHardwareInfo.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("向服务器查询cpu数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()), cpuResp.ExecutorServiceRespCommon)
 	}
 	serverInfo.HardwareInfo.CPUHardwareInfo.Info = cpuResp.CPU
 	// GPU
-	gpuResp, err := es.GetGPUHardware()
-	serverInfo.HardwareInfo.GPUHardwareInfos.Output = gpuResp.Output
+	gpuResp, err := collected.GPUHardware, collected.GPUHardwareErr
+	fillServerInfoCommon(serverInfo.HardwareInfo.GPUHardwareInfos.ServerInfoCommon, gpuResp.ExecutorServiceRespCommon)
 	if err != nil {
-		serverInfo.HardwareInfo.GPUHardwareInfos.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{
-			CauseDescription: fmt.Sprintf("向服务器查询gpu数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()),
-		}
+		serverInfo.HardwareInfo.GPUHardwareInfos.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("向服务器查询gpu数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()), gpuResp.ExecutorServiceRespCommon)
 	}
 	serverInfo.HardwareInfo.GPUHardwareInfos.Infos = gpuResp.GPUs
 }
 
 // loadRemoteAccessUsages 加载正在远程访问该Server的用户使用信息。
-func (s *ServersService) loadRemoteAccessUsages(es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg, serverInfo *internal_models.ServerInfo) {
-	if !arg.WithRemoteAccessUsages {
+func (s *ServersService) loadRemoteAccessUsages(es fmt.Stringer, collected *server_executor.ExecutorServiceCollectInfoResp, serverInfo *internal_models.ServerInfo) {
+	if collected.RemoteAccessInfos == nil {
 		return
 	}
 	serverInfo.RemoteAccessingUsageInfo = &internal_models.ServerRemoteAccessingUsagesInfo{
@@ -452,20 +787,18 @@ func (s *ServersService) loadRemoteAccessUsages(es server_executor.ExecutorServi
 		},
 		Infos: nil,
 	}
-	resp, err := es.GetRemoteAccessInfos()
-	serverInfo.RemoteAccessingUsageInfo.Output = resp.Output
+	resp, err := collected.RemoteAccessInfos, collected.RemoteAccessInfosErr
+	fillServerInfoCommon(serverInfo.RemoteAccessingUsageInfo.ServerInfoCommon, resp.ExecutorServiceRespCommon)
 	if err != nil {
-		serverInfo.RemoteAccessingUsageInfo.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{
-			CauseDescription: fmt.Sprintf("向服务器查询远端访问数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()),
-		}
+		serverInfo.RemoteAccessingUsageInfo.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("向服务器查询远端访问数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()), resp.ExecutorServiceRespCommon)
 		return
 	}
 	serverInfo.RemoteAccessingUsageInfo.Infos = resp.RemoteAccessingAccountInfos
 }
 
-// loadGPUUsages 加载GPU的使用情况
-func (s *ServersService) loadGPUUsages(es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg, serverInfo *internal_models.ServerInfo) {
-	if !arg.WithGPUUsages {
+// loadGPUUsages 加载GPU的使用情况，需要在loadHardwareInfo之后调用。
+func (s *ServersService) loadGPUUsages(es fmt.Stringer, collected *server_executor.ExecutorServiceCollectInfoResp, serverInfo *internal_models.ServerInfo) {
+	if collected.GPUUsages == nil {
 		return
 	}
 	serverInfo.GPUUsageInfo = &internal_models.ServerGPUUsageInfo{
@@ -474,28 +807,49 @@ func (s *ServersService) loadGPUUsages(es server_executor.ExecutorService, arg *
 			FailedInfo: nil,
 		},
 	}
-	resp, err := es.GetGPUUsages()
+	resp, err := collected.GPUUsages, collected.GPUUsagesErr
+	fillServerInfoCommon(serverInfo.GPUUsageInfo.ServerInfoCommon, resp.ExecutorServiceRespCommon)
 	if err != nil {
-		serverInfo.GPUUsageInfo.FailedInfo.CauseDescription = fmt.Sprintf("向服务器查询GPU使用数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error())
+		serverInfo.GPUUsageInfo.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("向服务器查询GPU使用数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()), resp.ExecutorServiceRespCommon)
 		return
 	}
-	serverInfo.GPUUsageInfo.Output = resp.Output
+	serverInfo.GPUUsageInfo.GPUs = resp.GPUs
+	serverInfo.GPUUsageInfo.Processes = resp.Processes
+	// 同时加载了硬件信息时，将使用情况填入对应的GPU中，方便前端直接展示每块GPU。
+	if hardware := serverInfo.HardwareInfo; hardware != nil && hardware.GPUHardwareInfos != nil {
+		server_executor.JoinGPUUsages(hardware.GPUHardwareInfos.Infos, resp.GPUs)
+	}
+}
+
+// loadFilesystemUsages 加载已挂载的文件系统的使用情况。
+func (s *ServersService) loadFilesystemUsages(es fmt.Stringer, collected *server_executor.ExecutorServiceCollectInfoResp, serverInfo *internal_models.ServerInfo) {
+	if collected.FilesystemUsages == nil {
+		return
+	}
+	serverInfo.FilesystemUsageInfo = &internal_models.ServerFilesystemUsageInfo{
+		ServerInfoCommon: &internal_models.ServerInfoCommon{},
+	}
+	resp, err := collected.FilesystemUsages, collected.FilesystemUsagesErr
+	fillServerInfoCommon(serverInfo.FilesystemUsageInfo.ServerInfoCommon, resp.ExecutorServiceRespCommon)
+	if err != nil {
+		serverInfo.FilesystemUsageInfo.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("向服务器查询文件系统使用数据时出错！es=[%s]，出错信息为：[%s]", es, err.Error()), resp.ExecutorServiceRespCommon)
+		return
+	}
+	serverInfo.FilesystemUsageInfo.Filesystems = resp.Filesystems
 }
 
 // loadCPUMemProcessesUsageInfo 加载当前正在使用CPU，内存，以及进程的占用信息。
-func (s *ServersService) loadCPUMemProcessesUsageInfo(es server_executor.ExecutorService, arg *internal_models.LoadServerDetailArg, serverInfo *internal_models.ServerInfo) {
-	if !arg.WithCPUMemProcessesUsage {
+func (s *ServersService) loadCPUMemProcessesUsageInfo(es fmt.Stringer, collected *server_executor.ExecutorServiceCollectInfoResp, serverInfo *internal_models.ServerInfo) {
+	if collected.CPUMemProcessesUsages == nil {
 		return
 	}
 	serverInfo.CPUMemProcessesUsageInfo = &internal_models.ServerCPUMemProcessesUsageInfo{
 		ServerInfoCommon: &internal_models.ServerInfoCommon{},
 	}
-	topResp, err := es.GetCPUMemProcessesUsages()
-	serverInfo.CPUMemProcessesUsageInfo.Output = topResp.Output
+	topResp, err := collected.CPUMemProcessesUsages, collected.CPUMemProcessesUsagesErr
+	fillServerInfoCommon(serverInfo.CPUMemProcessesUsageInfo.ServerInfoCommon, topResp.ExecutorServiceRespCommon)
 	if err != nil {
-		serverInfo.CPUMemProcessesUsageInfo.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{
-			CauseDescription: fmt.Sprintf("加载Top信息时失败！es=[%s]，出错信息为：[%s]", es, err),
-		}
+		serverInfo.CPUMemProcessesUsageInfo.FailedInfo = newLoadingFailedInfo(fmt.Sprintf("加载Top信息时失败！es=[%s]，出错信息为：[%s]", es, err), topResp.ExecutorServiceRespCommon)
 		return
 	}
 	serverInfo.CPUMemProcessesUsageInfo.CPUMemUsage = topResp.CPUMemUsage
@@ -514,6 +868,21 @@ func (s *ServersService) packServer(server *daModels.Server) *internal_models.Se
 		AdminAccountName: server.AdminAccountName,
 		AdminAccountPwd:  server.AdminAccountPwd,
 		OSType:           server.OSType,
+
+		AuthType:                  server.GetAuthType(),
+		AdminAgentSocket:          server.AdminAgentSocket,
+		AdminPrivateKey:           server.AdminPrivateKey,
+		AdminPrivateKeyPassphrase: server.AdminPrivateKeyPassphrase,
+		HostKeyFingerprint:        server.HostKeyFingerprint,
+		OSReleaseID:               server.OSReleaseID,
+		OSReleaseIDLike:           server.OSReleaseIDLike,
+		OSVersionID:               server.OSVersionID,
+		OSPrettyName:              server.OSPrettyName,
+		ServerJumpParam: internal_models.ServerJumpParam{
+			JumpServerHost: server.JumpServerHost,
+			JumpServerPort: server.JumpServerPort,
+			ProxyJump:      server.ProxyJump,
+		},
 	}
 }
 
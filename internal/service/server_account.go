@@ -31,7 +31,7 @@ func (s *ServersService) AddAccount(c *gin.Context, Host string, Port uint, Acco
 
 func (s *ServersService) accountNameExists(c *gin.Context, es server_executor.ExecutorService, Host string, Port uint, AccountName string) (bool, *SErr.APIErr) {
 	serverInfo := &internal_models.ServerInfo{}
-	s.loadInfoFromServer(serverInfo, es, &internal_models.LoadServerDetailArg{
+	s.loadInfoFromServer(requestContext(c), serverInfo, es, &internal_models.LoadServerDetailArg{
 		WithAccounts:                 true,
 		WithAccountsIgnoreDBAccounts: true,
 	})
@@ -49,12 +49,15 @@ func (s *ServersService) accountNameExists(c *gin.Context, es server_executor.Ex
 }
 
 func (s *ServersService) doAddAccount(c *gin.Context, es server_executor.ExecutorService, Host string, Port uint, AccountName, AccountPwd string) *SErr.APIErr {
+	if !validator.ValidateAccountName(AccountName) {
+		return SErr.InvalidParamErr.CustomMessageF("服务器账户名不符合要求！账户名需要以小写字母或下划线开头，只包含小写字母、数字、下划线以及-，长度不超过32。")
+	}
 	if !validator.ValidateAccountPassword(AccountPwd) {
 		return SErr.InvalidParamErr.CustomMessageF("服务器账户密码不符合要求！")
 	}
-	resp, err := es.AddAccount(AccountName, AccountPwd)
+	resp, err := es.AddAccount(requestContext(c), AccountName, AccountPwd)
 	if err != nil {
-		log.Printf("ServersService AddAccount Failed ES=[%s], AccountName=[%s], AccountPwd=[%s], resp=[%s]", es, AccountName, AccountPwd, util.Pretty(resp))
+		log.Printf("ServersService AddAccount Failed ES=[%s], AccountName=[%s], resp=[%s]", es, AccountName, util.Pretty(resp))
 		msg := fmt.Sprintf("添加账户失败！出错信息为：err=[%s]", err.Error())
 		log.Println(msg)
 		return err.CustomMessage(msg)
@@ -69,7 +72,7 @@ func (s *ServersService) doAddAccount(c *gin.Context, es server_executor.Executo
 	err = accDal.Upsert([]*daModels.Account{acc})
 	if err != nil {
 		// 该MySQL操作失败也没关系，因为这不是关键操作。
-		log.Printf("ServersService Upsert ServerAccount to MySQL Failed, err=[%+v] ES=[%s], AccountName=[%s], AccountPwd=[%s]", err, es, AccountName, AccountPwd)
+		log.Printf("ServersService Upsert ServerAccount to MySQL Failed, err=[%+v] ES=[%s], AccountName=[%s]", err, es, AccountName)
 	}
 	return nil
 }
@@ -88,7 +91,7 @@ func (s *ServersService) DeleteAccount(c *gin.Context, Host string, Port uint, A
 		var targetBackupDir string
 		if Backup {
 			// 需要将home目录进行备份。
-			resp, err := es.BackupAccountHomeDir(AccountName)
+			resp, err := es.BackupAccountHomeDir(requestContext(c), AccountName)
 			if err != nil {
 				msg := fmt.Sprintf("备份该用户的home目录失败！出错原因：[%s]，ES=[%s]， 请手动检查！", err.Error(), es)
 				log.Println(msg)
@@ -96,7 +99,7 @@ func (s *ServersService) DeleteAccount(c *gin.Context, Host string, Port uint, A
 			}
 			targetBackupDir = resp.TargetDir
 		}
-		_, err = es.DeleteAccount(AccountName)
+		_, err = es.DeleteAccount(requestContext(c), AccountName)
 		if err != nil {
 			msg := fmt.Sprintf("删除账户失败！出错原因：[%s]，ES=[%s]", err.Error(), es)
 			log.Println(msg)
@@ -128,7 +131,7 @@ func (s *ServersService) RecoverAccount(c *gin.Context, Host string, Port uint,
 			return err.CustomMessage(msg)
 		}
 		if RecoverBackup {
-			_, err := es.RecoverAccountHomeDir(AccountName, true)
+			_, err := es.RecoverAccountHomeDir(requestContext(c), AccountName, true)
 			if err != nil {
 				msg := fmt.Sprintf("恢复账户home目录失败！出错原因：[%s]，ES=[%s]", err.Error(), es)
 				log.Println(msg)
@@ -162,7 +165,7 @@ func (s *ServersService) UpdateAccount(c *gin.Context, Host string, Port uint, A
 		accDal := dal.GetAccountDal()
 		err = accDal.Upsert([]*daModels.Account{acc})
 		if err != nil {
-			log.Printf("ServersService Upsert ServerAccount to MySQL Failed, err=[%+v] ES=[%s], AccountName=[%s], AccountPwd=[%s]", err, es, AccountName, AccountPwd)
+			log.Printf("ServersService Upsert ServerAccount to MySQL Failed, err=[%+v] ES=[%s], AccountName=[%s]", err, es, AccountName)
 			return err
 		}
 		return nil
@@ -177,7 +180,7 @@ func (s *ServersService) UpdateAccount(c *gin.Context, Host string, Port uint, A
 func (s *ServersService) BackupDirInfo(c *gin.Context, Host string, Port uint, AccountName string) (*internal_models.ServerAccountBackupDirInfo, *SErr.APIErr) {
 	var res *internal_models.ServerAccountBackupDirInfo
 	err := s.withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
-		backupDirResp, err := es.GetBackupDir(AccountName)
+		backupDirResp, err := es.GetBackupDir(requestContext(c), AccountName)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"ServerServing/config"
+	SErr "ServerServing/err"
+	"ServerServing/internal/dal"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 服务器上可以运行cmd/agent，它定期在本机采集硬件信息以及CPU、内存、进程、GPU的使用情况，推送到ServerServing。
+// 收到的数据保存在内存中，在过期之前，Info与Infos中这些部分使用agent的数据，而不再通过SSH执行top等命令。
+
+// agentTokenBytes agent token的随机字节数，token为它的十六进制。
+const agentTokenBytes = 32
+
+// defaultAgentStaleIntervals 没有配置过期时间时，超过多少个推送间隔没有收到数据视为过期。
+const defaultAgentStaleIntervals = 3
+
+// defaultAgentMaxReportBytes 没有配置时，agent一次推送的数据大小上限。
+const defaultAgentMaxReportBytes = 8 << 20
+
+// 使用agent数据的部分在ServerAgentInfo.Sections中的名称，与LoadServerDetailArg中的参数名一致。
+const (
+	agentSectionHardwareInfo = "with_hardware_info"
+	agentSectionCMPUsages    = "with_cmp_usages"
+	agentSectionGPUUsages    = "with_gpu_usages"
+)
+
+// receivedAgentReport 收到的agent数据，以及收到它的时间（以ServerServing的时间为准，不依赖agent所在服务器的时间）。
+type receivedAgentReport struct {
+	report     *server_executor.AgentReport
+	receivedAt time.Time
+}
+
+// agentReportStore 按服务器保存最近一次收到的agent数据。
+type agentReportStore struct {
+	mu      sync.Mutex
+	reports map[string]*receivedAgentReport
+	now     func() time.Time
+}
+
+var agentReports = &agentReportStore{
+	reports: make(map[string]*receivedAgentReport),
+	now:     time.Now,
+}
+
+func agentReportKey(Host string, Port uint) string {
+	return net.JoinHostPort(Host, strconv.Itoa(int(Port)))
+}
+
+func (s *agentReportStore) put(Host string, Port uint, report *server_executor.AgentReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[agentReportKey(Host, Port)] = &receivedAgentReport{report: report, receivedAt: s.now()}
+}
+
+func (s *agentReportStore) forget(Host string, Port uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.reports, agentReportKey(Host, Port))
+}
+
+// fresh 返回没有过期的agent数据，没有数据或者已经过期时返回nil。
+func (s *agentReportStore) fresh(Host string, Port uint) *receivedAgentReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	received, ok := s.reports[agentReportKey(Host, Port)]
+	if !ok || s.now().Sub(received.receivedAt) > agentStaleAfter(received.report) {
+		return nil
+	}
+	return received
+}
+
+// agentStaleAfter 数据的过期时间，配置文件中的配置优先。
+func agentStaleAfter(report *server_executor.AgentReport) time.Duration {
+	if conf := config.GetConfig(); conf != nil && conf.AgentConfig != nil && conf.AgentConfig.StaleAfterSeconds > 0 {
+		return time.Duration(conf.AgentConfig.StaleAfterSeconds) * time.Second
+	}
+	return defaultAgentStaleIntervals * time.Duration(report.IntervalSeconds) * time.Second
+}
+
+// AgentMaxReportBytes agent一次推送的数据大小上限，配置文件中的配置优先。
+func AgentMaxReportBytes() int64 {
+	if conf := config.GetConfig(); conf != nil && conf.AgentConfig != nil && conf.AgentConfig.MaxReportBytes > 0 {
+		return conf.AgentConfig.MaxReportBytes
+	}
+	return defaultAgentMaxReportBytes
+}
+
+func hashAgentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RotateAgentToken 为服务器生成新的agent token，之前的token立即失效。token只在这里返回一次，数据库中只保存它的SHA256。
+func (s *ServersService) RotateAgentToken(c *gin.Context, Host string, Port uint) (string, *SErr.APIErr) {
+	bs := make([]byte, agentTokenBytes)
+	if _, err := rand.Read(bs); err != nil {
+		return "", SErr.InternalErr.CustomMessageF("生成agent token失败！出错信息为：%s", err)
+	}
+	token := hex.EncodeToString(bs)
+	if err := dal.GetServerDal().UpdateAgentTokenHash(Host, Port, hashAgentToken(token)); err != nil {
+		return "", err
+	}
+	agentReports.forget(Host, Port)
+	log.Printf("ServersService RotateAgentToken, Host=[%s], Port=[%d]", Host, Port)
+	return token, nil
+}
+
+// RevokeAgentToken 不再接受该服务器的agent推送的数据，已经收到的数据也不再使用。
+func (s *ServersService) RevokeAgentToken(c *gin.Context, Host string, Port uint) *SErr.APIErr {
+	if err := dal.GetServerDal().UpdateAgentTokenHash(Host, Port, ""); err != nil {
+		return err
+	}
+	agentReports.forget(Host, Port)
+	log.Printf("ServersService RevokeAgentToken, Host=[%s], Port=[%d]", Host, Port)
+	return nil
+}
+
+// IngestAgentReport 接收agent推送的数据，token需要与该服务器的agent token一致。
+func (s *ServersService) IngestAgentReport(c *gin.Context, Host string, Port uint, token string, report *server_executor.AgentReport) *SErr.APIErr {
+	daServer, err := dal.GetServerDal().Get(Host, Port, false)
+	if err != nil {
+		return err
+	}
+	if token == "" || daServer.AgentTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(hashAgentToken(token)), []byte(daServer.AgentTokenHash)) != 1 {
+		return SErr.ForbiddenErr.CustomMessageF("agent token不正确，或者该服务器没有启用agent！Host=[%s] Port=[%d]", Host, Port)
+	}
+	if err := report.Validate(); err != nil {
+		return err
+	}
+	agentReports.put(Host, Port, report)
+	return nil
+}
+
+// agentSource 在出错信息中代替ExecutorService，指明数据来自agent。
+type agentSource struct {
+	Host string
+	Port uint
+}
+
+func (a agentSource) String() string {
+	return fmt.Sprintf("agent=[%s]", agentReportKey(a.Host, a.Port))
+}
+
+// loadInfoFromAgent 如果有没有过期的agent数据，使用它加载arg中agent能够提供的部分，返回还需要通过SSH加载的部分。
+// 没有可用的agent数据时，返回的就是arg本身，used为false。
+func (s *ServersService) loadInfoFromAgent(serverInfo *internal_models.ServerInfo, arg *internal_models.LoadServerDetailArg) (remaining *internal_models.LoadServerDetailArg, used bool) {
+	basic := serverInfo.Basic
+	received := agentReports.fresh(basic.Host, basic.Port)
+	if received == nil {
+		return arg, false
+	}
+	report := received.report
+	source := agentSource{Host: basic.Host, Port: basic.Port}
+	sections := make([]string, 0, 3)
+	copied := *arg
+	if arg.WithHardwareInfo {
+		s.loadHardwareInfo(source, report.Info, serverInfo)
+		copied.WithHardwareInfo = false
+		sections = append(sections, agentSectionHardwareInfo)
+	}
+	if arg.WithCPUMemProcessesUsage {
+		s.loadCPUMemProcessesUsageInfo(source, report.Info, serverInfo)
+		copied.WithCPUMemProcessesUsage = false
+		sections = append(sections, agentSectionCMPUsages)
+	}
+	if arg.WithGPUUsages {
+		s.loadGPUUsages(source, report.Info, serverInfo)
+		copied.WithGPUUsages = false
+		sections = append(sections, agentSectionGPUUsages)
+	}
+	s.loadAccountUsages(serverInfo)
+	serverInfo.Agent = &internal_models.ServerAgentInfo{
+		Hostname:        report.Hostname,
+		IntervalSeconds: report.IntervalSeconds,
+		CollectedAt:     report.CollectedAt,
+		ReceivedAt:      received.receivedAt.Unix(),
+		Sections:        sections,
+	}
+	return &copied, true
+}
+
+// needsServer 是否还有需要连接服务器才能加载的部分。
+func needsServer(arg *internal_models.LoadServerDetailArg) bool {
+	return arg.WithAccounts || arg.WithHardwareInfo || arg.WithRemoteAccessUsages || arg.WithCPUMemProcessesUsage || arg.WithGPUUsages || arg.WithFilesystemUsages
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"ServerServing/da/mysql"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestAgentReport(cores int) *server_executor.AgentReport {
+	memTotal := "2048MB"
+	info := &server_executor.ExecutorServiceCollectInfoResp{
+		CPUHardware: &server_executor.ExecutorServiceCPUHardwareResp{
+			CPU: &internal_models.ServerCPUs{Cores: &cores},
+		},
+		GPUHardware: &server_executor.ExecutorServiceGPUHardwareResp{},
+		CPUMemProcessesUsages: &server_executor.ExecutorServiceCPUMemProcessesUsagesResp{
+			CPUMemUsage: &internal_models.ServerCPUMemUsage{MemTotal: &memTotal},
+		},
+		GPUUsages:    &server_executor.ExecutorServiceGPUUsagesResp{},
+		GPUUsagesErr: SErr.CommandFailedErr.CustomMessage("nvidia-smi: command not found"),
+	}
+	return &server_executor.AgentReport{Hostname: "agent-host", IntervalSeconds: 10, CollectedAt: time.Now().Unix(), Info: info}
+}
+
+func TestServersService_AgentReport(t *testing.T) {
+	servers := initReplayEnv(t)
+	svc := GetServersService()
+
+	// 该服务器的端口已经关闭，需要连接它时Info会失败，以此确认是否使用了agent的数据。
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	closedPort := uint(listener.Addr().(*net.TCPAddr).Port)
+	_ = listener.Close()
+	res := mysql.GetDB().Create(&daModels.Server{
+		Name:             "agent_only",
+		Host:             "127.0.0.1",
+		Port:             closedPort,
+		AdminAccountName: "admin",
+		AdminAccountPwd:  "admin_pwd",
+		OSType:           daModels.OSTypeLinux,
+	})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	now := time.Now()
+	agentReports.now = func() time.Time { return now }
+	t.Cleanup(func() {
+		agentReports.now = time.Now
+		agentReports.forget("127.0.0.1", closedPort)
+	})
+
+	// 没有生成过agent token时不接受推送。
+	if err := svc.IngestAgentReport(nil, "127.0.0.1", closedPort, "any", newTestAgentReport(4)); err == nil || err.Code != SErr.CodeForbidden {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	token, err := svc.RotateAgentToken(nil, "127.0.0.1", closedPort)
+	if err != nil || len(token) != 2*agentTokenBytes {
+		t.Fatalf("unexpected token %q, err %+v", token, err)
+	}
+	if err := svc.IngestAgentReport(nil, "127.0.0.1", closedPort, token+"x", newTestAgentReport(4)); err == nil || err.Code != SErr.CodeForbidden {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	incomplete := newTestAgentReport(4)
+	incomplete.Info.CPUMemProcessesUsages = nil
+	if err := svc.IngestAgentReport(nil, "127.0.0.1", closedPort, token, incomplete); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if err := svc.IngestAgentReport(nil, "127.0.0.1", closedPort, token, newTestAgentReport(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	// agent能够提供全部部分时不连接服务器。
+	arg := &internal_models.LoadServerDetailArg{WithHardwareInfo: true, WithCPUMemProcessesUsage: true, WithGPUUsages: true}
+	info, err := svc.Info(nil, "127.0.0.1", closedPort, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Agent == nil || len(info.Agent.Sections) != 3 || info.Agent.Hostname != "agent-host" || info.Status.Status != string(server_executor.ServerStatusOnline) {
+		t.Fatalf("unexpected info %+v, agent %+v", info, info.Agent)
+	}
+	if cpu := info.HardwareInfo.CPUHardwareInfo; cpu.FailedInfo != nil || *cpu.Info.Cores != 4 {
+		t.Fatalf("unexpected cpu %+v", cpu)
+	}
+	if usage := info.CPUMemProcessesUsageInfo; usage.FailedInfo != nil || *usage.CPUMemUsage.MemTotal != "2048MB" {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	if gpu := info.GPUUsageInfo; gpu.FailedInfo == nil {
+		t.Fatalf("the gpu usage error should be kept, got %+v", gpu)
+	}
+	infos, _, err := svc.Infos(nil, 0, 10, arg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if info.Basic.Port == closedPort && (info.AccessFailedInfo != nil || info.Agent == nil) {
+			t.Fatalf("unexpected info %+v", info)
+		}
+	}
+
+	// 数据过期之后重新通过SSH获取。
+	now = now.Add(31 * time.Second)
+	if _, err := svc.Info(nil, "127.0.0.1", closedPort, arg); err == nil {
+		t.Fatal("stale agent data should not be used")
+	}
+
+	// 其余部分仍然通过SSH获取。
+	ubuntu := servers[0].transcript
+	ubuntuToken, err := svc.RotateAgentToken(nil, ubuntu.Host, ubuntu.Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		agentReports.forget(ubuntu.Host, ubuntu.Port)
+	})
+	if err := svc.IngestAgentReport(nil, ubuntu.Host, ubuntu.Port, ubuntuToken, newTestAgentReport(99)); err != nil {
+		t.Fatal(err)
+	}
+	info, err = svc.Info(nil, ubuntu.Host, ubuntu.Port, &internal_models.LoadServerDetailArg{WithHardwareInfo: true, WithAccounts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *info.HardwareInfo.CPUHardwareInfo.Info.Cores != 99 || len(info.Agent.Sections) != 1 || len(info.AccountInfos.Accounts) == 0 || info.AccountInfos.FailedInfo != nil {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	// 撤销之后不再使用已经收到的数据，也不再接受推送。
+	if err := svc.RevokeAgentToken(nil, ubuntu.Host, ubuntu.Port); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.IngestAgentReport(nil, ubuntu.Host, ubuntu.Port, ubuntuToken, newTestAgentReport(99)); err == nil || err.Code != SErr.CodeForbidden {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	info, err = svc.Info(nil, ubuntu.Host, ubuntu.Port, &internal_models.LoadServerDetailArg{WithHardwareInfo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Agent != nil || *info.HardwareInfo.CPUHardwareInfo.Info.Cores == 99 {
+		t.Fatalf("unexpected info %+v", info)
+	}
+}
@@ -0,0 +1,58 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"context"
+	"os"
+	"time"
+)
+
+// AgentTokenHeader agent推送数据时携带agent token的请求头。
+const AgentTokenHeader = "X-Agent-Token"
+
+// AgentCollectInfoArg agent采集的部分，即ExecutorHardwareInfoService与ExecutorHardwareUsageService中的方法所得到的数据。
+var AgentCollectInfoArg = CollectInfoArg{
+	HardwareInfo:          true,
+	CPUMemProcessesUsages: true,
+	GPUUsages:             true,
+}
+
+// AgentReport 服务器上运行的agent定期推送的数据。
+// Info 与通过SSH调用CollectInfo(AgentCollectInfoArg)得到的结果相同，所以两者可以互相代替。
+type AgentReport struct {
+	// Hostname agent所在服务器的主机名，只用于展示。
+	Hostname string `json:"hostname"`
+	// IntervalSeconds agent推送数据的间隔，用于判断数据是否过期。
+	IntervalSeconds int `json:"interval_seconds"`
+	// CollectedAt 采集数据的时间（agent所在服务器的时间），Unix时间戳，单位为秒。
+	CollectedAt int64                           `json:"collected_at"`
+	Info        *ExecutorServiceCollectInfoResp `json:"info"`
+}
+
+// CollectAgentReport 使用es采集一次agent推送的数据。每个部分的错误记录在Info中，不会使整个采集失败。
+func CollectAgentReport(ctx context.Context, es ExecutorService, interval time.Duration) (*AgentReport, *SErr.APIErr) {
+	arg := AgentCollectInfoArg
+	info, err := es.CollectInfo(ctx, &arg)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	return &AgentReport{
+		Hostname:        hostname,
+		IntervalSeconds: int(interval / time.Second),
+		CollectedAt:     time.Now().Unix(),
+		Info:            info,
+	}, nil
+}
+
+// Validate 检查推送的数据是否包含了AgentCollectInfoArg中的全部部分，缺少任何部分时都无法代替通过SSH获取的数据。
+func (r *AgentReport) Validate() *SErr.APIErr {
+	if r.IntervalSeconds <= 0 {
+		return SErr.InvalidParamErr.CustomMessageF("agent推送数据的间隔不合法！interval_seconds=[%d]", r.IntervalSeconds)
+	}
+	info := r.Info
+	if info == nil || info.CPUHardware == nil || info.GPUHardware == nil || info.CPUMemProcessesUsages == nil || info.GPUUsages == nil {
+		return SErr.InvalidParamErr.CustomMessage("agent推送的数据不完整！需要包含CPU与GPU的硬件信息、CPU内存进程的使用情况以及GPU的使用情况。")
+	}
+	return nil
+}
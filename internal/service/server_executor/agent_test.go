@@ -0,0 +1,57 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCollectAgentReport(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	report, err := CollectAgentReport(context.Background(), es, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.IntervalSeconds != 30 || report.CollectedAt == 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// 推送时经过JSON编码，解码之后与采集的结果相同。
+	bs, e := json.Marshal(report)
+	if e != nil {
+		t.Fatal(e)
+	}
+	decoded := &AgentReport{}
+	if e := json.Unmarshal(bs, decoded); e != nil {
+		t.Fatal(e)
+	}
+	if err := decoded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	info := decoded.Info
+	if info.CPUHardwareErr != nil || *info.CPUHardware.CPU.Cores != *report.Info.CPUHardware.CPU.Cores || len(info.GPUHardware.GPUs) != len(report.Info.GPUHardware.GPUs) {
+		t.Fatalf("unexpected hardware %+v", info)
+	}
+	usages := info.CPUMemProcessesUsages
+	if info.CPUMemProcessesUsagesErr != nil || *usages.CPUMemUsage.MemTotal != *report.Info.CPUMemProcessesUsages.CPUMemUsage.MemTotal || len(usages.ProcessInfos) == 0 {
+		t.Fatalf("unexpected usages %+v", usages)
+	}
+	if info.GPUUsages.Output != report.Info.GPUUsages.Output || len(info.GPUUsages.GPUs) != 2 || *info.GPUUsages.GPUs[1].MemoryTotalMiB != 24268 || info.GPUUsages.Results[0].StartedAt.IsZero() {
+		t.Fatalf("unexpected gpu usages %+v", info.GPUUsages)
+	}
+	// 没有采集的部分为nil。
+	if info.AccountList != nil || info.RemoteAccessInfos != nil {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	decoded.Info.GPUUsages = nil
+	if err := decoded.Validate(); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if err := (&AgentReport{Info: report.Info}).Validate(); err == nil {
+		t.Fatal("the interval should be required")
+	}
+}
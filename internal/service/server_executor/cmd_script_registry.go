@@ -0,0 +1,338 @@
+package server_executor
+
+import (
+	"ServerServing/cmds_scripts"
+	"ServerServing/config"
+	SErr "ServerServing/err"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CmdScriptSource 命令脚本的来源。
+type CmdScriptSource string
+
+const (
+	// CmdScriptSourceEmbedded 随程序一起编译进来的默认脚本。
+	CmdScriptSourceEmbedded CmdScriptSource = "embedded"
+	// CmdScriptSourceOverride 从配置的覆盖目录中加载的脚本。
+	CmdScriptSourceOverride CmdScriptSource = "override"
+)
+
+// commonCmdScriptDir 所有Linux发行版通用的脚本目录，发行版自己的目录中没有某个脚本时使用它。
+const commonCmdScriptDir = "linux_common"
+
+// supportedLinuxOSTypes 目前支持的发行版，启动时需要检查每个发行版都能找到requiredCmdScripts中的全部脚本。
+var supportedLinuxOSTypes = []LinuxOSType{Ubuntu, CentOS, Debian, RHEL, SUSE}
+
+// isSupportedLinuxOSType osType是否为supportedLinuxOSTypes之一。
+func isSupportedLinuxOSType(osType LinuxOSType) bool {
+	for _, supported := range supportedLinuxOSTypes {
+		if supported == osType {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredCmdScript 一个被程序使用的脚本，params为调用方传入的参数，脚本声明的参数（包括类型）必须与之一致。
+type requiredCmdScript struct {
+	name   string
+	params []*cmdTemplateParam
+}
+
+// requiredCmdScripts LinuxSSHExecutorServiceTemplate以及LinuxSSHConnection中使用到的全部脚本。
+// 修改调用某个脚本时传入的参数时，需要同时修改这里，否则覆盖目录中的旧脚本会在启动时被拒绝，而不是在第一次执行时才失败。
+var requiredCmdScripts = []*requiredCmdScript{
+	{name: "sudo_privilege"},
+	{name: "os_info"},
+	{name: "mv", params: []*cmdTemplateParam{{Name: "src", Type: CmdParamTypePath}, {Name: "dst", Type: CmdParamTypePath}}},
+	{name: "mv_force", params: []*cmdTemplateParam{{Name: "src", Type: CmdParamTypePath}, {Name: "dst", Type: CmdParamTypePath}}},
+	{name: "is_file", params: []*cmdTemplateParam{{Name: "file_path", Type: CmdParamTypePath}}},
+	{name: "is_dir", params: []*cmdTemplateParam{{Name: "dir_path", Type: CmdParamTypePath}}},
+	{name: "path_exists", params: []*cmdTemplateParam{{Name: "path", Type: CmdParamTypePath}}},
+	{name: "mkdir", params: []*cmdTemplateParam{{Name: "dir_path", Type: CmdParamTypePath}}},
+	{name: "top"},
+	{name: "meminfo"},
+	{name: "cpu_stat"},
+	{name: "df_usage"},
+	{name: "lscpu"},
+	{name: "lsgpu"},
+	{name: "w"},
+	{name: "nvidia_gpu_query"},
+	{name: "nvidia_compute_apps"},
+	{name: "cat_sudoers"},
+	{name: "add_sudoers", params: []*cmdTemplateParam{{Name: "account_name", Type: CmdParamTypeAccount}}},
+	{name: "user_add_with_openssl_pwd", params: []*cmdTemplateParam{{Name: "pwd", Type: CmdParamTypeSecret}, {Name: "account_name", Type: CmdParamTypeAccount}}},
+	{name: "get_account_list"},
+	{name: "user_del", params: []*cmdTemplateParam{{Name: "account_name", Type: CmdParamTypeAccount}}},
+	{name: "get_user_home_dir", params: []*cmdTemplateParam{{Name: "account_name", Type: CmdParamTypeAccount}}},
+	{name: "backup_dir_list", params: []*cmdTemplateParam{{Name: "backup_root", Type: CmdParamTypePath}}},
+	{name: "run_script", params: []*cmdTemplateParam{{Name: "script", Type: CmdParamTypeSecret}}},
+	{name: sftpServerScript},
+}
+
+// CmdScriptInfo 一个生效中的脚本。
+type CmdScriptInfo struct {
+	// Dir 脚本所在的目录，即发行版名称或者linux_common。
+	Dir      string
+	Name     string
+	Source   CmdScriptSource
+	Location string
+	Params   []*cmdTemplateParam
+}
+
+type registeredCmdScript struct {
+	info     *CmdScriptInfo
+	template *cmdTemplate
+}
+
+// cmdScriptRegistry 生效中的全部脚本，创建后不再修改，所以可以被并发读取。
+type cmdScriptRegistry struct {
+	scripts map[string]map[string]*registeredCmdScript
+}
+
+var (
+	cmdScriptRegistryMu sync.RWMutex
+	activeCmdScripts    *cmdScriptRegistry
+)
+
+// InitCmdScripts 加载内嵌的脚本以及配置的覆盖目录中的脚本，并检查每个支持的发行版都能找到全部需要的脚本。
+// 在启动时调用，返回错误时不应该继续启动。
+func InitCmdScripts() *SErr.APIErr {
+	var overrideDir string
+	if conf := config.GetConfig(); conf != nil {
+		overrideDir = conf.CmdsScriptsPath
+	}
+	registry, err := newCmdScriptRegistry(overrideDir)
+	if err != nil {
+		return err
+	}
+	if err := registry.validate(); err != nil {
+		return err
+	}
+	cmdScriptRegistryMu.Lock()
+	defer cmdScriptRegistryMu.Unlock()
+	activeCmdScripts = registry
+	return nil
+}
+
+// getCmdScriptRegistry 获取生效中的脚本。没有调用过InitCmdScripts时（例如在测试中），只使用内嵌的脚本。
+func getCmdScriptRegistry() (*cmdScriptRegistry, *SErr.APIErr) {
+	cmdScriptRegistryMu.RLock()
+	registry := activeCmdScripts
+	cmdScriptRegistryMu.RUnlock()
+	if registry != nil {
+		return registry, nil
+	}
+	registry, err := newCmdScriptRegistry("")
+	if err != nil {
+		return nil, err
+	}
+	cmdScriptRegistryMu.Lock()
+	defer cmdScriptRegistryMu.Unlock()
+	if activeCmdScripts == nil {
+		activeCmdScripts = registry
+	}
+	return activeCmdScripts, nil
+}
+
+func newCmdScriptRegistry(overrideDir string) (*cmdScriptRegistry, *SErr.APIErr) {
+	registry := &cmdScriptRegistry{
+		scripts: make(map[string]map[string]*registeredCmdScript),
+	}
+	dirs, err := fs.ReadDir(cmds_scripts.FS, ".")
+	if err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("读取内嵌的命令脚本失败！err=[%v]", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		files, err := fs.ReadDir(cmds_scripts.FS, dir.Name())
+		if err != nil {
+			return nil, SErr.InternalErr.CustomMessageF("读取内嵌的命令脚本失败！dir=[%s], err=[%v]", dir.Name(), err)
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			location := path.Join(dir.Name(), f.Name())
+			bs, err := fs.ReadFile(cmds_scripts.FS, location)
+			if err != nil {
+				return nil, SErr.InternalErr.CustomMessageF("读取内嵌的命令脚本失败！script=[%s], err=[%v]", location, err)
+			}
+			if sErr := registry.add(dir.Name(), f.Name(), CmdScriptSourceEmbedded, location, string(bs)); sErr != nil {
+				return nil, sErr
+			}
+		}
+	}
+	if overrideDir == "" {
+		return registry, nil
+	}
+	overrideDirs, err := ioutil.ReadDir(overrideDir)
+	if os.IsNotExist(err) {
+		log.Printf("cmdScriptRegistry override dir not exists, use embedded scripts only, dir=[%s]", overrideDir)
+		return registry, nil
+	}
+	if err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("读取命令脚本的覆盖目录失败！dir=[%s], err=[%v]", overrideDir, err)
+	}
+	for _, dir := range overrideDirs {
+		if !dir.IsDir() {
+			continue
+		}
+		dirPath := path.Join(overrideDir, dir.Name())
+		files, err := ioutil.ReadDir(dirPath)
+		if err != nil {
+			return nil, SErr.InternalErr.CustomMessageF("读取命令脚本的覆盖目录失败！dir=[%s], err=[%v]", dirPath, err)
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			location := path.Join(dirPath, f.Name())
+			bs, err := ioutil.ReadFile(location)
+			if err != nil {
+				return nil, SErr.InternalErr.CustomMessageF("读取命令脚本失败！script=[%s], err=[%v]", location, err)
+			}
+			if sErr := registry.add(dir.Name(), f.Name(), CmdScriptSourceOverride, location, string(bs)); sErr != nil {
+				return nil, sErr
+			}
+		}
+	}
+	return registry, nil
+}
+
+// add 解析并注册脚本，同名的脚本后注册的覆盖先注册的。
+func (r *cmdScriptRegistry) add(dir, name string, source CmdScriptSource, location, content string) *SErr.APIErr {
+	t, err := parseCmdTemplate(name, content)
+	if err != nil {
+		return err.CustomMessageF("加载命令脚本%s失败！%s", location, err.Message)
+	}
+	if _, ok := r.scripts[dir]; !ok {
+		r.scripts[dir] = make(map[string]*registeredCmdScript)
+	}
+	r.scripts[dir][name] = &registeredCmdScript{
+		info: &CmdScriptInfo{
+			Dir:      dir,
+			Name:     name,
+			Source:   source,
+			Location: location,
+			Params:   t.Params,
+		},
+		template: t,
+	}
+	return nil
+}
+
+// lookup 按dirs的顺序查找脚本，使用第一个找到的，dirs一般由cmdScriptDirs生成。
+func (r *cmdScriptRegistry) lookup(dirs []string, name string) (*registeredCmdScript, bool) {
+	for _, dir := range dirs {
+		if script, ok := r.scripts[dir][name]; ok {
+			return script, true
+		}
+	}
+	return nil, false
+}
+
+// validate 检查每个支持的发行版都能找到全部需要的脚本，并且每个目录（包括按ID、ID_LIKE查找的目录，例如rocky）中的
+// 脚本声明的参数都与调用方传入的参数一致，使得参数不一致的脚本在启动时就被发现，而不是在某台服务器上执行时才失败。
+func (r *cmdScriptRegistry) validate() *SErr.APIErr {
+	for _, osType := range supportedLinuxOSTypes {
+		for _, required := range requiredCmdScripts {
+			if _, ok := r.lookup(cmdScriptDirs(osType, nil), required.name); !ok {
+				return SErr.InternalErr.CustomMessageF("缺少命令脚本！发行版为%s，脚本名为%s", osType, required.name)
+			}
+		}
+	}
+	dirs := make([]string, 0, len(r.scripts))
+	for dir := range r.scripts {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		for _, required := range requiredCmdScripts {
+			script, ok := r.scripts[dir][required.name]
+			if !ok {
+				continue
+			}
+			if !sameCmdParams(script.template.Params, required.params) {
+				return SErr.InternalErr.CustomMessageF("命令脚本的参数与程序传入的参数不一致！目录为%s，脚本为%s，脚本声明的参数为[%s]，程序传入的参数为[%s]",
+					dir, script.info.Location, formatCmdParams(script.template.Params), formatCmdParams(required.params))
+			}
+		}
+	}
+	return nil
+}
+
+// sameCmdParams 两组参数的名称与类型是否相同，不考虑顺序。
+func sameCmdParams(params, expected []*cmdTemplateParam) bool {
+	if len(params) != len(expected) {
+		return false
+	}
+	types := make(map[string]CmdParamType, len(params))
+	for _, param := range params {
+		types[param.Name] = param.Type
+	}
+	for _, param := range expected {
+		if paramType, ok := types[param.Name]; !ok || paramType != param.Type {
+			return false
+		}
+	}
+	return true
+}
+
+// formatCmdParams 按脚本中@param的格式输出参数，用于出错信息。
+func formatCmdParams(params []*cmdTemplateParam) string {
+	res := make([]string, 0, len(params))
+	for _, param := range params {
+		res = append(res, fmt.Sprintf("%s %s", param.Name, param.Type))
+	}
+	return strings.Join(res, ", ")
+}
+
+// list 返回全部生效中的脚本，按目录与脚本名排序。
+func (r *cmdScriptRegistry) list() []*CmdScriptInfo {
+	res := make([]*CmdScriptInfo, 0)
+	for _, scripts := range r.scripts {
+		for _, script := range scripts {
+			res = append(res, script.info)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Dir != res[j].Dir {
+			return res[i].Dir < res[j].Dir
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// ListCmdScripts 返回全部生效中的脚本以及它们的来源。
+func ListCmdScripts() ([]*CmdScriptInfo, *SErr.APIErr) {
+	registry, err := getCmdScriptRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return registry.list(), nil
+}
+
+// loadCmdTemplate 按dirs的顺序查找命令模板，dirs一般由cmdScriptDirs生成。
+func loadCmdTemplate(dirs []string, name string) (*cmdTemplate, *SErr.APIErr) {
+	registry, err := getCmdScriptRegistry()
+	if err != nil {
+		return nil, err
+	}
+	script, ok := registry.lookup(dirs, name)
+	if !ok {
+		return nil, SErr.InternalErr.CustomMessageF("找不到命令脚本！查找的目录为%v，脚本名为%s", dirs, name)
+	}
+	return script.template, nil
+}
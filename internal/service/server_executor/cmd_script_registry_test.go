@@ -0,0 +1,132 @@
+package server_executor
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// TestEmbeddedCmdScripts 内嵌的脚本都需要是合法的模板，并且每个支持的发行版都能找到全部需要的脚本。
+func TestEmbeddedCmdScripts(t *testing.T) {
+	registry, err := newCmdScriptRegistry("")
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if err := registry.validate(); err != nil {
+		t.Fatal(err.Message)
+	}
+	for _, info := range registry.list() {
+		if info.Source != CmdScriptSourceEmbedded {
+			t.Errorf("%s/%s: source = %s", info.Dir, info.Name, info.Source)
+		}
+	}
+}
+
+func TestOverrideCmdScripts(t *testing.T) {
+	dir, e := ioutil.TempDir("", "cmds_scripts_override")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	if e := os.MkdirAll(path.Join(dir, "ubuntu"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e := ioutil.WriteFile(path.Join(dir, "ubuntu", "lsgpu"), []byte("lspci | grep -E 'VGA|3D'\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	registry, err := newCmdScriptRegistry(dir)
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	script, ok := registry.lookup(cmdScriptDirs(Ubuntu, nil), "lsgpu")
+	if !ok || script.info.Source != CmdScriptSourceOverride {
+		t.Fatalf("ubuntu lsgpu should come from the override dir, got %+v", script)
+	}
+	script, ok = registry.lookup(cmdScriptDirs(CentOS, nil), "lsgpu")
+	if !ok || script.info.Source != CmdScriptSourceEmbedded {
+		t.Fatalf("centos lsgpu should fall back to the embedded script, got %+v", script)
+	}
+
+	// 覆盖目录中不合法的模板在加载时就失败。
+	if e := ioutil.WriteFile(path.Join(dir, "ubuntu", "user_del"), []byte("# @param account_name user\nsudo userdel {{account_name}}\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	if _, err := newCmdScriptRegistry(dir); err == nil {
+		t.Fatal("expected an error for an invalid override template")
+	}
+}
+
+// TestValidateCmdScriptParams 覆盖目录中的脚本声明的参数与程序传入的参数不一致时，在启动时就失败。
+func TestValidateCmdScriptParams(t *testing.T) {
+	for script, content := range map[string]string{
+		// 缺少参数。
+		"mv": "# @param src path\nsudo mv {{src}} /tmp\n",
+		// 参数名不同。
+		"user_del": "# @param name account\nsudo userdel {{name}}\n",
+		// 参数类型不同。
+		"get_user_home_dir": "# @param account_name string\ngetent passwd {{account_name}}\n",
+		// 多出的参数。
+		"lscpu": "# @param extra string\nlscpu {{extra}}\n",
+	} {
+		dir, e := ioutil.TempDir("", "cmds_scripts_override")
+		if e != nil {
+			t.Fatal(e)
+		}
+		defer os.RemoveAll(dir)
+		if e := os.MkdirAll(path.Join(dir, "centos"), 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e := ioutil.WriteFile(path.Join(dir, "centos", script), []byte(content), 0644); e != nil {
+			t.Fatal(e)
+		}
+		registry, err := newCmdScriptRegistry(dir)
+		if err != nil {
+			t.Fatal(err.Message)
+		}
+		if err := registry.validate(); err == nil || !strings.Contains(err.Message, script) {
+			t.Fatalf("validating an override %s with mismatched params should fail, err=%v", script, err)
+		}
+	}
+
+	// 按ID、ID_LIKE查找的目录不在supportedLinuxOSTypes中，其中的脚本同样在启动时检查。
+	overlayDir, e := ioutil.TempDir("", "cmds_scripts_override")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(overlayDir)
+	if e := os.MkdirAll(path.Join(overlayDir, "rocky"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e := ioutil.WriteFile(path.Join(overlayDir, "rocky", "user_del"), []byte("# @param name account\nsudo userdel {{name}}\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	overlayRegistry, err := newCmdScriptRegistry(overlayDir)
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if err := overlayRegistry.validate(); err == nil || !strings.Contains(err.Message, path.Join("rocky", "user_del")) {
+		t.Fatalf("validating a rocky overlay with mismatched params should fail, err=%v", err)
+	}
+
+	// 参数的顺序不同时仍然可以使用。
+	dir, e := ioutil.TempDir("", "cmds_scripts_override")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	if e := os.MkdirAll(path.Join(dir, "centos"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e := ioutil.WriteFile(path.Join(dir, "centos", "mv"), []byte("# @param dst path\n# @param src path\nsudo mv {{src}} {{dst}}\n"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	registry, err := newCmdScriptRegistry(dir)
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	if err := registry.validate(); err != nil {
+		t.Fatal(err.Message)
+	}
+}
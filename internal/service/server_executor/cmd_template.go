@@ -0,0 +1,308 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 命令模板。模板文件的开头使用注释声明它的参数，正文中使用{{name}}引用参数，例如：
+//
+//	# @param src path
+//	# @param dst path
+//	sudo mv {{src}} {{dst}}
+//
+// 渲染时，每个参数的值都会先按照它声明的类型做校验，再使用单引号进行shell转义，所以模板正文中不需要（也不应该）再给参数加引号。
+// 只有{{}}中是声明过的参数名时才会被替换，其它的{{}}原样保留，例如docker ps --format '{{.Names}}'。
+// 声明的参数在正文中没有被引用时，加载模板就会失败。
+
+// CmdParamType 命令模板参数的类型。
+type CmdParamType string
+
+const (
+	// CmdParamTypeAccount Linux账户名。
+	CmdParamTypeAccount CmdParamType = "account"
+	// CmdParamTypePath 绝对路径。
+	CmdParamTypePath CmdParamType = "path"
+	// CmdParamTypeString 任意单行字符串。
+	CmdParamTypeString CmdParamType = "string"
+	// CmdParamTypeInt 非负整数。
+	CmdParamTypeInt CmdParamType = "int"
+	// CmdParamTypeSecret 密码等敏感信息，不会出现在日志中。
+	CmdParamTypeSecret CmdParamType = "secret"
+)
+
+var (
+	cmdParamDeclReg    = regexp.MustCompile(`^#\s*@param\s+([a-z_][a-z0-9_]*)\s+([a-z]+)\s*$`)
+	cmdPlaceholderReg  = regexp.MustCompile(`{{\s*([^{}]*?)\s*}}`)
+	accountNameReg     = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+	absolutePathReg    = regexp.MustCompile(`^/[A-Za-z0-9._/+@-]*$`)
+	redactedArgValue   = "******"
+	redactedParamValue = "'" + redactedArgValue + "'"
+)
+
+// IsValidAccountName 账户名需要以小写字母或下划线开头，只包含小写字母、数字、下划线以及-，长度不超过32。
+func IsValidAccountName(name string) bool {
+	return accountNameReg.MatchString(name)
+}
+
+// IsValidPath 路径需要是绝对路径，只包含字母、数字以及._/+@-，并且不包含..。
+func IsValidPath(p string) bool {
+	if !absolutePathReg.MatchString(p) {
+		return false
+	}
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+type cmdTemplateParam struct {
+	Name string
+	Type CmdParamType
+}
+
+// cmdTemplate 解析后的命令模板。
+type cmdTemplate struct {
+	Name   string
+	Params []*cmdTemplateParam
+	body   string
+}
+
+// CmdArgs 渲染命令模板时使用的参数，key为参数名。
+type CmdArgs map[string]string
+
+// parseCmdTemplate 解析命令模板，检查声明的参数与正文中引用的参数是否一致。
+func parseCmdTemplate(name, content string) (*cmdTemplate, *SErr.APIErr) {
+	t := &cmdTemplate{
+		Name:   name,
+		Params: make([]*cmdTemplateParam, 0),
+	}
+	declared := make(map[string]*cmdTemplateParam)
+	bodyLines := make([]string, 0)
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") && strings.Contains(trimmed, "@param") {
+			m := cmdParamDeclReg.FindStringSubmatch(trimmed)
+			if len(m) < 3 {
+				return nil, SErr.InternalErr.CustomMessageF("命令模板%s的参数声明格式有误！该行为：[%s]", name, trimmed)
+			}
+			paramType := CmdParamType(m[2])
+			switch paramType {
+			case CmdParamTypeAccount, CmdParamTypePath, CmdParamTypeString, CmdParamTypeInt, CmdParamTypeSecret:
+			default:
+				return nil, SErr.InternalErr.CustomMessageF("命令模板%s的参数%s的类型%s不受支持！", name, m[1], m[2])
+			}
+			if _, ok := declared[m[1]]; ok {
+				return nil, SErr.InternalErr.CustomMessageF("命令模板%s重复声明了参数%s！", name, m[1])
+			}
+			param := &cmdTemplateParam{Name: m[1], Type: paramType}
+			declared[param.Name] = param
+			t.Params = append(t.Params, param)
+			continue
+		}
+		bodyLines = append(bodyLines, line)
+	}
+	t.body = strings.TrimSpace(strings.Join(bodyLines, "\n"))
+
+	used := make(map[string]bool)
+	for _, loc := range t.placeholders() {
+		used[t.body[loc[2]:loc[3]]] = true
+	}
+	for _, param := range t.Params {
+		if !used[param.Name] {
+			return nil, SErr.InternalErr.CustomMessageF("命令模板%s声明了参数%s，但是没有使用它！", name, param.Name)
+		}
+	}
+	return t, nil
+}
+
+// placeholders 正文中引用了声明过的参数的占位符，每一项为FindAllStringSubmatchIndex返回的位置，[2]与[3]为参数名的位置。
+func (t *cmdTemplate) placeholders() [][]int {
+	declared := make(map[string]bool, len(t.Params))
+	for _, param := range t.Params {
+		declared[param.Name] = true
+	}
+	locs := make([][]int, 0)
+	for _, loc := range cmdPlaceholderReg.FindAllStringSubmatchIndex(t.body, -1) {
+		if declared[t.body[loc[2]:loc[3]]] {
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// Render 使用args渲染模板，返回可以直接执行的命令以及隐藏了敏感参数的、可以打印到日志中的命令。
+// args必须与模板声明的参数完全一致，并且每个值都需要通过其类型的校验。
+func (t *cmdTemplate) Render(args CmdArgs) (string, string, *SErr.APIErr) {
+	if len(args) != len(t.Params) {
+		return "", "", SErr.InternalErr.CustomMessageF("命令模板%s需要%d个参数，实际传入了%d个！", t.Name, len(t.Params), len(args))
+	}
+	quoted := make(map[string]string, len(t.Params))
+	redacted := make(map[string]string, len(t.Params))
+	for _, param := range t.Params {
+		value, ok := args[param.Name]
+		if !ok {
+			return "", "", SErr.InternalErr.CustomMessageF("命令模板%s缺少参数%s！", t.Name, param.Name)
+		}
+		if err := validateCmdParam(param, value); err != nil {
+			return "", "", err
+		}
+		quoted[param.Name] = shellQuote(value)
+		redacted[param.Name] = quoted[param.Name]
+		if param.Type == CmdParamTypeSecret {
+			redacted[param.Name] = redactedParamValue
+		}
+	}
+	locs := t.placeholders()
+	replace := func(values map[string]string) string {
+		b := &strings.Builder{}
+		last := 0
+		for _, loc := range locs {
+			b.WriteString(t.body[last:loc[0]])
+			b.WriteString(values[t.body[loc[2]:loc[3]]])
+			last = loc[1]
+		}
+		b.WriteString(t.body[last:])
+		return b.String()
+	}
+	return replace(quoted), replace(redacted), nil
+}
+
+// renderedScript 渲染后的脚本。Args为渲染时使用的参数，其中敏感参数的值已经被隐藏，可以打印到日志中或者被录制下来。
+type renderedScript struct {
+	Name   string
+	Args   CmdArgs
+	Cmd    string
+	LogCmd string
+}
+
+// renderScript 按dirs的顺序查找名为name的命令模板，并使用args渲染。
+func renderScript(dirs []string, name string, args CmdArgs) (*renderedScript, *SErr.APIErr) {
+	t, err := loadCmdTemplate(dirs, name)
+	if err != nil {
+		return nil, err
+	}
+	cmd, logCmd, err := t.Render(args)
+	if err != nil {
+		return nil, err
+	}
+	return &renderedScript{
+		Name:   name,
+		Args:   t.redactArgs(args),
+		Cmd:    cmd,
+		LogCmd: logCmd,
+	}, nil
+}
+
+// redactArgs 复制args，并隐藏其中敏感参数的值。
+func (t *cmdTemplate) redactArgs(args CmdArgs) CmdArgs {
+	redactedArgs := make(CmdArgs, len(args))
+	for _, param := range t.Params {
+		redactedArgs[param.Name] = args[param.Name]
+		if param.Type == CmdParamTypeSecret {
+			redactedArgs[param.Name] = redactedArgValue
+		}
+	}
+	return redactedArgs
+}
+
+// ParseScriptParams 解析一段使用命令模板格式编写的脚本（例如脚本目录中管理员定义的脚本），返回它声明的参数。
+// 脚本由用户提交，所以解析失败时返回InvalidParamErr。
+func ParseScriptParams(name, content string) ([]*cmdTemplateParam, *SErr.APIErr) {
+	t, err := parseCmdTemplate(name, content)
+	if err != nil {
+		return nil, SErr.InvalidParamErr.CustomMessage(err.Message)
+	}
+	return t.Params, nil
+}
+
+// RedactScriptArgs 复制args，并隐藏其中secret类型的参数以及脚本中没有声明的参数的值，使得它们可以被保存下来。
+// 脚本本身无法解析时，隐藏全部参数的值。
+func RedactScriptArgs(content string, args CmdArgs) CmdArgs {
+	types := make(map[string]CmdParamType)
+	if t, err := parseCmdTemplate("", content); err == nil {
+		for _, param := range t.Params {
+			types[param.Name] = param.Type
+		}
+	}
+	redactedArgs := make(CmdArgs, len(args))
+	for name, value := range args {
+		paramType, ok := types[name]
+		if !ok || paramType == CmdParamTypeSecret {
+			value = redactedArgValue
+		}
+		redactedArgs[name] = value
+	}
+	return redactedArgs
+}
+
+// renderUserScript 使用args渲染一段使用命令模板格式编写的脚本，再通过run_script以root身份执行它。
+// 渲染后的脚本以base64编码后作为run_script的参数，所以可以包含多行以及任意字符；
+// 返回结果的Name、Args以及LogCmd都来自该脚本本身，而不是run_script，使得日志以及录制记录中能够看到真正执行的内容。
+func renderUserScript(dirs []string, name, content string, args CmdArgs) (*renderedScript, *SErr.APIErr) {
+	t, err := parseCmdTemplate(name, content)
+	if err != nil {
+		return nil, SErr.InvalidParamErr.CustomMessage(err.Message)
+	}
+	cmd, logCmd, err := t.Render(args)
+	if err != nil {
+		return nil, SErr.InvalidParamErr.CustomMessage(err.Message)
+	}
+	return wrapInRunScript(dirs, name, t.redactArgs(args), cmd, logCmd)
+}
+
+// renderUserCommand 不经过命令模板，直接通过run_script以root身份执行cmd。cmd中的{{}}原样保留，例如docker的--format参数。
+// 录制与回放时以command作为参数。
+func renderUserCommand(dirs []string, name, cmd string) (*renderedScript, *SErr.APIErr) {
+	if strings.TrimSpace(cmd) == "" {
+		return nil, SErr.InvalidParamErr.CustomMessage("要执行的命令不能为空！")
+	}
+	return wrapInRunScript(dirs, name, CmdArgs{"command": cmd}, cmd, cmd)
+}
+
+// wrapInRunScript 将cmd以base64编码后作为run_script的参数，返回的脚本使用name、redactedArgs以及logCmd描述自己。
+func wrapInRunScript(dirs []string, name string, redactedArgs CmdArgs, cmd, logCmd string) (*renderedScript, *SErr.APIErr) {
+	runner, err := renderScript(dirs, "run_script", CmdArgs{"script": base64.StdEncoding.EncodeToString([]byte(cmd + "\n"))})
+	if err != nil {
+		return nil, err
+	}
+	return &renderedScript{
+		Name:   name,
+		Args:   redactedArgs,
+		Cmd:    runner.Cmd,
+		LogCmd: logCmd,
+	}, nil
+}
+
+func validateCmdParam(param *cmdTemplateParam, value string) *SErr.APIErr {
+	valid := true
+	switch param.Type {
+	case CmdParamTypeAccount:
+		valid = IsValidAccountName(value)
+	case CmdParamTypePath:
+		valid = IsValidPath(value)
+	case CmdParamTypeInt:
+		_, err := strconv.ParseUint(value, 10, 64)
+		valid = err == nil
+	case CmdParamTypeString, CmdParamTypeSecret:
+		valid = !strings.ContainsAny(value, "\x00\r\n")
+	}
+	if valid {
+		return nil
+	}
+	if param.Type == CmdParamTypeSecret {
+		return SErr.InvalidParamErr.CustomMessageF("参数%s包含不允许的字符！", param.Name)
+	}
+	return SErr.InvalidParamErr.CustomMessageF("参数%s的值[%s]不是合法的%s！", param.Name, value, param.Type)
+}
+
+// shellQuote 使用单引号转义，单引号本身转义为'"'"'。
+func shellQuote(s string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", `'"'"'`))
+}
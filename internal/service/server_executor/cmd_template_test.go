@@ -0,0 +1,82 @@
+package server_executor
+
+import (
+	"testing"
+)
+
+func TestParseCmdTemplateMismatch(t *testing.T) {
+	cases := map[string]string{
+		"unused":       "# @param account_name account\nsudo userdel someone",
+		"unknown type": "# @param account_name user\nsudo userdel {{account_name}}",
+		"duplicated":   "# @param p path\n# @param p path\nsudo mkdir {{p}}",
+		"bad decl":     "# @param\nsudo mkdir /tmp",
+	}
+	for name, content := range cases {
+		if _, err := parseCmdTemplate(name, content); err == nil {
+			t.Errorf("%s: expected parse error", name)
+		}
+	}
+}
+
+func TestCmdTemplateRender(t *testing.T) {
+	tmpl, err := parseCmdTemplate("user_add", "# @param pwd secret\n# @param account_name account\nsudo useradd -p \"$(openssl passwd -crypt {{pwd}})\" {{account_name}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, logCmd, err := tmpl.Render(CmdArgs{"pwd": "it's; rm -rf /", "account_name": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCmd := `sudo useradd -p "$(openssl passwd -crypt 'it'"'"'s; rm -rf /')" 'alice'`
+	if cmd != wantCmd {
+		t.Errorf("cmd = %s, want %s", cmd, wantCmd)
+	}
+	wantLog := `sudo useradd -p "$(openssl passwd -crypt '******')" 'alice'`
+	if logCmd != wantLog {
+		t.Errorf("logCmd = %s, want %s", logCmd, wantLog)
+	}
+
+	invalid := []CmdArgs{
+		{"pwd": "x", "account_name": "x; rm -rf /"},
+		{"pwd": "x", "account_name": "Root"},
+		{"pwd": "x\ny", "account_name": "alice"},
+		{"pwd": "x"},
+		{"pwd": "x", "account_name": "alice", "extra": "1"},
+	}
+	for _, args := range invalid {
+		if _, _, err := tmpl.Render(args); err == nil {
+			t.Errorf("args %v: expected render error", args)
+		}
+	}
+}
+
+func TestCmdTemplateRenderKeepsOtherBraces(t *testing.T) {
+	// 只替换声明过的参数，docker与kubectl的Go模板原样保留。
+	tmpl, err := parseCmdTemplate("docker_names", "# @param name string\ndocker ps --filter name={{ name }} --format '{{.Names}} {{name2}}' && kubectl get pods -o go-template='{{range .items}}{{end}}'\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd, _, err := tmpl.Render(CmdArgs{"name": "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "docker ps --filter name='web' --format '{{.Names}} {{name2}}' && kubectl get pods -o go-template='{{range .items}}{{end}}'"
+	if cmd != want {
+		t.Errorf("cmd = %s, want %s", cmd, want)
+	}
+}
+
+func TestIsValidPath(t *testing.T) {
+	for p, want := range map[string]bool{
+		"/home/alice":          true,
+		"/backup/alice.backup": true,
+		"relative/path":        false,
+		"/home/../etc":         false,
+		"/tmp/$(reboot)":       false,
+		"/tmp/a b":             false,
+	} {
+		if got := IsValidPath(p); got != want {
+			t.Errorf("IsValidPath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
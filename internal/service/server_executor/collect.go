@@ -0,0 +1,448 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// collectScriptName 组合脚本的名称，用于日志以及出错信息。
+const collectScriptName = "collect_info"
+
+// collectSectionTimeoutStatus 组合命令中的脚本超过自己的超时时间、被timeout终止时的退出码。
+const collectSectionTimeoutStatus = 124
+
+// collectTimeoutSlack 组合命令的超时时间在各个脚本的超时时间之和的基础上额外留出的时间，用于分隔符的输出等。
+const collectTimeoutSlack = 5 * time.Second
+
+// CollectInfoArg 指定CollectInfo需要收集的部分，与LoadServerDetailArg中的可选项一一对应。
+type CollectInfoArg struct {
+	AccountList bool
+	// BackupDirs 列出备份文件夹中全部的<账户名>.backup，用于得到每个账户的备份文件夹信息。
+	BackupDirs            bool
+	HardwareInfo          bool
+	RemoteAccessInfos     bool
+	CPUMemProcessesUsages bool
+	GPUUsages             bool
+	FilesystemUsages      bool
+}
+
+// batchScriptRunner 能够在一个session中执行多个渲染后的脚本的commandRunner，例如录制、回放与审计。
+// 返回的results与errs与scripts一一对应，results中的每个结果都不为nil。
+type batchScriptRunner interface {
+	runScripts(ctx context.Context, scripts []*renderedScript) ([]*internal_models.CommandResult, []*SErr.APIErr)
+}
+
+// runScriptsWith 使用runner执行多个渲染后的脚本，每个脚本的结果与单独执行它时相同。
+// runner实现了batchScriptRunner时交给它处理；实现了scriptRunner时（例如回放）逐个执行；
+// 否则将全部脚本组合为一条命令，只打开一个session执行，再按分隔符拆分出每个脚本的结果。
+func runScriptsWith(ctx context.Context, runner commandRunner, scripts []*renderedScript) ([]*internal_models.CommandResult, []*SErr.APIErr) {
+	if br, ok := runner.(batchScriptRunner); ok {
+		return br.runScripts(ctx, scripts)
+	}
+	results := make([]*internal_models.CommandResult, len(scripts))
+	errs := make([]*SErr.APIErr, len(scripts))
+	if _, ok := runner.(scriptRunner); ok {
+		for i, script := range scripts {
+			results[i], errs[i] = runScriptWith(ctx, runner, script)
+		}
+		return results, errs
+	}
+	marker := newCollectMarker()
+	// 每个脚本由timeout按自己的超时时间终止，组合命令的超时只用于兜底，例如脚本处于不可中断的状态而无法被终止时。
+	timeout := collectTimeoutSlack
+	for _, script := range scripts {
+		timeout += commandTimeout(script.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := runner.runCommand(ctx, nil, collectScriptName, buildCollectCmd(marker, scripts), true)
+	sections := splitCollectOutput(marker, result.Stdout)
+	for i, script := range scripts {
+		results[i], errs[i] = sectionResult(script, sections[i], result, err)
+	}
+	return results, errs
+}
+
+// newCollectMarker 生成本次组合命令的分隔符，其中的随机数使得命令的输出几乎不可能与它冲突。
+func newCollectMarker() string {
+	nonce := make([]byte, 12)
+	_, _ = rand.Read(nonce)
+	return "@@ss-collect-" + hex.EncodeToString(nonce) + "@@"
+}
+
+// buildCollectCmd 将scripts组合为一条命令。每个脚本在独立的sh中执行，并由timeout按该脚本自己的超时时间（commandTimeout）终止，
+// 所以一个卡住的脚本（例如df遇到无响应的NFS）不会耗尽之后的脚本的时间。sudo是sudoPrelude定义的shell函数，无法传递给新的sh，
+// 所以每个脚本之前同样加上sudoPrelude，并通过管道把组合命令已经读取的密码交给它。标准错误输出写入临时文件，输出格式为：
+//
+//	\n<marker> begin <序号> <开始时间>\n<标准输出>\n<marker> end <退出码> <结束时间>\n<标准错误输出>
+//
+// 全部脚本执行完成后输出\n<marker> done\n。时间为date +%s%N输出的纳秒时间戳。
+// 每个分隔符之前都额外输出了一个换行，拆分时去掉它，所以脚本的输出不会被改变。
+func buildCollectCmd(marker string, scripts []*renderedScript) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "__ss_m=%s; __ss_err=$(mktemp) || exit 1\n", shellQuote(marker))
+	for i, script := range scripts {
+		fmt.Fprintf(b, "printf '\\n%%s begin %d %%s\\n' \"$__ss_m\" \"$(date +%%s%%N)\"\n", i)
+		fmt.Fprintf(b, "printf '%%s\\n' \"${__ss_sudo_pwd-}\" | timeout %d sh -c %s 2>\"$__ss_err\"\n",
+			collectSectionTimeoutSeconds(script.Name), shellQuote(sudoPrelude+script.Cmd))
+		b.WriteString("__ss_rc=$?; printf '\\n%s end %s %s\\n' \"$__ss_m\" \"$__ss_rc\" \"$(date +%s%N)\"; cat \"$__ss_err\"\n")
+	}
+	b.WriteString("printf '\\n%s done\\n' \"$__ss_m\"; rm -f \"$__ss_err\"")
+	return b.String()
+}
+
+// collectSectionTimeoutSeconds 组合命令中的脚本的超时时间，timeout只接受整数秒，不足1秒的部分向上取整。
+func collectSectionTimeoutSeconds(scriptName string) int {
+	return int((commandTimeout(scriptName) + time.Second - 1) / time.Second)
+}
+
+// collectSection 从组合命令的输出中拆分出的一个脚本的结果。ended表示是否输出了结束的分隔符。
+type collectSection struct {
+	ended     bool
+	stdout    string
+	stderr    string
+	exitCode  int
+	startedAt int64
+	endedAt   int64
+}
+
+// splitCollectOutput 按分隔符拆分组合命令的输出，key为脚本的序号，没有开始执行的脚本不在其中。
+func splitCollectOutput(marker string, output string) map[int]*collectSection {
+	sections := make(map[int]*collectSection)
+	var current *collectSection
+	for _, part := range strings.Split(output, "\n"+marker+" ") {
+		header, content := part, ""
+		if i := strings.IndexByte(part, '\n'); i >= 0 {
+			header, content = part[:i], part[i+1:]
+		}
+		fields := strings.Fields(header)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "begin":
+			if len(fields) < 2 {
+				continue
+			}
+			index, err := strconv.Atoi(fields[1])
+			if err != nil {
+				continue
+			}
+			current = &collectSection{stdout: content}
+			if len(fields) >= 3 {
+				current.startedAt, _ = strconv.ParseInt(fields[2], 10, 64)
+			}
+			sections[index] = current
+		case "end":
+			if current == nil || current.ended || len(fields) < 2 {
+				continue
+			}
+			exitCode, err := strconv.Atoi(fields[1])
+			if err != nil {
+				continue
+			}
+			current.ended = true
+			current.exitCode = exitCode
+			current.stderr = content
+			if len(fields) >= 3 {
+				current.endedAt, _ = strconv.ParseInt(fields[2], 10, 64)
+			}
+		}
+	}
+	return sections
+}
+
+// sectionResult 生成一个脚本的结果。脚本正常结束时与单独执行它时相同；
+// 组合命令在该脚本结束之前失败时（例如超时或者连接断开），使用组合命令的错误。
+func sectionResult(script *renderedScript, section *collectSection, collectResult *internal_models.CommandResult, collectErr *SErr.APIErr) (*internal_models.CommandResult, *SErr.APIErr) {
+	result := &internal_models.CommandResult{
+		Script:     script.Name,
+		ExitStatus: -1,
+		StartedAt:  collectResult.StartedAt,
+	}
+	if section != nil {
+		result.Stdout = section.stdout
+		if section.startedAt > 0 {
+			result.StartedAt = time.Unix(0, section.startedAt)
+		}
+		if section.ended {
+			result.Stderr = section.stderr
+			result.ExitStatus = section.exitCode
+			if section.startedAt > 0 && section.endedAt >= section.startedAt {
+				result.DurationMs = (section.endedAt - section.startedAt) / int64(time.Millisecond)
+			}
+		}
+	}
+	if section == nil || !section.ended {
+		err := collectErr
+		if section != nil && err != nil && err.Code == SErr.CommandTimeoutErr.Code {
+			// 组合命令超时时，正在执行的是这个脚本。
+			err = commandTimeoutErr(script.Name)
+		}
+		if err == nil {
+			err = SErr.CommandFailedErr.CustomMessageF("组合命令没有输出该命令的结果！命令为：%s，服务器输出为：%s", script.Name, strings.TrimSpace(collectResult.Stderr))
+		}
+		result.Error = err.Message
+		return result, err
+	}
+	if result.ExitStatus == collectSectionTimeoutStatus && sectionTimedOut(script, section) {
+		// 与单独执行时超时一样，没有正常退出的命令的退出码为-1。
+		result.ExitStatus = -1
+		err := commandTimeoutErr(script.Name)
+		result.Error = err.Message
+		return result, err
+	}
+	if result.ExitStatus != 0 {
+		err := commandFailedErr(result)
+		result.Error = err.Message
+		return result, err
+	}
+	return result, nil
+}
+
+// sectionTimedOut 以collectSectionTimeoutStatus退出的脚本是否是被timeout终止的。脚本自己也可能以124退出，
+// 所以能够得到耗时时，只有耗时达到了超时时间才认为是超时。
+func sectionTimedOut(script *renderedScript, section *collectSection) bool {
+	if section.startedAt <= 0 || section.endedAt < section.startedAt {
+		return true
+	}
+	return time.Duration(section.endedAt-section.startedAt) >= time.Duration(collectSectionTimeoutSeconds(script.Name))*time.Second
+}
+
+// collectSectionScript CollectInfo中的一个脚本，以及执行之后处理它的结果的函数。
+type collectSectionScript struct {
+	name   string
+	args   CmdArgs
+	handle func(result *internal_models.CommandResult, err *SErr.APIErr)
+}
+
+// CollectInfo 只打开一个session，执行arg中指定的全部部分的脚本，再分别解析每个部分的输出。
+// 每个部分的结果与单独调用对应的方法（例如GetCPUHardware）时相同，包括Output、Results以及错误；没有指定的部分为nil。
+// 返回的错误只表示没能生成脚本，此时每个指定的部分也都以该错误失败。
+func (s *LinuxSSHExecutorServiceTemplate) CollectInfo(ctx context.Context, arg *CollectInfoArg) (*ExecutorServiceCollectInfoResp, *SErr.APIErr) {
+	resp := newCollectInfoResp(arg)
+	sections := make([]*collectSectionScript, 0, 8)
+	add := func(name string, args CmdArgs, handle func(result *internal_models.CommandResult, err *SErr.APIErr)) {
+		sections = append(sections, &collectSectionScript{name: name, args: args, handle: handle})
+	}
+	if arg.AccountList {
+		add("get_account_list", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.AccountList.addResult(result)
+			if resp.AccountListErr = err; err == nil {
+				s.parseAccountList(resp.AccountList, result.Stdout)
+			}
+		})
+	}
+	if arg.BackupDirs {
+		add("backup_dir_list", CmdArgs{"backup_root": backupRoot()}, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.BackupDirs.addResult(result)
+			if resp.BackupDirsErr = err; err == nil {
+				s.parseBackupDirs(resp.BackupDirs, result.Stdout)
+			}
+		})
+	}
+	if arg.HardwareInfo {
+		add("lscpu", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.CPUHardware.addResult(result)
+			if resp.CPUHardwareErr = err; err == nil {
+				s.parseCPUHardware(resp.CPUHardware, result.Stdout)
+			}
+		})
+		add("lsgpu", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.GPUHardware.addResult(result)
+			if resp.GPUHardwareErr = err; err == nil {
+				s.parseGPUHardware(resp.GPUHardware, result.Stdout)
+			}
+		})
+	}
+	if arg.RemoteAccessInfos {
+		add("w", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.RemoteAccessInfos.addResult(result)
+			if resp.RemoteAccessInfosErr = err; err == nil {
+				s.parseRemoteAccessInfos(resp.RemoteAccessInfos, result.Stdout)
+			}
+		})
+	}
+	if arg.CPUMemProcessesUsages {
+		// 与GetCPUMemProcessesUsages一样，只有top中没有解析出内存使用率时才使用meminfo的结果。
+		// 为了不再打开一个session，meminfo总是一起执行，但是不需要它时不会出现在结果中。
+		var topOutput string
+		add("top", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.CPUMemProcessesUsages.addResult(result)
+			if resp.CPUMemProcessesUsagesErr = err; err == nil {
+				topOutput = result.Stdout
+				s.parseTop(resp.CPUMemProcessesUsages, topOutput)
+			}
+		})
+		add("meminfo", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			usages := resp.CPUMemProcessesUsages
+			if resp.CPUMemProcessesUsagesErr != nil || usages.CPUMemUsage.MemUsage != nil {
+				return
+			}
+			log.Printf("LinuxSSHExecutorServiceTemplate CollectInfo use /proc/meminfo")
+			usages.addResult(result)
+			usages.Output = fmt.Sprintf("%s--- meminfo ---\n%s", topOutput, result.Stdout)
+			if resp.CPUMemProcessesUsagesErr = err; err == nil {
+				s.parseMeminfoUsage(usages, result.Stdout)
+			}
+		})
+		add("cpu_stat", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			usages := resp.CPUMemProcessesUsages
+			if resp.CPUMemProcessesUsagesErr != nil {
+				return
+			}
+			prevOutput := usages.Output
+			usages.addResult(result)
+			s.appendCPUStat(usages, prevOutput, result.Stdout, err)
+		})
+	}
+	if arg.GPUUsages {
+		add("nvidia_gpu_query", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.GPUUsages.addResult(result)
+			if resp.GPUUsagesErr = err; err == nil {
+				resp.GPUUsages.GPUs, resp.GPUUsagesErr = parseNvidiaGPUQuery(result.Stdout)
+			}
+		})
+		// 与GetGPUUsages一样，查询进程失败时只记录在Results中。
+		add("nvidia_compute_apps", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.GPUUsages.addResult(result)
+			if resp.GPUUsagesErr == nil {
+				s.parseGPUProcesses(resp.GPUUsages, result.Stdout, err)
+			}
+		})
+	}
+	if arg.FilesystemUsages {
+		add("df_usage", nil, func(result *internal_models.CommandResult, err *SErr.APIErr) {
+			resp.FilesystemUsages.addResult(result)
+			if resp.FilesystemUsagesErr = err; err == nil {
+				resp.FilesystemUsages.Filesystems, resp.FilesystemUsagesErr = parseDfUsage(result.Stdout, backupRoot())
+			}
+		})
+	}
+	if len(sections) == 0 {
+		return resp, nil
+	}
+
+	scripts := make([]*renderedScript, 0, len(sections))
+	for _, section := range sections {
+		script, err := s.renderScript(section.name, section.args)
+		if err != nil {
+			resp.fail(err)
+			return resp, err
+		}
+		scripts = append(scripts, script)
+	}
+	results, errs := runScriptsWith(withOutputHost(ctx, s.Host, s.Port), s.runner, scripts)
+	for i, section := range sections {
+		section.handle(results[i], errs[i])
+	}
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] CollectInfo, scripts=[%d]", s, len(scripts))
+	return resp, nil
+}
+
+func newCollectInfoResp(arg *CollectInfoArg) *ExecutorServiceCollectInfoResp {
+	resp := &ExecutorServiceCollectInfoResp{}
+	if arg.AccountList {
+		resp.AccountList = &ExecutorServiceGetAccountListResp{}
+	}
+	if arg.BackupDirs {
+		resp.BackupDirs = &ExecutorServiceBackupDirsResp{BackupRoot: backupRoot()}
+	}
+	if arg.HardwareInfo {
+		resp.CPUHardware = &ExecutorServiceCPUHardwareResp{}
+		resp.GPUHardware = &ExecutorServiceGPUHardwareResp{}
+	}
+	if arg.RemoteAccessInfos {
+		resp.RemoteAccessInfos = &ExecutorServiceRemoteAccessResp{}
+	}
+	if arg.CPUMemProcessesUsages {
+		resp.CPUMemProcessesUsages = &ExecutorServiceCPUMemProcessesUsagesResp{}
+	}
+	if arg.GPUUsages {
+		resp.GPUUsages = &ExecutorServiceGPUUsagesResp{}
+	}
+	if arg.FilesystemUsages {
+		resp.FilesystemUsages = &ExecutorServiceFilesystemUsagesResp{}
+	}
+	return resp
+}
+
+// fail 使每个收集的部分都以err失败。
+func (r *ExecutorServiceCollectInfoResp) fail(err *SErr.APIErr) {
+	if r.AccountList != nil {
+		r.AccountListErr = err
+	}
+	if r.BackupDirs != nil {
+		r.BackupDirsErr = err
+	}
+	if r.CPUHardware != nil {
+		r.CPUHardwareErr = err
+		r.GPUHardwareErr = err
+	}
+	if r.RemoteAccessInfos != nil {
+		r.RemoteAccessInfosErr = err
+	}
+	if r.CPUMemProcessesUsages != nil {
+		r.CPUMemProcessesUsagesErr = err
+	}
+	if r.GPUUsages != nil {
+		r.GPUUsagesErr = err
+	}
+	if r.FilesystemUsages != nil {
+		r.FilesystemUsagesErr = err
+	}
+}
+
+// parseBackupDirs 解析backup_dir_list的输出，每行为find -printf '%Y|%f'的结果，即文件类型（跟随符号链接）与文件名。
+func (s *LinuxSSHExecutorServiceTemplate) parseBackupDirs(resp *ExecutorServiceBackupDirsResp, output string) {
+	resp.Entries = make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "|", 2)
+		if len(fields) != 2 || fields[1] == "" {
+			continue
+		}
+		resp.Entries[fields[1]] = fields[0]
+	}
+}
+
+// BackupDirOf 返回某个账户的备份文件夹，以及它是否存在。与GetBackupDir相同，PathExists表示它是文件或者文件夹。
+func (r *ExecutorServiceBackupDirsResp) BackupDirOf(accountName string) (backupDir string, pathExists bool, dirExists bool) {
+	elem := fmt.Sprintf("%s.backup", accountName)
+	fileType := r.Entries[elem]
+	return path.Join(r.BackupRoot, elem), fileType == "d" || fileType == "f", fileType == "d"
+}
+
+// RespCommonOf 只保留accountName的<账户名>.backup对应的输出，其它账户的条目被去掉，用于填入该账户自己的BackupDirInfo。
+// Results中的每个结果都是复制后修改的，不影响r本身。
+func (r *ExecutorServiceBackupDirsResp) RespCommonOf(accountName string) ExecutorServiceRespCommon {
+	elem := fmt.Sprintf("%s.backup", accountName)
+	narrow := func(output string) string {
+		b := &strings.Builder{}
+		for _, line := range strings.SplitAfter(output, "\n") {
+			fields := strings.SplitN(strings.TrimSpace(line), "|", 2)
+			if len(fields) == 2 && fields[1] == elem {
+				b.WriteString(line)
+			}
+		}
+		return b.String()
+	}
+	common := ExecutorServiceRespCommon{
+		Output:  narrow(r.Output),
+		Results: make([]*internal_models.CommandResult, 0, len(r.Results)),
+	}
+	for _, result := range r.Results {
+		narrowed := *result
+		narrowed.Stdout = narrow(result.Stdout)
+		common.Results = append(common.Results, &narrowed)
+	}
+	return common
+}
@@ -0,0 +1,176 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCollectOutput(t *testing.T) {
+	const m = "@@m@@"
+	output := "\n" + m + " begin 0 1000000\nline1\nline2\n\n" + m + " end 0 3000000\n" +
+		"\n" + m + " begin 1 3000000\nno newline\n" + m + " end 2 4000000\nwarn\n" +
+		"\n" + m + " begin 2 4000000\npartial\n"
+	sections := splitCollectOutput(m, output)
+	if len(sections) != 3 {
+		t.Fatalf("unexpected sections %+v", sections)
+	}
+	if s := sections[0]; !s.ended || s.stdout != "line1\nline2\n" || s.stderr != "" || s.exitCode != 0 || s.endedAt-s.startedAt != 2000000 {
+		t.Fatalf("unexpected section %+v", s)
+	}
+	if s := sections[1]; !s.ended || s.stdout != "no newline" || s.stderr != "warn\n" || s.exitCode != 2 {
+		t.Fatalf("unexpected section %+v", s)
+	}
+	if s := sections[2]; s.ended || s.stdout != "partial\n" {
+		t.Fatalf("unexpected section %+v", s)
+	}
+
+	script := &renderedScript{Name: "top"}
+	collectResult := &internal_models.CommandResult{}
+	if result, err := sectionResult(script, sections[0], collectResult, nil); err != nil || result.Stdout != "line1\nline2\n" || result.DurationMs != 2 {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+	if result, err := sectionResult(script, sections[1], collectResult, nil); err == nil || err.Code != SErr.CodeCommandFailed || result.ExitStatus != 2 {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+	// 没有结束的脚本使用组合命令的错误。
+	if result, err := sectionResult(script, sections[2], collectResult, SErr.SSHConnectionErr); err != SErr.SSHConnectionErr || result.ExitStatus != -1 || result.Stdout != "partial\n" {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+	if _, err := sectionResult(script, nil, collectResult, nil); err == nil || err.Code != SErr.CodeCommandFailed {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
+
+func TestRunScriptsWithLocalShell(t *testing.T) {
+	scripts := []*renderedScript{
+		{Name: "first", Cmd: "echo a; echo b"},
+		{Name: "failed", Cmd: "printf 'x'; echo oops 1>&2; exit 3"},
+		{Name: "last", Cmd: "cat /proc/self/status >/dev/null && echo done"},
+	}
+	results, errs := runScriptsWith(context.Background(), &linuxLocalRunner{}, scripts)
+	if errs[0] != nil || results[0].Stdout != "a\nb\n" || results[0].Script != "first" || results[0].StartedAt.IsZero() {
+		t.Fatalf("unexpected result %+v, err %+v", results[0], errs[0])
+	}
+	if errs[1] == nil || errs[1].Code != SErr.CodeCommandFailed || results[1].ExitStatus != 3 || results[1].Stdout != "x" || results[1].Stderr != "oops\n" {
+		t.Fatalf("unexpected result %+v, err %+v", results[1], errs[1])
+	}
+	if errs[2] != nil || results[2].Stdout != "done\n" {
+		t.Fatalf("unexpected result %+v, err %+v", results[2], errs[2])
+	}
+}
+
+func TestRunScriptsWithSectionTimeout(t *testing.T) {
+	defaultScriptTimeouts["slow"] = time.Second
+	defer delete(defaultScriptTimeouts, "slow")
+	scripts := []*renderedScript{
+		{Name: "slow", Cmd: "echo started; sleep 5; echo late"},
+		{Name: "exit124", Cmd: "exit 124"},
+		{Name: "last", Cmd: "echo done"},
+	}
+	startedAt := time.Now()
+	results, errs := runScriptsWith(context.Background(), &linuxLocalRunner{}, scripts)
+	// 超时的脚本被单独终止，不影响之后的脚本。
+	if elapsed := time.Since(startedAt); elapsed > 4*time.Second {
+		t.Fatalf("slow script was not terminated, elapsed %s", elapsed)
+	}
+	if errs[0] == nil || errs[0].Code != SErr.CommandTimeoutErr.Code || !strings.Contains(errs[0].Message, "slow") ||
+		results[0].ExitStatus != -1 || results[0].Stdout != "started\n" {
+		t.Fatalf("unexpected result %+v, err %+v", results[0], errs[0])
+	}
+	// 脚本自己以124退出时不是超时。
+	if errs[1] == nil || errs[1].Code != SErr.CodeCommandFailed || results[1].ExitStatus != 124 {
+		t.Fatalf("unexpected result %+v, err %+v", results[1], errs[1])
+	}
+	if errs[2] != nil || results[2].Stdout != "done\n" {
+		t.Fatalf("unexpected result %+v, err %+v", results[2], errs[2])
+	}
+}
+
+func TestLinuxSSHCollectInfo(t *testing.T) {
+	server := newTestSSHServer(t)
+	ctx := context.Background()
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	if _, err := es.BackupAccountHomeDir(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	server.Fail("lsgpu", 127, "sh: 1: lspci: not found\n")
+	executed := len(server.Executed())
+
+	resp, err := es.CollectInfo(ctx, &CollectInfoArg{
+		AccountList:           true,
+		BackupDirs:            true,
+		HardwareInfo:          true,
+		RemoteAccessInfos:     true,
+		CPUMemProcessesUsages: true,
+		FilesystemUsages:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 全部脚本只在一个session中执行。
+	if got := strings.Join(server.Executed()[executed:], ","); got != "collect_info,get_account_list,backup_dir_list,lscpu,lsgpu,w,top,meminfo,cpu_stat,df_usage" {
+		t.Fatalf("unexpected executed scripts %s", got)
+	}
+	if resp.AccountListErr != nil || len(resp.AccountList.Accounts) == 0 || len(resp.AccountList.Results) != 1 {
+		t.Fatalf("unexpected account list %+v, err %+v", resp.AccountList, resp.AccountListErr)
+	}
+	if dir, pathExists, dirExists := resp.BackupDirs.BackupDirOf("alice"); resp.BackupDirsErr != nil || dir != "/backup/alice.backup" || !pathExists || !dirExists {
+		t.Fatalf("unexpected backup dirs %+v, err %+v", resp.BackupDirs, resp.BackupDirsErr)
+	}
+	if _, pathExists, _ := resp.BackupDirs.BackupDirOf("bob"); pathExists {
+		t.Fatalf("unexpected backup dirs %+v", resp.BackupDirs)
+	}
+	// 每个账户只保留自己的条目，不修改共享的结果。
+	listed := resp.BackupDirs.Results[0].Stdout
+	if common := resp.BackupDirs.RespCommonOf("alice"); common.Output != "d|alice.backup\n" || common.Results[0].Stdout != common.Output || resp.BackupDirs.Results[0].Stdout != listed {
+		t.Fatalf("unexpected resp common %+v, listed %q", common, listed)
+	}
+	if common := resp.BackupDirs.RespCommonOf("bob"); common.Output != "" || len(common.Results) != 1 || common.Results[0].ExitStatus != 0 {
+		t.Fatalf("unexpected resp common %+v", common)
+	}
+	shared := &ExecutorServiceBackupDirsResp{}
+	shared.addResult(&internal_models.CommandResult{Script: "backup_dir_list", Stdout: "d|alice.backup\nf|alice2.backup\nd|bob.backup\n"})
+	if common := shared.RespCommonOf("bob"); common.Output != "d|bob.backup\n" || common.Results[0].Stdout != "d|bob.backup\n" {
+		t.Fatalf("unexpected resp common %+v", common)
+	}
+	if resp.CPUHardwareErr != nil || resp.CPUHardware.CPU == nil || resp.CPUHardware.CPU.Cores == nil {
+		t.Fatalf("unexpected cpu hardware %+v, err %+v", resp.CPUHardware, resp.CPUHardwareErr)
+	}
+	// 失败的部分不影响其他部分。
+	if resp.GPUHardwareErr == nil || !strings.Contains(resp.GPUHardwareErr.Message, "lspci") || resp.GPUHardware.Results[0].ExitStatus != 127 {
+		t.Fatalf("unexpected gpu hardware %+v, err %+v", resp.GPUHardware, resp.GPUHardwareErr)
+	}
+	if resp.RemoteAccessInfosErr != nil || len(resp.RemoteAccessInfos.RemoteAccessingAccountInfos) == 0 {
+		t.Fatalf("unexpected remote access infos %+v, err %+v", resp.RemoteAccessInfos, resp.RemoteAccessInfosErr)
+	}
+	usages := resp.CPUMemProcessesUsages
+	if resp.CPUMemProcessesUsagesErr != nil || usages.CPUMemUsage.MemTotal == nil || len(usages.ProcessInfos) == 0 {
+		t.Fatalf("unexpected usages %+v, err %+v", usages, resp.CPUMemProcessesUsagesErr)
+	}
+	if resp.FilesystemUsagesErr != nil || len(resp.FilesystemUsages.Filesystems) == 0 || len(resp.FilesystemUsages.Results) != 1 {
+		t.Fatalf("unexpected filesystem usages %+v, err %+v", resp.FilesystemUsages, resp.FilesystemUsagesErr)
+	}
+	if resp.GPUUsages != nil {
+		t.Fatalf("gpu usages should not be collected, got %+v", resp.GPUUsages)
+	}
+
+	// 连接在某个脚本执行时断开，之前的脚本仍然有结果，之后的脚本以连接的错误失败。
+	server.DropOn("w")
+	resp, err = es.CollectInfo(ctx, &CollectInfoArg{HardwareInfo: true, RemoteAccessInfos: true, GPUUsages: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.CPUHardwareErr != nil {
+		t.Fatalf("unexpected err %+v", resp.CPUHardwareErr)
+	}
+	if resp.RemoteAccessInfosErr == nil || resp.RemoteAccessInfosErr.Code != SErr.SSHConnectionErr.Code {
+		t.Fatalf("unexpected err %+v", resp.RemoteAccessInfosErr)
+	}
+	if resp.GPUUsagesErr == nil || resp.GPUUsagesErr.Code != SErr.SSHConnectionErr.Code || len(resp.GPUUsages.Results) != 2 {
+		t.Fatalf("unexpected gpu usages %+v, err %+v", resp.GPUUsages, resp.GPUUsagesErr)
+	}
+}
@@ -0,0 +1,173 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CommandAuditEntry 在服务器上执行的一条命令的审计记录。
+// Args 中敏感参数（例如密码）的值已经被隐藏；不是通过脚本执行的命令没有参数，Script 为命令本身。
+// 输出不会被保存，只保存stdout与stderr的摘要以及长度，用于事后核对输出是否一致。
+type CommandAuditEntry struct {
+	// ActorUserID 触发该命令的平台用户，不是由用户的请求触发时为0。
+	ActorUserID int
+	Host        string
+	Port        uint
+	Script      string
+	Args        CmdArgs
+	ExitStatus  int
+	// ErrCode 执行失败时返回的错误码，成功时为0。
+	ErrCode      int
+	Error        string
+	StartedAt    time.Time
+	Duration     time.Duration
+	OutputDigest string
+	OutputSize   int
+}
+
+// CommandAuditor 接收每条命令的审计记录，多个请求会同时调用它。
+type CommandAuditor func(entry *CommandAuditEntry)
+
+var (
+	commandAuditorMu sync.RWMutex
+	commandAuditor   CommandAuditor
+)
+
+// UseCommandAuditor 使之后打开的ExecutorService执行的每条命令都交给auditor审计，返回的函数用于恢复原状。
+func UseCommandAuditor(auditor CommandAuditor) func() {
+	commandAuditorMu.Lock()
+	previous := commandAuditor
+	commandAuditor = auditor
+	commandAuditorMu.Unlock()
+	return func() {
+		commandAuditorMu.Lock()
+		defer commandAuditorMu.Unlock()
+		commandAuditor = previous
+	}
+}
+
+func commandAuditorOf() CommandAuditor {
+	commandAuditorMu.RLock()
+	defer commandAuditorMu.RUnlock()
+	return commandAuditor
+}
+
+type actorKey struct{}
+
+// WithActor 返回一个带有平台用户ID的ctx，使用它执行的命令在审计记录中会记录为由该用户触发。
+func WithActor(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+func actorOf(ctx context.Context) int {
+	userID, _ := ctx.Value(actorKey{}).(int)
+	return userID
+}
+
+// OutputDigest 命令输出的摘要，为stdout与stderr（以\x00分隔）的SHA-256。
+func OutputDigest(stdout, stderr string) string {
+	h := sha256.New()
+	h.Write([]byte(stdout))
+	h.Write([]byte{0})
+	h.Write([]byte(stderr))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditingRunner 将每次脚本调用的名称、参数、退出码、耗时以及输出摘要交给auditor，命令本身交给runner执行。
+type auditingRunner struct {
+	runner  commandRunner
+	auditor CommandAuditor
+	Host    string
+	Port    uint
+}
+
+func (r *auditingRunner) String() string {
+	return fmt.Sprintf("auditingRunner=[%s]", r.runner)
+}
+
+func (r *auditingRunner) Close() error {
+	return r.runner.Close()
+}
+
+func (r *auditingRunner) runScript(ctx context.Context, script *renderedScript) (*internal_models.CommandResult, *SErr.APIErr) {
+	startedAt := time.Now()
+	var result *internal_models.CommandResult
+	var err *SErr.APIErr
+	if sr, ok := r.runner.(scriptRunner); ok {
+		result, err = sr.runScript(ctx, script)
+	} else {
+		result, err = r.runner.runCommand(ctx, nil, script.Name, script.Cmd, true)
+	}
+	r.audit(ctx, startedAt, script.Name, script.Args, result, err)
+	return result, err
+}
+
+// runScripts 每个脚本分别审计，开始时间与耗时使用该脚本自己的结果。
+func (r *auditingRunner) runScripts(ctx context.Context, scripts []*renderedScript) ([]*internal_models.CommandResult, []*SErr.APIErr) {
+	startedAt := time.Now()
+	results, errs := runScriptsWith(ctx, r.runner, scripts)
+	for i, script := range scripts {
+		entry := r.newEntry(ctx, startedAt, script.Name, script.Args, results[i], errs[i])
+		if !results[i].StartedAt.IsZero() {
+			entry.StartedAt = results[i].StartedAt
+			entry.Duration = time.Duration(results[i].DurationMs) * time.Millisecond
+		}
+		r.auditor(entry)
+	}
+	return results, errs
+}
+
+// runCommand 不是通过脚本执行的命令没有参数，以desc作为脚本名审计。
+func (r *auditingRunner) runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr) {
+	startedAt := time.Now()
+	result, err := r.runner.runCommand(ctx, envs, desc, cmd, withSudo)
+	r.audit(ctx, startedAt, desc, nil, result, err)
+	return result, err
+}
+
+func (r *auditingRunner) audit(ctx context.Context, startedAt time.Time, script string, args CmdArgs, result *internal_models.CommandResult, err *SErr.APIErr) {
+	r.auditor(r.newEntry(ctx, startedAt, script, args, result, err))
+}
+
+func (r *auditingRunner) newEntry(ctx context.Context, startedAt time.Time, script string, args CmdArgs, result *internal_models.CommandResult, err *SErr.APIErr) *CommandAuditEntry {
+	entry := &CommandAuditEntry{
+		ActorUserID: actorOf(ctx),
+		Host:        r.Host,
+		Port:        r.Port,
+		Script:      script,
+		Args:        args,
+		ExitStatus:  -1,
+		StartedAt:   startedAt,
+		Duration:    time.Since(startedAt),
+	}
+	if result != nil {
+		entry.ExitStatus = result.ExitStatus
+		entry.OutputDigest = OutputDigest(result.Stdout, result.Stderr)
+		entry.OutputSize = len(result.Stdout) + len(result.Stderr)
+	}
+	if err != nil {
+		entry.ErrCode = err.Code
+		entry.Error = err.Message
+	}
+	return entry
+}
+
+// auditCommandsIfConfigured 设置了auditor时，审计template执行的每个脚本。
+func auditCommandsIfConfigured(template *LinuxSSHExecutorServiceTemplate) {
+	auditor := commandAuditorOf()
+	if auditor == nil {
+		return
+	}
+	template.runner = &auditingRunner{
+		runner:  template.runner,
+		auditor: auditor,
+		Host:    template.Host,
+		Port:    template.Port,
+	}
+}
@@ -0,0 +1,88 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// auditLog 收集审计记录，可以并发调用。
+type auditLog struct {
+	mu      sync.Mutex
+	entries []*CommandAuditEntry
+}
+
+func (l *auditLog) audit(entry *CommandAuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func (l *auditLog) scripts() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	scripts := make([]string, 0, len(l.entries))
+	for _, entry := range l.entries {
+		scripts = append(scripts, entry.Script)
+	}
+	return scripts
+}
+
+func TestLinuxSSHCommandAudit(t *testing.T) {
+	server := newTestSSHServer(t)
+	log := &auditLog{}
+	t.Cleanup(UseCommandAuditor(log.audit))
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	if strings.Contains(es.String(), server.OpenParam().AdminAccountPwd) {
+		t.Fatalf("the password should not be printed: %s", es)
+	}
+
+	ctx := WithActor(context.Background(), 7)
+	if _, err := es.AddAccount(ctx, "bob", "bob'pwd"); err != nil {
+		t.Fatal(err)
+	}
+	server.Fail("nvidia_gpu_query", 9, "NVIDIA-SMI has failed\n")
+	if _, err := es.GetGPUUsages(context.Background()); err == nil {
+		t.Fatal("GetGPUUsages should fail")
+	}
+
+	// 建立连接时执行的检查不经过ExecutorService，不会被审计。
+	if got := strings.Join(log.scripts(), ","); got != "user_add_with_openssl_pwd,cat_sudoers,add_sudoers,nvidia_gpu_query" {
+		t.Fatalf("unexpected audited scripts %s", got)
+	}
+	add := log.entries[0]
+	if add.ActorUserID != 7 || add.Host != server.Host || add.Port != server.Port || add.ExitStatus != 0 || add.ErrCode != 0 || add.StartedAt.IsZero() {
+		t.Fatalf("unexpected entry %+v", add)
+	}
+	if add.Args["account_name"] != "bob" || add.Args["pwd"] != redactedArgValue {
+		t.Fatalf("unexpected args %v", add.Args)
+	}
+	if add.OutputDigest != OutputDigest("", "") || add.OutputSize != 0 {
+		t.Fatalf("unexpected output digest %s, size %d", add.OutputDigest, add.OutputSize)
+	}
+	failed := log.entries[3]
+	if failed.ActorUserID != 0 || failed.ExitStatus != 9 || failed.ErrCode != SErr.CodeCommandFailed || !strings.Contains(failed.Error, "NVIDIA-SMI") {
+		t.Fatalf("unexpected entry %+v", failed)
+	}
+	if failed.OutputDigest != OutputDigest("", "NVIDIA-SMI has failed\n") || failed.OutputSize != len("NVIDIA-SMI has failed\n") {
+		t.Fatalf("unexpected output digest %s, size %d", failed.OutputDigest, failed.OutputSize)
+	}
+	for _, entry := range log.entries {
+		for _, value := range entry.Args {
+			if strings.Contains(value, "bob'pwd") {
+				t.Fatalf("the password should be redacted: %+v", entry)
+			}
+		}
+	}
+}
+
+func TestOutputDigest(t *testing.T) {
+	if OutputDigest("ab", "c") == OutputDigest("a", "bc") {
+		t.Fatal("stdout and stderr should be separated")
+	}
+	if len(OutputDigest("", "")) != 64 {
+		t.Fatalf("unexpected digest %s", OutputDigest("", ""))
+	}
+}
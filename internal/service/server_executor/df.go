@@ -0,0 +1,166 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/util"
+	"context"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dfUsageInodesSeparator df_usage的输出中分隔df -PTk与df -Pi的行。
+const dfUsageInodesSeparator = "--- inodes ---"
+
+// pseudoFSTypes 不占用磁盘空间的伪文件系统，不展示它们的使用情况。
+var pseudoFSTypes = map[string]bool{
+	"autofs":          true,
+	"binfmt_misc":     true,
+	"bpf":             true,
+	"cgroup":          true,
+	"cgroup2":         true,
+	"configfs":        true,
+	"debugfs":         true,
+	"devpts":          true,
+	"devtmpfs":        true,
+	"efivarfs":        true,
+	"fusectl":         true,
+	"fuse.lxcfs":      true,
+	"fuse.gvfsd-fuse": true,
+	"hugetlbfs":       true,
+	"mqueue":          true,
+	"nsfs":            true,
+	"overlay":         true,
+	"proc":            true,
+	"pstore":          true,
+	"ramfs":           true,
+	"rpc_pipefs":      true,
+	"securityfs":      true,
+	"squashfs":        true,
+	"sysfs":           true,
+	"tmpfs":           true,
+	"tracefs":         true,
+}
+
+// dfSpaceLineReg df -PTk输出的一行：Filesystem Type 1024-blocks Used Available Capacity Mounted on。挂载点中可以有空格。
+var dfSpaceLineReg = regexp.MustCompile(`^(\S+)\s+(\S+)\s+([0-9]+)\s+([0-9]+)\s+([0-9]+)\s+(\S+)\s+(/.*)$`)
+
+// dfInodeLineReg df -Pi输出的一行：Filesystem Inodes IUsed IFree IUse% Mounted on。不支持inode统计的文件系统各列为-。
+var dfInodeLineReg = regexp.MustCompile(`^(\S+)\s+(\S+)\s+(\S+)\s+(\S+)\s+(\S+)\s+(/.*)$`)
+
+// GetFilesystemUsages 使用df查询已挂载的文件系统的空间与inode使用情况，不包括伪文件系统。
+func (s *LinuxSSHExecutorServiceTemplate) GetFilesystemUsages(ctx context.Context) (*ExecutorServiceFilesystemUsagesResp, *SErr.APIErr) {
+	resp := &ExecutorServiceFilesystemUsagesResp{}
+	script, err := s.renderScript("df_usage", nil)
+	if err != nil {
+		return resp, err
+	}
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	if err != nil {
+		return resp, err
+	}
+	resp.Filesystems, err = parseDfUsage(output, backupRoot())
+	return resp, err
+}
+
+// parseDfUsage 解析df_usage的输出：先是df -PTk，之后是dfUsageInodesSeparator以及df -Pi。
+// 部分文件系统无法访问时df仍然会输出其它文件系统，所以脚本忽略了df的退出码，只有没有输出表头时才认为失败。
+// 按挂载点将inode的使用情况填入对应的文件系统，并标出存放/、/home以及backupRoot的文件系统。
+func parseDfUsage(output string, backupRoot string) ([]*internal_models.ServerFilesystem, *SErr.APIErr) {
+	spaceOutput, inodeOutput := output, ""
+	if i := strings.Index(output, dfUsageInodesSeparator+"\n"); i >= 0 {
+		spaceOutput, inodeOutput = output[:i], output[i+len(dfUsageInodesSeparator)+1:]
+	}
+	lines := util.SplitLine(spaceOutput)
+	if len(lines) == 0 || !strings.HasPrefix(strings.TrimSpace(lines[0]), "Filesystem") {
+		return nil, SErr.InternalErr.CustomMessageF("解析df的输出失败！没有输出表头，输出为：%s", output)
+	}
+	filesystems := make([]*internal_models.ServerFilesystem, 0, len(lines)-1)
+	byMountPoint := make(map[string]*internal_models.ServerFilesystem, len(lines)-1)
+	for _, line := range lines[1:] {
+		m := dfSpaceLineReg.FindStringSubmatch(strings.TrimRight(line, " \r"))
+		if len(m) < 8 || pseudoFSTypes[m[2]] {
+			continue
+		}
+		// 已经按正则表达式检查过，不会失败。
+		size, _ := strconv.ParseUint(m[3], 10, 64)
+		used, _ := strconv.ParseUint(m[4], 10, 64)
+		available, _ := strconv.ParseUint(m[5], 10, 64)
+		fs := &internal_models.ServerFilesystem{
+			Device:         m[1],
+			FSType:         m[2],
+			MountPoint:     m[7],
+			SizeBytes:      size * 1024,
+			UsedBytes:      used * 1024,
+			AvailableBytes: available * 1024,
+			UsePercent:     dfPercent(m[6]),
+			Highlights:     make([]string, 0),
+		}
+		filesystems = append(filesystems, fs)
+		byMountPoint[fs.MountPoint] = fs
+	}
+	for _, line := range util.SplitLine(inodeOutput) {
+		m := dfInodeLineReg.FindStringSubmatch(strings.TrimRight(line, " \r"))
+		if len(m) < 7 {
+			continue
+		}
+		fs, ok := byMountPoint[m[6]]
+		if !ok {
+			continue
+		}
+		fs.InodesTotal = dfCount(m[2])
+		fs.InodesUsed = dfCount(m[3])
+		fs.InodesFree = dfCount(m[4])
+		fs.InodeUsePercent = dfPercent(m[5])
+		// btrfs等文件系统动态分配inode，总数为0，此时没有意义。
+		if fs.InodesTotal != nil && *fs.InodesTotal == 0 {
+			fs.InodesTotal, fs.InodesUsed, fs.InodesFree, fs.InodeUsePercent = nil, nil, nil, nil
+		}
+	}
+	highlightFilesystem(filesystems, "/", internal_models.ServerFilesystemHighlightRoot)
+	highlightFilesystem(filesystems, "/home", internal_models.ServerFilesystemHighlightHome)
+	highlightFilesystem(filesystems, backupRoot, internal_models.ServerFilesystemHighlightBackupRoot)
+	return filesystems, nil
+}
+
+// highlightFilesystem 标出存放p的文件系统，即挂载点是p或者p的上级目录中最长的一个。
+func highlightFilesystem(filesystems []*internal_models.ServerFilesystem, p string, highlight string) {
+	var found *internal_models.ServerFilesystem
+	for _, fs := range filesystems {
+		if !pathContains(fs.MountPoint, p) {
+			continue
+		}
+		if found == nil || len(fs.MountPoint) > len(found.MountPoint) {
+			found = fs
+		}
+	}
+	if found != nil {
+		found.Highlights = append(found.Highlights, highlight)
+	}
+}
+
+// pathContains p是否为dir本身或者dir之下的路径。
+func pathContains(dir, p string) bool {
+	dir, p = path.Clean(dir), path.Clean(p)
+	return dir == "/" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// dfPercent 解析df输出的使用率，例如37%，没有意义时（-）为nil。
+func dfPercent(v string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+// dfCount 解析df输出的数量，没有意义时（-）为nil。
+func dfCount(v string) *uint64 {
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
@@ -0,0 +1,116 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"context"
+	"testing"
+)
+
+func TestParseDfUsage(t *testing.T) {
+	filesystems, err := parseDfUsage(readTestdata(t, "df", "gpu_server.txt"), "/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不包括devtmpfs、tmpfs、squashfs以及overlay。
+	mountPoints := make([]string, 0, len(filesystems))
+	for _, fs := range filesystems {
+		mountPoints = append(mountPoints, fs.MountPoint)
+	}
+	if len(filesystems) != 5 || mountPoints[0] != "/" || mountPoints[1] != "/boot/efi" || mountPoints[4] != "/mnt/shared data" {
+		t.Fatalf("unexpected mount points %q", mountPoints)
+	}
+	root := filesystems[0]
+	if root.Device != "/dev/nvme0n1p2" || root.FSType != "ext4" || root.SizeBytes != 479596204*1024 || root.UsedBytes != 452930712*1024 ||
+		root.AvailableBytes != 2246488*1024 || *root.UsePercent != 100 {
+		t.Fatalf("unexpected filesystem %+v", root)
+	}
+	if *root.InodesTotal != 30531584 || *root.InodesUsed != 30531584 || *root.InodesFree != 0 || *root.InodeUsePercent != 100 {
+		t.Fatalf("unexpected inodes %+v", root)
+	}
+	// vfat不支持inode统计。
+	if efi := filesystems[1]; efi.InodesTotal != nil || efi.InodeUsePercent != nil || *efi.UsePercent != 2 {
+		t.Fatalf("unexpected filesystem %+v", efi)
+	}
+	if nfs := filesystems[4]; nfs.Device != "nas:/export/ds" || nfs.SizeBytes != 20971520000*1024 || *nfs.InodesUsed != 2871041 {
+		t.Fatalf("unexpected filesystem %+v", nfs)
+	}
+	for i, expected := range [][]string{
+		{internal_models.ServerFilesystemHighlightRoot},
+		{},
+		{internal_models.ServerFilesystemHighlightHome},
+		{internal_models.ServerFilesystemHighlightBackupRoot},
+		{},
+	} {
+		if highlights := filesystems[i].Highlights; len(highlights) != len(expected) || len(expected) > 0 && highlights[0] != expected[0] {
+			t.Fatalf("unexpected highlights of %s: %q", filesystems[i].MountPoint, highlights)
+		}
+	}
+
+	// 只有一个根文件系统时，/、/home以及备份文件夹都在它上面，btrfs的inode数量没有意义。
+	filesystems, err = parseDfUsage(readTestdata(t, "df", "single_root_btrfs.txt"), "/data/backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filesystems) != 3 || filesystems[0].InodesTotal != nil || filesystems[0].InodeUsePercent != nil || *filesystems[2].InodesUsed != 356 {
+		t.Fatalf("unexpected filesystems %+v", filesystems)
+	}
+	if highlights := filesystems[0].Highlights; len(highlights) != 3 || highlights[0] != internal_models.ServerFilesystemHighlightRoot ||
+		highlights[1] != internal_models.ServerFilesystemHighlightHome || highlights[2] != internal_models.ServerFilesystemHighlightBackupRoot {
+		t.Fatalf("unexpected highlights %q", highlights)
+	}
+	// 挂载在/var上的子卷不存放这些路径。
+	if len(filesystems[1].Highlights) != 0 {
+		t.Fatalf("unexpected highlights %q", filesystems[1].Highlights)
+	}
+
+	// 没有inode的部分时只有空间的使用情况。
+	filesystems, err = parseDfUsage("Filesystem Type 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 ext4 100 40 60 40% /\n", "/backup")
+	if err != nil || len(filesystems) != 1 || *filesystems[0].UsePercent != 40 || filesystems[0].InodesTotal != nil {
+		t.Fatalf("unexpected filesystems %+v, err %+v", filesystems, err)
+	}
+	for _, output := range []string{
+		"",
+		"sudo: a password is required\n",
+		"--- inodes ---\nFilesystem Inodes IUsed IFree IUse% Mounted on\n",
+	} {
+		if _, err := parseDfUsage(output, "/backup"); err == nil {
+			t.Fatalf("parsing %q should fail", output)
+		}
+	}
+}
+
+func TestPathContains(t *testing.T) {
+	for _, c := range []struct {
+		dir, p   string
+		expected bool
+	}{
+		{"/", "/home", true},
+		{"/home", "/home", true},
+		{"/home/", "/home/alice", true},
+		{"/home", "/home2", false},
+		{"/data", "/backup", false},
+	} {
+		if got := pathContains(c.dir, c.p); got != c.expected {
+			t.Fatalf("pathContains(%q, %q) = %v, want %v", c.dir, c.p, got, c.expected)
+		}
+	}
+}
+
+func TestLinuxSSHGetFilesystemUsages(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	ctx := context.Background()
+	resp, err := es.GetFilesystemUsages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Filesystems) != 5 || len(resp.Results) != 1 || resp.Filesystems[3].Highlights[0] != internal_models.ServerFilesystemHighlightBackupRoot {
+		t.Fatalf("unexpected resp %+v", resp)
+	}
+
+	server.Fail("df_usage", 1, "sudo: a password is required\n")
+	resp, err = es.GetFilesystemUsages(ctx)
+	if err == nil || resp.Results[0].ExitStatus != 1 {
+		t.Fatalf("unexpected resp %+v, err %+v", resp, err)
+	}
+}
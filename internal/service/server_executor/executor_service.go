@@ -1,25 +1,31 @@
 package server_executor
 
 import (
+	"ServerServing/config"
 	daModels "ServerServing/da/mysql/da_models"
 	SErr "ServerServing/err"
 	"ServerServing/internal/internal_models"
-	"fmt"
+	"context"
 	"github.com/tredoe/osutil/v2/userutil/crypt/sha512_crypt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
-	"path"
 	"time"
 )
 
+// LinuxOSType 发行版类型，同时也是该类发行版的脚本目录名。由/etc/os-release中的ID与ID_LIKE确定，参考OSRelease.LinuxOSType。
 type LinuxOSType string
 
 const (
 	Unknown LinuxOSType = "unknown"
 	Ubuntu  LinuxOSType = "ubuntu"
 	CentOS  LinuxOSType = "centos"
+	// Debian Debian以及ID_LIKE为debian的发行版。
+	Debian LinuxOSType = "debian"
+	// RHEL RHEL、Fedora以及Rocky Linux、AlmaLinux等ID_LIKE为rhel的发行版。
+	RHEL LinuxOSType = "rhel"
+	// SUSE openSUSE与SLES。
+	SUSE LinuxOSType = "suse"
 )
 
 type OpenExecutorServiceParam struct {
@@ -28,100 +34,174 @@ type OpenExecutorServiceParam struct {
 	OSType           daModels.OSType
 	AdminAccountName string
 	AdminAccountPwd  string
+
+	// AuthType 为空时使用密码认证。
+	AuthType                  daModels.SSHAuthType
+	AdminPrivateKey           string
+	AdminPrivateKeyPassphrase string
+	AdminAgentSocket          string
+
+	// HostKeyFingerprint 记录的host key指纹，为空时接受服务器提供的任意host key（trust-on-first-use）。
+	HostKeyFingerprint string
+
+	// JumpHops 连接该服务器时依次经过的跳板机，为空时直接连接。
+	JumpHops []*SSHJumpHop
+
+	// IgnoreBackoff 即使该服务器处于连续连接失败之后的冷却期，也尝试建立连接，用于管理员主动测试连接等场景。
+	IgnoreBackoff bool
+}
+
+func (p *OpenExecutorServiceParam) sshAuth() *SSHAuth {
+	return &SSHAuth{
+		Type:                 p.AuthType,
+		Password:             p.AdminAccountPwd,
+		PrivateKey:           p.AdminPrivateKey,
+		PrivateKeyPassphrase: p.AdminPrivateKeyPassphrase,
+		AgentSocket:          p.AdminAgentSocket,
+	}
 }
 
-func OpenExecutorService(param *OpenExecutorServiceParam) (ExecutorService, *SErr.APIErr) {
+// OpenExecutorService 按param.OSType获取Executor服务实例。测试中可以通过UseReplayTranscripts替换为回放录制结果的实现。
+var OpenExecutorService = openExecutorService
+
+func openExecutorService(param *OpenExecutorServiceParam) (ExecutorService, *SErr.APIErr) {
 	switch param.OSType {
 	case daModels.OSTypeLinux:
-		return openLinuxSSHExecutorService(param.Host, param.Port, param.AdminAccountName, param.AdminAccountPwd)
+		return openLinuxSSHExecutorService(param)
+	case daModels.OSTypeLinuxLocal:
+		return openLinuxLocalExecutorService(param)
 	default:
 		panic("Unimplemented")
 	}
 }
 
-// use loadCmdScript to load a file-base command or script
-var loadCmdScript = func() func(path, name string) (string, *SErr.APIErr) {
-	cache := map[string]string{}
-	refresh := map[string]time.Time{}
-	refreshInterval := 1 * time.Minute
-	genKey := func(path, name string) string {
-		return fmt.Sprintf("Path:%s, Name:%s", path, name)
+const defaultCommandTimeout = 60 * time.Second
+
+// defaultScriptTimeouts 部分脚本的默认超时时间，例如移动用户的home目录可能需要很久。
+var defaultScriptTimeouts = map[string]time.Duration{
+	"mv":            30 * time.Minute,
+	"mv_force":      30 * time.Minute,
+	"sftp_upload":   30 * time.Minute,
+	"sftp_download": 30 * time.Minute,
+	"sftp_remove":   30 * time.Minute,
+}
+
+// commandTimeout 获取某个脚本的超时时间，配置文件中的配置优先。
+func commandTimeout(scriptName string) time.Duration {
+	var c *config.CommandTimeoutConfig
+	if conf := config.GetConfig(); conf != nil {
+		c = conf.CommandTimeoutConfig
 	}
-	return func(fPath, name string) (string, *SErr.APIErr) {
-		cmdKey := genKey(fPath, name)
-		if _, ok := refresh[cmdKey]; !ok {
-			refresh[cmdKey] = time.Now()
-		}
-		if lastRefresh, ok := refresh[cmdKey]; ok && time.Now().Sub(lastRefresh) < refreshInterval {
-			cmd := cache[cmdKey]
-			if cmd != "" {
-				return cmd, nil
-			}
-		}
-		p := path.Join(fPath, name)
-		f, err := os.Open(p)
-		if err != nil {
-			panic(err)
-		}
-		bs, err := ioutil.ReadAll(f)
-		if err != nil {
-			log.Printf("loadCmdScript ioutil.readAll failed")
-			return "", SErr.InternalErr.CustomMessageF("loadCmdScript加载命令行数据失败！err=[%s]", err.Error())
+	if c != nil {
+		if seconds, ok := c.ScriptSeconds[scriptName]; ok && seconds > 0 {
+			return time.Duration(seconds) * time.Second
 		}
-		cmd := string(bs)
-		cache[cmdKey] = cmd
-		return cmd, nil
 	}
-}()
+	if timeout, ok := defaultScriptTimeouts[scriptName]; ok {
+		return timeout
+	}
+	if c != nil && c.DefaultSeconds > 0 {
+		return time.Duration(c.DefaultSeconds) * time.Second
+	}
+	return defaultCommandTimeout
+}
 
 type ExecutorFileSystemService interface {
-	Move(src, dst string, force bool) (*ExecutorServiceVoidResp, *SErr.APIErr)
-	FileExists(filepath string) (*ExecutorServiceExistsResp, *SErr.APIErr)
-	DirExists(dirPath string) (*ExecutorServiceExistsResp, *SErr.APIErr)
-	PathExists(path string) (*ExecutorServiceExistsResp, *SErr.APIErr)
-	Mkdir(dirPath string) (*ExecutorServiceVoidResp, *SErr.APIErr)
-	MkdirIfNotExists(dirPath string) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	Move(ctx context.Context, src, dst string, force bool) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	FileExists(ctx context.Context, filepath string) (*ExecutorServiceExistsResp, *SErr.APIErr)
+	DirExists(ctx context.Context, dirPath string) (*ExecutorServiceExistsResp, *SErr.APIErr)
+	PathExists(ctx context.Context, path string) (*ExecutorServiceExistsResp, *SErr.APIErr)
+	Mkdir(ctx context.Context, dirPath string) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	MkdirIfNotExists(ctx context.Context, dirPath string) (*ExecutorServiceVoidResp, *SErr.APIErr)
+
+	// 以下的文件操作通过以root身份运行的sftp-server完成，路径必须是规范的绝对路径。
+	ListDir(ctx context.Context, dirPath string) (*ExecutorServiceListDirResp, *SErr.APIErr)
+	Stat(ctx context.Context, path string) (*ExecutorServiceFileResp, *SErr.APIErr)
+	Upload(ctx context.Context, path string, content io.Reader, perm os.FileMode) (*ExecutorServiceFileResp, *SErr.APIErr)
+	Download(ctx context.Context, path string, target DownloadTarget) (*ExecutorServiceFileResp, *SErr.APIErr)
+	Chmod(ctx context.Context, path string, perm os.FileMode) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	Chown(ctx context.Context, path string, uid, gid uint) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	Remove(ctx context.Context, path string, recursive bool) (*ExecutorServiceVoidResp, *SErr.APIErr)
 }
 
 type ExecutorHardwareUsageService interface {
-	GetCPUMemProcessesUsages() (*ExecutorServiceCPUMemProcessesUsagesResp, *SErr.APIErr)
-	GetGPUUsages() (*ExecutorServiceVoidResp, *SErr.APIErr)
+	GetCPUMemProcessesUsages(ctx context.Context) (*ExecutorServiceCPUMemProcessesUsagesResp, *SErr.APIErr)
+	GetGPUUsages(ctx context.Context) (*ExecutorServiceGPUUsagesResp, *SErr.APIErr)
+	GetFilesystemUsages(ctx context.Context) (*ExecutorServiceFilesystemUsagesResp, *SErr.APIErr)
 }
 
 type ExecutorAccountService interface {
-	AddAccount(accountName, pwd string) (*ExecutorServiceVoidResp, *SErr.APIErr)
-	DeleteAccount(accountName string) (*ExecutorServiceVoidResp, *SErr.APIErr)
-	GetAccountList() (*ExecutorServiceGetAccountListResp, *SErr.APIErr)
-	GetBackupDir(accountName string) (*ExecutorServiceGetBackupDirResp, *SErr.APIErr)
-	BackupAccountHomeDir(accountName string) (*ExecutorServiceBackupAccountResp, *SErr.APIErr)
-	RecoverAccountHomeDir(accountName string, force bool) (*ExecutorServiceRecoverAccountResp, *SErr.APIErr)
-	GetAccountHomeDir(accountName string) (*ExecutorServiceGetAccountHomeDirResp, *SErr.APIErr)
+	AddAccount(ctx context.Context, accountName, pwd string) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	DeleteAccount(ctx context.Context, accountName string) (*ExecutorServiceVoidResp, *SErr.APIErr)
+	GetAccountList(ctx context.Context) (*ExecutorServiceGetAccountListResp, *SErr.APIErr)
+	GetBackupDir(ctx context.Context, accountName string) (*ExecutorServiceGetBackupDirResp, *SErr.APIErr)
+	BackupAccountHomeDir(ctx context.Context, accountName string) (*ExecutorServiceBackupAccountResp, *SErr.APIErr)
+	RecoverAccountHomeDir(ctx context.Context, accountName string, force bool) (*ExecutorServiceRecoverAccountResp, *SErr.APIErr)
+	GetAccountHomeDir(ctx context.Context, accountName string) (*ExecutorServiceGetAccountHomeDirResp, *SErr.APIErr)
 }
 
 type ExecutorHardwareInfoService interface {
-	GetGPUHardware() (*ExecutorServiceGPUHardwareResp, *SErr.APIErr)
-	GetCPUHardware() (*ExecutorServiceCPUHardwareResp, *SErr.APIErr)
-	GetMemoryHardware() (*ExecutorServiceMemoryHardwareResp, *SErr.APIErr)
+	GetGPUHardware(ctx context.Context) (*ExecutorServiceGPUHardwareResp, *SErr.APIErr)
+	GetCPUHardware(ctx context.Context) (*ExecutorServiceCPUHardwareResp, *SErr.APIErr)
+	GetMemoryHardware(ctx context.Context) (*ExecutorServiceMemoryHardwareResp, *SErr.APIErr)
 }
 
 type ExecutorRemoteAccessService interface {
-	GetRemoteAccessInfos() (*ExecutorServiceRemoteAccessResp, *SErr.APIErr)
+	GetRemoteAccessInfos(ctx context.Context) (*ExecutorServiceRemoteAccessResp, *SErr.APIErr)
+}
+
+type ExecutorInfoCollectService interface {
+	// CollectInfo 在一个session中收集arg指定的全部信息，每个部分的结果与单独调用对应的方法时相同。
+	CollectInfo(ctx context.Context, arg *CollectInfoArg) (*ExecutorServiceCollectInfoResp, *SErr.APIErr)
+}
+
+type ExecutorScriptService interface {
+	// RunScript 以root身份执行一段使用命令模板格式编写的脚本，name用于在结果、日志以及录制记录中标识它，也用于按脚本名配置超时时间。
+	RunScript(ctx context.Context, name, content string, args CmdArgs) (*ExecutorServiceRunScriptResp, *SErr.APIErr)
+	// RunCommand 以root身份直接执行一段shell命令，不经过命令模板。name的用途与RunScript相同。
+	RunCommand(ctx context.Context, name, cmd string) (*ExecutorServiceRunScriptResp, *SErr.APIErr)
 }
 
 // ExecutorService 描述远端命令组成的的外部可用接口。目前只包括Linux服务器。
 // 其中每个接口的第一个返回参数永远都是从服务器返回的真实output，用于在复杂情况下debug，或者直接给用户展示它的内容。
+// 每个接口的ctx一般来自于http请求，请求被取消时，正在服务器上执行的命令也会被终止。
 type ExecutorService interface {
 	ExecutorFileSystemService
 	ExecutorHardwareUsageService
 	ExecutorAccountService
 	ExecutorHardwareInfoService
 	ExecutorRemoteAccessService
+	ExecutorScriptService
+	ExecutorInfoCollectService
 	io.Closer
 	String() string
+	// HostKeyFingerprint 返回本次连接中服务器提供的host key指纹。
+	HostKeyFingerprint() string
+	// OSRelease 返回建立连接时解析的/etc/os-release，回放录制记录时可能为nil。
+	OSRelease() *OSRelease
 }
 
+// ExecutorServiceRespCommon 每个Resp都包含的结构。
+// Output 为最后执行的命令的标准输出。
+// Results 为本次调用在服务器上执行的每条命令的结果，按执行顺序排列，包括失败的那一条。
 type ExecutorServiceRespCommon struct {
-	Output string
+	Output  string
+	Results []*internal_models.CommandResult
+}
+
+// addResult 记录一条命令的结果。
+func (r *ExecutorServiceRespCommon) addResult(result *internal_models.CommandResult) {
+	if result == nil {
+		return
+	}
+	r.Output = result.Stdout
+	r.Results = append(r.Results, result)
+}
+
+// merge 合并另一次调用的结果。
+func (r *ExecutorServiceRespCommon) merge(other ExecutorServiceRespCommon) {
+	r.Output = other.Output
+	r.Results = append(r.Results, other.Results...)
 }
 
 type ExecutorServiceVoidResp struct {
@@ -174,6 +254,19 @@ type ExecutorServiceGPUHardwareResp struct {
 	GPUs []*internal_models.ServerGPU
 }
 
+// ExecutorServiceGPUUsagesResp GPUs为nvidia-smi查询到的每块GPU的使用情况，Processes为正在使用GPU的进程。
+type ExecutorServiceGPUUsagesResp struct {
+	ExecutorServiceRespCommon
+	GPUs      []*internal_models.ServerGPUUsage
+	Processes []*internal_models.ServerGPUProcess
+}
+
+// ExecutorServiceFilesystemUsagesResp Filesystems为df查询到的已挂载的文件系统，不包括伪文件系统。
+type ExecutorServiceFilesystemUsagesResp struct {
+	ExecutorServiceRespCommon
+	Filesystems []*internal_models.ServerFilesystem
+}
+
 type ExecutorServiceMemoryHardwareResp struct {
 	ExecutorServiceRespCommon
 	MemoryStats *internal_models.ServerMemory
@@ -184,6 +277,25 @@ type ExecutorServiceRemoteAccessResp struct {
 	RemoteAccessingAccountInfos []*internal_models.ServerRemoteAccessingAccount
 }
 
+// ExecutorServiceRunScriptResp Args为渲染脚本时使用的参数，LogCmd为渲染后的脚本，其中敏感参数的值都已经被隐藏。
+type ExecutorServiceRunScriptResp struct {
+	ExecutorServiceRespCommon
+	Args   CmdArgs
+	LogCmd string
+}
+
+type ExecutorServiceListDirResp struct {
+	ExecutorServiceRespCommon
+	Files []*internal_models.ServerFileInfo
+}
+
+// ExecutorServiceFileResp Transferred 为上传或下载的字节数。
+type ExecutorServiceFileResp struct {
+	ExecutorServiceRespCommon
+	File        *internal_models.ServerFileInfo
+	Transferred int64
+}
+
 type ExecutorServiceGetBackupDirResp struct {
 	ExecutorServiceRespCommon
 	BackupDir  string
@@ -191,8 +303,49 @@ type ExecutorServiceGetBackupDirResp struct {
 	DirExists  bool
 }
 
+// ExecutorServiceBackupDirsResp Entries为备份文件夹中每个<账户名>.backup的文件类型（find -printf的%Y），key为文件名。
+type ExecutorServiceBackupDirsResp struct {
+	ExecutorServiceRespCommon
+	BackupRoot string
+	Entries    map[string]string
+}
+
+// ExecutorServiceCollectInfoResp CollectInfo的结果，每个部分的结果以及错误与单独调用对应的方法时相同，没有收集的部分为nil。
+type ExecutorServiceCollectInfoResp struct {
+	AccountList              *ExecutorServiceGetAccountListResp
+	AccountListErr           *SErr.APIErr
+	BackupDirs               *ExecutorServiceBackupDirsResp
+	BackupDirsErr            *SErr.APIErr
+	CPUHardware              *ExecutorServiceCPUHardwareResp
+	CPUHardwareErr           *SErr.APIErr
+	GPUHardware              *ExecutorServiceGPUHardwareResp
+	GPUHardwareErr           *SErr.APIErr
+	RemoteAccessInfos        *ExecutorServiceRemoteAccessResp
+	RemoteAccessInfosErr     *SErr.APIErr
+	CPUMemProcessesUsages    *ExecutorServiceCPUMemProcessesUsagesResp
+	CPUMemProcessesUsagesErr *SErr.APIErr
+	GPUUsages                *ExecutorServiceGPUUsagesResp
+	GPUUsagesErr             *SErr.APIErr
+	FilesystemUsages         *ExecutorServiceFilesystemUsagesResp
+	FilesystemUsagesErr      *SErr.APIErr
+}
+
 type executorServiceCommon struct{}
 
+// commandRunner 执行命令的底层实现，例如到服务器的SSH连接，或者在本机通过os/exec执行。
+// runCommand 执行cmd，desc用于在结果以及出错信息中描述该命令；withSudo为true时，命令中的sudo会使用管理员账户的密码。
+// 返回的结果即使在命令失败时也不为nil。
+type commandRunner interface {
+	runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr)
+	String() string
+	io.Closer
+}
+
+// scriptRunner 能够直接执行渲染后的脚本的commandRunner。录制与回放需要知道脚本名与参数，而不仅仅是渲染后的命令。
+type scriptRunner interface {
+	runScript(ctx context.Context, script *renderedScript) (*internal_models.CommandResult, *SErr.APIErr)
+}
+
 func (c *executorServiceCommon) encrypt(pwd string) string {
 	// Generate a random string for use in the salt
 	//const charset = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
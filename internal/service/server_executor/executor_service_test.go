@@ -2,7 +2,12 @@ package server_executor
 
 import (
 	"ServerServing/config"
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
 	"ServerServing/util"
+	"ServerServing/util/testutil"
+	"context"
+	"strings"
 	"testing"
 )
 
@@ -12,9 +17,10 @@ func initEnv(t *testing.T) {
 	if es != nil {
 		return
 	}
-	config.InitConfigWithFile("/Users/purchaser/go/src/ServerServing/config.yml", "dev")
+	configPath := testutil.LiveConfigPath(t, "连接真实服务器")
+	config.InitConfigWithFile(configPath, "dev")
 	//s, err := openLinuxSSHExecutorService("47.93.56.75", 22, "someuser", "zhjT9910123!")
-	s, err := openLinuxSSHExecutorService("114.116.101.120", 22, "someadmin", "zhjT9910123!")
+	s, err := openLinuxSSHExecutorService(&OpenExecutorServiceParam{Host: "114.116.101.120", Port: 22, AdminAccountName: "someadmin", AdminAccountPwd: "zhjT9910123!"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -23,7 +29,7 @@ func initEnv(t *testing.T) {
 
 func TestSSHExecutorService_GetAccountList(t *testing.T) {
 	initEnv(t)
-	resp, e := es.GetAccountList()
+	resp, e := es.GetAccountList(context.Background())
 	if e != nil {
 		t.Fatal(e)
 	}
@@ -32,7 +38,7 @@ func TestSSHExecutorService_GetAccountList(t *testing.T) {
 
 func TestSSHExecutorService_CreateAccount(t *testing.T) {
 	initEnv(t)
-	_, e := es.AddAccount("golang_test_acc_1", "123456")
+	_, e := es.AddAccount(context.Background(), "golang_test_acc_1", "123456")
 	if e != nil {
 		t.Fatal(e)
 	}
@@ -40,7 +46,7 @@ func TestSSHExecutorService_CreateAccount(t *testing.T) {
 
 func TestSSHExecutorService_DeleteAccount(t *testing.T) {
 	initEnv(t)
-	resp, e := es.DeleteAccount("golang_test_acc_1")
+	resp, e := es.DeleteAccount(context.Background(), "golang_test_acc_1")
 	t.Log(resp)
 	if e != nil {
 		t.Fatal(e)
@@ -49,17 +55,17 @@ func TestSSHExecutorService_DeleteAccount(t *testing.T) {
 
 func TestSSHExecutorService_Path(t *testing.T) {
 	initEnv(t)
-	resp, e := es.PathExists("/root")
+	resp, e := es.PathExists(context.Background(), "/root")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(util.Pretty(resp))
-	resp, e = es.DirExists("/")
+	resp, e = es.DirExists(context.Background(), "/")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(util.Pretty(resp))
-	resp, e = es.DirExists("/home/mynewuser/some_file.txt")
+	resp, e = es.DirExists(context.Background(), "/home/mynewuser/some_file.txt")
 	if e != nil {
 		t.Fatal(e)
 	}
@@ -68,32 +74,32 @@ func TestSSHExecutorService_Path(t *testing.T) {
 
 func TestSSHExecutorService_FileSystem(t *testing.T) {
 	initEnv(t)
-	output, e := es.Mkdir("/some_dir")
+	output, e := es.Mkdir(context.Background(), "/some_dir")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(output)
-	resp, e := es.PathExists("/some_dir")
+	resp, e := es.PathExists(context.Background(), "/some_dir")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(util.Pretty(resp))
-	resp, e = es.PathExists("/dummy")
+	resp, e = es.PathExists(context.Background(), "/dummy")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(util.Pretty(resp))
-	resp, e = es.DirExists("/some_dir")
+	resp, e = es.DirExists(context.Background(), "/some_dir")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(util.Pretty(resp))
-	resp, e = es.FileExists("/home/mynewuser/some_file.txt")
+	resp, e = es.FileExists(context.Background(), "/home/mynewuser/some_file.txt")
 	if e != nil {
 		t.Fatal(e)
 	}
 	t.Log(util.Pretty(resp))
-	mvResp, e := es.Move("/some_dir", "/some_dir_2", false)
+	mvResp, e := es.Move(context.Background(), "/some_dir", "/some_dir_2", false)
 	if e != nil {
 		t.Fatal(e)
 	}
@@ -102,7 +108,7 @@ func TestSSHExecutorService_FileSystem(t *testing.T) {
 
 func TestSSHExecutorService_Backup(t *testing.T) {
 	initEnv(t)
-	resp, err := es.BackupAccountHomeDir("golang_test_acc_1")
+	resp, err := es.BackupAccountHomeDir(context.Background(), "golang_test_acc_1")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -111,7 +117,7 @@ func TestSSHExecutorService_Backup(t *testing.T) {
 
 func TestSSHExecutorService_Recover(t *testing.T) {
 	initEnv(t)
-	resp, err := es.RecoverAccountHomeDir("golang_test_acc_1", false)
+	resp, err := es.RecoverAccountHomeDir(context.Background(), "golang_test_acc_1", false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -120,7 +126,7 @@ func TestSSHExecutorService_Recover(t *testing.T) {
 
 func TestSSHExecutorService_Top(t *testing.T) {
 	initEnv(t)
-	resp, err := es.GetCPUMemProcessesUsages()
+	resp, err := es.GetCPUMemProcessesUsages(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -131,11 +137,11 @@ func TestConnect(t *testing.T) {
 	initEnv(t)
 	// c, err := openLinuxSSHConnection("47.93.56.75", 22, "someuser", "zhjT9910123!")
 	// c, err := openLinuxSSHConnection("47.93.56.75:22", "mynewuser", "123456")
-	c, err := openLinuxSSHConnection("114.116.101.120", 22, "someadmin", "zhjT9910123!")
+	c, err := openLinuxSSHConnection("114.116.101.120", 22, "someadmin", &SSHAuth{Password: "zhjT9910123!"}, "", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	output, err := c.SendCommands("top -bn1")
+	output, err := c.SendCommands(context.Background(), "top -bn1")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -144,7 +150,7 @@ func TestConnect(t *testing.T) {
 
 func TestGPUInfo(t *testing.T) {
 	initEnv(t)
-	resp, err := es.GetGPUHardware()
+	resp, err := es.GetGPUHardware(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -153,7 +159,7 @@ func TestGPUInfo(t *testing.T) {
 
 func TestCPUInfo(t *testing.T) {
 	initEnv(t)
-	resp, err := es.GetCPUHardware()
+	resp, err := es.GetCPUHardware(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -162,7 +168,7 @@ func TestCPUInfo(t *testing.T) {
 
 func TestRemoteAccess(t *testing.T) {
 	initEnv(t)
-	resp, err := es.GetRemoteAccessInfos()
+	resp, err := es.GetRemoteAccessInfos(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -171,7 +177,7 @@ func TestRemoteAccess(t *testing.T) {
 
 func TestMemInfo(t *testing.T) {
 	initEnv(t)
-	resp, err := es.GetMemoryHardware()
+	resp, err := es.GetMemoryHardware(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -180,10 +186,49 @@ func TestMemInfo(t *testing.T) {
 
 func TestGPUUsage(t *testing.T) {
 	initEnv(t)
-	resp, err := es.GetGPUUsages()
+	resp, err := es.GetGPUUsages(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
 	t.Log("output:", resp.Output)
 	t.Log(util.Pretty(resp))
 }
+
+func TestCommandTimeout(t *testing.T) {
+	if d := commandTimeout("lscpu"); d != defaultCommandTimeout {
+		t.Fatalf("lscpu timeout = %v, want %v", d, defaultCommandTimeout)
+	}
+	if d := commandTimeout("mv"); d != defaultScriptTimeouts["mv"] {
+		t.Fatalf("mv timeout = %v, want %v", d, defaultScriptTimeouts["mv"])
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	if err := commandContextErr(ctx, "nvidia_gpu_query"); err.Code != SErr.CodeCommandTimeout || !strings.Contains(err.Message, "nvidia_gpu_query") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := commandContextErr(ctx, "top"); err.Code != SErr.CodeCommandCanceled {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
+
+func TestCommandResults(t *testing.T) {
+	rc := &ExecutorServiceRespCommon{}
+	rc.addResult(&internal_models.CommandResult{Script: "path_exists", Stdout: "1\n"})
+	other := ExecutorServiceRespCommon{}
+	failed := &internal_models.CommandResult{Script: "mv", Stdout: "", Stderr: "mv: cannot stat '/a': No such file or directory\n", ExitStatus: 1}
+	other.addResult(failed)
+	rc.merge(other)
+	if len(rc.Results) != 2 || rc.Output != "" || rc.Results[1] != failed {
+		t.Fatalf("unexpected resp %+v", rc)
+	}
+	if !rc.Results[0].Succeeded() || failed.Succeeded() {
+		t.Fatalf("unexpected Succeeded")
+	}
+	err := commandFailedErr(failed)
+	if err.Code != SErr.CodeCommandFailed || !strings.Contains(err.Message, "退出码为：1") || !strings.Contains(err.Message, "No such file or directory") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
@@ -2,10 +2,13 @@ package server_executor
 
 import (
 	"ServerServing/config"
+	daModels "ServerServing/da/mysql/da_models"
 	SErr "ServerServing/err"
 	"ServerServing/internal/internal_models"
 	"ServerServing/util"
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"golang.org/x/crypto/ssh"
 	"io"
@@ -16,52 +19,95 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // openLinuxSSHExecutorService
-// 获取一个到某服务器的SSH Executor服务实例。建立新的ssh连接。在一次http请求中复用这个服务可以减少连接的创建数量。
-// 目前不确定是否可以在多个请求间共享，暂时不太建议这么做（需要测试）。这容易引起并发问题，反正总的并发量不高，目前先随便做一做。
-func openLinuxSSHExecutorService(host string, port uint, account, pwd string) (ExecutorService, *SErr.APIErr) {
-	SSHConn, err := openLinuxSSHConnection(host, port, account, pwd)
+// 获取一个到某服务器的SSH Executor服务实例。底层的ssh连接从连接池中获取，在多个http请求之间共享。
+// 使用完毕后必须调用Close，它会把连接归还给连接池，而不是真正关闭连接。
+func openLinuxSSHExecutorService(param *OpenExecutorServiceParam) (ExecutorService, *SErr.APIErr) {
+	pool := getSSHConnPool()
+	pc, err := pool.Get(param)
 	if err != nil {
-		return nil, SErr.SSHConnectionErr.CustomMessageF("与该服务器建立SSH连接失败！服务器地址为%s:%d，用户名为：%s，密码为：%s", host, port, account, pwd)
+		return nil, err
 	}
-	output, hasSudo, err := SSHConn.CheckSudoPrivilege()
-	log.Printf("CheckSudoPrivilege output=[%s]", output)
-	if err != nil {
-		return nil, SErr.SSHConnectionErr.CustomMessageF("检查用户是否具有sudo权限时失败！服务器地址为%s:%d，用户名为：%s，密码为：%s", host, port, account, pwd)
-	}
-	if !hasSudo {
-		return nil, SErr.SSHConnectionErr.CustomMessageF("该用户并不具有sudo权限！服务器地址为%s:%d，用户名为：%s，密码为：%s", host, port, account, pwd)
-	}
-	output, osType, err := SSHConn.CheckOSInfo()
-	log.Printf("CheckOSInfo output=[%s]", output)
-	if err != nil {
-		return nil, SErr.SSHConnectionErr.CustomMessageF("检查该服务器的操作系统类型失败！服务器地址为%s:%d，用户名为：%s，密码为：%s", host, port, account, pwd)
-	}
-	if osType == Unknown {
-		return nil, SErr.SSHConnectionErr.CustomMessageF("该服务器的操作系统类型为不支持的类型！服务器地址为%s:%d，用户名为：%s，密码为：%s", host, port, account, pwd)
+	template := NewLinuxSSHExecutorServiceTemplate(pc.osRelease.LinuxOSType(), param.AdminAccountName, param.AdminAccountPwd, pc.conn)
+	template.osRelease = pc.osRelease
+	template.release = func() {
+		pool.Release(pc)
 	}
-	var impl ExecutorService
-	switch osType {
+	recordTranscriptIfConfigured(template)
+	auditCommandsIfConfigured(template)
+	return newLinuxExecutorService(template), nil
+}
+
+// newLinuxExecutorService 根据操作系统类型，生成持有template的具体实现。
+func newLinuxExecutorService(template *LinuxSSHExecutorServiceTemplate) ExecutorService {
+	switch template.OSType {
 	case Ubuntu:
 		// golang 的模板方法需要上下两层分别持有引用。
-		template := NewLinuxSSHExecutorServiceTemplate(Ubuntu, account, pwd, SSHConn)
 		svc := NewUbuntuSSHExecutorService()
 		template.implement = svc
 		svc.LinuxSSHExecutorServiceTemplate = template
-		impl = svc
+		return svc
 	case CentOS:
-		template := NewLinuxSSHExecutorServiceTemplate(CentOS, account, pwd, SSHConn)
 		svc := NewCentOSSSHExecutorService()
 		template.implement = svc
 		svc.LinuxSSHExecutorServiceTemplate = template
-		impl = svc
+		return svc
+	case Debian:
+		svc := NewDebianSSHExecutorService()
+		template.implement = svc
+		svc.LinuxSSHExecutorServiceTemplate = template
+		return svc
+	case RHEL:
+		svc := NewRHELSSHExecutorService()
+		template.implement = svc
+		svc.LinuxSSHExecutorServiceTemplate = template
+		return svc
+	case SUSE:
+		svc := NewSUSESSHExecutorService()
+		template.implement = svc
+		svc.LinuxSSHExecutorServiceTemplate = template
+		return svc
 	default:
+		_ = template.Close()
 		panic("Unsupported LinuxOSType")
 	}
-	return impl, nil
+}
+
+// dialLinuxSSHConnection 建立一条新的ssh连接，并检查sudo权限与操作系统类型。只有通过了检查的连接才会被放入连接池。
+func dialLinuxSSHConnection(param *OpenExecutorServiceParam) (*LinuxSSHConnection, *OSRelease, *SErr.APIErr) {
+	host, port, account, auth := param.Host, param.Port, param.AdminAccountName, param.sshAuth()
+	SSHConn, err := openLinuxSSHConnection(host, port, account, auth, param.HostKeyFingerprint, param.JumpHops)
+	if err != nil {
+		// 保留原有的错误码，使得host key不一致之类的错误可以被上层区分。
+		return nil, nil, err.CustomMessageF("与该服务器建立SSH连接失败！服务器地址为%s:%d，用户名为：%s，认证方式为：%s，错误信息为：%s", host, port, account, auth.Type, err.Message)
+	}
+	fail := func(err *SErr.APIErr) (*LinuxSSHConnection, *OSRelease, *SErr.APIErr) {
+		_ = SSHConn.Close()
+		return nil, nil, err
+	}
+	// 连接会被放入连接池，被多个请求共享，所以不使用某个请求的ctx。
+	ctx := context.Background()
+	result, hasSudo, err := checkSudoPrivilege(ctx, SSHConn)
+	log.Printf("CheckSudoPrivilege result=[%s]", util.Pretty(result))
+	if err != nil {
+		return fail(SErr.SSHConnectionErr.CustomMessageF("检查用户是否具有sudo权限时失败！服务器地址为%s:%d，用户名为：%s，认证方式为：%s，错误信息为：%s", host, port, account, auth.Type, err.Message))
+	}
+	if !hasSudo {
+		return fail(SErr.SSHConnectionErr.CustomMessageF("该用户并不具有sudo权限！服务器地址为%s:%d，用户名为：%s，认证方式为：%s", host, port, account, auth.Type))
+	}
+	result, release, err := checkOSInfo(ctx, SSHConn)
+	log.Printf("CheckOSInfo result=[%s]", util.Pretty(result))
+	if err != nil {
+		return fail(SErr.SSHConnectionErr.CustomMessageF("检查该服务器的操作系统类型失败！服务器地址为%s:%d，用户名为：%s，认证方式为：%s，错误信息为：%s", host, port, account, auth.Type, err.Message))
+	}
+	if release.LinuxOSType() == Unknown {
+		return fail(SErr.SSHConnectionErr.CustomMessageF("该服务器的操作系统类型为不支持的类型！服务器地址为%s:%d，用户名为：%s，认证方式为：%s，发行版为：%s（ID=%s，ID_LIKE=%v）", host, port, account, auth.Type, release, release.ID, release.IDLike))
+	}
+	return SSHConn, release, nil
 }
 
 type LinuxSSHExecutorServiceTemplate struct {
@@ -72,57 +118,109 @@ type LinuxSSHExecutorServiceTemplate struct {
 	Account string
 	Pwd     string
 
-	OSType     LinuxOSType
-	SSHConn    *LinuxSSHConnection
-	commonPath string
-	ubuntuPath string
-	centosPath string
+	OSType LinuxOSType
+	// osRelease 建立连接时解析的/etc/os-release，查找脚本时依次使用ID与ID_LIKE对应的目录。回放录制记录时可能为nil。
+	osRelease *OSRelease
+	// runner 执行命令的底层实现。通过SSH连接时与SSHConn相同，在本机执行时SSHConn为nil。
+	runner  commandRunner
+	SSHConn *LinuxSSHConnection
 
 	implement ExecutorService
+	// release 将连接归还给连接池。
+	release   func()
+	closeOnce sync.Once
 }
 
 func NewLinuxSSHExecutorServiceTemplate(osType LinuxOSType, Account string, Pwd string, SSHConn *LinuxSSHConnection) *LinuxSSHExecutorServiceTemplate {
-	csp := config.GetConfig().CmdsScriptsPath
-	ubuntu := path.Join(csp, "ubuntu")
-	common := path.Join(csp, "linux_common")
-	centos := path.Join(csp, "centos")
+	template := newLinuxExecutorServiceTemplate(osType, SSHConn.Host, SSHConn.Port, Account, Pwd, SSHConn)
+	template.SSHConn = SSHConn
+	return template
+}
+
+func newLinuxExecutorServiceTemplate(osType LinuxOSType, Host string, Port uint, Account string, Pwd string, runner commandRunner) *LinuxSSHExecutorServiceTemplate {
 	return &LinuxSSHExecutorServiceTemplate{
 		executorServiceCommon: &executorServiceCommon{},
-		Host:                  SSHConn.Host,
-		Port:                  SSHConn.Port,
+		Host:                  Host,
+		Port:                  Port,
 		Account:               Account,
 		Pwd:                   Pwd,
 		OSType:                osType,
-		SSHConn:               SSHConn,
-		commonPath:            common,
-		ubuntuPath:            ubuntu,
-		centosPath:            centos,
+		runner:                runner,
+	}
+}
+
+// HostKeyFingerprint 不是通过SSH连接时为空。
+func (s *LinuxSSHExecutorServiceTemplate) HostKeyFingerprint() string {
+	if s.SSHConn == nil {
+		return ""
+	}
+	return s.SSHConn.HostKeyFingerprint
+}
+
+// OSRelease 建立连接时解析的/etc/os-release。
+func (s *LinuxSSHExecutorServiceTemplate) OSRelease() *OSRelease {
+	return s.osRelease
+}
+
+// renderScript 加载名为scriptName的命令模板并使用args渲染。
+func (s *LinuxSSHExecutorServiceTemplate) renderScript(scriptName string, args CmdArgs) (*renderedScript, *SErr.APIErr) {
+	return renderScript(cmdScriptDirs(s.OSType, s.osRelease), scriptName, args)
+}
+
+// runScript 在服务器上执行渲染后的脚本，超时时间按脚本名配置。超时或者ctx被取消时，远端的session会被终止。
+// 执行结果（无论成功与否）会记录到rc中，返回值为命令的标准输出。
+func (s *LinuxSSHExecutorServiceTemplate) runScript(ctx context.Context, rc *ExecutorServiceRespCommon, script *renderedScript) (string, *SErr.APIErr) {
+	result, err := runScriptWith(withOutputHost(ctx, s.Host, s.Port), s.runner, script)
+	rc.addResult(result)
+	return result.Stdout, err
+}
+
+// RunScript 以root身份执行一段使用命令模板格式编写的脚本，例如脚本目录中管理员定义的脚本。
+func (s *LinuxSSHExecutorServiceTemplate) RunScript(ctx context.Context, name, content string, args CmdArgs) (*ExecutorServiceRunScriptResp, *SErr.APIErr) {
+	resp := &ExecutorServiceRunScriptResp{}
+	script, err := renderUserScript(cmdScriptDirs(s.OSType, s.osRelease), name, content, args)
+	if err != nil {
+		return resp, err
+	}
+	return s.runUserScript(ctx, resp, script)
+}
+
+// RunCommand 以root身份直接执行一段shell命令。
+func (s *LinuxSSHExecutorServiceTemplate) RunCommand(ctx context.Context, name, cmd string) (*ExecutorServiceRunScriptResp, *SErr.APIErr) {
+	resp := &ExecutorServiceRunScriptResp{}
+	script, err := renderUserCommand(cmdScriptDirs(s.OSType, s.osRelease), name, cmd)
+	if err != nil {
+		return resp, err
 	}
+	return s.runUserScript(ctx, resp, script)
+}
 
+func (s *LinuxSSHExecutorServiceTemplate) runUserScript(ctx context.Context, resp *ExecutorServiceRunScriptResp, script *renderedScript) (*ExecutorServiceRunScriptResp, *SErr.APIErr) {
+	resp.Args = script.Args
+	resp.LogCmd = script.LogCmd
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], run %s, cmd=[%s], output=[%s]", s, script.Name, script.LogCmd, output)
+	return resp, err
 }
 
 func (s *LinuxSSHExecutorServiceTemplate) String() string {
-	return fmt.Sprintf("LinuxSSHExecutorServiceTemplate=[Host=%s, Port=%d, ServerAccount=%s, Pwd=%s, OSType=%s]", s.Host, s.Port, s.Account, s.Pwd, s.OSType)
+	return fmt.Sprintf("LinuxSSHExecutorServiceTemplate=[Host=%s, Port=%d, ServerAccount=%s, OSType=%s]", s.Host, s.Port, s.Account, s.OSType)
 }
 
 // Move 移动文件或文件夹
-func (s *LinuxSSHExecutorServiceTemplate) Move(src, dst string, force bool) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) Move(ctx context.Context, src, dst string, force bool) (*ExecutorServiceVoidResp, *SErr.APIErr) {
 	resp := &ExecutorServiceVoidResp{}
-	var cmd string
-	var err *SErr.APIErr
+	scriptName := "mv"
 	if force {
-		cmd, err = loadCmdScript(s.commonPath, "mv_force")
-	} else {
-		cmd, err = loadCmdScript(s.commonPath, "mv")
+		scriptName = "mv_force"
 	}
+	// sudo mv {{src}} {{dst}}
+	script, err := s.renderScript(scriptName, CmdArgs{"src": src, "dst": dst})
 	if err != nil {
 		return resp, err
 	}
-	// sudo mv %s %s
-	cmd = fmt.Sprintf(cmd, src, dst)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
-	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], mv, cmd=[%s], output=[%s]", s, cmd, output)
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], mv, cmd=[%s], output=[%s]", s, script.LogCmd, output)
 	if err != nil {
 		return resp, err
 	}
@@ -130,10 +228,10 @@ func (s *LinuxSSHExecutorServiceTemplate) Move(src, dst string, force bool) (*Ex
 }
 
 // FileExists 检查文件是否存在
-func (s *LinuxSSHExecutorServiceTemplate) FileExists(filepath string) (*ExecutorServiceExistsResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) FileExists(ctx context.Context, filepath string) (*ExecutorServiceExistsResp, *SErr.APIErr) {
 	resp := &ExecutorServiceExistsResp{}
-	pathExistsResp, err := s.implement.PathExists(filepath)
-	resp.Output = pathExistsResp.Output
+	pathExistsResp, err := s.implement.PathExists(ctx, filepath)
+	resp.merge(pathExistsResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, nil
 	}
@@ -141,19 +239,17 @@ func (s *LinuxSSHExecutorServiceTemplate) FileExists(filepath string) (*Executor
 		resp.Exists = false
 		return resp, nil
 	}
-	cmd, err := loadCmdScript(s.commonPath, "is_file")
+	// sudo [ -f {{file_path}} ] && echo 1 || echo 0
+	script, err := s.renderScript("is_file", CmdArgs{"file_path": filepath})
 	if err != nil {
-		return resp, nil
+		return resp, err
 	}
-	// sudo [ -f "%s" ] && echo 1 || echo 0
-	cmd = fmt.Sprintf(cmd, filepath)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
-	log.Printf("UbuntuSSHExecutorService=[%s] FileExists, cmd=[%s], output=[%s]", s, cmd, output)
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	log.Printf("UbuntuSSHExecutorService=[%s] FileExists, cmd=[%s], output=[%s]", s, script.LogCmd, output)
 	if err != nil {
 		return resp, err
 	}
-	if strings.Contains(output, "1") {
+	if strings.TrimSpace(output) == "1" {
 		resp.Exists = true
 		return resp, nil
 	} else {
@@ -163,10 +259,10 @@ func (s *LinuxSSHExecutorServiceTemplate) FileExists(filepath string) (*Executor
 }
 
 // DirExists 检查文件夹是否存在
-func (s *LinuxSSHExecutorServiceTemplate) DirExists(dirPath string) (*ExecutorServiceExistsResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) DirExists(ctx context.Context, dirPath string) (*ExecutorServiceExistsResp, *SErr.APIErr) {
 	resp := &ExecutorServiceExistsResp{}
-	pathExistsResp, err := s.implement.PathExists(dirPath)
-	resp.Output = pathExistsResp.Output
+	pathExistsResp, err := s.implement.PathExists(ctx, dirPath)
+	resp.merge(pathExistsResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
@@ -174,19 +270,17 @@ func (s *LinuxSSHExecutorServiceTemplate) DirExists(dirPath string) (*ExecutorSe
 		resp.Exists = false
 		return resp, nil
 	}
-	cmd, err := loadCmdScript(s.commonPath, "is_dir")
+	// sudo [ -d {{dir_path}} ] && echo 1 || echo 0
+	script, err := s.renderScript("is_dir", CmdArgs{"dir_path": dirPath})
 	if err != nil {
 		return resp, err
 	}
-	// [ -d "%s" ] && echo 1 || echo 0
-	cmd = fmt.Sprintf(cmd, dirPath)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
-	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] DirExists, cmd=[%s] output=[%s]", s, cmd, output)
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] DirExists, cmd=[%s] output=[%s]", s, script.LogCmd, output)
 	if err != nil {
 		return resp, err
 	}
-	if strings.Contains(output, "1") {
+	if strings.TrimSpace(output) == "1" {
 		resp.Exists = true
 		return resp, nil
 	} else {
@@ -195,21 +289,19 @@ func (s *LinuxSSHExecutorServiceTemplate) DirExists(dirPath string) (*ExecutorSe
 	}
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) PathExists(path string) (*ExecutorServiceExistsResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) PathExists(ctx context.Context, path string) (*ExecutorServiceExistsResp, *SErr.APIErr) {
 	resp := &ExecutorServiceExistsResp{}
-	cmd, err := loadCmdScript(s.commonPath, "path_exists")
+	// ([ -f {{path}} ] || [ -d {{path}} ]) && echo 1 || echo 0
+	script, err := s.renderScript("path_exists", CmdArgs{"path": path})
 	if err != nil {
 		return resp, err
 	}
-	// ([ -f "%s" ] || [ -d "%s" ]) && echo 1 || echo 0
-	cmd = fmt.Sprintf(cmd, path, path)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
-	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] PathExists, cmd=[%s] output=[%s]", s, cmd, output)
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] PathExists, cmd=[%s] output=[%s]", s, script.LogCmd, output)
 	if err != nil {
 		return resp, err
 	}
-	if strings.Contains(output, "1") {
+	if strings.TrimSpace(output) == "1" {
 		resp.Exists = true
 		return resp, err
 	} else {
@@ -219,15 +311,13 @@ func (s *LinuxSSHExecutorServiceTemplate) PathExists(path string) (*ExecutorServ
 }
 
 // Mkdir 创建文件夹，不检查文件夹是否存在。
-func (s *LinuxSSHExecutorServiceTemplate) Mkdir(path string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) Mkdir(ctx context.Context, path string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
 	resp := &ExecutorServiceVoidResp{}
-	cmd, err := loadCmdScript(s.commonPath, "mkdir")
+	script, err := s.renderScript("mkdir", CmdArgs{"dir_path": path})
 	if err != nil {
 		return resp, err
 	}
-	cmd = fmt.Sprintf(cmd, path)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	_, err = s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	if err != nil {
 		return resp, err
 	}
@@ -235,15 +325,16 @@ func (s *LinuxSSHExecutorServiceTemplate) Mkdir(path string) (*ExecutorServiceVo
 }
 
 // MkdirIfNotExists 顾名思义
-func (s *LinuxSSHExecutorServiceTemplate) MkdirIfNotExists(dirPath string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) MkdirIfNotExists(ctx context.Context, dirPath string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
 	resp := &ExecutorServiceVoidResp{}
-	pathExistsResp, err := s.implement.PathExists(dirPath)
-	resp.Output = pathExistsResp.Output
+	pathExistsResp, err := s.implement.PathExists(ctx, dirPath)
+	resp.merge(pathExistsResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
 	if !pathExistsResp.Exists {
-		resp, err := s.implement.Mkdir(dirPath)
+		mkdirResp, err := s.implement.Mkdir(ctx, dirPath)
+		resp.merge(mkdirResp.ExecutorServiceRespCommon)
 		if err != nil {
 			return resp, err
 		}
@@ -252,7 +343,7 @@ func (s *LinuxSSHExecutorServiceTemplate) MkdirIfNotExists(dirPath string) (*Exe
 }
 
 // GetCPUMemProcessesUsages 获取CPU，Mem占用，以及Process占用的信息。
-func (s *LinuxSSHExecutorServiceTemplate) GetCPUMemProcessesUsages() (*ExecutorServiceCPUMemProcessesUsagesResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetCPUMemProcessesUsages(ctx context.Context) (*ExecutorServiceCPUMemProcessesUsagesResp, *SErr.APIErr) {
 	// top - 08:32:57 up 12 days,  5:24,  5 users,  load average: 1.86, 2.28, 2.49
 	// Tasks: 620 total,   1 running, 471 sleeping,   0 stopped,   0 zombie
 	// %Cpu(s): 11.1 us,  3.8 sy,  0.9 ni, 83.0 id,  0.1 wa,  0.0 hi,  1.0 si,  0.0 st
@@ -272,150 +363,55 @@ func (s *LinuxSSHExecutorServiceTemplate) GetCPUMemProcessesUsages() (*ExecutorS
 	// 22228 1337      20   0  199444  55848  29520 S   5.6  0.0  38:58.67 envoy
 	//     1 root      20   0   80592  11652   6588 S   0.0  0.0  92:23.60 systemd
 	resp := &ExecutorServiceCPUMemProcessesUsagesResp{}
-	cmd, err := loadCmdScript(s.commonPath, "top")
+	script, err := s.renderScript("top", nil)
 	if err != nil {
 		return resp, err
 	}
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	if err != nil {
 		return resp, err
 	}
 
-	resp.ProcessInfos = make([]*internal_models.ServerProcessInfo, 0)
-	resp.CPUMemUsage = &internal_models.ServerCPUMemUsage{
-		UserProcCPUUsage: nil,
-		MemUsage:         nil,
-	}
-	lines := util.SplitLine(output)
-	matchCPU := func(line string) {
-		if resp.CPUMemUsage.UserProcCPUUsage != nil {
-			return
-		}
-		reg := regexp.MustCompile(`^.*Cpu\(s\):\s+([0-9.]+) us.*$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 2 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchCPU=[%+v]", util.Pretty(m))
-		f, err := strconv.ParseFloat(m[1], 64)
-		if err != nil {
-			log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchCPU=[%+v], parseFloat failed, err=[%+v]", util.Pretty(m), err)
-			return
-		}
-		resp.CPUMemUsage.UserProcCPUUsage = &f
-	}
-	matchMem := func(line string) {
-		if resp.CPUMemUsage.MemUsage != nil {
-			return
-		}
-		reg := regexp.MustCompile(`^.*Mem.*:.*,\s+([0-9]+) free,\s+([0-9]+) used,\s+([0-9]+) buff/cache.*$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 4 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchMem=[%+v]", util.Pretty(m))
-		free, _ := util.ParseInt(m[1])
-		used, _ := util.ParseInt(m[2])
-		buff, _ := util.ParseInt(m[3])
-		if free == 0 || used == 0 || buff == 0 {
-			log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchMem=[%+v], parseInt return 0", m)
-			return
-		}
-		usage := 100 * float64(used) / float64(free+used+buff)
-		total := float64(free + used + buff)
-		resp.CPUMemUsage.MemUsage = &usage
-		totalStr := strconv.Itoa(int(total/1024.)) + "MB"
-		resp.CPUMemUsage.MemTotal = &totalStr
-	}
-	matchProcLine := func(line string) {
-		line = strings.TrimSpace(line)
-		splits := util.SplitSpaces(line)
-		if len(splits) != 12 {
-			if len(resp.ProcessInfos) > 0 {
-				// 如果已经开始分析Proc行的数据了，但是却没有匹配成功，则此行数据可能出现匹配问题，打log看看
-				log.Printf("出现Proc行后，但是匹配失败的：line=[%s]", line)
-			}
-			return
-		}
-		// 228 root      19  -1  174840  69976  58876 S  0.0  3.4   0:19.95 systemd-journal
-		pidStr := splits[0]
-		pidInt, err := util.ParseInt(pidStr)
-		if err != nil {
-			return
-		}
-		pid := uint(pidInt)
-		user := splits[1]
-		cpuUsage := splits[8]
-		cpuUsageF, err := strconv.ParseFloat(cpuUsage, 64)
-		if err != nil {
-			return
-		}
-		memUsage := splits[9]
-		memUsageF, err := strconv.ParseFloat(memUsage, 64)
-		command := splits[11]
-		resp.ProcessInfos = append(resp.ProcessInfos, &internal_models.ServerProcessInfo{
-			PID:              &pid,
-			Command:          &command,
-			OwnerAccountName: &user,
-			CPUUsage:         &cpuUsageF,
-			MemUsage:         &memUsageF,
-			GPUUsage:         nil, // TODO
-		})
-	}
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
-		matchCPU(line)
-		matchMem(line)
-		matchProcLine(line)
-	}
+	s.parseTop(resp, output)
 	if resp.CPUMemUsage.MemUsage == nil {
 		// use cat /proc/meminfo
 		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages use /proc/meminfo")
-		cmd, err = loadCmdScript(s.commonPath, "meminfo")
+		script, err = s.renderScript("meminfo", nil)
 		if err != nil {
 			return resp, err
 		}
-		output, err := s.SSHConn.SendCommands(cmd)
-		resp.Output += fmt.Sprintf("--- meminfo ---\n%s", output)
+		topOutput := output
+		output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+		resp.Output = fmt.Sprintf("%s--- meminfo ---\n%s", topOutput, output)
 		if err != nil {
 			log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages meminfo failed, err=[%+v]", err)
 			return resp, err
 		}
-		lines := util.SplitLine(output)
-		totalReg := regexp.MustCompile(`^MemTotal:\s*([0-9]+)\s+kB$`)
-		freeReg := regexp.MustCompile(`^MemFree:\s*([0-9]+)\s+kB$`)
-		var totalMatched string
-		var freeMatched string
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			m := totalReg.FindStringSubmatch(line)
-			if len(m) == 2 {
-				totalMatched = m[1]
-			}
-			m = freeReg.FindStringSubmatch(line)
-			if len(m) == 2 {
-				freeMatched = m[1]
-			}
-		}
-		free, _ := util.ParseInt(freeMatched)
-		total, _ := util.ParseInt(totalMatched)
-		if free == 0 || total == 0 {
-			log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages meminfo, parseInt return 0")
-			return resp, err
-		}
-		usage := 100 * float64(total - free) / float64(total)
-		resp.CPUMemUsage.MemUsage = &usage
-		totalStr := strconv.Itoa(total/1024) + "MB"
-		resp.CPUMemUsage.MemTotal = &totalStr
+		s.parseMeminfoUsage(resp, output)
 	}
 
+	// top的第一次迭代是开机以来的平均值，所以CPU使用率以两次采样/proc/stat的结果为准。失败时保留top的结果。
+	script, err = s.renderScript("cpu_stat", nil)
+	if err != nil {
+		return resp, err
+	}
+	prevOutput := resp.Output
+	output, err = s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	s.appendCPUStat(resp, prevOutput, output, err)
 	return resp, nil
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) GetCPUHardware() (*ExecutorServiceCPUHardwareResp, *SErr.APIErr) {
+// appendCPUStat 在top（以及meminfo）的输出之后追加cpu_stat的输出，并解析它。cpu_stat失败时只打印log。
+func (s *LinuxSSHExecutorServiceTemplate) appendCPUStat(resp *ExecutorServiceCPUMemProcessesUsagesResp, prevOutput string, output string, err *SErr.APIErr) {
+	resp.Output = fmt.Sprintf("%s--- cpu_stat ---\n%s", prevOutput, output)
+	if err != nil {
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages cpu_stat failed, err=[%+v]", err)
+		return
+	}
+	s.parseCPUStat(resp.CPUMemUsage, output)
+}
+
+func (s *LinuxSSHExecutorServiceTemplate) GetCPUHardware(ctx context.Context) (*ExecutorServiceCPUHardwareResp, *SErr.APIErr) {
 	// Architecture:        x86_64
 	// CPU op-mode(s):      32-bit, 64-bit
 	// Byte Order:          Little Endian
@@ -440,133 +436,41 @@ func (s *LinuxSSHExecutorServiceTemplate) GetCPUHardware() (*ExecutorServiceCPUH
 	// L3 cache:            40960K
 	// NUMA node0 CPU(s):   0
 	resp := &ExecutorServiceCPUHardwareResp{}
-	cmd, err := loadCmdScript(s.commonPath, "lscpu")
+	script, err := s.renderScript("lscpu", nil)
 	if err != nil {
 		return resp, err
 	}
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	if err != nil {
 		return resp, err
 	}
-	resp.CPU = &internal_models.ServerCPUs{}
-	lines := util.SplitLine(output)
-	matchArch := func(line string) {
-		if resp.CPU.Architecture != nil {
-			return
-		}
-		line = strings.TrimSpace(line)
-		reg := regexp.MustCompile(`^Architecture:\s+([^\s]+)$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 2 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchArch, line=[%s], m=[%+v]", line, m)
-		resp.CPU.Architecture = &m[1]
-	}
-	matchCores := func(line string) {
-		if resp.CPU.Cores != nil {
-			return
-		}
-		line = strings.TrimSpace(line)
-		reg := regexp.MustCompile(`^CPU\(s\):\s+([^\s]+)$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 2 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchCores, line=[%s], m=[%+v]", line, m)
-		cores, err := util.ParseInt(m[1])
-		if err != nil {
-			return
-		}
-		resp.CPU.Cores = &cores
-	}
-	matchThreadsPerCore := func(line string) {
-		if resp.CPU.ThreadsPerCore != nil {
-			return
-		}
-		line = strings.TrimSpace(line)
-		reg := regexp.MustCompile(`^Thread\(s\) per core:\s+([^\s]+)$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 2 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchThreadsPerCore, line=[%s], m=[%+v]", line, m)
-		threadsPerCore, err := util.ParseInt(m[1])
-		if err != nil {
-			return
-		}
-		resp.CPU.ThreadsPerCore = &threadsPerCore
-	}
-	matchModelName := func(line string) {
-		if resp.CPU.ModelName != nil {
-			return
-		}
-		line = strings.TrimSpace(line)
-		reg := regexp.MustCompile(`^Model name:\s+(.*)$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 2 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchModelName, line=[%s], m=[%+v]", line, m)
-		resp.CPU.ModelName = &m[1]
-	}
-	matchers := []func(line string){
-		matchArch,
-		matchCores,
-		matchThreadsPerCore,
-		matchModelName,
-	}
-	for _, line := range lines {
-		for _, matcher := range matchers {
-			matcher(line)
-		}
-	}
+	s.parseCPUHardware(resp, output)
 	return resp, nil
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) GetGPUHardware() (*ExecutorServiceGPUHardwareResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetGPUHardware(ctx context.Context) (*ExecutorServiceGPUHardwareResp, *SErr.APIErr) {
 	// 17:00.0 VGA compatible controller: NVIDIA Corporation GV102 (rev a1)
 	// b3:00.0 VGA compatible controller: NVIDIA Corporation GV102 (rev a1)
 	resp := &ExecutorServiceGPUHardwareResp{}
-	cmd, err := loadCmdScript(s.commonPath, "lsgpu")
+	script, err := s.renderScript("lsgpu", nil)
 	if err != nil {
 		return resp, err
 	}
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	if err != nil {
 		return resp, err
 	}
-	resp.GPUs = make([]*internal_models.ServerGPU, 0)
-	lines := util.SplitLine(output)
-	matchVGA := func(line string) {
-		line = strings.TrimSpace(line)
-		reg := regexp.MustCompile(`^.*VGA.*controller: (.*)$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 2 {
-			return
-		}
-		log.Printf("LinuxSSHExecutorServiceTemplate GetGPUHardware matchVGA, line=[%s], m=[%+v]", line, m)
-		resp.GPUs = append(resp.GPUs, &internal_models.ServerGPU{
-			Product: &m[1],
-		})
-	}
-	for _, line := range lines {
-		matchVGA(line)
-	}
-
+	s.parseGPUHardware(resp, output)
 	return resp, nil
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) GetMemoryHardware() (*ExecutorServiceMemoryHardwareResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetMemoryHardware(ctx context.Context) (*ExecutorServiceMemoryHardwareResp, *SErr.APIErr) {
 	resp := &ExecutorServiceMemoryHardwareResp{}
-	cmd, err := loadCmdScript(s.commonPath, "meminfo")
+	script, err := s.renderScript("meminfo", nil)
 	if err != nil {
 		return resp, err
 	}
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	if err != nil {
 		return resp, err
 	}
@@ -590,22 +494,35 @@ func (s *LinuxSSHExecutorServiceTemplate) GetMemoryHardware() (*ExecutorServiceM
 	return resp, nil
 }
 
+// defaultBackupRoot 没有配置backup_root时，备份用户home目录的文件夹。
+const defaultBackupRoot = "/backup"
+
+// backupRoot 备份用户home目录的文件夹，每个用户备份到其中的<账户名>.backup。配置的路径不是绝对路径时使用默认值。
+func backupRoot() string {
+	if conf := config.GetConfig(); conf != nil && path.IsAbs(conf.BackupRoot) {
+		return path.Clean(conf.BackupRoot)
+	}
+	return defaultBackupRoot
+}
+
 // GetBackupDir 获取备份文件夹路径。目前，就简单备份到/backup目录下，如果不存在则创建。
-func (s *LinuxSSHExecutorServiceTemplate) GetBackupDir(accountName string) (*ExecutorServiceGetBackupDirResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetBackupDir(ctx context.Context, accountName string) (*ExecutorServiceGetBackupDirResp, *SErr.APIErr) {
 	resp := &ExecutorServiceGetBackupDirResp{}
-	backupDirPath := "/backup"
-	mkdirResp, err := s.implement.MkdirIfNotExists(backupDirPath)
-	resp.Output = mkdirResp.Output
+	backupDirPath := backupRoot()
+	mkdirResp, err := s.implement.MkdirIfNotExists(ctx, backupDirPath)
+	resp.merge(mkdirResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
 	targetDirElem := fmt.Sprintf("%s.backup", accountName)
 	targetDir := path.Join(backupDirPath, targetDirElem)
-	dirExists, err := s.implement.DirExists(targetDir)
+	dirExists, err := s.implement.DirExists(ctx, targetDir)
+	resp.merge(dirExists.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
-	pathExists, err := s.implement.PathExists(targetDir)
+	pathExists, err := s.implement.PathExists(ctx, targetDir)
+	resp.merge(pathExists.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
@@ -616,15 +533,15 @@ func (s *LinuxSSHExecutorServiceTemplate) GetBackupDir(accountName string) (*Exe
 }
 
 // BackupAccountHomeDir 将某用户的用户文件夹mv到一份备份。是模板方法
-func (s *LinuxSSHExecutorServiceTemplate) BackupAccountHomeDir(accountName string) (*ExecutorServiceBackupAccountResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) BackupAccountHomeDir(ctx context.Context, accountName string) (*ExecutorServiceBackupAccountResp, *SErr.APIErr) {
 	resp := &ExecutorServiceBackupAccountResp{}
-	getAccountHomeDirResp, err := s.implement.GetAccountHomeDir(accountName)
-	resp.Output = getAccountHomeDirResp.Output
+	getAccountHomeDirResp, err := s.implement.GetAccountHomeDir(ctx, accountName)
+	resp.merge(getAccountHomeDirResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
-	dirExists, err := s.implement.DirExists(getAccountHomeDirResp.HomeDir)
-	resp.Output = dirExists.Output
+	dirExists, err := s.implement.DirExists(ctx, getAccountHomeDirResp.HomeDir)
+	resp.merge(dirExists.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
@@ -632,8 +549,8 @@ func (s *LinuxSSHExecutorServiceTemplate) BackupAccountHomeDir(accountName strin
 		return resp, SErr.BackupDirNotExists.CustomMessageF("备份用户文件夹时，该用户的home目录文件夹不存在！该目录为%s", getAccountHomeDirResp.HomeDir)
 	}
 
-	getBackupDirResp, err := s.implement.GetBackupDir(accountName)
-	resp.Output = getBackupDirResp.Output
+	getBackupDirResp, err := s.implement.GetBackupDir(ctx, accountName)
+	resp.merge(getBackupDirResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
@@ -641,8 +558,8 @@ func (s *LinuxSSHExecutorServiceTemplate) BackupAccountHomeDir(accountName strin
 	if getBackupDirResp.PathExists {
 		return resp, SErr.BackupTargetDirAlreadyExists.CustomMessageF("备份用户文件夹时，目标的文件夹被占用！该目标路径为：%s", getBackupDirResp.BackupDir)
 	}
-	moveResp, err := s.implement.Move(getAccountHomeDirResp.HomeDir, getBackupDirResp.BackupDir, false)
-	resp.Output = moveResp.Output
+	moveResp, err := s.implement.Move(ctx, getAccountHomeDirResp.HomeDir, getBackupDirResp.BackupDir, false)
+	resp.merge(moveResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
@@ -651,31 +568,31 @@ func (s *LinuxSSHExecutorServiceTemplate) BackupAccountHomeDir(accountName strin
 }
 
 // RecoverAccountHomeDir 恢复备份的用户目录文件夹。调用该函数时需要保证该用户是存在的，否则无法使用GetAccountHomeDir获取到用户的home目录
-func (s *LinuxSSHExecutorServiceTemplate) RecoverAccountHomeDir(accountName string, force bool) (*ExecutorServiceRecoverAccountResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) RecoverAccountHomeDir(ctx context.Context, accountName string, force bool) (*ExecutorServiceRecoverAccountResp, *SErr.APIErr) {
 	resp := &ExecutorServiceRecoverAccountResp{}
-	getAccountHomeDirResp, err := s.implement.GetAccountHomeDir(accountName)
-	resp.Output = getAccountHomeDirResp.Output
+	getAccountHomeDirResp, err := s.implement.GetAccountHomeDir(ctx, accountName)
+	resp.merge(getAccountHomeDirResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
-	pathExistsResp, err := s.implement.PathExists(getAccountHomeDirResp.HomeDir)
-	resp.Output = pathExistsResp.Output
+	pathExistsResp, err := s.implement.PathExists(ctx, getAccountHomeDirResp.HomeDir)
+	resp.merge(pathExistsResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
 	if pathExistsResp.Exists && !force {
 		return resp, SErr.BackupTargetDirAlreadyExists.CustomMessageF("您要恢复到的home文件夹已被占用！请避免覆盖数据！该目标路径为%s", getAccountHomeDirResp.HomeDir)
 	}
-	getBackupDirResp, err := s.implement.GetBackupDir(accountName)
-	resp.Output = getBackupDirResp.Output
+	getBackupDirResp, err := s.implement.GetBackupDir(ctx, accountName)
+	resp.merge(getBackupDirResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
 	if !getBackupDirResp.DirExists {
 		return resp, SErr.BackupDirNotExists.CustomMessageF("恢复用户的目录文件夹时，该备份的文件夹不存在！其路径为：%s", getBackupDirResp.BackupDir)
 	}
-	moveResp, err := s.implement.Move(getBackupDirResp.BackupDir, getAccountHomeDirResp.HomeDir, false)
-	resp.Output = moveResp.Output
+	moveResp, err := s.implement.Move(ctx, getBackupDirResp.BackupDir, getAccountHomeDirResp.HomeDir, false)
+	resp.merge(moveResp.ExecutorServiceRespCommon)
 	if err != nil {
 		return resp, err
 	}
@@ -683,31 +600,295 @@ func (s *LinuxSSHExecutorServiceTemplate) RecoverAccountHomeDir(accountName stri
 	return resp, nil
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) GetRemoteAccessInfos() (*ExecutorServiceRemoteAccessResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetRemoteAccessInfos(ctx context.Context) (*ExecutorServiceRemoteAccessResp, *SErr.APIErr) {
 	resp := &ExecutorServiceRemoteAccessResp{}
 
 	//_, _ = s.SSHConn.SendCommands("export PROCPS_USERLEN=20")
-	cmd, err := loadCmdScript(s.commonPath, "w")
+	script, err := s.renderScript("w", nil)
 	if err != nil {
 		return resp, err
 	}
-	log.Printf("LinuxSSHExecutorServiceTemplate GetRemoteAccessInfos cmd=[%s]", cmd)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	log.Printf("LinuxSSHExecutorServiceTemplate GetRemoteAccessInfos cmd=[%s]", script.LogCmd)
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	log.Printf("LinuxSSHExecutorServiceTemplate GetRemoteAccessInfos w executed, output=[%s], err=[%v]", output, err)
 	if err != nil {
 		return resp, err
 	}
-	matchRemoteAccessing := func(line string) {
-		// root     pts/0    114.254.1.92      6.00s sudo w -s -h
-		line = strings.TrimSpace(line)
-		reg := regexp.MustCompile(`^(\w+)\s+[^\s]+\s+[^\s]+\s+[^\s]+\s+(.*)$`)
-		m := reg.FindStringSubmatch(line)
-		if len(m) < 3 {
-			return
-		}
-		accountName := m[1]
-		what := m[2]
+	s.parseRemoteAccessInfos(resp, output)
+	return resp, nil
+}
+
+// GetGPUUsages 使用nvidia-smi查询每块GPU的使用情况，以及正在使用GPU的进程，目前只支持NVIDIA的GPU。
+// 查询进程失败时只记录在Results中，不影响GPU的使用情况。
+func (s *LinuxSSHExecutorServiceTemplate) GetGPUUsages(ctx context.Context) (*ExecutorServiceGPUUsagesResp, *SErr.APIErr) {
+	resp := &ExecutorServiceGPUUsagesResp{}
+	script, err := s.renderScript("nvidia_gpu_query", nil)
+	if err != nil {
+		return resp, err
+	}
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	if err != nil {
+		return resp, err
+	}
+	resp.GPUs, err = parseNvidiaGPUQuery(output)
+	if err != nil {
+		return resp, err
+	}
+	script, err = s.renderScript("nvidia_compute_apps", nil)
+	if err != nil {
+		return resp, err
+	}
+	output, err = s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
+	s.parseGPUProcesses(resp, output, err)
+	return resp, nil
+}
+
+// parseGPUProcesses 解析nvidia_compute_apps的输出，失败时只打印log。
+func (s *LinuxSSHExecutorServiceTemplate) parseGPUProcesses(resp *ExecutorServiceGPUUsagesResp, output string, err *SErr.APIErr) {
+	if err == nil {
+		resp.Processes, err = parseNvidiaComputeApps(output, resp.GPUs)
+	}
+	if err != nil {
+		log.Printf("LinuxSSHExecutorServiceTemplate=[%s] query gpu processes failed, err=[%s]", s, err.Message)
+	}
+}
+
+// parseTop 解析top -bn1的输出。没有解析出内存使用率时（例如Ubuntu 20.04的top以MiB为单位输出带小数的内存），MemUsage为nil。
+func (s *LinuxSSHExecutorServiceTemplate) parseTop(resp *ExecutorServiceCPUMemProcessesUsagesResp, output string) {
+	resp.ProcessInfos = make([]*internal_models.ServerProcessInfo, 0)
+	resp.CPUMemUsage = &internal_models.ServerCPUMemUsage{
+		UserProcCPUUsage: nil,
+		MemUsage:         nil,
+	}
+	lines := util.SplitLine(output)
+	matchCPU := func(line string) {
+		if resp.CPUMemUsage.UserProcCPUUsage != nil {
+			return
+		}
+		reg := regexp.MustCompile(`^.*Cpu\(s\):\s+([0-9.]+) us.*$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchCPU=[%+v]", util.Pretty(m))
+		f, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchCPU=[%+v], parseFloat failed, err=[%+v]", util.Pretty(m), err)
+			return
+		}
+		resp.CPUMemUsage.UserProcCPUUsage = &f
+	}
+	matchMem := func(line string) {
+		if resp.CPUMemUsage.MemUsage != nil {
+			return
+		}
+		reg := regexp.MustCompile(`^.*Mem.*:.*,\s+([0-9]+) free,\s+([0-9]+) used,\s+([0-9]+) buff/cache.*$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 4 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchMem=[%+v]", util.Pretty(m))
+		free, _ := util.ParseInt(m[1])
+		used, _ := util.ParseInt(m[2])
+		buff, _ := util.ParseInt(m[3])
+		if free == 0 || used == 0 || buff == 0 {
+			log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages matchMem=[%+v], parseInt return 0", m)
+			return
+		}
+		usage := 100 * float64(used) / float64(free+used+buff)
+		total := float64(free + used + buff)
+		resp.CPUMemUsage.MemUsage = &usage
+		totalStr := strconv.Itoa(int(total/1024.)) + "MB"
+		resp.CPUMemUsage.MemTotal = &totalStr
+	}
+	matchProcLine := func(line string) {
+		line = strings.TrimSpace(line)
+		splits := util.SplitSpaces(line)
+		if len(splits) != 12 {
+			if len(resp.ProcessInfos) > 0 {
+				// 如果已经开始分析Proc行的数据了，但是却没有匹配成功，则此行数据可能出现匹配问题，打log看看
+				log.Printf("出现Proc行后，但是匹配失败的：line=[%s]", line)
+			}
+			return
+		}
+		// 228 root      19  -1  174840  69976  58876 S  0.0  3.4   0:19.95 systemd-journal
+		pidStr := splits[0]
+		pidInt, err := util.ParseInt(pidStr)
+		if err != nil {
+			return
+		}
+		pid := uint(pidInt)
+		user := splits[1]
+		cpuUsage := splits[8]
+		cpuUsageF, err := strconv.ParseFloat(cpuUsage, 64)
+		if err != nil {
+			return
+		}
+		memUsage := splits[9]
+		memUsageF, err := strconv.ParseFloat(memUsage, 64)
+		command := splits[11]
+		resp.ProcessInfos = append(resp.ProcessInfos, &internal_models.ServerProcessInfo{
+			PID:              &pid,
+			Command:          &command,
+			OwnerAccountName: &user,
+			CPUUsage:         &cpuUsageF,
+			MemUsage:         &memUsageF,
+		})
+	}
+	for _, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		matchCPU(line)
+		matchMem(line)
+		matchProcLine(line)
+	}
+}
+
+// parseMeminfoUsage 使用/proc/meminfo的输出计算内存使用率。
+func (s *LinuxSSHExecutorServiceTemplate) parseMeminfoUsage(resp *ExecutorServiceCPUMemProcessesUsagesResp, output string) {
+	lines := util.SplitLine(output)
+	totalReg := regexp.MustCompile(`^MemTotal:\s*([0-9]+)\s+kB$`)
+	freeReg := regexp.MustCompile(`^MemFree:\s*([0-9]+)\s+kB$`)
+	var totalMatched string
+	var freeMatched string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		m := totalReg.FindStringSubmatch(line)
+		if len(m) == 2 {
+			totalMatched = m[1]
+		}
+		m = freeReg.FindStringSubmatch(line)
+		if len(m) == 2 {
+			freeMatched = m[1]
+		}
+	}
+	free, _ := util.ParseInt(freeMatched)
+	total, _ := util.ParseInt(totalMatched)
+	if free == 0 || total == 0 {
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUMemProcessesUsages meminfo, parseInt return 0")
+		return
+	}
+	usage := 100 * float64(total-free) / float64(total)
+	resp.CPUMemUsage.MemUsage = &usage
+	totalStr := strconv.Itoa(total/1024) + "MB"
+	resp.CPUMemUsage.MemTotal = &totalStr
+}
+
+// parseCPUHardware 解析lscpu的输出。
+func (s *LinuxSSHExecutorServiceTemplate) parseCPUHardware(resp *ExecutorServiceCPUHardwareResp, output string) {
+	resp.CPU = &internal_models.ServerCPUs{}
+	lines := util.SplitLine(output)
+	matchArch := func(line string) {
+		if resp.CPU.Architecture != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		reg := regexp.MustCompile(`^Architecture:\s+([^\s]+)$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchArch, line=[%s], m=[%+v]", line, m)
+		resp.CPU.Architecture = &m[1]
+	}
+	matchCores := func(line string) {
+		if resp.CPU.Cores != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		reg := regexp.MustCompile(`^CPU\(s\):\s+([^\s]+)$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchCores, line=[%s], m=[%+v]", line, m)
+		cores, err := util.ParseInt(m[1])
+		if err != nil {
+			return
+		}
+		resp.CPU.Cores = &cores
+	}
+	matchThreadsPerCore := func(line string) {
+		if resp.CPU.ThreadsPerCore != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		reg := regexp.MustCompile(`^Thread\(s\) per core:\s+([^\s]+)$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchThreadsPerCore, line=[%s], m=[%+v]", line, m)
+		threadsPerCore, err := util.ParseInt(m[1])
+		if err != nil {
+			return
+		}
+		resp.CPU.ThreadsPerCore = &threadsPerCore
+	}
+	matchModelName := func(line string) {
+		if resp.CPU.ModelName != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		reg := regexp.MustCompile(`^Model name:\s+(.*)$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 2 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetCPUHardware matchModelName, line=[%s], m=[%+v]", line, m)
+		resp.CPU.ModelName = &m[1]
+	}
+	matchers := []func(line string){
+		matchArch,
+		matchCores,
+		matchThreadsPerCore,
+		matchModelName,
+	}
+	for _, line := range lines {
+		for _, matcher := range matchers {
+			matcher(line)
+		}
+	}
+}
+
+// parseGPUHardware 解析lspci | grep VGA的输出，每行的第一列为PCI总线地址。
+func (s *LinuxSSHExecutorServiceTemplate) parseGPUHardware(resp *ExecutorServiceGPUHardwareResp, output string) {
+	resp.GPUs = make([]*internal_models.ServerGPU, 0)
+	lines := util.SplitLine(output)
+	matchVGA := func(line string) {
+		line = strings.TrimSpace(line)
+		reg := regexp.MustCompile(`^(\S+ )?.*VGA.*controller: (.*)$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 3 {
+			return
+		}
+		log.Printf("LinuxSSHExecutorServiceTemplate GetGPUHardware matchVGA, line=[%s], m=[%+v]", line, m)
+		gpu := &internal_models.ServerGPU{
+			Product: &m[2],
+		}
+		if busID := strings.TrimSpace(m[1]); busID != "" {
+			gpu.BusID = &busID
+		}
+		resp.GPUs = append(resp.GPUs, gpu)
+	}
+	for _, line := range lines {
+		matchVGA(line)
+	}
+}
+
+// parseRemoteAccessInfos 解析w -s -h的输出。
+func (s *LinuxSSHExecutorServiceTemplate) parseRemoteAccessInfos(resp *ExecutorServiceRemoteAccessResp, output string) {
+	matchRemoteAccessing := func(line string) {
+		// root     pts/0    114.254.1.92      6.00s sudo w -s -h
+		line = strings.TrimSpace(line)
+		reg := regexp.MustCompile(`^(\w+)\s+[^\s]+\s+[^\s]+\s+[^\s]+\s+(.*)$`)
+		m := reg.FindStringSubmatch(line)
+		if len(m) < 3 {
+			return
+		}
+		accountName := m[1]
+		what := m[2]
 		resp.RemoteAccessingAccountInfos = append(resp.RemoteAccessingAccountInfos, &internal_models.ServerRemoteAccessingAccount{
 			AccountName: accountName,
 			What:        what,
@@ -718,46 +899,45 @@ func (s *LinuxSSHExecutorServiceTemplate) GetRemoteAccessInfos() (*ExecutorServi
 	for _, line := range lines {
 		matchRemoteAccessing(line)
 	}
-	return resp, nil
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) GetGPUUsages() (*ExecutorServiceVoidResp, *SErr.APIErr) {
-	resp := &ExecutorServiceVoidResp{}
-	//gpuHardwareResp, err := s.implement.GetGPUHardware()
-	//if err != nil {
-	//	return resp, err
-	//}
-	//// 目前只写CUDA的。别的GPU写了也没用啊！
-	//isNvidia := false
-	//for _, gpu := range gpuHardwareResp.GPUs {
-	//	if gpu.IsNvidia() {
-	//		isNvidia = true
-	//	}
-	//}
-	//if isNvidia {
-		cmd, err := loadCmdScript(s.commonPath, "nvidia_gpu_name")
-		if err != nil {
-			return resp, err
+// parseAccountList 解析get_account_list的输出。
+func (s *LinuxSSHExecutorServiceTemplate) parseAccountList(resp *ExecutorServiceGetAccountListResp, output string) {
+	// output format: account line by line：UserName|UID|GID
+	reg := regexp.MustCompile(`^(.+?)\|([0-9]+?)\|([0-9]+?)$`)
+
+	lines := util.SplitLine(output)
+	accounts := make([]*internal_models.ServerAccount, 0, 4)
+	for _, line := range lines {
+		log.Printf("LinuxSSHExecutorServiceTemplate=[%s] GetAccountList, line=[%s]", s, line)
+		subs := reg.FindStringSubmatch(line)
+		if len(subs) < 4 {
+			continue
 		}
-		output, _ := s.SSHConn.SendCommands(cmd)
-		gpuNames := output
-		cmd, err = loadCmdScript(s.commonPath, "nvidia_gpu_usage")
+		account := subs[1]
+		uidStr := subs[2]
+		gidStr := subs[3]
+		uid, err := strconv.Atoi(uidStr)
 		if err != nil {
-			return resp, err
+			continue
 		}
-		output, err = s.SSHConn.SendCommands(cmd)
-		resp.Output = fmt.Sprintf("%s\n%s", gpuNames, output)
+		gid, err := strconv.Atoi(gidStr)
 		if err != nil {
-			return resp, err
+			continue
 		}
-		return resp, nil
-	//}
-	//log.Printf("LinuxSSHExecutorServiceTemplate GetGPUUsages no cuda gpu, skip.")
-	//return resp, nil
+		accounts = append(accounts, &internal_models.ServerAccount{
+			Host: s.Host,
+			Port: s.Port,
+			Name: account,
+			UID:  uint(uid),
+			GID:  uint(gid),
+		})
+	}
+	resp.Accounts = accounts
 }
 
-// LoadSudoersLines 查询/etc/sudoers文件，返回去掉注释的每行内容
-func (s *LinuxSSHExecutorServiceTemplate) LoadSudoersLines() (string, []string, *SErr.APIErr) {
+// LoadSudoersLines 查询/etc/sudoers文件，返回去掉注释的每行内容。执行结果记录到rc中。
+func (s *LinuxSSHExecutorServiceTemplate) LoadSudoersLines(ctx context.Context, rc *ExecutorServiceRespCommon) ([]string, *SErr.APIErr) {
 	// 这里给出一个sudoers文件的样例。需要注意的是，要过滤掉注释的行。
 	// #
 	// # This file MUST be edited with the 'visudo' command as root.
@@ -790,15 +970,15 @@ func (s *LinuxSSHExecutorServiceTemplate) LoadSudoersLines() (string, []string,
 	//
 	// #includedir /etc/sudoers.d
 	// someuser ALL=(ALL:ALL) ALL
-	cmd, err := loadCmdScript(s.commonPath, "cat_sudoers")
+	script, err := s.renderScript("cat_sudoers", nil)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	output, err := s.SSHConn.SendCommands(cmd)
+	output, err := s.runScript(ctx, rc, script)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	log.Printf("LinuxSSHExecutorServiceTemplate GetSudoersList cmd=[%s], output=[%s]", cmd, output)
+	log.Printf("LinuxSSHExecutorServiceTemplate GetSudoersList cmd=[%s], output=[%s]", script.LogCmd, output)
 	lines := util.SplitLine(output)
 	validLines := make([]string, 0, 4)
 	for _, line := range lines {
@@ -809,14 +989,14 @@ func (s *LinuxSSHExecutorServiceTemplate) LoadSudoersLines() (string, []string,
 		}
 		validLines = append(validLines, line)
 	}
-	return "", validLines, nil
+	return validLines, nil
 }
 
-// Add2Sudoers 为用户添加到sudo权限
-func (s *LinuxSSHExecutorServiceTemplate) Add2Sudoers(accountName string) (string, *SErr.APIErr) {
-	output, lines, err := s.LoadSudoersLines()
+// Add2Sudoers 为用户添加到sudo权限。执行结果记录到rc中。
+func (s *LinuxSSHExecutorServiceTemplate) Add2Sudoers(ctx context.Context, rc *ExecutorServiceRespCommon, accountName string) *SErr.APIErr {
+	lines, err := s.LoadSudoersLines(ctx, rc)
 	if err != nil {
-		return output, err
+		return err
 	}
 	reg := regexp.MustCompile(`^(.*)?\s+ALL\s*=\s*\(\s*ALL\s*:\s*ALL\s*\)\s*ALL$`)
 	for _, line := range lines {
@@ -827,30 +1007,29 @@ func (s *LinuxSSHExecutorServiceTemplate) Add2Sudoers(accountName string) (strin
 		if m[1] == accountName {
 			// 在已经有的SudoersFile已经找到了该用户，则放弃本次操作
 			log.Printf("LinuxSSHExecutorServiceTemplate=[%s], Add2Sudoers found account already in sudoers file, line=[%s]", s, line)
-			return "", nil
+			return nil
 		}
 	}
 
-	cmd, err := loadCmdScript(s.commonPath, "add_sudoers")
-	cmd = fmt.Sprintf(cmd, accountName)
+	script, err := s.renderScript("add_sudoers", CmdArgs{"account_name": accountName})
 	if err != nil {
-		return "", err
+		return err
 	}
-	output, err = s.SSHConn.SendCommands(cmd)
+	output, err := s.runScript(ctx, rc, script)
 	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], Add2Sudoers output=[%s]", s, output)
 	if err != nil {
-		return output, err
+		return err
 	}
-	return output, nil
+	return nil
 }
 
 // AddAccount 添加一个用户，在该用户有可能是重复的情况下添加。
-func (s *LinuxSSHExecutorServiceTemplate) AddAccount(accountName, pwd string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) AddAccount(ctx context.Context, accountName, pwd string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
 	resp := &ExecutorServiceVoidResp{}
 	if pwd == "" || accountName == "" {
 		panic("LinuxSSHExecutorServiceTemplate AddAccount should have valid param")
 	}
-	//cmd, err := loadCmdScript(s.commonPath, "openssl_pwd_digest")
+	//cmd, err := loadCmdScript(s.OSType, "openssl_pwd_digest")
 	//if err != nil {
 	//	return resp, err
 	//}
@@ -863,7 +1042,7 @@ func (s *LinuxSSHExecutorServiceTemplate) AddAccount(accountName, pwd string) (*
 	//	return resp, err
 	//}
 	// 第二步，使用生成的密码添加用户
-	//cmd, err := loadCmdScript(s.commonPath, "user_add")
+	//cmd, err := loadCmdScript(s.OSType, "user_add")
 	//if err != nil {
 	//	return resp, err
 	//}
@@ -871,20 +1050,20 @@ func (s *LinuxSSHExecutorServiceTemplate) AddAccount(accountName, pwd string) (*
 	//cmd = fmt.Sprintf(cmd, digestPwd, accountName)
 
 	// 使用 user_add_with_openssl_pwd
-	// sudo useradd -s /bin/bash -m -p $(openssl passwd -crypt "%s") "%s"
-	cmd, err := loadCmdScript(s.commonPath, "user_add_with_openssl_pwd")
-	cmd = fmt.Sprintf(cmd, pwd, accountName)
-	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], AddAccount cmd=[%s]", s, cmd)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	// sudo useradd -s /bin/bash -m -p "$(openssl passwd -crypt {{pwd}})" {{account_name}}
+	script, err := s.renderScript("user_add_with_openssl_pwd", CmdArgs{"pwd": pwd, "account_name": accountName})
+	if err != nil {
+		return resp, err
+	}
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], AddAccount cmd=[%s]", s, script.LogCmd)
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	log.Printf("LinuxSSHExecutorServiceTemplate=[%s], AddAccount, user_add cmd output=[%s]", s, output)
 	if err != nil {
 		return resp, err
 	}
 
 	// 第三步，将用户添加sudo权限。
-	output, err = s.Add2Sudoers(accountName)
-	resp.Output = output
+	err = s.Add2Sudoers(ctx, &resp.ExecutorServiceRespCommon, accountName)
 	if err != nil {
 		return resp, err
 	}
@@ -892,63 +1071,30 @@ func (s *LinuxSSHExecutorServiceTemplate) AddAccount(accountName, pwd string) (*
 	return resp, nil
 }
 
-func (s *LinuxSSHExecutorServiceTemplate) GetAccountList() (*ExecutorServiceGetAccountListResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetAccountList(ctx context.Context) (*ExecutorServiceGetAccountListResp, *SErr.APIErr) {
 	resp := &ExecutorServiceGetAccountListResp{}
-	cmd, err := loadCmdScript(s.commonPath, "get_account_list")
+	script, err := s.renderScript("get_account_list", nil)
 	if err != nil {
 		return resp, err
 	}
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] GetAccountList, output=[%s]", s, output)
 	if err != nil {
 		return resp, err
 	}
-	// output format: account line by line：UserName|UID|GID
-	reg := regexp.MustCompile(`^(.+?)\|([0-9]+?)\|([0-9]+?)$`)
-
-	lines := util.SplitLine(output)
-	accounts := make([]*internal_models.ServerAccount, 0, 4)
-	for _, line := range lines {
-		log.Printf("LinuxSSHExecutorServiceTemplate=[%s] GetAccountList, line=[%s]", s, line)
-		subs := reg.FindStringSubmatch(line)
-		if len(subs) < 4 {
-			continue
-		}
-		account := subs[1]
-		uidStr := subs[2]
-		gidStr := subs[3]
-		uid, err := strconv.Atoi(uidStr)
-		if err != nil {
-			continue
-		}
-		gid, err := strconv.Atoi(gidStr)
-		if err != nil {
-			continue
-		}
-		accounts = append(accounts, &internal_models.ServerAccount{
-			Host: s.SSHConn.Host,
-			Port: s.SSHConn.Port,
-			Name: account,
-			UID:  uint(uid),
-			GID:  uint(gid),
-		})
-	}
-	resp.Accounts = accounts
+	s.parseAccountList(resp, output)
 	return resp, nil
 }
 
 // DeleteAccount 删除Linux账户，需注意，删除账户后，使用GetAccountHomeDir会找不到该用户的home目录。所以需要备份的话，需要在删除账户之前做。
-func (s *LinuxSSHExecutorServiceTemplate) DeleteAccount(accountName string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) DeleteAccount(ctx context.Context, accountName string) (*ExecutorServiceVoidResp, *SErr.APIErr) {
 	resp := &ExecutorServiceVoidResp{}
-	cmd, err := loadCmdScript(s.commonPath, "user_del")
+	// sudo userdel {{account_name}}
+	script, err := s.renderScript("user_del", CmdArgs{"account_name": accountName})
 	if err != nil {
 		return resp, err
 	}
-	// userdel %s
-	cmd = fmt.Sprintf(cmd, accountName)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	_, err = s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	if err != nil {
 		return resp, err
 	}
@@ -956,16 +1102,14 @@ func (s *LinuxSSHExecutorServiceTemplate) DeleteAccount(accountName string) (*Ex
 }
 
 // GetAccountHomeDir 获取账户的home目录
-func (s *LinuxSSHExecutorServiceTemplate) GetAccountHomeDir(accountName string) (*ExecutorServiceGetAccountHomeDirResp, *SErr.APIErr) {
+func (s *LinuxSSHExecutorServiceTemplate) GetAccountHomeDir(ctx context.Context, accountName string) (*ExecutorServiceGetAccountHomeDirResp, *SErr.APIErr) {
 	resp := &ExecutorServiceGetAccountHomeDirResp{}
-	cmd, err := loadCmdScript(s.commonPath, "get_user_home_dir")
+	// sudo getent passwd {{account_name}} | cut -d: -f6
+	script, err := s.renderScript("get_user_home_dir", CmdArgs{"account_name": accountName})
 	if err != nil {
 		return resp, err
 	}
-	// getent passwd "%s" | cut -d: -f6
-	cmd = fmt.Sprintf(cmd, accountName)
-	output, err := s.SSHConn.SendCommands(cmd)
-	resp.Output = output
+	output, err := s.runScript(ctx, &resp.ExecutorServiceRespCommon, script)
 	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] GetAccountHomeDir, output=[%s]", s, output)
 	if err != nil {
 		return resp, err
@@ -975,132 +1119,248 @@ func (s *LinuxSSHExecutorServiceTemplate) GetAccountHomeDir(accountName string)
 	return resp, nil
 }
 
+// Close 将连接归还给连接池。不是从连接池中获取的连接则直接关闭。
 func (s *LinuxSSHExecutorServiceTemplate) Close() error {
-	return s.SSHConn.Close()
+	var err error
+	s.closeOnce.Do(func() {
+		if s.release != nil {
+			s.release()
+			return
+		}
+		err = s.runner.Close()
+	})
+	return err
 }
 
+// UbuntuSSHExecutorService Ubuntu特有的脚本放在ubuntu目录下，没有的则依次使用ID_LIKE对应的目录（debian）以及linux_common中的脚本。
 type UbuntuSSHExecutorService struct {
 	*LinuxSSHExecutorServiceTemplate
-	ubuntuPath string
 }
 
 func NewUbuntuSSHExecutorService() *UbuntuSSHExecutorService {
-	pCmdScrPath := config.GetConfig().CmdsScriptsPath
-	ubuntu := path.Join(pCmdScrPath, "ubuntu")
-	return &UbuntuSSHExecutorService{
-		ubuntuPath: ubuntu,
-	}
+	return &UbuntuSSHExecutorService{}
 }
 
+// CentOSSSHExecutorService CentOS特有的脚本放在centos目录下，没有的则使用linux_common中的脚本。
 type CentOSSSHExecutorService struct {
 	*LinuxSSHExecutorServiceTemplate
-	centOSPath string
 }
 
 func NewCentOSSSHExecutorService() *CentOSSSHExecutorService {
-	pCmdScrPath := config.GetConfig().CmdsScriptsPath
-	centos := path.Join(pCmdScrPath, "centos")
-	return &CentOSSSHExecutorService{
-		centOSPath: centos,
-	}
+	return &CentOSSSHExecutorService{}
+}
+
+// DebianSSHExecutorService Debian以及其衍生发行版，特有的脚本放在debian目录下。
+type DebianSSHExecutorService struct {
+	*LinuxSSHExecutorServiceTemplate
+}
+
+func NewDebianSSHExecutorService() *DebianSSHExecutorService {
+	return &DebianSSHExecutorService{}
+}
+
+// RHELSSHExecutorService RHEL、Fedora以及Rocky Linux、AlmaLinux等衍生发行版，特有的脚本放在rhel目录下。
+type RHELSSHExecutorService struct {
+	*LinuxSSHExecutorServiceTemplate
+}
+
+func NewRHELSSHExecutorService() *RHELSSHExecutorService {
+	return &RHELSSHExecutorService{}
+}
+
+// SUSESSHExecutorService openSUSE与SLES，特有的脚本放在suse目录下。
+type SUSESSHExecutorService struct {
+	*LinuxSSHExecutorServiceTemplate
+}
+
+func NewSUSESSHExecutorService() *SUSESSHExecutorService {
+	return &SUSESSHExecutorService{}
 }
 
 // LinuxSSHConnection SSH的底层连接。
+// Password 为sudo时使用的密码，为空时表示该账户是NOPASSWD的sudo用户。
+// HostKeyFingerprint 为建立连接时服务器提供的host key指纹。
 type LinuxSSHConnection struct {
 	*ssh.Client
-	Password string
-	Host     string
-	Port     uint
-	Account  string
+	Password           string
+	Host               string
+	Port               uint
+	Account            string
+	AuthType           daModels.SSHAuthType
+	HostKeyFingerprint string
+
+	// sessionLimiter 限制同一个服务器同时打开的session数量，由连接池设置，为nil时不限制。
+	sessionLimiter chan struct{}
+	closed         int32
+	// jumpClients 经过的跳板机的连接，关闭本连接时一并关闭。
+	jumpClients []*ssh.Client
 }
 
-func openLinuxSSHConnection(Host string, Port uint, account, password string) (*LinuxSSHConnection, *SErr.APIErr) {
+// openLinuxSSHConnection 建立SSH连接，jumps不为空时依次经过其中的每个跳板机。
+// expectedFingerprint 不为空时，服务器提供的host key必须与之一致，否则返回HostKeyMismatchErr；为空时接受任意host key并记录它的指纹。
+func openLinuxSSHConnection(Host string, Port uint, account string, auth *SSHAuth, expectedFingerprint string, jumps []*SSHJumpHop) (*LinuxSSHConnection, *SErr.APIErr) {
+	target := &SSHJumpHop{
+		Host:               Host,
+		Port:               Port,
+		Account:            account,
+		Auth:               auth,
+		HostKeyFingerprint: expectedFingerprint,
+	}
+	conn, jumpClients, presentedFingerprint, err := dialSSHChain(jumps, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinuxSSHConnection{
+		Client:             conn,
+		Password:           auth.Password,
+		Host:               Host,
+		Port:               Port,
+		Account:            account,
+		AuthType:           auth.Type,
+		HostKeyFingerprint: presentedFingerprint,
+		jumpClients:        jumpClients,
+	}, nil
+}
+
+// ScanHostKeyFingerprint 只进行SSH握手，获取服务器当前提供的host key指纹，不对服务器进行认证。
+// jumps不为空时，需要先经过认证连接上每个跳板机。
+func ScanHostKeyFingerprint(Host string, Port uint, jumps []*SSHJumpHop) (string, *SErr.APIErr) {
+	var presentedFingerprint string
 	sshConfig := &ssh.ClientConfig{
-		Timeout: 10 * time.Second,
-		User:    account,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
+		Timeout: sshDialTimeout,
+		User:    "ServerServing",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			presentedFingerprint = ssh.FingerprintSHA256(key)
+			return nil
 		},
-		HostKeyCallback: ssh.HostKeyCallback(func(hostname string, remote net.Addr, key ssh.PublicKey) error { return nil }),
 	}
-
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", Host, Port), sshConfig)
-	if err != nil {
-		return nil, SErr.SSHConnectionErr.CustomMessageF("连接ssh失败！错误信息为%s", err.Error())
+	var via *ssh.Client
+	if len(jumps) > 0 {
+		last := jumps[len(jumps)-1]
+		lastClient, jumpClients, _, err := dialSSHChain(jumps[:len(jumps)-1], last)
+		if err != nil {
+			return "", err.CustomMessageF("获取服务器%s:%d的host key时，连接跳板机失败！错误信息为%s", Host, Port, err.Message)
+		}
+		defer closeSSHClients(append(jumpClients, lastClient))
+		via = lastClient
 	}
-
-	return &LinuxSSHConnection{conn, password, Host, Port, account}, nil
+	conn, err := dialSSHClient(via, net.JoinHostPort(Host, strconv.Itoa(int(Port))), sshConfig)
+	if err == nil {
+		_ = conn.Close()
+	}
+	if presentedFingerprint == "" {
+		// 没有走到host key校验这一步，说明网络不通或者握手失败。
+		return "", SErr.SSHConnectionErr.CustomMessageF("获取服务器%s:%d的host key失败！错误信息为%v", Host, Port, err)
+	}
+	return presentedFingerprint, nil
 }
 
 func (conn *LinuxSSHConnection) String() string {
-	return fmt.Sprintf("LinuxSSHConnection=[Host=%s, Port=%d, ServerAccount=%s, Password=%s]", conn.Host, conn.Port, conn.Account, conn.Password)
+	return fmt.Sprintf("LinuxSSHConnection=[Host=%s, Port=%d, ServerAccount=%s, AuthType=%s]", conn.Host, conn.Port, conn.Account, conn.AuthType)
 }
 
 func (conn *LinuxSSHConnection) Close() error {
-	return conn.Client.Close()
+	atomic.StoreInt32(&conn.closed, 1)
+	err := conn.Client.Close()
+	closeSSHClients(conn.jumpClients)
+	return err
+}
+
+// healthy 连接是否仍然可用。只检查连接是否已经被关闭，真正的探活由sendKeepalive完成。
+func (conn *LinuxSSHConnection) healthy() bool {
+	return atomic.LoadInt32(&conn.closed) == 0
+}
+
+// sendKeepalive 发送一个keepalive请求，在timeout内没有得到回应则认为连接已经失效，并关闭它。
+func (conn *LinuxSSHConnection) sendKeepalive(timeout time.Duration) bool {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			_ = conn.Close()
+			return false
+		}
+		return true
+	case <-time.After(timeout):
+		_ = conn.Close()
+		return false
+	}
 }
 
-// CheckSudoPrivilege 检查该连接的用户是否具有sudo权限。
-func (conn *LinuxSSHConnection) CheckSudoPrivilege() (string, bool, *SErr.APIErr) {
-	commonCmdPath := path.Join(config.GetConfig().CmdsScriptsPath, "linux_common")
-	cmd, err := loadCmdScript(commonCmdPath, "sudo_privilege")
+// checkSudoPrivilege 检查执行命令的用户是否具有sudo权限。
+func checkSudoPrivilege(ctx context.Context, runner commandRunner) (*internal_models.CommandResult, bool, *SErr.APIErr) {
+	// 此时还不知道操作系统类型，只能使用通用的脚本。
+	script, err := renderScript(cmdScriptDirs(Unknown, nil), "sudo_privilege", nil)
 	if err != nil {
-		return "", false, err
+		return nil, false, err
 	}
-	output, err := conn.SendCommands(cmd)
+	result, err := runScriptWith(ctx, runner, script)
 	if err != nil {
-		return output, false, err
+		return result, false, err
 	}
-	return output, true, nil
+	return result, true, nil
 }
 
-// CheckOSInfo 目前只包含操作系统类型，之后可能会针对操作系统版本做改进。
-func (conn *LinuxSSHConnection) CheckOSInfo() (string, LinuxOSType, *SErr.APIErr) {
-	commonCmdPath := path.Join(config.GetConfig().CmdsScriptsPath, "linux_common")
-	cmd, err := loadCmdScript(commonCmdPath, "os_info")
+// checkOSInfo 读取并解析/etc/os-release，发行版类型由OSRelease.LinuxOSType确定，不支持时为Unknown。
+func checkOSInfo(ctx context.Context, runner commandRunner) (*internal_models.CommandResult, *OSRelease, *SErr.APIErr) {
+	// 此时还不知道操作系统类型，只能使用通用的脚本。
+	script, err := renderScript(cmdScriptDirs(Unknown, nil), "os_info", nil)
 	if err != nil {
-		return "", "", err
+		return nil, nil, err
 	}
-	output, err := conn.SendCommands(cmd)
+	result, err := runScriptWith(ctx, runner, script)
 	if err != nil {
-		return output, "", err
+		return result, nil, err
 	}
-	lower := strings.ToLower(output)
-	var osType LinuxOSType
-	if strings.Contains(lower, "ubuntu") {
-		osType = Ubuntu
-	} else if strings.Contains(lower, "centos") {
-		osType = CentOS
-	} else {
-		osType = Unknown
-	}
-	return output, osType, nil
+	return result, ParseOSRelease(result.Stdout), nil
 }
 
-func (conn *LinuxSSHConnection) SendCommandsNoSudo(envs []string, cmds ...string) (string, *SErr.APIErr) {
-	session, err := conn.NewSession()
-	if err != nil {
-		log.Fatal(err)
+// runScriptWith 使用runner执行渲染后的脚本，按脚本名设置超时时间。
+// runner实现了scriptRunner时（例如录制与回放），直接交给它处理，使它能够得到脚本名与参数。
+func runScriptWith(ctx context.Context, runner commandRunner, script *renderedScript) (*internal_models.CommandResult, *SErr.APIErr) {
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout(script.Name))
+	defer cancel()
+	if sr, ok := runner.(scriptRunner); ok {
+		return sr.runScript(ctx, script)
 	}
-	defer func() {
-		_ = session.Close()
-	}()
+	return runner.runCommand(ctx, nil, script.Name, script.Cmd, true)
+}
 
+// SendCommandsNoSudo 直接执行命令，不会向stdin写入sudo密码。
+func (conn *LinuxSSHConnection) SendCommandsNoSudo(ctx context.Context, envs map[string]string, cmds ...string) (*internal_models.CommandResult, *SErr.APIErr) {
 	cmd := strings.Join(cmds, "; ")
-	output, err := session.CombinedOutput(cmd)
-	if err != nil {
-		return string(output), SErr.SSHConnectionErr.CustomMessageF("发送请求后，返回失败信息！失败信息为：%s", err.Error())
-	}
-
-	return string(output), nil
+	return conn.runCommand(ctx, envs, cmd, cmd, false)
 }
 
-func (conn *LinuxSSHConnection) withSession(envs map[string]string, f func(session *ssh.Session)) *SErr.APIErr {
+// withSession 打开一个session并执行f。连接池设置了session数量上限时，会等待直到有空闲的名额或者ctx被取消。
+func (conn *LinuxSSHConnection) withSession(ctx context.Context, envs map[string]string, f func(session *ssh.Session)) *SErr.APIErr {
+	if conn.sessionLimiter != nil {
+		select {
+		case conn.sessionLimiter <- struct{}{}:
+		case <-ctx.Done():
+			return commandContextErr(ctx, "等待空闲的SSH session")
+		}
+		defer func() {
+			<-conn.sessionLimiter
+		}()
+	}
 	session, err := conn.NewSession()
 	if err != nil {
 		log.Printf("LinuxSSHConnection ssh new session failed, err=[%v]", err)
+		if err == io.EOF {
+			// 连接已经断开，关闭它使得连接池不再复用。
+			_ = conn.Close()
+		}
 		return SErr.SSHConnectionErr.CustomMessageF("SSH New Session Failed, err=[%v]", err)
 	}
+	defer func() {
+		_ = session.Close()
+	}()
 	if envs != nil {
 		for key, value := range envs {
 			envErr := session.Setenv(key, value)
@@ -1110,106 +1370,124 @@ func (conn *LinuxSSHConnection) withSession(envs map[string]string, f func(sessi
 		}
 	}
 	f(session)
-	defer func() {
-		_ = session.Close()
-	}()
 	return nil
 }
 
-func (conn *LinuxSSHConnection) SendCommands(cmds ...string) (string, *SErr.APIErr) {
-	var output string
-	var err *SErr.APIErr
-	sessErr := conn.withSession(nil, func(session *ssh.Session) {
-		output, err = conn.sendCommandsWithSession(session, cmds...)
-	})
-	if sessErr != nil {
-		return "", SErr.SSHConnectionErr.CustomMessageF("SSH New Session Failed")
-	}
-	return output, err
-}
-
-func (conn *LinuxSSHConnection) SendCommandsWithEnv(envs map[string]string, cmds ...string) (string, *SErr.APIErr) {
-	var output string
-	var err *SErr.APIErr
-	sessErr := conn.withSession(envs, func(session *ssh.Session) {
-		output, err = conn.sendCommandsWithSession(session, cmds...)
-	})
-	if sessErr != nil {
-		return "", SErr.SSHConnectionErr.CustomMessageF("SSH New Session Failed")
-	}
-	return output, err
-}
-
-func (conn *LinuxSSHConnection) sendCommandsWithSession(session *ssh.Session, cmds ...string) (string, *SErr.APIErr) {
-	modes := ssh.TerminalModes{
-		// ssh.ECHO:          1,     // disable echoing
-		ssh.TTY_OP_ISPEED: 14400, // input speed = 14.4kbaud
-		ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
+// runSession 在session上执行cmd，直到命令结束或者ctx结束。ctx结束时会向远端进程发送KILL信号并关闭session。
+// desc 用于在出错信息中描述正在执行的命令，例如脚本名。
+// 命令正常退出时返回它的退出码（包括非0的退出码），此时error为nil；命令没有正常退出时返回-1以及对应的错误。
+func (conn *LinuxSSHConnection) runSession(ctx context.Context, session *ssh.Session, desc string, cmd string) (int, *SErr.APIErr) {
+	if err := ctx.Err(); err != nil {
+		return -1, commandContextErr(ctx, desc)
 	}
-
-	err := session.RequestPty("xterm", 1600, 1600, modes)
+	err := session.Start(cmd)
 	if err != nil {
-		return "", SErr.SSHConnectionErr.CustomMessageF("发送ssh命令失败！失败信息为：%s", err.Error())
+		return -1, SErr.SSHConnectionErr.CustomMessageF("发送ssh命令失败！命令为：%s，失败信息为：%s", desc, err.Error())
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		// 等待session真正结束，避免读取输出的goroutine泄露。
+		<-done
+		log.Printf("LinuxSSHConnection=[%s] command terminated, desc=[%s], ctxErr=[%v]", conn, desc, ctx.Err())
+		return -1, commandContextErr(ctx, desc)
 	}
-
-	in, err := session.StdinPipe()
 	if err != nil {
-		log.Fatal(err)
+		exitErr := &ssh.ExitError{}
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitStatus(), nil
+		}
+		// 命令没有返回退出码，可能是连接已经断开。探活失败时连接会被关闭，使得连接池不再复用它。
+		conn.sendKeepalive(sshKeepaliveTimeout)
+		return -1, SErr.SSHConnectionErr.CustomMessageF("发送请求后，返回失败信息！命令为：%s，失败信息为：%s", desc, err.Error())
 	}
+	return 0, nil
+}
 
-	out, err := session.StdoutPipe()
-	if err != nil {
-		log.Fatal(err)
+// commandContextErr 根据ctx结束的原因，返回超时或者取消的错误。
+func commandContextErr(ctx context.Context, desc string) *SErr.APIErr {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return commandTimeoutErr(desc)
 	}
+	return SErr.CommandCanceledErr.CustomMessageF("请求已被取消，已终止在服务器上执行的命令！正在执行的命令为：%s", desc)
+}
 
-	var output []byte
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	go func(in io.WriteCloser, out io.Reader, output *[]byte) {
-		defer wg.Done()
-		var (
-			line string
-			r    = bufio.NewReader(out)
-		)
-		for {
-			b, err := r.ReadByte()
-			if err != nil {
-				break
-			}
+// commandTimeoutErr 命令超过超时时间被终止时的错误，desc为正在执行的命令。
+func commandTimeoutErr(desc string) *SErr.APIErr {
+	return SErr.CommandTimeoutErr.CustomMessageF("在服务器上执行命令超时，已终止该命令！正在执行的命令为：%s", desc)
+}
 
-			*output = append(*output, b)
+// commandFailedErr 命令以非0的退出码结束时的错误，附带服务器的标准错误输出，标准错误输出为空时附带标准输出。
+func commandFailedErr(result *internal_models.CommandResult) *SErr.APIErr {
+	output := strings.TrimSpace(result.Stderr)
+	if output == "" {
+		output = strings.TrimSpace(result.Stdout)
+	}
+	return SErr.CommandFailedErr.CustomMessageF("在服务器上执行命令失败！命令为：%s，退出码为：%d，服务器输出为：%s", result.Script, result.ExitStatus, output)
+}
 
-			if b == byte('\n') {
-				line = ""
-				continue
-			}
+func (conn *LinuxSSHConnection) SendCommands(ctx context.Context, cmds ...string) (*internal_models.CommandResult, *SErr.APIErr) {
+	cmd := strings.Join(cmds, "; ")
+	return conn.runCommand(ctx, nil, cmd, cmd, true)
+}
 
-			line += string(b)
+func (conn *LinuxSSHConnection) SendCommandsWithEnv(ctx context.Context, envs map[string]string, cmds ...string) (*internal_models.CommandResult, *SErr.APIErr) {
+	cmd := strings.Join(cmds, "; ")
+	return conn.runCommand(ctx, envs, cmd, cmd, true)
+}
 
-			if strings.HasPrefix(line, "[sudo] password for ") && strings.HasSuffix(line, ": ") {
-				_, err = in.Write([]byte(conn.Password + "\n"))
-				if err != nil {
-					break
-				}
+// sudoPrelude 在命令之前定义sudo函数，使命令中的sudo通过-S从stdin读取密码，从而不需要分配PTY，stdout与stderr也不会混在一起。
+// 不需要密码时（NOPASSWD或者凭据仍在缓存中）不读取stdin；需要密码时从stdin读取一行密码，之后每次调用sudo都通过管道把密码交给它。
+// printf是shell的内建命令，密码不会出现在进程列表中。最后把stdin重定向到/dev/null，避免命令读到多余的输入。
+const sudoPrelude = `if command sudo -n true 2>/dev/null; then sudo() { command sudo -n "$@"; }; ` +
+	`else IFS= read -r __ss_sudo_pwd; sudo() { printf '%s\n' "$__ss_sudo_pwd" | command sudo -S -p '' "$@"; }; fi; ` +
+	`exec </dev/null; `
+
+// runCommand 打开一个session执行cmd，desc用于在结果以及出错信息中描述该命令。
+// withSudo为true时，命令中的sudo会使用该连接的密码。
+// 返回的结果总是不为nil（除非没能打开session），即使命令执行失败，也可以从中得到服务器的输出以及失败原因。
+func (conn *LinuxSSHConnection) runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr) {
+	result := &internal_models.CommandResult{
+		Script:     desc,
+		ExitStatus: -1,
+	}
+	var err *SErr.APIErr
+	sessErr := conn.withSession(ctx, envs, func(session *ssh.Session) {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		var flush func()
+		session.Stdout, session.Stderr, flush = teeOutput(ctx, desc, stdout, stderr)
+		if withSudo {
+			cmd = sudoPrelude + cmd
+			// 没有密码时（例如使用私钥登录却不是NOPASSWD的sudo用户），stdin直接结束，sudo会立即失败，而不是一直等待。
+			if conn.Password != "" {
+				session.Stdin = strings.NewReader(conn.Password + "\n")
+			} else {
+				session.Stdin = strings.NewReader("")
 			}
 		}
-	}(in, out, &output)
-	cmd := strings.Join(cmds, "; ")
-	// bs, err := session.CombinedOutput(cmd)
-	err = session.Run(cmd)
-	if err != nil {
-		return string(output), SErr.SSHConnectionErr.CustomMessageF("发送请求后，返回失败信息！失败信息为：%s，服务器输出为：%s", err.Error(), string(output))
-	}
-	wg.Wait()
-	// remove sudo line
-	outStr := string(output)
-	splits := util.SplitLine(outStr)
-	outs := make([]string, 0, len(splits))
-	for _, str := range splits {
-		if strings.HasPrefix(str, "[sudo] password for ") {
-			continue
-		}
-		outs = append(outs, str)
+		result.StartedAt = time.Now()
+		result.ExitStatus, err = conn.runSession(ctx, session, desc, cmd)
+		result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+		flush()
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+	})
+	if sessErr != nil {
+		result.Error = sessErr.Message
+		return result, sessErr
+	}
+	if err == nil && result.ExitStatus != 0 {
+		err = commandFailedErr(result)
+	}
+	if err != nil {
+		result.Error = err.Message
 	}
-	return strings.Join(outs, "\n"), nil
+	return result, err
 }
@@ -0,0 +1,294 @@
+package server_executor
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"context"
+	"strings"
+	"testing"
+)
+
+// 以下测试连接进程内的testSSHServer，覆盖真实的SSH建连、sudo密码处理以及账户相关的脚本，不需要任何外部机器。
+
+func openTestSSHExecutorService(t *testing.T, param *OpenExecutorServiceParam) ExecutorService {
+	t.Helper()
+	es, err := openLinuxSSHExecutorService(param)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = es.Close()
+	})
+	return es
+}
+
+func TestOpenLinuxSSHExecutorService(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	if _, ok := es.(*UbuntuSSHExecutorService); !ok {
+		t.Fatalf("unexpected executor %s", es)
+	}
+	if es.HostKeyFingerprint() != server.HostKeyFingerprint {
+		t.Fatalf("unexpected fingerprint %s, want %s", es.HostKeyFingerprint(), server.HostKeyFingerprint)
+	}
+	executed := server.Executed()
+	if len(executed) != 2 || executed[0] != "sudo_privilege" || executed[1] != "os_info" {
+		t.Fatalf("unexpected executed scripts %v", executed)
+	}
+
+	ctx := context.Background()
+	cpuResp, err := es.GetCPUHardware(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpuResp.CPU.Cores == nil || *cpuResp.CPU.Cores != 40 || len(cpuResp.Results) != 1 || cpuResp.Results[0].Script != "lscpu" {
+		t.Fatalf("unexpected cpu resp %+v", cpuResp)
+	}
+	usageResp, err := es.GetCPUMemProcessesUsages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usageResp.CPUMemUsage.MemTotal == nil || *usageResp.CPUMemUsage.MemTotal != "128584MB" || len(usageResp.ProcessInfos) == 0 {
+		t.Fatalf("unexpected usage resp %+v", usageResp)
+	}
+
+	// 记录的指纹与服务器不一致时拒绝连接。
+	param := server.OpenParam()
+	param.HostKeyFingerprint = "SHA256:mismatch"
+	if _, err := openLinuxSSHExecutorService(param); err == nil || err.Code != SErr.HostKeyMismatchErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
+
+func TestLinuxSSHConnectionSudo(t *testing.T) {
+	server := newTestSSHServer(t)
+	conn, err := openLinuxSSHConnection(server.Host, server.Port, server.Account, &SSHAuth{Password: server.Password}, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+	// 密码中的单引号不影响通过stdin传给sudo。
+	result, hasSudo, err := checkSudoPrivilege(ctx, conn)
+	if err != nil || !hasSudo || result.Stdout != "Test Sudo\n" {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+	// 没有sudoPrelude时，sudo无法读取密码。
+	result, err = conn.SendCommandsNoSudo(ctx, nil, `sudo echo "Test Sudo"`)
+	if err == nil || err.Code != SErr.CodeCommandFailed || !strings.Contains(result.Stderr, "terminal is required") {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+
+	server.SetSudoPassword("another")
+	result, hasSudo, err = checkSudoPrivilege(ctx, conn)
+	if err == nil || hasSudo || result.ExitStatus != 1 || !strings.Contains(result.Stderr, "incorrect password") {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+	if _, err := openLinuxSSHExecutorService(server.OpenParam()); err == nil || err.Code != SErr.SSHConnectionErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+
+	// 使用私钥登录时没有密码，sudo立即失败而不是一直等待，除非该用户是NOPASSWD的sudo用户。
+	keyParam := server.OpenParam()
+	keyParam.AuthType = daModels.SSHAuthTypePrivateKey
+	keyParam.AdminAccountPwd = ""
+	keyParam.AdminPrivateKey = server.AuthorizeNewKey()
+	if _, err := openLinuxSSHExecutorService(keyParam); err == nil || !strings.Contains(err.Message, "sudo") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	server.SetSudoNoPassword(true)
+	es := openTestSSHExecutorService(t, keyParam)
+	if _, err := es.GetAccountList(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLinuxSSHAccountLifecycle(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	ctx := context.Background()
+
+	if _, err := es.AddAccount(ctx, "bob", "bob'pwd"); err != nil {
+		t.Fatal(err)
+	}
+	listResp, err := es.GetAccountList(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(listResp.Accounts))
+	for _, account := range listResp.Accounts {
+		names = append(names, account.Name)
+	}
+	if strings.Join(names, ",") != "root,ubuntu,alice,bob" || listResp.Accounts[3].UID != 1002 || listResp.Accounts[3].Port != server.Port {
+		t.Fatalf("unexpected accounts %v", names)
+	}
+	if !strings.Contains(server.machine.sudoers, "bob ALL=(ALL:ALL) ALL") {
+		t.Fatalf("bob should be added to sudoers:\n%s", server.machine.sudoers)
+	}
+	if _, err := es.AddAccount(ctx, "bob", "bob'pwd"); err == nil || err.Code != SErr.CodeCommandFailed {
+		t.Fatalf("unexpected err %+v", err)
+	}
+
+	backupResp, err := es.BackupAccountHomeDir(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backupResp.TargetDir != "/backup/bob.backup" || server.machine.dirExists("/home/bob") || !server.machine.dirExists("/backup/bob.backup") {
+		t.Fatalf("unexpected backup resp %+v", backupResp)
+	}
+	if _, err := es.DeleteAccount(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := es.DeleteAccount(ctx, "bob"); err == nil || err.Code != SErr.CodeCommandFailed || !strings.Contains(err.Message, "does not exist") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+
+	// 重新添加账户时useradd会创建新的home目录，恢复备份需要force。
+	if _, err := es.AddAccount(ctx, "bob", "bob'pwd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := es.RecoverAccountHomeDir(ctx, "bob", false); err == nil || err.Code != SErr.BackupTargetDirAlreadyExists.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	recoverResp, err := es.RecoverAccountHomeDir(ctx, "bob", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recoverResp.HomeDir != "/home/bob" || server.machine.dirExists("/backup/bob.backup") {
+		t.Fatalf("unexpected recover resp %+v", recoverResp)
+	}
+	if _, err := es.RecoverAccountHomeDir(ctx, "bob", true); err == nil || err.Code != SErr.BackupDirNotExists.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
+
+func TestLinuxSSHFailureInjection(t *testing.T) {
+	server := newTestSSHServer(t)
+	ctx := context.Background()
+
+	server.RejectAuth(true)
+	if _, err := openLinuxSSHExecutorService(server.OpenParam()); err == nil || err.Code != SErr.SSHAuthFailedErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	server.RejectAuth(false)
+
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	server.Fail("nvidia_gpu_query", 9, "NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver.\n")
+	gpuResp, err := es.GetGPUUsages(ctx)
+	if err == nil || err.Code != SErr.CodeCommandFailed || !strings.Contains(err.Message, "NVIDIA driver") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if last := gpuResp.Results[len(gpuResp.Results)-1]; last.ExitStatus != 9 || last.Succeeded() {
+		t.Fatalf("unexpected result %+v", last)
+	}
+
+	server.DropOn("top")
+	usageResp, err := es.GetCPUMemProcessesUsages(ctx)
+	if err == nil || err.Code != SErr.SSHConnectionErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if len(usageResp.Results) != 1 || usageResp.Results[0].ExitStatus != -1 {
+		t.Fatalf("unexpected results %+v", usageResp.Results)
+	}
+	// 断开的连接不会再被复用，重新打开时会建立新的连接。
+	_ = es.Close()
+	reopened := openTestSSHExecutorService(t, server.OpenParam())
+	if _, err := reopened.GetAccountList(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLinuxSSHJumpHost(t *testing.T) {
+	jump := newTestSSHServer(t)
+	target := newTestSSHServer(t)
+	param := target.OpenParam()
+	param.JumpHops = []*SSHJumpHop{{
+		Host:               jump.Host,
+		Port:               jump.Port,
+		Account:            jump.Account,
+		Auth:               &SSHAuth{Password: jump.Password},
+		HostKeyFingerprint: jump.HostKeyFingerprint,
+	}}
+	es := openTestSSHExecutorService(t, param)
+	if es.HostKeyFingerprint() != target.HostKeyFingerprint {
+		t.Fatalf("unexpected fingerprint %s, want %s", es.HostKeyFingerprint(), target.HostKeyFingerprint)
+	}
+	if _, err := es.GetAccountList(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 跳板机只转发连接，脚本都在目标服务器上执行。
+	if len(jump.Executed()) != 0 || len(target.Executed()) != 3 {
+		t.Fatalf("unexpected executed scripts, jump=%v, target=%v", jump.Executed(), target.Executed())
+	}
+}
+
+func TestOpenLinuxSSHExecutorServiceOSRelease(t *testing.T) {
+	server := newTestSSHServer(t)
+	server.SetFixture("os_info", "NAME=\"Rocky Linux\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.2\"\nPRETTY_NAME=\"Rocky Linux 9.2 (Blue Onyx)\"\n")
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	if _, ok := es.(*RHELSSHExecutorService); !ok {
+		t.Fatalf("unexpected executor %s", es)
+	}
+	if release := es.OSRelease(); release.ID != "rocky" || release.VersionID != "9.2" || release.PrettyName != "Rocky Linux 9.2 (Blue Onyx)" {
+		t.Fatalf("unexpected release %+v", release)
+	}
+
+	// 不支持的发行版在建连时被拒绝，错误信息中带有发行版的名称。
+	archServer := newTestSSHServer(t)
+	archServer.SetFixture("os_info", "NAME=\"Arch Linux\"\nID=arch\nPRETTY_NAME=\"Arch Linux\"\n")
+	if _, err := openLinuxSSHExecutorService(archServer.OpenParam()); err == nil || !strings.Contains(err.Message, "Arch Linux") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
+
+func TestLinuxSSHRunScript(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	ctx := context.Background()
+	content := "# @param dir path\n# @param token secret\ndu -sh {{dir}}\ncurl -H {{token}} https://example.com\n"
+
+	resp, err := es.RunScript(ctx, "script:disk_usage", content, CmdArgs{"dir": "/data", "token": "t'0k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 测试服务器输出解码后的脚本，多行脚本以及参数中的单引号都原样到达服务器。
+	if resp.Output != "du -sh '/data'\ncurl -H 't'\"'\"'0k' https://example.com\n" {
+		t.Fatalf("unexpected output %q", resp.Output)
+	}
+	if strings.Contains(resp.LogCmd, "0k") || resp.Args["token"] != "******" || resp.Args["dir"] != "/data" {
+		t.Fatalf("secret leaked, log cmd %q, args %v", resp.LogCmd, resp.Args)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Script != "script:disk_usage" {
+		t.Fatalf("unexpected results %+v", resp.Results)
+	}
+
+	// 参数或者脚本本身有误时不会在服务器上执行任何命令。
+	executed := len(server.Executed())
+	if _, err := es.RunScript(ctx, "script:disk_usage", content, CmdArgs{"dir": "../etc", "token": "t"}); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if _, err := es.RunScript(ctx, "script:broken", "# @param dir path\nrm -rf /tmp/cache", nil); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if len(server.Executed()) != executed {
+		t.Fatalf("unexpected executed scripts %v", server.Executed())
+	}
+
+	// 直接执行的命令不经过命令模板，其中的{{}}原样保留。
+	resp, err = es.RunCommand(ctx, "command", "docker ps --format '{{.Names}}'")
+	if err != nil || resp.Output != "docker ps --format '{{.Names}}'\n" || resp.Args["command"] != "docker ps --format '{{.Names}}'" {
+		t.Fatalf("unexpected resp %+v, err %+v", resp, err)
+	}
+
+	// 脚本中不是声明过的参数的{{}}同样原样保留。
+	resp, err = es.RunScript(ctx, "script:docker_names", "# @param name string\ndocker ps --filter name={{name}} --format '{{.Names}}'", CmdArgs{"name": "web"})
+	if err != nil || resp.Output != "docker ps --filter name='web' --format '{{.Names}}'\n" {
+		t.Fatalf("unexpected resp %+v, err %+v", resp, err)
+	}
+
+	server.Fail("run_script", 3, "du: cannot access '/data': No such file or directory\n")
+	resp, err = es.RunScript(ctx, "script:disk_usage", content, CmdArgs{"dir": "/data", "token": "t"})
+	if err == nil || err.Code != SErr.CodeCommandFailed || resp.Results[0].ExitStatus != 3 {
+		t.Fatalf("unexpected resp %+v, err %+v", resp, err)
+	}
+}
@@ -0,0 +1,126 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/util"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// openLinuxLocalExecutorService
+// 获取一个在本机执行命令的Executor服务实例，用于管理运行ServerServing的这台机器本身（例如登录节点），而不需要SSH到localhost。
+// 使用与SSH相同的cmds_scripts以及LinuxSSHExecutorServiceTemplate中的解析逻辑，只是命令通过os/exec执行。
+// AdminAccountPwd 作为sudo密码使用，运行ServerServing的用户是NOPASSWD的sudo用户（或者root）时可以为空。
+func openLinuxLocalExecutorService(param *OpenExecutorServiceParam) (ExecutorService, *SErr.APIErr) {
+	runner := &linuxLocalRunner{Password: param.AdminAccountPwd}
+	ctx := context.Background()
+	result, hasSudo, err := checkSudoPrivilege(ctx, runner)
+	log.Printf("linuxLocalRunner CheckSudoPrivilege result=[%s]", util.Pretty(result))
+	if err != nil || !hasSudo {
+		return nil, SErr.SSHConnectionErr.CustomMessageF("运行ServerServing的用户在本机不具有sudo权限！错误信息为：%v", err)
+	}
+	result, release, err := checkOSInfo(ctx, runner)
+	log.Printf("linuxLocalRunner CheckOSInfo result=[%s]", util.Pretty(result))
+	if err != nil {
+		return nil, SErr.SSHConnectionErr.CustomMessageF("检查本机的操作系统类型失败！错误信息为：%s", err.Message)
+	}
+	if release.LinuxOSType() == Unknown {
+		return nil, SErr.SSHConnectionErr.CustomMessageF("本机的操作系统类型为不支持的类型！发行版为：%s（ID=%s，ID_LIKE=%v）", release, release.ID, release.IDLike)
+	}
+	template := newLinuxExecutorServiceTemplate(release.LinuxOSType(), param.Host, param.Port, param.AdminAccountName, param.AdminAccountPwd, runner)
+	template.osRelease = release
+	recordTranscriptIfConfigured(template)
+	auditCommandsIfConfigured(template)
+	return newLinuxExecutorService(template), nil
+}
+
+// linuxLocalRunner 通过os/exec在本机执行命令。
+// Password 为sudo时使用的密码，与SSH连接一样通过sudo -S从stdin传入。
+type linuxLocalRunner struct {
+	Password string
+}
+
+func (r *linuxLocalRunner) String() string {
+	return "linuxLocalRunner"
+}
+
+func (r *linuxLocalRunner) Close() error {
+	return nil
+}
+
+// runCommand 使用/bin/sh -c在本机执行cmd，ctx结束时杀死整个进程组。
+func (r *linuxLocalRunner) runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr) {
+	result := &internal_models.CommandResult{
+		Script:     desc,
+		ExitStatus: -1,
+	}
+	fail := func(err *SErr.APIErr) (*internal_models.CommandResult, *SErr.APIErr) {
+		result.Error = err.Message
+		return result, err
+	}
+	if ctx.Err() != nil {
+		return fail(commandContextErr(ctx, desc))
+	}
+	stdin := ""
+	if withSudo {
+		cmd = sudoPrelude + cmd
+		if r.Password != "" {
+			stdin = r.Password + "\n"
+		}
+	}
+	c := exec.Command("/bin/sh", "-c", cmd)
+	c.Stdin = strings.NewReader(stdin)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	var flush func()
+	c.Stdout, c.Stderr, flush = teeOutput(ctx, desc, stdout, stderr)
+	if len(envs) > 0 {
+		c.Env = os.Environ()
+		for key, value := range envs {
+			c.Env = append(c.Env, fmt.Sprintf("%s=%s", key, value))
+		}
+	}
+	setProcessGroup(c)
+
+	result.StartedAt = time.Now()
+	if err := c.Start(); err != nil {
+		return fail(SErr.InternalErr.CustomMessageF("在本机执行命令失败！命令为：%s，失败信息为：%s", desc, err.Error()))
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := c.Wait()
+		flush()
+		done <- err
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		killProcessGroup(c)
+		<-done
+		result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+		result.Stdout, result.Stderr = stdout.String(), stderr.String()
+		log.Printf("linuxLocalRunner command terminated, desc=[%s], ctxErr=[%v]", desc, ctx.Err())
+		return fail(commandContextErr(ctx, desc))
+	}
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	result.Stdout, result.Stderr = stdout.String(), stderr.String()
+	if err != nil {
+		exitErr := &exec.ExitError{}
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() < 0 {
+			return fail(SErr.InternalErr.CustomMessageF("在本机执行命令失败！命令为：%s，失败信息为：%s", desc, err.Error()))
+		}
+		result.ExitStatus = exitErr.ExitCode()
+		return fail(commandFailedErr(result))
+	}
+	result.ExitStatus = 0
+	return result, nil
+}
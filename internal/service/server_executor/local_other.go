@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package server_executor
+
+import "os/exec"
+
+func setProcessGroup(c *exec.Cmd) {}
+
+func killProcessGroup(c *exec.Cmd) {
+	if c.Process == nil {
+		return
+	}
+	_ = c.Process.Kill()
+}
@@ -0,0 +1,67 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinuxLocalRunner(t *testing.T) {
+	r := &linuxLocalRunner{}
+	result, err := r.runCommand(context.Background(), map[string]string{"SS_TEST": "v"}, "echo", "echo out $SS_TEST; echo err 1>&2", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stdout != "out v\n" || result.Stderr != "err\n" || result.ExitStatus != 0 || !result.Succeeded() {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	result, err = r.runCommand(context.Background(), nil, "exit3", "echo failed 1>&2; exit 3", false)
+	if err == nil || err.Code != SErr.CodeCommandFailed || result.ExitStatus != 3 || result.Stderr != "failed\n" {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err = r.runCommand(ctx, nil, "sleep", "sleep 10 & sleep 10", false)
+	if err == nil || err.Code != SErr.CodeCommandTimeout || result.ExitStatus != -1 || time.Since(start) > 5*time.Second {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+}
+
+func TestLinuxLocalRunnerSudoPassword(t *testing.T) {
+	// 用一个假的sudo验证密码是通过stdin传给sudo -S的。
+	dir := t.TempDir()
+	fakeSudo := `#!/bin/sh
+if [ "$1" = "-n" ]; then echo "sudo: a password is required" 1>&2; exit 1; fi
+shift 3
+IFS= read -r pwd
+[ "$pwd" = "secret" ] || { echo "sudo: incorrect password" 1>&2; exit 1; }
+exec "$@"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "sudo"), []byte(fakeSudo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	envs := map[string]string{"PATH": dir + string(os.PathListSeparator) + os.Getenv("PATH")}
+
+	r := &linuxLocalRunner{Password: "secret"}
+	result, err := r.runCommand(context.Background(), envs, "sudo", "sudo echo 1; sudo echo 2 | cat", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stdout != "1\n2\n" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	r = &linuxLocalRunner{Password: "wrong"}
+	result, err = r.runCommand(context.Background(), envs, "sudo", "sudo echo 1", true)
+	if err == nil || !strings.Contains(result.Stderr, "incorrect password") {
+		t.Fatalf("unexpected result %+v, err %+v", result, err)
+	}
+}
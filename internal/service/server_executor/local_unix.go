@@ -0,0 +1,21 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package server_executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让命令在新的进程组中运行，使得超时时可以连同sudo等子进程一起杀死。
+func setProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(c *exec.Cmd) {
+	if c.Process == nil {
+		return
+	}
+	_ = syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,242 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/util"
+	"encoding/csv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nvidia-smi --query-gpu输出的列名，与cmds_scripts中的nvidia_gpu_query一致。
+const (
+	nvidiaQueryIndex           = "index"
+	nvidiaQueryUUID            = "uuid"
+	nvidiaQueryName            = "name"
+	nvidiaQueryBusID           = "pci.bus_id"
+	nvidiaQueryUtilization     = "utilization.gpu"
+	nvidiaQueryMemoryUsed      = "memory.used"
+	nvidiaQueryMemoryTotal     = "memory.total"
+	nvidiaQueryTemperature     = "temperature.gpu"
+	nvidiaQueryPowerDraw       = "power.draw"
+	nvidiaQueryPowerLimit      = "power.limit"
+	nvidiaQueryFanSpeed        = "fan.speed"
+	nvidiaQueryPersistenceMode = "persistence_mode"
+)
+
+// nvidia-smi --query-compute-apps输出的列名，与cmds_scripts中的nvidia_compute_apps一致。
+// 查询used_memory时，较新的nvidia-smi在表头中输出used_gpu_memory，两者都可以识别。
+const (
+	nvidiaComputeAppPID           = "pid"
+	nvidiaComputeAppGPUUUID       = "gpu_uuid"
+	nvidiaComputeAppUsedMemory    = "used_memory"
+	nvidiaComputeAppUsedGPUMemory = "used_gpu_memory"
+	nvidiaComputeAppsPsSeparator  = "--- ps ---"
+)
+
+// nvidiaCSV nvidia-smi --format=csv,nounits的输出。使用nounits时表头仍然带有单位，例如"memory.used [MiB]"，
+// columns中的列名已经去掉了单位。按表头确定每一列的含义，所以列的顺序可以与脚本中的不同（例如被cmds_scripts_path中的脚本覆盖时）。
+type nvidiaCSV struct {
+	columns map[string]int
+	records [][]string
+}
+
+// readNvidiaCSV 读取nvidia-smi输出的CSV，required为必须存在的列。没有数据时（例如没有GPU）nvidia-smi只输出表头。
+func readNvidiaCSV(output string, required string) (*nvidiaCSV, *SErr.APIErr) {
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, SErr.InternalErr.CustomMessage("解析nvidia-smi的输出失败！输出为空。")
+	}
+	if err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("解析nvidia-smi的输出失败！出错信息为：%s", err)
+	}
+	result := &nvidiaCSV{columns: make(map[string]int, len(header))}
+	for i, name := range header {
+		if idx := strings.Index(name, " ["); idx >= 0 {
+			name = name[:idx]
+		}
+		result.columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := result.columns[required]; !ok {
+		return nil, SErr.InternalErr.CustomMessageF("解析nvidia-smi的输出失败！表头中没有%s，表头为：%s", required, strings.Join(header, ","))
+	}
+	result.records, err = reader.ReadAll()
+	if err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("解析nvidia-smi的输出失败！出错信息为：%s", err)
+	}
+	return result, nil
+}
+
+// value 第i行中column列的值，没有该列或者查询不到时为空字符串。
+func (c *nvidiaCSV) value(i int, column string) string {
+	idx, ok := c.columns[column]
+	if !ok {
+		return ""
+	}
+	return nvidiaQueryValue(c.records[i][idx])
+}
+
+// parseNvidiaGPUQuery 解析nvidia_gpu_query（nvidia-smi --query-gpu）的输出，缺少的列视为查询不到。
+func parseNvidiaGPUQuery(output string) ([]*internal_models.ServerGPUUsage, *SErr.APIErr) {
+	c, err := readNvidiaCSV(output, nvidiaQueryIndex)
+	if err != nil {
+		return nil, err
+	}
+	gpus := make([]*internal_models.ServerGPUUsage, 0, len(c.records))
+	for i, record := range c.records {
+		index, e := strconv.Atoi(c.value(i, nvidiaQueryIndex))
+		if e != nil {
+			return nil, SErr.InternalErr.CustomMessageF("解析nvidia-smi的输出失败！GPU编号不合法，该行为：%s", strings.Join(record, ","))
+		}
+		gpus = append(gpus, &internal_models.ServerGPUUsage{
+			Index:              index,
+			UUID:               c.value(i, nvidiaQueryUUID),
+			Name:               c.value(i, nvidiaQueryName),
+			BusID:              c.value(i, nvidiaQueryBusID),
+			UtilizationPercent: nvidiaQueryFloat(c.value(i, nvidiaQueryUtilization)),
+			MemoryUsedMiB:      nvidiaQueryFloat(c.value(i, nvidiaQueryMemoryUsed)),
+			MemoryTotalMiB:     nvidiaQueryFloat(c.value(i, nvidiaQueryMemoryTotal)),
+			TemperatureC:       nvidiaQueryFloat(c.value(i, nvidiaQueryTemperature)),
+			PowerDrawW:         nvidiaQueryFloat(c.value(i, nvidiaQueryPowerDraw)),
+			PowerLimitW:        nvidiaQueryFloat(c.value(i, nvidiaQueryPowerLimit)),
+			FanSpeedPercent:    nvidiaQueryFloat(c.value(i, nvidiaQueryFanSpeed)),
+			PersistenceMode:    nvidiaQueryBool(c.value(i, nvidiaQueryPersistenceMode)),
+		})
+	}
+	return gpus, nil
+}
+
+// parseNvidiaComputeApps 解析nvidia_compute_apps的输出：先是nvidia-smi --query-compute-apps的CSV，
+// 之后是nvidiaComputeAppsPsSeparator，以及ps -o pid=,user=,args=输出的每个进程的所有者与完整命令行。
+// 查询之后已经退出的进程不在ps的输出中，它的所有者与命令行为nil。gpus用于由GPU的UUID得到GPU编号。
+func parseNvidiaComputeApps(output string, gpus []*internal_models.ServerGPUUsage) ([]*internal_models.ServerGPUProcess, *SErr.APIErr) {
+	appsOutput, psOutput := output, ""
+	if i := strings.Index(output, nvidiaComputeAppsPsSeparator+"\n"); i >= 0 {
+		appsOutput, psOutput = output[:i], output[i+len(nvidiaComputeAppsPsSeparator)+1:]
+	}
+	c, err := readNvidiaCSV(appsOutput, nvidiaComputeAppPID)
+	if err != nil {
+		return nil, err
+	}
+	owners, cmdlines := parsePsOwners(psOutput)
+	indexes := make(map[string]int, len(gpus))
+	for _, gpu := range gpus {
+		indexes[gpu.UUID] = gpu.Index
+	}
+	usedMemoryColumn := nvidiaComputeAppUsedGPUMemory
+	if _, ok := c.columns[usedMemoryColumn]; !ok {
+		usedMemoryColumn = nvidiaComputeAppUsedMemory
+	}
+	processes := make([]*internal_models.ServerGPUProcess, 0, len(c.records))
+	for i, record := range c.records {
+		pid, e := strconv.ParseUint(c.value(i, nvidiaComputeAppPID), 10, 32)
+		if e != nil {
+			return nil, SErr.InternalErr.CustomMessageF("解析nvidia-smi的输出失败！进程号不合法，该行为：%s", strings.Join(record, ","))
+		}
+		process := &internal_models.ServerGPUProcess{
+			PID:              uint(pid),
+			GPUUUID:          c.value(i, nvidiaComputeAppGPUUUID),
+			UsedMemoryMiB:    nvidiaQueryFloat(c.value(i, usedMemoryColumn)),
+			OwnerAccountName: owners[uint(pid)],
+			CommandLine:      cmdlines[uint(pid)],
+		}
+		if index, ok := indexes[process.GPUUUID]; ok {
+			process.GPUIndex = &index
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+// psLineReg ps -o pid=,user=,args=输出的一行，命令行中可以有空格。
+var psLineReg = regexp.MustCompile(`^\s*([0-9]+)\s+(\S+)\s+(.*)$`)
+
+// parsePsOwners 解析ps -o pid=,user=,args=的输出，返回每个进程的所有者与完整命令行。
+func parsePsOwners(output string) (map[uint]*string, map[uint]*string) {
+	owners := make(map[uint]*string)
+	cmdlines := make(map[uint]*string)
+	for _, line := range util.SplitLine(output) {
+		m := psLineReg.FindStringSubmatch(strings.TrimRight(line, " \r"))
+		if len(m) < 4 {
+			continue
+		}
+		pid, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		owner, cmdline := m[2], m[3]
+		owners[uint(pid)] = &owner
+		cmdlines[uint(pid)] = &cmdline
+	}
+	return owners, cmdlines
+}
+
+// nvidiaQueryValue 去掉值两端的空白，nvidia-smi查询不到的值（[N/A]、[Not Supported]、[Unknown Error]等）返回空字符串。
+func nvidiaQueryValue(raw string) string {
+	v := strings.TrimSpace(raw)
+	if v == "N/A" || strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		return ""
+	}
+	return v
+}
+
+func nvidiaQueryFloat(v string) *float64 {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func nvidiaQueryBool(v string) *bool {
+	var b bool
+	switch v {
+	case "Enabled":
+		b = true
+	case "Disabled":
+		b = false
+	default:
+		return nil
+	}
+	return &b
+}
+
+// normalizePCIBusID 将PCI总线地址统一为小写的"域:总线:设备.功能"形式，域为4位十六进制。
+// lspci默认不输出域（例如17:00.0），nvidia-smi输出8位的域（例如00000000:17:00.0），两者需要统一之后才能比较。
+func normalizePCIBusID(busID string) string {
+	busID = strings.ToLower(strings.TrimSpace(busID))
+	if busID == "" {
+		return ""
+	}
+	domain := uint64(0)
+	if parts := strings.SplitN(busID, ":", 3); len(parts) == 3 {
+		d, err := strconv.ParseUint(parts[0], 16, 32)
+		if err != nil {
+			return busID
+		}
+		domain = d
+		busID = parts[1] + ":" + parts[2]
+	}
+	return strconv.FormatUint(domain+0x10000, 16)[1:] + ":" + busID
+}
+
+// JoinGPUUsages 按PCI总线地址将GPU的使用情况填入对应的硬件信息中。没有对应使用情况的GPU（例如非NVIDIA的GPU），Usage为nil。
+func JoinGPUUsages(gpus []*internal_models.ServerGPU, usages []*internal_models.ServerGPUUsage) {
+	byBusID := make(map[string]*internal_models.ServerGPUUsage, len(usages))
+	for _, usage := range usages {
+		if busID := normalizePCIBusID(usage.BusID); busID != "" {
+			byBusID[busID] = usage
+		}
+	}
+	for _, gpu := range gpus {
+		if gpu.BusID == nil {
+			continue
+		}
+		gpu.Usage = byBusID[normalizePCIBusID(*gpu.BusID)]
+	}
+}
@@ -0,0 +1,177 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func readTestdata(t *testing.T, elem ...string) string {
+	bs, err := ioutil.ReadFile(filepath.Join(append([]string{"testdata"}, elem...)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+func TestParseNvidiaGPUQuery(t *testing.T) {
+	gpus, err := parseNvidiaGPUQuery(readTestdata(t, "nvidia_smi", "rtx3090x2.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus) != 2 {
+		t.Fatalf("unexpected gpus %+v", gpus)
+	}
+	gpu := gpus[0]
+	if gpu.Index != 0 || gpu.UUID != "GPU-5d1f6a7e-2c4b-7d1e-9a3f-0b6c2e8f4a11" || gpu.Name != "NVIDIA GeForce RTX 3090" || gpu.BusID != "00000000:17:00.0" {
+		t.Fatalf("unexpected gpu %+v", gpu)
+	}
+	if *gpu.UtilizationPercent != 97 || *gpu.MemoryUsedMiB != 20113 || *gpu.MemoryTotalMiB != 24268 || *gpu.TemperatureC != 71 ||
+		*gpu.PowerDrawW != 301.42 || *gpu.PowerLimitW != 350 || *gpu.FanSpeedPercent != 62 || *gpu.PersistenceMode {
+		t.Fatalf("unexpected gpu %+v", gpu)
+	}
+	if gpus[1].Index != 1 || *gpus[1].UtilizationPercent != 0 || *gpus[1].MemoryUsedMiB != 3 {
+		t.Fatalf("unexpected gpu %+v", gpus[1])
+	}
+
+	// 被动散热的GPU没有风扇，出错的GPU查询不到大部分数据，这些值为nil。
+	gpus, err = parseNvidiaGPUQuery(readTestdata(t, "nvidia_smi", "tesla_t4_passive.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gpus) != 2 || gpus[0].FanSpeedPercent != nil || *gpus[0].PowerLimitW != 70 || !*gpus[0].PersistenceMode {
+		t.Fatalf("unexpected gpu %+v", gpus[0])
+	}
+	if broken := gpus[1]; broken.UtilizationPercent != nil || broken.MemoryUsedMiB != nil || broken.TemperatureC != nil ||
+		broken.PowerDrawW != nil || broken.PowerLimitW != nil || *broken.MemoryTotalMiB != 15109 {
+		t.Fatalf("unexpected gpu %+v", broken)
+	}
+
+	gpus, err = parseNvidiaGPUQuery(readTestdata(t, "nvidia_smi", "no_gpu.csv"))
+	if err != nil || gpus == nil || len(gpus) != 0 {
+		t.Fatalf("unexpected gpus %+v, err %+v", gpus, err)
+	}
+
+	// 按表头确定列，顺序不同或者缺少某些列时也能解析。
+	gpus, err = parseNvidiaGPUQuery("name, index, memory.used [MiB]\nTesla V100-SXM2-32GB, 3, 1024\n")
+	if err != nil || len(gpus) != 1 || gpus[0].Index != 3 || gpus[0].Name != "Tesla V100-SXM2-32GB" || *gpus[0].MemoryUsedMiB != 1024 || gpus[0].MemoryTotalMiB != nil {
+		t.Fatalf("unexpected gpus %+v, err %+v", gpus, err)
+	}
+
+	for _, output := range []string{
+		"",
+		"NVIDIA-SMI has failed because it couldn't communicate with the NVIDIA driver.\n",
+		"index, uuid\n0, GPU-1, extra\n",
+		"index, uuid\nfirst, GPU-1\n",
+	} {
+		if _, err := parseNvidiaGPUQuery(output); err == nil {
+			t.Fatalf("parsing %q should fail", output)
+		}
+	}
+}
+
+func TestParseNvidiaComputeApps(t *testing.T) {
+	gpus, err := parseNvidiaGPUQuery(readTestdata(t, "nvidia_smi", "rtx3090x2.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processes, err := parseNvidiaComputeApps(readTestdata(t, "nvidia_smi", "compute_apps_shared.txt"), gpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processes) != 4 {
+		t.Fatalf("unexpected processes %+v", processes)
+	}
+	if p := processes[0]; p.PID != 184213 || *p.GPUIndex != 0 || *p.UsedMemoryMiB != 20109 || *p.OwnerAccountName != "alice" || *p.CommandLine != "python train.py --epochs 100" {
+		t.Fatalf("unexpected process %+v", p)
+	}
+	// 使用多块GPU的进程在每块GPU上各有一条。
+	if p := processes[2]; p.PID != 190544 || *p.GPUIndex != 1 || *p.UsedMemoryMiB != 1520 || *p.OwnerAccountName != "experiment_runner" || *p.CommandLine != "torchrun --nproc_per_node=2 ddp.py --data /data/imagenet" {
+		t.Fatalf("unexpected process %+v", p)
+	}
+	// 查询之后已经退出的进程。
+	if p := processes[3]; p.UsedMemoryMiB != nil || p.OwnerAccountName != nil || p.CommandLine != nil {
+		t.Fatalf("unexpected process %+v", p)
+	}
+	// 没有GPU信息时不知道GPU编号。
+	processes, err = parseNvidiaComputeApps(readTestdata(t, "nvidia_smi", "compute_apps_shared.txt"), nil)
+	if err != nil || processes[0].GPUIndex != nil {
+		t.Fatalf("unexpected processes %+v, err %+v", processes, err)
+	}
+
+	processes, err = parseNvidiaComputeApps(readTestdata(t, "nvidia_smi", "compute_apps_none.txt"), gpus)
+	if err != nil || processes == nil || len(processes) != 0 {
+		t.Fatalf("unexpected processes %+v, err %+v", processes, err)
+	}
+	// 旧版本的nvidia-smi在表头中输出used_memory。
+	processes, err = parseNvidiaComputeApps("pid, gpu_uuid, used_memory [MiB]\n42, GPU-x, 128\n", gpus)
+	if err != nil || len(processes) != 1 || *processes[0].UsedMemoryMiB != 128 || processes[0].OwnerAccountName != nil {
+		t.Fatalf("unexpected processes %+v, err %+v", processes, err)
+	}
+	if _, err := parseNvidiaComputeApps("pid, gpu_uuid\nabc, GPU-x\n--- ps ---\n", gpus); err == nil {
+		t.Fatal("an invalid pid should fail")
+	}
+}
+
+func TestLinuxSSHGetGPUUsages(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	ctx := context.Background()
+	resp, err := es.GetGPUUsages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GPUs) != 2 || len(resp.Processes) != 1 || *resp.Processes[0].GPUIndex != 0 || *resp.Processes[0].OwnerAccountName != "alice" || len(resp.Results) != 2 {
+		t.Fatalf("unexpected resp %+v", resp)
+	}
+
+	// 查询进程失败时仍然返回GPU的使用情况。
+	server.Fail("nvidia_compute_apps", 6, "No devices were found\n")
+	resp, err = es.GetGPUUsages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GPUs) != 2 || resp.Processes != nil || resp.Results[1].ExitStatus != 6 {
+		t.Fatalf("unexpected resp %+v", resp)
+	}
+}
+
+func TestJoinGPUUsages(t *testing.T) {
+	usages, err := parseNvidiaGPUQuery(readTestdata(t, "nvidia_smi", "rtx3090x2.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := &ExecutorServiceGPUHardwareResp{}
+	(&LinuxSSHExecutorServiceTemplate{}).parseGPUHardware(resp, readTestdata(t, "ssh_server", "lsgpu")+"00:02.0 VGA compatible controller: Cirrus Logic GD 5446\n")
+	if len(resp.GPUs) != 3 || *resp.GPUs[1].BusID != "b3:00.0" {
+		t.Fatalf("unexpected gpus %+v", resp.GPUs)
+	}
+	JoinGPUUsages(resp.GPUs, usages)
+	if resp.GPUs[0].Usage != usages[0] || resp.GPUs[1].Usage != usages[1] || resp.GPUs[2].Usage != nil {
+		t.Fatalf("unexpected joined gpus %+v", resp.GPUs)
+	}
+
+	// 没有总线地址时无法对应。
+	product := "NVIDIA Corporation GA102"
+	gpus := []*internal_models.ServerGPU{{Product: &product}}
+	JoinGPUUsages(gpus, usages)
+	if gpus[0].Usage != nil {
+		t.Fatalf("unexpected joined gpu %+v", gpus[0])
+	}
+}
+
+func TestNormalizePCIBusID(t *testing.T) {
+	for busID, expected := range map[string]string{
+		"17:00.0":           "0000:17:00.0",
+		"0000:B3:00.0":      "0000:b3:00.0",
+		"00000000:B3:00.0":  "0000:b3:00.0",
+		"00000001:3B:00.0 ": "0001:3b:00.0",
+		"":                  "",
+	} {
+		if got := normalizePCIBusID(busID); got != expected {
+			t.Fatalf("normalizePCIBusID(%q) = %q, want %q", busID, got, expected)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package server_executor
+
+import (
+	"strings"
+)
+
+// OSRelease 解析/etc/os-release得到的发行版信息。
+// 参考 https://www.freedesktop.org/software/systemd/man/os-release.html
+type OSRelease struct {
+	// ID 发行版的标识，例如ubuntu、centos、rocky、opensuse-leap。
+	ID string `json:"id"`
+	// IDLike 与之相近的发行版，按相近程度排列，例如Rocky Linux为[rhel centos fedora]。
+	IDLike []string `json:"id_like"`
+	// VersionID 版本号，例如20.04、7。
+	VersionID string `json:"version_id"`
+	// PrettyName 供展示用的名称，例如Ubuntu 20.04.3 LTS。
+	PrettyName string `json:"pretty_name"`
+}
+
+// ParseOSRelease 解析os-release文件的内容。无法识别的行会被忽略，没有ID时ID为linux（与规范中的默认值一致）。
+func ParseOSRelease(content string) *OSRelease {
+	values := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if idx <= 0 {
+			continue
+		}
+		values[strings.TrimSpace(line[:idx])] = unquoteOSReleaseValue(strings.TrimSpace(line[idx+1:]))
+	}
+	release := &OSRelease{
+		ID:         strings.ToLower(values["ID"]),
+		IDLike:     strings.Fields(strings.ToLower(values["ID_LIKE"])),
+		VersionID:  values["VERSION_ID"],
+		PrettyName: values["PRETTY_NAME"],
+	}
+	if release.ID == "" {
+		release.ID = "linux"
+	}
+	return release
+}
+
+// unquoteOSReleaseValue 去掉值两端的单引号或双引号。双引号中的\"、\\、\$、\`为转义。
+func unquoteOSReleaseValue(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1]
+	case value[0] == '"' && value[len(value)-1] == '"':
+		value = value[1 : len(value)-1]
+		b := &strings.Builder{}
+		for i := 0; i < len(value); i++ {
+			if value[i] == '\\' && i+1 < len(value) && strings.IndexByte("\"\\$`", value[i+1]) >= 0 {
+				i++
+			}
+			b.WriteByte(value[i])
+		}
+		return b.String()
+	}
+	return value
+}
+
+// osReleaseFamilies os-release中的ID（以及ID_LIKE中的每一项）与支持的发行版类型的对应关系。
+var osReleaseFamilies = map[string]LinuxOSType{
+	"ubuntu":   Ubuntu,
+	"debian":   Debian,
+	"centos":   CentOS,
+	"rhel":     RHEL,
+	"fedora":   RHEL,
+	"suse":     SUSE,
+	"opensuse": SUSE,
+	"sles":     SUSE,
+}
+
+// candidates 依次为ID以及ID_LIKE中的每一项。
+func (r *OSRelease) candidates() []string {
+	return append([]string{r.ID}, r.IDLike...)
+}
+
+// LinuxOSType 依次使用ID以及ID_LIKE中的每一项匹配支持的发行版类型，使得衍生发行版（例如Rocky Linux、Linux Mint）不需要额外的配置。
+func (r *OSRelease) LinuxOSType() LinuxOSType {
+	for _, id := range r.candidates() {
+		if osType, ok := osReleaseFamilies[id]; ok {
+			return osType
+		}
+	}
+	return Unknown
+}
+
+// String 用于日志以及错误信息。
+func (r *OSRelease) String() string {
+	if r.PrettyName != "" {
+		return r.PrettyName
+	}
+	return strings.TrimSpace(r.ID + " " + r.VersionID)
+}
+
+// cmdScriptDirs 查找脚本时依次使用的目录：ID、ID_LIKE中的每一项、发行版类型，最后为linux_common。
+// release为nil时（例如回放录制记录）只使用发行版类型的目录。
+func cmdScriptDirs(osType LinuxOSType, release *OSRelease) []string {
+	dirs := make([]string, 0, 4)
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	if release != nil {
+		for _, id := range release.candidates() {
+			add(id)
+		}
+	}
+	add(string(osType))
+	add(commonCmdScriptDir)
+	return dirs
+}
@@ -0,0 +1,94 @@
+package server_executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	cases := []struct {
+		name       string
+		content    string
+		id         string
+		idLike     string
+		versionID  string
+		prettyName string
+		osType     LinuxOSType
+	}{
+		{
+			name:    "ubuntu",
+			content: "NAME=\"Ubuntu\"\nVERSION=\"20.04.3 LTS (Focal Fossa)\"\nID=ubuntu\nID_LIKE=debian\nPRETTY_NAME=\"Ubuntu 20.04.3 LTS\"\nVERSION_ID=\"20.04\"\n",
+			id:      "ubuntu", idLike: "debian", versionID: "20.04", prettyName: "Ubuntu 20.04.3 LTS", osType: Ubuntu,
+		},
+		{
+			name:    "centos",
+			content: "NAME=\"CentOS Linux\"\nVERSION=\"7 (Core)\"\nID=\"centos\"\nID_LIKE=\"rhel fedora\"\nVERSION_ID=\"7\"\nPRETTY_NAME=\"CentOS Linux 7 (Core)\"\n",
+			id:      "centos", idLike: "rhel fedora", versionID: "7", prettyName: "CentOS Linux 7 (Core)", osType: CentOS,
+		},
+		{
+			name:    "debian",
+			content: "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nNAME=\"Debian GNU/Linux\"\nVERSION_ID=\"12\"\nID=debian\n",
+			id:      "debian", idLike: "", versionID: "12", prettyName: "Debian GNU/Linux 12 (bookworm)", osType: Debian,
+		},
+		{
+			name:    "rocky",
+			content: "NAME=\"Rocky Linux\"\nVERSION=\"9.2 (Blue Onyx)\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.2\"\nPRETTY_NAME=\"Rocky Linux 9.2 (Blue Onyx)\"\n",
+			id:      "rocky", idLike: "rhel centos fedora", versionID: "9.2", prettyName: "Rocky Linux 9.2 (Blue Onyx)", osType: RHEL,
+		},
+		{
+			name:    "almalinux",
+			content: "NAME=\"AlmaLinux\"\nID=\"almalinux\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"8.8\"\nPRETTY_NAME=\"AlmaLinux 8.8 (Sapphire Caracal)\"\n",
+			id:      "almalinux", idLike: "rhel centos fedora", versionID: "8.8", prettyName: "AlmaLinux 8.8 (Sapphire Caracal)", osType: RHEL,
+		},
+		{
+			name:    "opensuse",
+			content: "NAME=\"openSUSE Leap\"\nVERSION=\"15.5\"\nID=\"opensuse-leap\"\nID_LIKE=\"suse opensuse\"\nVERSION_ID=\"15.5\"\nPRETTY_NAME=\"openSUSE Leap 15.5\"\n",
+			id:      "opensuse-leap", idLike: "suse opensuse", versionID: "15.5", prettyName: "openSUSE Leap 15.5", osType: SUSE,
+		},
+		{
+			name:    "mint",
+			content: "NAME=\"Linux Mint\"\nID=linuxmint\nID_LIKE=\"ubuntu debian\"\nPRETTY_NAME=\"Linux Mint 21.2\"\nVERSION_ID=\"21.2\"\n",
+			id:      "linuxmint", idLike: "ubuntu debian", versionID: "21.2", prettyName: "Linux Mint 21.2", osType: Ubuntu,
+		},
+		{
+			name:    "quoted and commented",
+			content: "# comment\n\nID='arch'\nPRETTY_NAME=\"Arch \\\"Linux\\\"\"\nnot a pair\n",
+			id:      "arch", idLike: "", versionID: "", prettyName: "Arch \"Linux\"", osType: Unknown,
+		},
+		{
+			name:    "empty",
+			content: "",
+			id:      "linux", idLike: "", versionID: "", prettyName: "", osType: Unknown,
+		},
+	}
+	for _, c := range cases {
+		release := ParseOSRelease(c.content)
+		if release.ID != c.id || strings.Join(release.IDLike, " ") != c.idLike || release.VersionID != c.versionID || release.PrettyName != c.prettyName {
+			t.Fatalf("%s: unexpected release %+v", c.name, release)
+		}
+		if release.LinuxOSType() != c.osType {
+			t.Fatalf("%s: unexpected os type %s, want %s", c.name, release.LinuxOSType(), c.osType)
+		}
+	}
+}
+
+func TestCmdScriptDirs(t *testing.T) {
+	rocky := ParseOSRelease("ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\n")
+	if dirs := strings.Join(cmdScriptDirs(rocky.LinuxOSType(), rocky), ","); dirs != "rocky,rhel,centos,fedora,linux_common" {
+		t.Fatalf("unexpected dirs %s", dirs)
+	}
+	if dirs := strings.Join(cmdScriptDirs(Ubuntu, nil), ","); dirs != "ubuntu,linux_common" {
+		t.Fatalf("unexpected dirs %s", dirs)
+	}
+
+	// openSUSE通过ID_LIKE使用suse目录下的lsgpu，其它脚本使用linux_common中的。
+	opensuse := ParseOSRelease("ID=\"opensuse-leap\"\nID_LIKE=\"suse opensuse\"\n")
+	script, err := renderScript(cmdScriptDirs(opensuse.LinuxOSType(), opensuse), "lsgpu", nil)
+	if err != nil || !strings.HasPrefix(script.Cmd, "/sbin/lspci") {
+		t.Fatalf("unexpected script %+v, err %+v", script, err)
+	}
+	script, err = renderScript(cmdScriptDirs(Ubuntu, nil), "lsgpu", nil)
+	if err != nil || !strings.HasPrefix(script.Cmd, "lspci") {
+		t.Fatalf("unexpected script %+v, err %+v", script, err)
+	}
+}
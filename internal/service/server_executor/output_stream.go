@@ -0,0 +1,112 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// OutputListener 接收命令执行过程中输出的每一行，stdout与stderr会在不同的goroutine中调用它，需要能够并发调用。
+type OutputListener func(line *internal_models.CommandOutputLine)
+
+type outputListenerKey struct{}
+
+// WithOutputListener 返回一个带有listener的ctx。使用它执行命令时，命令的输出在保存到结果中的同时，每输出一行就交给listener一次，
+// 从而可以在命令结束之前看到执行进度。
+func WithOutputListener(ctx context.Context, listener OutputListener) context.Context {
+	return context.WithValue(ctx, outputListenerKey{}, listener)
+}
+
+// withOutputHost ctx中有listener时，使交给它的每一行都带有执行命令的服务器。
+func withOutputHost(ctx context.Context, host string, port uint) context.Context {
+	listener := outputListenerOf(ctx)
+	if listener == nil {
+		return ctx
+	}
+	return WithOutputListener(ctx, func(line *internal_models.CommandOutputLine) {
+		line.Host, line.Port = host, port
+		listener(line)
+	})
+}
+
+// outputListenerOf 获取ctx中的listener，没有时返回nil。
+func outputListenerOf(ctx context.Context) OutputListener {
+	listener, _ := ctx.Value(outputListenerKey{}).(OutputListener)
+	return listener
+}
+
+// outputLineWriter 将写入的内容按行交给listener，最后一行没有换行符时在Flush时交给listener。
+type outputLineWriter struct {
+	script   string
+	stream   string
+	listener OutputListener
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func newOutputLineWriter(script, stream string, listener OutputListener) *outputLineWriter {
+	return &outputLineWriter{
+		script:   script,
+		stream:   stream,
+		listener: listener,
+	}
+}
+
+func (w *outputLineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *outputLineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *outputLineWriter) emit(line []byte) {
+	w.listener(&internal_models.CommandOutputLine{
+		Script: w.script,
+		Stream: w.stream,
+		Line:   string(bytes.TrimSuffix(line, []byte("\r"))),
+	})
+}
+
+// teeOutput ctx中有listener时，返回同时写入stdout、stderr以及按行交给listener的writer，以及在命令结束后调用的flush；
+// 没有listener时原样返回stdout与stderr。
+func teeOutput(ctx context.Context, script string, stdout, stderr io.Writer) (io.Writer, io.Writer, func()) {
+	listener := outputListenerOf(ctx)
+	if listener == nil {
+		return stdout, stderr, func() {}
+	}
+	stdoutLines := newOutputLineWriter(script, internal_models.OutputStreamStdout, listener)
+	stderrLines := newOutputLineWriter(script, internal_models.OutputStreamStderr, listener)
+	flush := func() {
+		stdoutLines.Flush()
+		stderrLines.Flush()
+	}
+	return io.MultiWriter(stdout, stdoutLines), io.MultiWriter(stderr, stderrLines), flush
+}
+
+// emitOutput 将已经得到的完整输出按行交给ctx中的listener，用于回放等不实际执行命令的场景。
+func emitOutput(ctx context.Context, script, stdout, stderr string) {
+	stdoutW, stderrW, flush := teeOutput(ctx, script, io.Discard, io.Discard)
+	_, _ = io.WriteString(stdoutW, stdout)
+	_, _ = io.WriteString(stderrW, stderr)
+	flush()
+}
@@ -0,0 +1,79 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// collectOutput 返回收集输出的listener以及获取已收集的输出的函数。
+func collectOutput() (OutputListener, func() []internal_models.CommandOutputLine) {
+	mu := sync.Mutex{}
+	var lines []internal_models.CommandOutputLine
+	listener := func(line *internal_models.CommandOutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, *line)
+	}
+	return listener, func() []internal_models.CommandOutputLine {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]internal_models.CommandOutputLine{}, lines...)
+	}
+}
+
+func TestOutputLineWriter(t *testing.T) {
+	listener, collected := collectOutput()
+	w := newOutputLineWriter("backup", internal_models.OutputStreamStdout, listener)
+	for _, chunk := range []string{"copy", "ing 1/3\r\n", "copying 2/3\n\ncopy", "ing 3/3"} {
+		_, _ = w.Write([]byte(chunk))
+	}
+	if got := len(collected()); got != 3 {
+		t.Fatalf("unexpected lines before flush %v", collected())
+	}
+	w.Flush()
+	w.Flush()
+	expected := []internal_models.CommandOutputLine{
+		{Script: "backup", Stream: internal_models.OutputStreamStdout, Line: "copying 1/3"},
+		{Script: "backup", Stream: internal_models.OutputStreamStdout, Line: "copying 2/3"},
+		{Script: "backup", Stream: internal_models.OutputStreamStdout, Line: ""},
+		{Script: "backup", Stream: internal_models.OutputStreamStdout, Line: "copying 3/3"},
+	}
+	if !reflect.DeepEqual(collected(), expected) {
+		t.Fatalf("unexpected lines %v", collected())
+	}
+}
+
+func TestLinuxSSHStreamOutput(t *testing.T) {
+	server := newTestSSHServer(t)
+	param := server.OpenParam()
+	es := openTestSSHExecutorService(t, param)
+	listener, collected := collectOutput()
+	ctx := WithOutputListener(context.Background(), listener)
+
+	// 测试服务器输出解码后的脚本本身，每一行都应该带着服务器的地址按顺序交给listener，与结果中的输出一致。
+	resp, err := es.RunCommand(ctx, "command", "echo 1\necho 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []internal_models.CommandOutputLine{
+		{Host: param.Host, Port: param.Port, Script: "command", Stream: internal_models.OutputStreamStdout, Line: "echo 1"},
+		{Host: param.Host, Port: param.Port, Script: "command", Stream: internal_models.OutputStreamStdout, Line: "echo 2"},
+	}
+	if !reflect.DeepEqual(collected(), expected) || resp.Output != "echo 1\necho 2\n" {
+		t.Fatalf("unexpected lines %v, output %q", collected(), resp.Output)
+	}
+
+	listener, collected = collectOutput()
+	ctx = WithOutputListener(context.Background(), listener)
+	server.Fail("run_script", 2, "rsync: connection reset\n")
+	if _, err := es.RunCommand(ctx, "command", "rsync -a /home/a /backup"); err == nil {
+		t.Fatal("command should fail")
+	}
+	expected = []internal_models.CommandOutputLine{{Host: param.Host, Port: param.Port, Script: "command", Stream: internal_models.OutputStreamStderr, Line: "rsync: connection reset"}}
+	if !reflect.DeepEqual(collected(), expected) {
+		t.Fatalf("unexpected lines %v", collected())
+	}
+}
@@ -0,0 +1,150 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"ServerServing/util"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// cpu_stat的输出中各部分之间的分隔行。分隔行之前为第一次采样的/proc/stat。
+const (
+	cpuStatSeparatorStat    = "--- stat ---"
+	cpuStatSeparatorLoadavg = "--- loadavg ---"
+	cpuStatSeparatorUptime  = "--- uptime ---"
+)
+
+// cpuTimes /proc/stat中一行cpu的各类时间，单位为USER_HZ。
+// 顺序为user nice system idle iowait irq softirq steal guest guest_nice，较旧的内核没有后面几列，视为0。
+type cpuTimes [10]uint64
+
+func (t cpuTimes) user() uint64   { return t[0] + t[1] }
+func (t cpuTimes) system() uint64 { return t[2] + t[5] + t[6] }
+func (t cpuTimes) idle() uint64   { return t[3] }
+func (t cpuTimes) iowait() uint64 { return t[4] }
+func (t cpuTimes) steal() uint64  { return t[7] }
+
+// total guest与guest_nice已经计入user与nice中，不重复计算。
+func (t cpuTimes) total() uint64 {
+	return t.user() + t.system() + t.idle() + t.iowait() + t.steal()
+}
+
+// parseProcStatCPUs 解析/proc/stat中cpu开头的行，返回按出现顺序排列的名称以及每个cpu的时间。
+func parseProcStatCPUs(output string) ([]string, map[string]cpuTimes) {
+	names := make([]string, 0)
+	times := make(map[string]cpuTimes)
+	for _, line := range util.SplitLine(output) {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		var t cpuTimes
+		ok := true
+		for i, field := range fields[1:] {
+			if i >= len(t) {
+				break
+			}
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				ok = false
+				break
+			}
+			t[i] = v
+		}
+		if !ok {
+			continue
+		}
+		names = append(names, fields[0])
+		times[fields[0]] = t
+	}
+	return names, times
+}
+
+// cpuStatBetween 计算两次采样之间的CPU使用率，两次采样的时间没有变化时返回nil。
+func cpuStatBetween(name string, before, after cpuTimes) *internal_models.ServerCPUStat {
+	// 计数器只会增加，CPU离线后重新上线时可能被重置，此时无法计算。
+	for i := range after {
+		if after[i] < before[i] {
+			return nil
+		}
+	}
+	total := after.total() - before.total()
+	if total == 0 {
+		return nil
+	}
+	percent := func(v uint64) float64 {
+		return 100 * float64(v) / float64(total)
+	}
+	idle := after.idle() - before.idle() + after.iowait() - before.iowait()
+	return &internal_models.ServerCPUStat{
+		CPU:    name,
+		Total:  percent(total - idle),
+		User:   percent(after.user() - before.user()),
+		System: percent(after.system() - before.system()),
+		IOWait: percent(after.iowait() - before.iowait()),
+		Steal:  percent(after.steal() - before.steal()),
+	}
+}
+
+// parseCPUStat 解析cpu_stat的输出：两次采样的/proc/stat，以及/proc/loadavg与/proc/uptime。
+// 无法解析的部分保持为nil，只打印log，不影响top的结果。
+func (s *LinuxSSHExecutorServiceTemplate) parseCPUStat(usage *internal_models.ServerCPUMemUsage, output string) {
+	sections := make(map[string]string)
+	current := ""
+	for _, line := range strings.Split(output, "\n") {
+		switch line = strings.TrimSpace(line); line {
+		case cpuStatSeparatorStat, cpuStatSeparatorLoadavg, cpuStatSeparatorUptime:
+			current = line
+			continue
+		}
+		sections[current] += line + "\n"
+	}
+
+	_, before := parseProcStatCPUs(sections[""])
+	names, after := parseProcStatCPUs(sections[cpuStatSeparatorStat])
+	usage.CPUStats = make([]*internal_models.ServerCPUStat, 0, len(names))
+	for _, name := range names {
+		b, ok := before[name]
+		if !ok {
+			continue
+		}
+		stat := cpuStatBetween(name, b, after[name])
+		if stat == nil {
+			continue
+		}
+		if name == "cpu" {
+			usage.CPUStat = stat
+			userUsage := stat.User
+			usage.UserProcCPUUsage = &userUsage
+			continue
+		}
+		usage.CPUStats = append(usage.CPUStats, stat)
+	}
+	if usage.CPUStat == nil {
+		log.Printf("LinuxSSHExecutorServiceTemplate=[%s] parseCPUStat no cpu line in /proc/stat samples, output=[%s]", s, output)
+	}
+
+	// /proc/loadavg: 1.32 1.18 1.05 2/1324 201877
+	if fields := strings.Fields(sections[cpuStatSeparatorLoadavg]); len(fields) >= 3 {
+		loads := make([]float64, 3)
+		ok := true
+		for i := range loads {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				ok = false
+				break
+			}
+			loads[i] = v
+		}
+		if ok {
+			usage.LoadAverage = &internal_models.ServerLoadAverage{Load1: loads[0], Load5: loads[1], Load15: loads[2]}
+		}
+	}
+	// /proc/uptime: 1987874.52 78261342.10，第一个为开机时长。
+	if fields := strings.Fields(sections[cpuStatSeparatorUptime]); len(fields) >= 1 {
+		if uptime, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			usage.UptimeSeconds = &uptime
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"context"
+	"math"
+	"testing"
+)
+
+func TestParseCPUStat(t *testing.T) {
+	expectStat := func(stat *internal_models.ServerCPUStat, cpu string, total, user, system, iowait, steal float64) {
+		t.Helper()
+		near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+		if stat == nil || stat.CPU != cpu || !near(stat.Total, total) || !near(stat.User, user) || !near(stat.System, system) || !near(stat.IOWait, iowait) || !near(stat.Steal, steal) {
+			t.Fatalf("unexpected stat %+v, want %s total=%v user=%v system=%v iowait=%v steal=%v", stat, cpu, total, user, system, iowait, steal)
+		}
+	}
+	s := &LinuxSSHExecutorServiceTemplate{}
+
+	usage := &internal_models.ServerCPUMemUsage{}
+	s.parseCPUStat(usage, readTestdata(t, "proc_stat", "vm_steal_4cores.txt"))
+	expectStat(usage.CPUStat, "cpu", 38.5, 25, 3.5, 10, 10)
+	if len(usage.CPUStats) != 4 || *usage.UserProcCPUUsage != 25 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	expectStat(usage.CPUStats[0], "cpu0", 80, 60, 10, 0, 10)
+	// user包含nice，system包含softirq。
+	expectStat(usage.CPUStats[1], "cpu1", 44, 40, 4, 0, 0)
+	expectStat(usage.CPUStats[2], "cpu2", 0, 0, 0, 40, 0)
+	expectStat(usage.CPUStats[3], "cpu3", 30, 0, 0, 0, 30)
+	if la := usage.LoadAverage; la == nil || la.Load1 != 2.41 || la.Load5 != 1.97 || la.Load15 != 1.60 {
+		t.Fatalf("unexpected load average %+v", la)
+	}
+	if *usage.UptimeSeconds != 136235.41 {
+		t.Fatalf("unexpected uptime %v", *usage.UptimeSeconds)
+	}
+
+	// 较旧的内核没有steal等列。
+	usage = &internal_models.ServerCPUMemUsage{}
+	s.parseCPUStat(usage, readTestdata(t, "proc_stat", "old_kernel_2cores.txt"))
+	expectStat(usage.CPUStat, "cpu", 51, 26, 25, 0, 0)
+	expectStat(usage.CPUStats[0], "cpu0", 100, 50, 50, 0, 0)
+	expectStat(usage.CPUStats[1], "cpu1", 2, 2, 0, 0, 0)
+
+	// 无法解析时保留top的结果。
+	topUsage := 2.6
+	usage = &internal_models.ServerCPUMemUsage{UserProcCPUUsage: &topUsage}
+	s.parseCPUStat(usage, "cpu  1 2 3 4 5 6 7 8 0 0\n--- stat ---\ncpu  1 2 3 4 5 6 7 8 0 0\n--- loadavg ---\n--- uptime ---\n")
+	if usage.CPUStat != nil || len(usage.CPUStats) != 0 || *usage.UserProcCPUUsage != topUsage || usage.LoadAverage != nil || usage.UptimeSeconds != nil {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	// 计数器被重置的核被跳过。
+	usage = &internal_models.ServerCPUMemUsage{}
+	s.parseCPUStat(usage, "cpu  10 0 0 10 0 0 0 0\ncpu0 10 0 0 10\n--- stat ---\ncpu  20 0 0 20 0 0 0 0\ncpu0 1 0 0 1\n")
+	if usage.CPUStat == nil || len(usage.CPUStats) != 0 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+}
+
+func TestLinuxSSHCPUStat(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	ctx := context.Background()
+	resp, err := es.GetCPUMemProcessesUsages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := resp.CPUMemUsage; usage.CPUStat == nil || len(usage.CPUStats) != 40 || usage.LoadAverage.Load1 != 1.32 || usage.UptimeSeconds == nil || len(resp.ProcessInfos) == 0 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+
+	// cpu_stat失败时保留top的结果。
+	server.Fail("cpu_stat", 2, "grep: /proc/stat: No such file or directory\n")
+	resp, err = es.GetCPUMemProcessesUsages(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := resp.CPUMemUsage; usage.CPUStat != nil || usage.UserProcCPUUsage == nil || *usage.UserProcCPUUsage != 2.6 || resp.Results[len(resp.Results)-1].ExitStatus != 2 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+}
@@ -0,0 +1,161 @@
+package server_executor
+
+import (
+	"ServerServing/config"
+	SErr "ServerServing/err"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReachabilityFailureThreshold = 2
+	defaultReachabilityBaseCooldown     = 15 * time.Second
+	defaultReachabilityMaxCooldown      = 10 * time.Minute
+)
+
+// ServerStatus 服务器最近一次建立连接的结果。
+type ServerStatus string
+
+const (
+	ServerStatusOnline ServerStatus = "online"
+	// ServerStatusUnreachable 网络不通、连接超时，或者连接跳板机失败。
+	ServerStatusUnreachable ServerStatus = "unreachable"
+	// ServerStatusAuthFailed 服务器拒绝了管理员账户的认证。
+	ServerStatusAuthFailed ServerStatus = "auth_failed"
+	// ServerStatusError 能够连接，但是无法使用，例如host key不一致、没有sudo权限或者发行版不受支持。
+	ServerStatusError ServerStatus = "error"
+)
+
+// Reachability 某个服务器的连接状态。
+// Since 为进入当前状态的时间；连续失败ConsecutiveFailures次之后进入冷却，RetryAt之前不会再尝试连接，零值表示可以立即连接。
+type Reachability struct {
+	Status              ServerStatus
+	Since               time.Time
+	LastError           string
+	ConsecutiveFailures int
+	RetryAt             time.Time
+}
+
+// reachabilityTracker 记录每个host:port建立连接的结果。
+// 网络不通或者认证失败时，连续失败failureThreshold次之后开始冷却，冷却时间从baseCooldown开始，每多失败一次翻倍，直到maxCooldown。
+// 冷却期间直接返回上一次的错误，而不是每次都等待建连超时。
+type reachabilityTracker struct {
+	mu     sync.Mutex
+	states map[string]*Reachability
+
+	failureThreshold int
+	baseCooldown     time.Duration
+	maxCooldown      time.Duration
+	now              func() time.Time
+}
+
+func newReachabilityTracker() *reachabilityTracker {
+	t := &reachabilityTracker{
+		states:           make(map[string]*Reachability),
+		failureThreshold: defaultReachabilityFailureThreshold,
+		baseCooldown:     defaultReachabilityBaseCooldown,
+		maxCooldown:      defaultReachabilityMaxCooldown,
+		now:              time.Now,
+	}
+	if conf := config.GetConfig(); conf != nil && conf.SSHPoolConfig != nil {
+		c := conf.SSHPoolConfig
+		if c.UnreachableFailureThreshold > 0 {
+			t.failureThreshold = c.UnreachableFailureThreshold
+		}
+		if c.UnreachableBaseCooldownSeconds > 0 {
+			t.baseCooldown = time.Duration(c.UnreachableBaseCooldownSeconds) * time.Second
+		}
+		if c.UnreachableMaxCooldownSeconds > 0 {
+			t.maxCooldown = time.Duration(c.UnreachableMaxCooldownSeconds) * time.Second
+		}
+	}
+	return t
+}
+
+// check 处于冷却中时返回ServerUnreachableErr，其中带有上一次连接失败的原因。
+func (t *reachabilityTracker) check(hostPort string) *SErr.APIErr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[hostPort]
+	if !ok || state.RetryAt.IsZero() || !t.now().Before(state.RetryAt) {
+		return nil
+	}
+	return SErr.ServerUnreachableErr.CustomMessageF("服务器%s已经连续%d次连接失败（状态为%s，自%s起），%s之前不会再尝试连接。上一次的错误信息为：%s",
+		hostPort, state.ConsecutiveFailures, state.Status, state.Since.Format(time.RFC3339), state.RetryAt.Format(time.RFC3339), state.LastError)
+}
+
+// record 记录一次建连的结果，err为nil表示成功。
+func (t *reachabilityTracker) record(hostPort string, err *SErr.APIErr) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	state, ok := t.states[hostPort]
+	if !ok {
+		state = &Reachability{}
+		t.states[hostPort] = state
+	}
+	status := StatusOfDialErr(err)
+	if status != state.Status {
+		state.Status = status
+		state.Since = now
+	}
+	if err == nil {
+		state.LastError = ""
+		state.ConsecutiveFailures = 0
+		state.RetryAt = time.Time{}
+		return
+	}
+	state.LastError = err.Message
+	state.ConsecutiveFailures++
+	// 能够连接但是无法使用时（例如没有sudo权限）建连本身很快，并且修复之后需要立即生效，所以不进入冷却。
+	if status == ServerStatusError || state.ConsecutiveFailures < t.failureThreshold {
+		state.RetryAt = time.Time{}
+		return
+	}
+	cooldown := t.baseCooldown
+	for i := t.failureThreshold; i < state.ConsecutiveFailures && cooldown < t.maxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > t.maxCooldown {
+		cooldown = t.maxCooldown
+	}
+	state.RetryAt = now.Add(cooldown)
+}
+
+// get 返回状态的拷贝，没有连接过时返回nil。
+func (t *reachabilityTracker) get(hostPort string) *Reachability {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.states[hostPort]
+	if !ok {
+		return nil
+	}
+	copied := *state
+	return &copied
+}
+
+func (t *reachabilityTracker) reset(hostPort string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.states, hostPort)
+}
+
+// StatusOfDialErr 根据建连时的错误码区分服务器的状态，err为nil时为online。
+func StatusOfDialErr(err *SErr.APIErr) ServerStatus {
+	if err == nil {
+		return ServerStatusOnline
+	}
+	switch err.Code {
+	case SErr.CodeSSHAuthFailed:
+		return ServerStatusAuthFailed
+	case SErr.CodeServerUnreachable:
+		return ServerStatusUnreachable
+	default:
+		return ServerStatusError
+	}
+}
+
+// ServerReachability 返回某个服务器的连接状态，还没有通过连接池连接过该服务器时返回nil。
+func ServerReachability(host string, port uint) *Reachability {
+	return getSSHConnPool().reachability.get(hostPortKey(host, port))
+}
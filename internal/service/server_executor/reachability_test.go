@@ -0,0 +1,113 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReachabilityTrackerBackoff(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := &reachabilityTracker{
+		states:           make(map[string]*Reachability),
+		failureThreshold: 2,
+		baseCooldown:     10 * time.Second,
+		maxCooldown:      35 * time.Second,
+		now:              func() time.Time { return now },
+	}
+	const hostPort = "192.0.2.1:22"
+	if tracker.get(hostPort) != nil || tracker.check(hostPort) != nil {
+		t.Fatal("an unknown server should be dialed")
+	}
+
+	tracker.record(hostPort, SErr.ServerUnreachableErr)
+	if tracker.check(hostPort) != nil {
+		t.Fatal("the first failure should not start the cooldown")
+	}
+	since := now
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 35 * time.Second, 35 * time.Second} {
+		now = now.Add(time.Minute)
+		tracker.record(hostPort, SErr.ServerUnreachableErr)
+		state := tracker.get(hostPort)
+		if state.Status != ServerStatusUnreachable || !state.Since.Equal(since) || state.RetryAt.Sub(now) != want {
+			t.Fatalf("unexpected state %+v, want cooldown %s", state, want)
+		}
+		if err := tracker.check(hostPort); err == nil || err.Code != SErr.CodeServerUnreachable {
+			t.Fatalf("unexpected err %+v", err)
+		}
+	}
+	now = now.Add(35 * time.Second)
+	if tracker.check(hostPort) != nil {
+		t.Fatal("the server should be dialed after the cooldown")
+	}
+
+	// 认证失败同样会冷却，状态改变时Since随之改变。
+	tracker.record(hostPort, SErr.SSHAuthFailedErr)
+	if state := tracker.get(hostPort); state.Status != ServerStatusAuthFailed || !state.Since.Equal(now) || state.ConsecutiveFailures != 6 || state.RetryAt.IsZero() {
+		t.Fatalf("unexpected state %+v", tracker.get(hostPort))
+	}
+
+	tracker.record(hostPort, nil)
+	if state := tracker.get(hostPort); state.Status != ServerStatusOnline || state.ConsecutiveFailures != 0 || !state.RetryAt.IsZero() || state.LastError != "" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	// 能够连接但是无法使用时不进入冷却。
+	for i := 0; i < 3; i++ {
+		tracker.record(hostPort, SErr.SSHConnectionErr)
+	}
+	if state := tracker.get(hostPort); state.Status != ServerStatusError || tracker.check(hostPort) != nil {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	tracker.reset(hostPort)
+	if tracker.get(hostPort) != nil {
+		t.Fatal("reset should forget the server")
+	}
+}
+
+func TestSSHConnPoolSkipsUnreachableServer(t *testing.T) {
+	// 监听之后立即关闭，连接该端口会被拒绝。
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	_ = listener.Close()
+	param := &OpenExecutorServiceParam{
+		Host:             addr.IP.String(),
+		Port:             uint(addr.Port),
+		AdminAccountName: "admin",
+		AdminAccountPwd:  "admin",
+	}
+	threshold := getSSHConnPool().reachability.failureThreshold
+	t.Cleanup(func() {
+		getSSHConnPool().reachability.reset(hostPortKey(param.Host, param.Port))
+	})
+
+	for i := 0; i < threshold; i++ {
+		if _, err := getSSHConnPool().Get(param); err == nil || err.Code != SErr.CodeServerUnreachable {
+			t.Fatalf("unexpected err %+v", err)
+		}
+	}
+	state := ServerReachability(param.Host, param.Port)
+	if state == nil || state.Status != ServerStatusUnreachable || state.ConsecutiveFailures != threshold || state.RetryAt.IsZero() {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	// 冷却期间不再尝试建连，失败次数不会增加；IgnoreBackoff时仍然会真正建连。
+	if _, err := getSSHConnPool().Get(param); err == nil || err.Code != SErr.CodeServerUnreachable {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if state := ServerReachability(param.Host, param.Port); state.ConsecutiveFailures != threshold {
+		t.Fatalf("the server should not be dialed during the cooldown, state %+v", state)
+	}
+	param.IgnoreBackoff = true
+	if _, err := getSSHConnPool().Get(param); err == nil {
+		t.Fatal("the closed port should not be connected")
+	}
+	if state := ServerReachability(param.Host, param.Port); state.ConsecutiveFailures != threshold+1 {
+		t.Fatalf("the server should be dialed with IgnoreBackoff, state %+v", state)
+	}
+}
@@ -0,0 +1,505 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sftpServerScript 以root身份启动sftp-server的脚本。
+// 它从stdin读取一行作为sudo的密码（没有密码时为空行），之后的stdin与stdout为SFTP协议。
+// 与sudoPrelude不同，它不能把stdin重定向到/dev/null，所以没有使用sudoPrelude。
+const sftpServerScript = "sftp_server"
+
+// removeProtectedPaths 即使是管理员也不允许删除的路径。
+var removeProtectedPaths = map[string]bool{
+	"/": true, "/bin": true, "/boot": true, "/dev": true, "/etc": true, "/home": true, "/lib": true, "/lib64": true,
+	"/proc": true, "/root": true, "/sbin": true, "/sys": true, "/usr": true, "/var": true,
+}
+
+// sftpOp 一次文件操作。Name 在结果与审计记录中代替脚本名，也用于按名称配置超时时间；Args 为操作的参数。
+type sftpOp struct {
+	Name string
+	Args CmdArgs
+}
+
+// sftpRunner 能够以root身份打开SFTP会话的commandRunner。
+// runSFTP 使用server启动sftp-server，在会话上执行f，f返回或者ctx结束时结束sftp-server。
+type sftpRunner interface {
+	runSFTP(ctx context.Context, op *sftpOp, server *renderedScript, f func(client *sftp.Client) error) *SErr.APIErr
+}
+
+// DownloadTarget 下载文件时，在得到文件信息之后、写入文件内容之前调用，返回写入文件内容的writer，例如已经设置好响应头的http响应。
+type DownloadTarget func(info *internal_models.ServerFileInfo) io.Writer
+
+// ParseFilePerm 解析八进制的权限位，例如0755或者4755。
+func ParseFilePerm(perm string) (os.FileMode, *SErr.APIErr) {
+	bits, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil || bits > 07777 {
+		return 0, SErr.InvalidParamErr.CustomMessageF("文件权限必须是不超过07777的八进制数！权限为：%s", perm)
+	}
+	mode := os.FileMode(bits) & os.ModePerm
+	if bits&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if bits&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if bits&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+// formatFilePerm 是ParseFilePerm的逆过程。
+func formatFilePerm(mode os.FileMode) string {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= 04000
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= 02000
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= 01000
+	}
+	return fmt.Sprintf("%04o", bits)
+}
+
+// checkFilePath 文件操作不经过shell，所以不限制路径中的字符，只要求是规范的绝对路径。
+func checkFilePath(p string) *SErr.APIErr {
+	if !path.IsAbs(p) || path.Clean(p) != p || strings.ContainsRune(p, 0) {
+		return SErr.InvalidParamErr.CustomMessageF("文件路径必须是规范的绝对路径！路径为：%s", p)
+	}
+	return nil
+}
+
+func newServerFileInfo(p string, fi os.FileInfo) *internal_models.ServerFileInfo {
+	info := &internal_models.ServerFileInfo{
+		Name:      path.Base(p),
+		Path:      p,
+		IsDir:     fi.IsDir(),
+		IsSymlink: fi.Mode()&os.ModeSymlink != 0,
+		Size:      fi.Size(),
+		Mode:      fi.Mode().String(),
+		Perm:      formatFilePerm(fi.Mode()),
+		ModTime:   fi.ModTime().Unix(),
+	}
+	if stat, ok := fi.Sys().(*sftp.FileStat); ok {
+		info.UID = uint(stat.UID)
+		info.GID = uint(stat.GID)
+	}
+	return info
+}
+
+// sftpErr 将SFTP返回的错误转换为APIErr，f中返回的APIErr原样返回。
+func sftpErr(op *sftpOp, err error) *SErr.APIErr {
+	var apiErr *SErr.APIErr
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return SErr.FileNotExistsErr.CustomMessageF("服务器上的文件或文件夹不存在！操作为：%s，路径为：%s", op.Name, op.Args["path"])
+	}
+	return SErr.FileOperationErr.CustomMessageF("在服务器上操作文件失败！操作为：%s，路径为：%s，错误信息为：%s", op.Name, op.Args["path"], err.Error())
+}
+
+// sftpProcess 一个已经启动的sftp-server，由SSH的session或者本机的进程实现。
+// kill 强制结束它，wait 等待它退出；stderr 只能在wait返回之后读取。
+type sftpProcess struct {
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr *bytes.Buffer
+	kill   func()
+	wait   func() error
+}
+
+// serveSFTP 向sftp-server写入sudo密码，在建立的SFTP会话上执行f。ctx结束时强制结束sftp-server。
+func serveSFTP(ctx context.Context, op *sftpOp, password string, p *sftpProcess, f func(client *sftp.Client) error) *SErr.APIErr {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.kill()
+		case <-done:
+		}
+	}()
+	if _, err := io.WriteString(p.stdin, password+"\n"); err != nil {
+		_ = p.stdin.Close()
+		_ = p.wait()
+		return SErr.FileOperationErr.CustomMessageF("启动sftp-server失败！操作为：%s，错误信息为：%s", op.Name, err.Error())
+	}
+	client, err := sftp.NewClientPipe(p.stdout, p.stdin)
+	if err != nil {
+		// sudo失败或者找不到sftp-server时，进程会直接退出，原因在stderr中。
+		_ = p.stdin.Close()
+		_ = p.wait()
+		if ctx.Err() != nil {
+			return commandContextErr(ctx, op.Name)
+		}
+		return SErr.FileOperationErr.CustomMessageF("启动sftp-server失败！操作为：%s，错误信息为：%s，服务器输出为：%s", op.Name, err.Error(), strings.TrimSpace(p.stderr.String()))
+	}
+	fErr := f(client)
+	// 关闭client会关闭stdin，sftp-server读到EOF后退出。
+	_ = client.Close()
+	_ = p.wait()
+	if ctx.Err() != nil {
+		return commandContextErr(ctx, op.Name)
+	}
+	if fErr != nil {
+		return sftpErr(op, fErr)
+	}
+	return nil
+}
+
+func (conn *LinuxSSHConnection) runSFTP(ctx context.Context, op *sftpOp, server *renderedScript, f func(client *sftp.Client) error) *SErr.APIErr {
+	var err *SErr.APIErr
+	sessErr := conn.withSession(ctx, nil, func(session *ssh.Session) {
+		if ctx.Err() != nil {
+			err = commandContextErr(ctx, op.Name)
+			return
+		}
+		stdin, e := session.StdinPipe()
+		if e != nil {
+			err = SErr.SSHConnectionErr.CustomMessageF("打开session的stdin失败！操作为：%s，失败信息为：%s", op.Name, e.Error())
+			return
+		}
+		stdout, e := session.StdoutPipe()
+		if e != nil {
+			err = SErr.SSHConnectionErr.CustomMessageF("打开session的stdout失败！操作为：%s，失败信息为：%s", op.Name, e.Error())
+			return
+		}
+		stderr := &bytes.Buffer{}
+		session.Stderr = stderr
+		if e := session.Start(server.Cmd); e != nil {
+			err = SErr.SSHConnectionErr.CustomMessageF("发送ssh命令失败！命令为：%s，失败信息为：%s", server.Name, e.Error())
+			return
+		}
+		err = serveSFTP(ctx, op, conn.Password, &sftpProcess{
+			stdin:  stdin,
+			stdout: stdout,
+			stderr: stderr,
+			kill: func() {
+				_ = session.Signal(ssh.SIGKILL)
+				_ = session.Close()
+			},
+			wait: session.Wait,
+		}, f)
+	})
+	if sessErr != nil {
+		return sessErr
+	}
+	return err
+}
+
+func (r *linuxLocalRunner) runSFTP(ctx context.Context, op *sftpOp, server *renderedScript, f func(client *sftp.Client) error) *SErr.APIErr {
+	if ctx.Err() != nil {
+		return commandContextErr(ctx, op.Name)
+	}
+	c := exec.Command("/bin/sh", "-c", server.Cmd)
+	stdin, err := c.StdinPipe()
+	if err != nil {
+		return SErr.InternalErr.CustomMessageF("在本机启动sftp-server失败！操作为：%s，失败信息为：%s", op.Name, err.Error())
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return SErr.InternalErr.CustomMessageF("在本机启动sftp-server失败！操作为：%s，失败信息为：%s", op.Name, err.Error())
+	}
+	stderr := &bytes.Buffer{}
+	c.Stderr = stderr
+	setProcessGroup(c)
+	if err := c.Start(); err != nil {
+		return SErr.InternalErr.CustomMessageF("在本机启动sftp-server失败！操作为：%s，失败信息为：%s", op.Name, err.Error())
+	}
+	return serveSFTP(ctx, op, r.Password, &sftpProcess{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		kill: func() {
+			killProcessGroup(c)
+		},
+		wait: c.Wait,
+	}, f)
+}
+
+func sftpUnsupportedErr(runner commandRunner) *SErr.APIErr {
+	return SErr.FileOperationErr.CustomMessageF("该连接不支持文件操作！runner=[%s]", runner)
+}
+
+// runSFTP 文件的内容无法回放，所以文件操作不会被录制。
+func (r *recordingRunner) runSFTP(ctx context.Context, op *sftpOp, server *renderedScript, f func(client *sftp.Client) error) *SErr.APIErr {
+	sr, ok := r.runner.(sftpRunner)
+	if !ok {
+		return sftpUnsupportedErr(r.runner)
+	}
+	return sr.runSFTP(ctx, op, server, f)
+}
+
+// runSFTP 以操作名代替脚本名审计。文件操作没有输出，成功时退出码记为0。
+func (r *auditingRunner) runSFTP(ctx context.Context, op *sftpOp, server *renderedScript, f func(client *sftp.Client) error) *SErr.APIErr {
+	sr, ok := r.runner.(sftpRunner)
+	if !ok {
+		return sftpUnsupportedErr(r.runner)
+	}
+	startedAt := time.Now()
+	err := sr.runSFTP(ctx, op, server, f)
+	var result *internal_models.CommandResult
+	if err == nil {
+		result = &internal_models.CommandResult{Script: op.Name}
+	}
+	r.audit(ctx, startedAt, op.Name, op.Args, result, err)
+	return err
+}
+
+// withSFTP 以root身份打开一个SFTP会话执行f，超时时间按op.Name配置。执行结果（无论成功与否）会记录到rc中。
+func (s *LinuxSSHExecutorServiceTemplate) withSFTP(ctx context.Context, rc *ExecutorServiceRespCommon, op *sftpOp, f func(client *sftp.Client) error) *SErr.APIErr {
+	result := &internal_models.CommandResult{
+		Script:     op.Name,
+		ExitStatus: -1,
+		StartedAt:  time.Now(),
+	}
+	err := func() *SErr.APIErr {
+		runner, ok := s.runner.(sftpRunner)
+		if !ok {
+			return sftpUnsupportedErr(s.runner)
+		}
+		server, err := s.renderScript(sftpServerScript, nil)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, commandTimeout(op.Name))
+		defer cancel()
+		return runner.runSFTP(ctx, op, server, f)
+	}()
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	if err != nil {
+		result.Error = err.Message
+	} else {
+		result.ExitStatus = 0
+	}
+	rc.addResult(result)
+	log.Printf("LinuxSSHExecutorServiceTemplate=[%s] %s, args=[%v], err=[%v]", s, op.Name, op.Args, err)
+	return err
+}
+
+// ListDir 列出文件夹中的文件，按文件名排序。符号链接不会被跟随。
+func (s *LinuxSSHExecutorServiceTemplate) ListDir(ctx context.Context, dirPath string) (*ExecutorServiceListDirResp, *SErr.APIErr) {
+	resp := &ExecutorServiceListDirResp{}
+	if err := checkFilePath(dirPath); err != nil {
+		return resp, err
+	}
+	op := &sftpOp{Name: "sftp_list_dir", Args: CmdArgs{"path": dirPath}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		infos, err := client.ReadDir(dirPath)
+		if err != nil {
+			return err
+		}
+		resp.Files = make([]*internal_models.ServerFileInfo, 0, len(infos))
+		for _, fi := range infos {
+			resp.Files = append(resp.Files, newServerFileInfo(path.Join(dirPath, fi.Name()), fi))
+		}
+		sort.Slice(resp.Files, func(i, j int) bool {
+			return resp.Files[i].Name < resp.Files[j].Name
+		})
+		return nil
+	})
+	return resp, err
+}
+
+// Stat 获取文件或文件夹的信息。path为符号链接时返回符号链接本身的信息。
+func (s *LinuxSSHExecutorServiceTemplate) Stat(ctx context.Context, filePath string) (*ExecutorServiceFileResp, *SErr.APIErr) {
+	resp := &ExecutorServiceFileResp{}
+	if err := checkFilePath(filePath); err != nil {
+		return resp, err
+	}
+	op := &sftpOp{Name: "sftp_stat", Args: CmdArgs{"path": filePath}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		fi, err := client.Lstat(filePath)
+		if err != nil {
+			return err
+		}
+		resp.File = newServerFileInfo(filePath, fi)
+		return nil
+	})
+	return resp, err
+}
+
+// Upload 将content写入filePath，权限为perm。
+// 内容先写入同一文件夹下的临时文件，写完之后再替换filePath，上传失败时不会留下不完整的文件。
+// 文件由sftp-server以root身份创建，所以新文件的所有者为root，需要时再调用Chown。
+func (s *LinuxSSHExecutorServiceTemplate) Upload(ctx context.Context, filePath string, content io.Reader, perm os.FileMode) (*ExecutorServiceFileResp, *SErr.APIErr) {
+	resp := &ExecutorServiceFileResp{}
+	if err := checkFilePath(filePath); err != nil {
+		return resp, err
+	}
+	op := &sftpOp{Name: "sftp_upload", Args: CmdArgs{"path": filePath, "perm": formatFilePerm(perm)}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		if fi, err := client.Stat(filePath); err == nil && fi.IsDir() {
+			return SErr.InvalidParamErr.CustomMessageF("上传的目标路径是一个文件夹！路径为：%s", filePath)
+		}
+		tmpPath := path.Join(path.Dir(filePath), fmt.Sprintf(".%s.ss_upload_%d", path.Base(filePath), time.Now().UnixNano()))
+		file, err := client.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+		if err != nil {
+			return err
+		}
+		uploaded := false
+		defer func() {
+			if !uploaded {
+				_ = client.Remove(tmpPath)
+			}
+		}()
+		resp.Transferred, err = file.ReadFrom(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		if err := client.Chmod(tmpPath, perm); err != nil {
+			return err
+		}
+		if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+			err = client.PosixRename(tmpPath, filePath)
+		} else {
+			// 标准的rename在目标存在时会失败。
+			if err := client.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			err = client.Rename(tmpPath, filePath)
+		}
+		if err != nil {
+			return err
+		}
+		uploaded = true
+		fi, err := client.Lstat(filePath)
+		if err != nil {
+			return err
+		}
+		resp.File = newServerFileInfo(filePath, fi)
+		return nil
+	})
+	return resp, err
+}
+
+// Download 将filePath的内容写入target返回的writer，只能下载普通文件。
+func (s *LinuxSSHExecutorServiceTemplate) Download(ctx context.Context, filePath string, target DownloadTarget) (*ExecutorServiceFileResp, *SErr.APIErr) {
+	resp := &ExecutorServiceFileResp{}
+	if err := checkFilePath(filePath); err != nil {
+		return resp, err
+	}
+	op := &sftpOp{Name: "sftp_download", Args: CmdArgs{"path": filePath}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		file, err := client.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fi, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return SErr.InvalidParamErr.CustomMessageF("只能下载普通文件！路径为：%s", filePath)
+		}
+		resp.File = newServerFileInfo(filePath, fi)
+		resp.Transferred, err = file.WriteTo(target(resp.File))
+		return err
+	})
+	return resp, err
+}
+
+// Chmod 修改文件或文件夹的权限，不会递归修改。
+func (s *LinuxSSHExecutorServiceTemplate) Chmod(ctx context.Context, filePath string, perm os.FileMode) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+	resp := &ExecutorServiceVoidResp{}
+	if err := checkFilePath(filePath); err != nil {
+		return resp, err
+	}
+	op := &sftpOp{Name: "sftp_chmod", Args: CmdArgs{"path": filePath, "perm": formatFilePerm(perm)}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		return client.Chmod(filePath, perm)
+	})
+	return resp, err
+}
+
+// Chown 修改文件或文件夹的所有者与组，不会递归修改。
+func (s *LinuxSSHExecutorServiceTemplate) Chown(ctx context.Context, filePath string, uid, gid uint) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+	resp := &ExecutorServiceVoidResp{}
+	if err := checkFilePath(filePath); err != nil {
+		return resp, err
+	}
+	op := &sftpOp{Name: "sftp_chown", Args: CmdArgs{"path": filePath, "uid": strconv.Itoa(int(uid)), "gid": strconv.Itoa(int(gid))}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		return client.Chown(filePath, int(uid), int(gid))
+	})
+	return resp, err
+}
+
+// Remove 删除文件或文件夹。recursive为false时只能删除文件与空文件夹；为true时删除整个文件夹，其中的符号链接不会被跟随。
+// removeProtectedPaths中的系统目录不允许删除。
+func (s *LinuxSSHExecutorServiceTemplate) Remove(ctx context.Context, filePath string, recursive bool) (*ExecutorServiceVoidResp, *SErr.APIErr) {
+	resp := &ExecutorServiceVoidResp{}
+	if err := checkFilePath(filePath); err != nil {
+		return resp, err
+	}
+	if removeProtectedPaths[filePath] {
+		return resp, SErr.InvalidParamErr.CustomMessageF("不允许删除系统目录！路径为：%s", filePath)
+	}
+	op := &sftpOp{Name: "sftp_remove", Args: CmdArgs{"path": filePath, "recursive": strconv.FormatBool(recursive)}}
+	err := s.withSFTP(ctx, &resp.ExecutorServiceRespCommon, op, func(client *sftp.Client) error {
+		if recursive {
+			return removeAll(client, filePath)
+		}
+		fi, err := client.Lstat(filePath)
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return client.RemoveDirectory(filePath)
+		}
+		return client.Remove(filePath)
+	})
+	return resp, err
+}
+
+// removeAll 先序遍历root，再按相反的顺序删除，使得文件夹中的内容总是先于文件夹本身被删除。
+func removeAll(client *sftp.Client, root string) error {
+	type entry struct {
+		path  string
+		isDir bool
+	}
+	var entries []entry
+	walker := client.Walk(root)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		entries = append(entries, entry{path: walker.Path(), isDir: walker.Stat().IsDir()})
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		var err error
+		if entries[i].isDir {
+			err = client.RemoveDirectory(entries[i].path)
+		} else {
+			err = client.Remove(entries[i].path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
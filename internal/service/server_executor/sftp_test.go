@@ -0,0 +1,170 @@
+package server_executor
+
+import (
+	"ServerServing/internal/internal_models"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLinuxSSHFileOperations(t *testing.T) {
+	server := newTestSSHServer(t)
+	log := &auditLog{}
+	t.Cleanup(UseCommandAuditor(log.audit))
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "deeper"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "deeper", "b.txt"), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	listResp, err := es.ListDir(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listResp.Files) != 3 || listResp.Files[0].Name != "a.txt" || listResp.Files[1].Name != "link" || listResp.Files[2].Name != "sub" {
+		t.Fatalf("unexpected files %+v", listResp.Files)
+	}
+	a, link, sub := listResp.Files[0], listResp.Files[1], listResp.Files[2]
+	if a.Size != 5 || a.IsDir || a.Perm != "0644" || a.Mode != "-rw-r--r--" || a.Path != filepath.Join(dir, "a.txt") || a.UID != uint(os.Getuid()) || a.ModTime == 0 {
+		t.Fatalf("unexpected file %+v", a)
+	}
+	if !sub.IsDir || !link.IsSymlink || link.IsDir {
+		t.Fatalf("unexpected files %+v, %+v", sub, link)
+	}
+	if len(listResp.Results) != 1 || listResp.Results[0].Script != "sftp_list_dir" || !listResp.Results[0].Succeeded() {
+		t.Fatalf("unexpected results %+v", listResp.Results)
+	}
+
+	if _, err := es.Stat(ctx, filepath.Join(dir, "missing")); err == nil || !strings.Contains(err.Message, "不存在") {
+		t.Fatalf("unexpected err %v", err)
+	}
+	for _, p := range []string{"relative", dir + "/../x", dir + "/"} {
+		if _, err := es.Stat(ctx, p); err == nil {
+			t.Fatalf("%s should be rejected", p)
+		}
+	}
+
+	// 上传会替换已有的文件，并且不会留下临时文件。
+	target := filepath.Join(dir, "a.txt")
+	uploadResp, err := es.Upload(ctx, target, strings.NewReader("uploaded content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploadResp.Transferred != 16 || uploadResp.File.Size != 16 || uploadResp.File.Perm != "0600" {
+		t.Fatalf("unexpected upload %+v, file %+v", uploadResp, uploadResp.File)
+	}
+	if bs, _ := ioutil.ReadFile(target); string(bs) != "uploaded content" {
+		t.Fatalf("unexpected content %q", bs)
+	}
+	if _, err := es.Upload(ctx, filepath.Join(dir, "sub"), strings.NewReader("x"), 0644); err == nil {
+		t.Fatal("uploading onto a directory should fail")
+	}
+	if names, _ := filepath.Glob(filepath.Join(dir, ".*")); len(names) != 0 {
+		t.Fatalf("temporary files left behind: %v", names)
+	}
+
+	buf := &bytes.Buffer{}
+	var announced *internal_models.ServerFileInfo
+	downloadResp, err := es.Download(ctx, target, func(info *internal_models.ServerFileInfo) io.Writer {
+		announced = info
+		return buf
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "uploaded content" || downloadResp.Transferred != 16 || announced == nil || announced.Size != 16 {
+		t.Fatalf("unexpected download %q, %+v", buf.String(), downloadResp)
+	}
+	if _, err := es.Download(ctx, dir, func(info *internal_models.ServerFileInfo) io.Writer { return buf }); err == nil {
+		t.Fatal("downloading a directory should fail")
+	}
+
+	if _, err := es.Chmod(ctx, target, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := os.Stat(target); fi.Mode().Perm() != 0640 {
+		t.Fatalf("unexpected mode %s", fi.Mode())
+	}
+	if _, err := es.Chown(ctx, target, uint(os.Getuid()), uint(os.Getgid())); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := es.Remove(ctx, filepath.Join(dir, "sub"), false); err == nil {
+		t.Fatal("removing a non-empty directory should fail without recursive")
+	}
+	// 递归删除时不跟随符号链接，删除link只删除链接本身。
+	if _, err := es.Remove(ctx, filepath.Join(dir, "link"), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, e := os.Stat(filepath.Join(dir, "sub", "deeper", "b.txt")); e != nil {
+		t.Fatalf("the symlink target should be kept, err=%v", e)
+	}
+	if _, err := es.Remove(ctx, filepath.Join(dir, "sub"), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, e := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(e) {
+		t.Fatalf("sub should be removed, err=%v", e)
+	}
+	if _, err := es.Remove(ctx, "/etc", true); err == nil {
+		t.Fatal("removing /etc should be rejected")
+	}
+
+	want := "sftp_list_dir,sftp_stat,sftp_upload,sftp_upload,sftp_download,sftp_download,sftp_chmod,sftp_chown,sftp_remove,sftp_remove,sftp_remove"
+	if got := strings.Join(log.scripts(), ","); got != want {
+		t.Fatalf("unexpected audited operations %s", got)
+	}
+	if upload := log.entries[2]; upload.Args["path"] != target || upload.Args["perm"] != "0600" || upload.ExitStatus != 0 || upload.ErrCode != 0 {
+		t.Fatalf("unexpected audit entry %+v", upload)
+	}
+	if failed := log.entries[1]; failed.ExitStatus != -1 || failed.ErrCode == 0 {
+		t.Fatalf("unexpected audit entry %+v", failed)
+	}
+}
+
+func TestLinuxSSHFileOperationsWrongSudoPassword(t *testing.T) {
+	server := newTestSSHServer(t)
+	es := openTestSSHExecutorService(t, server.OpenParam())
+	server.SetSudoPassword("another password")
+	_, err := es.ListDir(context.Background(), t.TempDir())
+	if err == nil || !strings.Contains(err.Message, "incorrect password") {
+		t.Fatalf("unexpected err %v", err)
+	}
+}
+
+func TestParseFilePerm(t *testing.T) {
+	for perm, want := range map[string]os.FileMode{
+		"755":  0755,
+		"0644": 0644,
+		"4755": 0755 | os.ModeSetuid,
+		"1777": 0777 | os.ModeSticky,
+	} {
+		got, err := ParseFilePerm(perm)
+		if err != nil || got != want {
+			t.Fatalf("ParseFilePerm(%s) = %s, %v", perm, got, err)
+		}
+		if again, _ := ParseFilePerm(formatFilePerm(got)); again != got {
+			t.Fatalf("formatFilePerm(%s) = %s", got, formatFilePerm(got))
+		}
+	}
+	for _, perm := range []string{"", "8", "rwx", "17777"} {
+		if _, err := ParseFilePerm(perm); err == nil {
+			t.Fatalf("ParseFilePerm(%s) should fail", perm)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package server_executor
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"net"
+	"os"
+)
+
+// SSHAuth 描述与服务器建立SSH连接时使用的认证信息。
+// Password除了在密码认证时用于登录以外，还用于sudo时输入的密码。
+type SSHAuth struct {
+	Type                 daModels.SSHAuthType
+	Password             string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	AgentSocket          string
+}
+
+// authMethods 根据认证类型生成ssh.AuthMethod。
+// 返回的closer用于在握手结束后关闭与ssh-agent的连接，不需要时为nil。
+func (a *SSHAuth) authMethods() ([]ssh.AuthMethod, func(), *SErr.APIErr) {
+	switch a.Type {
+	case daModels.SSHAuthTypePassword, "":
+		return []ssh.AuthMethod{ssh.Password(a.Password)}, nil, nil
+	case daModels.SSHAuthTypePrivateKey:
+		var signer ssh.Signer
+		var err error
+		if a.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(a.PrivateKey), []byte(a.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(a.PrivateKey))
+		}
+		if err != nil {
+			return nil, nil, SErr.SSHConnectionErr.CustomMessageF("解析私钥失败！错误信息为：%s", err.Error())
+		}
+		return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil, nil
+	case daModels.SSHAuthTypeAgent:
+		socket := a.AgentSocket
+		if socket == "" {
+			socket = os.Getenv("SSH_AUTH_SOCK")
+		}
+		if socket == "" {
+			return nil, nil, SErr.SSHConnectionErr.CustomMessage("未指定ssh-agent的socket路径，并且环境变量SSH_AUTH_SOCK为空！")
+		}
+		agentConn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, nil, SErr.SSHConnectionErr.CustomMessageF("连接ssh-agent失败！socket=[%s]，错误信息为：%s", socket, err.Error())
+		}
+		agentClient := agent.NewClient(agentConn)
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(agentClient.Signers)}, func() {
+			_ = agentConn.Close()
+		}, nil
+	default:
+		return nil, nil, SErr.InvalidParamErr.CustomMessageF("不支持的SSH认证方式：%s", a.Type)
+	}
+}
@@ -0,0 +1,187 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSSHPort = 22
+	// MaxSSHJumpHops 一条连接最多经过的跳板机数量，用于避免配置错误时无限地连接下去。
+	MaxSSHJumpHops = 8
+	sshDialTimeout = 10 * time.Second
+)
+
+// SSHJumpHop 建立SSH连接时经过的一个跳板机，与OpenSSH的ProxyJump类似，连接会按顺序经过每一个跳板机。
+// HostKeyFingerprint 为空时接受跳板机提供的任意host key。
+type SSHJumpHop struct {
+	Host               string
+	Port               uint
+	Account            string
+	Auth               *SSHAuth
+	HostKeyFingerprint string
+}
+
+func (h *SSHJumpHop) String() string {
+	return fmt.Sprintf("%s@%s", h.Account, net.JoinHostPort(h.Host, strconv.Itoa(int(h.Port))))
+}
+
+// ParseProxyJump 解析OpenSSH ProxyJump格式的跳板机列表，例如 "admin@gateway:2222,inner-gateway"。
+// 多个跳板机使用逗号分隔，按顺序连接。没有指定用户名时使用defaultAccount，没有指定端口时使用22端口。
+// 临时指定的跳板机没有单独的认证信息，统一使用auth进行认证。
+func ParseProxyJump(proxyJump string, defaultAccount string, auth *SSHAuth) ([]*SSHJumpHop, *SErr.APIErr) {
+	hops := make([]*SSHJumpHop, 0, 2)
+	for _, spec := range strings.Split(proxyJump, ",") {
+		spec = strings.TrimPrefix(strings.TrimSpace(spec), "ssh://")
+		if spec == "" || strings.ContainsAny(spec, " \t/") {
+			return nil, SErr.InvalidParamErr.CustomMessageF("跳板机[%s]的格式不合法！格式应为[user@]host[:port]，多个跳板机使用逗号分隔。", spec)
+		}
+		account := defaultAccount
+		if i := strings.LastIndex(spec, "@"); i >= 0 {
+			account, spec = spec[:i], spec[i+1:]
+		}
+		host, port := spec, uint(defaultSSHPort)
+		if strings.HasPrefix(spec, "[") || strings.Count(spec, ":") == 1 {
+			h, p, err := net.SplitHostPort(spec)
+			if err != nil {
+				return nil, SErr.InvalidParamErr.CustomMessageF("跳板机[%s]的格式不合法！错误信息为：%s", spec, err.Error())
+			}
+			portInt, err := strconv.ParseUint(p, 10, 16)
+			if err != nil || portInt == 0 {
+				return nil, SErr.InvalidParamErr.CustomMessageF("跳板机[%s]的端口不合法！", spec)
+			}
+			host, port = h, uint(portInt)
+		}
+		if host == "" || account == "" {
+			return nil, SErr.InvalidParamErr.CustomMessageF("跳板机[%s]缺少主机名或者用户名！", spec)
+		}
+		hops = append(hops, &SSHJumpHop{
+			Host:    host,
+			Port:    port,
+			Account: account,
+			Auth:    auth,
+		})
+	}
+	if len(hops) > MaxSSHJumpHops {
+		return nil, SErr.InvalidParamErr.CustomMessageF("跳板机数量不能超过%d个！", MaxSSHJumpHops)
+	}
+	return hops, nil
+}
+
+// dialSSHChain 依次经过jumps中的每个跳板机，与target建立SSH连接。
+// 返回与target的连接、与每个跳板机的连接（关闭target的连接后需要按相反的顺序关闭它们），以及target提供的host key指纹。
+// 任意一跳失败时，已经建立的连接都会被关闭，并且出错信息中会指明是哪一跳失败。
+func dialSSHChain(jumps []*SSHJumpHop, target *SSHJumpHop) (*ssh.Client, []*ssh.Client, string, *SErr.APIErr) {
+	if len(jumps) > MaxSSHJumpHops {
+		return nil, nil, "", SErr.InvalidParamErr.CustomMessageF("跳板机数量不能超过%d个！", MaxSSHJumpHops)
+	}
+	jumpClients := make([]*ssh.Client, 0, len(jumps))
+	var via *ssh.Client
+	for i, hop := range jumps {
+		client, _, err := dialSSHHop(via, hop)
+		if err != nil {
+			closeSSHClients(jumpClients)
+			return nil, nil, "", err.CustomMessageF("通过跳板机连接失败！第%d跳（跳板机%s）连接失败，错误信息为：%s", i+1, hop, err.Message)
+		}
+		jumpClients = append(jumpClients, client)
+		via = client
+	}
+	client, fingerprint, err := dialSSHHop(via, target)
+	if err != nil {
+		closeSSHClients(jumpClients)
+		if len(jumps) > 0 {
+			err = err.CustomMessageF("通过跳板机连接失败！第%d跳（目标服务器%s）连接失败，错误信息为：%s", len(jumps)+1, target, err.Message)
+		}
+		return nil, nil, "", err
+	}
+	return client, jumpClients, fingerprint, nil
+}
+
+// dialSSHHop 通过via与hop建立SSH连接，via为nil时直接连接。
+func dialSSHHop(via *ssh.Client, hop *SSHJumpHop) (*ssh.Client, string, *SErr.APIErr) {
+	authMethods, closeAuth, sErr := hop.Auth.authMethods()
+	if sErr != nil {
+		return nil, "", sErr
+	}
+	if closeAuth != nil {
+		defer closeAuth()
+	}
+	var presentedFingerprint string
+	expectedFingerprint := hop.HostKeyFingerprint
+	sshConfig := &ssh.ClientConfig{
+		Timeout: sshDialTimeout,
+		User:    hop.Account,
+		Auth:    authMethods,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			presentedFingerprint = ssh.FingerprintSHA256(key)
+			if expectedFingerprint != "" && presentedFingerprint != expectedFingerprint {
+				return errors.New("host key mismatch")
+			}
+			return nil
+		},
+	}
+	addr := net.JoinHostPort(hop.Host, strconv.Itoa(int(hop.Port)))
+	client, err := dialSSHClient(via, addr, sshConfig)
+	if err != nil {
+		// ssh.Dial不会包装HostKeyCallback返回的error，所以直接比较记录下来的指纹。
+		if expectedFingerprint != "" && presentedFingerprint != "" && presentedFingerprint != expectedFingerprint {
+			return nil, "", SErr.HostKeyMismatchErr.CustomMessageF("服务器%s的host key与记录的指纹不一致！记录的指纹为：%s，服务器提供的指纹为：%s。如果确认服务器重装过，请管理员重新接受新的host key。", addr, expectedFingerprint, presentedFingerprint)
+		}
+		if strings.Contains(err.Error(), "unable to authenticate") {
+			return nil, "", SErr.SSHAuthFailedErr.CustomMessageF("连接ssh失败，服务器拒绝了认证！错误信息为%s", err.Error())
+		}
+		return nil, "", SErr.ServerUnreachableErr.CustomMessageF("连接ssh失败！错误信息为%s", err.Error())
+	}
+	return client, presentedFingerprint, nil
+}
+
+// dialSSHClient 建立到addr的SSH连接，via不为nil时通过via的direct-tcpip通道连接。
+func dialSSHClient(via *ssh.Client, addr string, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	if via == nil {
+		return ssh.Dial("tcp", addr, sshConfig)
+	}
+	type dialResult struct {
+		client *ssh.Client
+		err    error
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		netConn, err := via.Dial("tcp", addr)
+		if err != nil {
+			done <- dialResult{err: err}
+			return
+		}
+		c, chans, reqs, err := ssh.NewClientConn(netConn, addr, sshConfig)
+		if err != nil {
+			_ = netConn.Close()
+			done <- dialResult{err: err}
+			return
+		}
+		done <- dialResult{client: ssh.NewClient(c, chans, reqs)}
+	}()
+	// 通过跳板机转发的连接不支持设置deadline，只能在超时后丢弃结果。
+	select {
+	case res := <-done:
+		return res.client, res.err
+	case <-time.After(sshConfig.Timeout):
+		go func() {
+			if res := <-done; res.client != nil {
+				_ = res.client.Close()
+			}
+		}()
+		return nil, fmt.Errorf("通过跳板机连接%s超时", addr)
+	}
+}
+
+// closeSSHClients 按与建立时相反的顺序关闭跳板机的连接。
+func closeSSHClients(clients []*ssh.Client) {
+	for i := len(clients) - 1; i >= 0; i-- {
+		_ = clients[i].Close()
+	}
+}
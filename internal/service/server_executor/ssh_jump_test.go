@@ -0,0 +1,29 @@
+package server_executor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProxyJump(t *testing.T) {
+	auth := &SSHAuth{Password: "pwd"}
+	hops, err := ParseProxyJump("admin@gateway:2222, inner-gw ,ssh://ops@[fe80::1]:22,fe80::2", "someadmin", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"admin@gateway:2222", "someadmin@inner-gw:22", "ops@[fe80::1]:22", "someadmin@[fe80::2]:22"}
+	if len(hops) != len(want) {
+		t.Fatalf("got %d hops, want %d", len(hops), len(want))
+	}
+	for i, hop := range hops {
+		if hop.String() != want[i] || hop.Auth != auth {
+			t.Fatalf("hop %d = %s, want %s", i, hop, want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "gw,,gw2", "gw:0", "gw:abc", "@gw", "gw/1", strings.Repeat("gw,", MaxSSHJumpHops) + "gw"} {
+		if _, err := ParseProxyJump(bad, "someadmin", auth); err == nil {
+			t.Fatalf("ParseProxyJump(%q) should fail", bad)
+		}
+	}
+}
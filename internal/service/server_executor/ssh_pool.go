@@ -0,0 +1,297 @@
+package server_executor
+
+import (
+	"ServerServing/config"
+	SErr "ServerServing/err"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxSessionsPerHost       = 8
+	defaultSSHConnIdleTimeout       = 5 * time.Minute
+	defaultSSHConnKeepaliveInterval = 30 * time.Second
+	sshKeepaliveTimeout             = 10 * time.Second
+)
+
+// sshConnPoolKey 连接池中连接的key，由host、port、认证信息以及经过的跳板机共同决定。认证信息或者跳板机变化后自然不会复用旧的连接。
+type sshConnPoolKey string
+
+func newSSHConnPoolKey(param *OpenExecutorServiceParam) sshConnPoolKey {
+	h := sha256.New()
+	for _, s := range []string{
+		param.AdminAccountName,
+		string(param.AuthType),
+		param.AdminAccountPwd,
+		param.AdminPrivateKey,
+		param.AdminPrivateKeyPassphrase,
+		param.AdminAgentSocket,
+		param.HostKeyFingerprint,
+	} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	for _, hop := range param.JumpHops {
+		for _, s := range []string{
+			hostPortKey(hop.Host, hop.Port),
+			hop.Account,
+			string(hop.Auth.Type),
+			hop.Auth.Password,
+			hop.Auth.PrivateKey,
+			hop.Auth.PrivateKeyPassphrase,
+			hop.Auth.AgentSocket,
+			hop.HostKeyFingerprint,
+		} {
+			h.Write([]byte(s))
+			h.Write([]byte{0})
+		}
+	}
+	return sshConnPoolKey(fmt.Sprintf("%s|%s", hostPortKey(param.Host, param.Port), hex.EncodeToString(h.Sum(nil))))
+}
+
+func hostPortKey(host string, port uint) string {
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// pooledSSHConn 连接池中的一个连接，同时缓存了建连时解析的/etc/os-release。
+// 建连时已经检查过sudo权限，没有sudo权限的连接不会被放入连接池，所以复用连接时不需要再次检查。
+type pooledSSHConn struct {
+	key      sshConnPoolKey
+	hostPort string
+	// jumpHostPorts 经过的跳板机，跳板机失效时经过它的连接也一并失效。
+	jumpHostPorts []string
+	conn          *LinuxSSHConnection
+	osRelease     *OSRelease
+
+	refs        int
+	lastUsed    time.Time
+	invalidated bool
+}
+
+// sshConnPool 在多个HTTP请求之间共享的SSH连接池。
+// 同一个key只保留一条连接，多个请求在这条连接上各自开session执行命令，并且每个host同时打开的session数量有上限。
+// 后台会定期对空闲连接发送keepalive，并淘汰空闲太久或者已经失效的连接。
+type sshConnPool struct {
+	mu    sync.Mutex
+	conns map[sshConnPoolKey]*pooledSSHConn
+	// sessionLimiters 每个host:port的session并发限制，同一个host的不同连接共享。
+	sessionLimiters map[string]chan struct{}
+	// reachability 每个host:port建连的结果，连续失败的服务器在冷却期内不会再尝试建连。
+	reachability *reachabilityTracker
+
+	maxSessionsPerHost int
+	idleTimeout        time.Duration
+	keepaliveInterval  time.Duration
+}
+
+var getSSHConnPool = func() func() *sshConnPool {
+	var pool *sshConnPool
+	once := &sync.Once{}
+	return func() *sshConnPool {
+		once.Do(func() {
+			pool = newSSHConnPool()
+			go pool.maintain()
+		})
+		return pool
+	}
+}()
+
+func newSSHConnPool() *sshConnPool {
+	pool := &sshConnPool{
+		conns:              make(map[sshConnPoolKey]*pooledSSHConn),
+		sessionLimiters:    make(map[string]chan struct{}),
+		reachability:       newReachabilityTracker(),
+		maxSessionsPerHost: defaultMaxSessionsPerHost,
+		idleTimeout:        defaultSSHConnIdleTimeout,
+		keepaliveInterval:  defaultSSHConnKeepaliveInterval,
+	}
+	if conf := config.GetConfig(); conf != nil && conf.SSHPoolConfig != nil {
+		c := conf.SSHPoolConfig
+		if c.MaxSessionsPerHost > 0 {
+			pool.maxSessionsPerHost = c.MaxSessionsPerHost
+		}
+		if c.IdleTimeoutSeconds > 0 {
+			pool.idleTimeout = time.Duration(c.IdleTimeoutSeconds) * time.Second
+		}
+		if c.KeepaliveIntervalSeconds > 0 {
+			pool.keepaliveInterval = time.Duration(c.KeepaliveIntervalSeconds) * time.Second
+		}
+	}
+	return pool
+}
+
+// Get 获取一条可用的连接，如果池中没有则新建，新建时会检查sudo权限以及操作系统类型并缓存下来。
+// 该服务器连续建连失败、处于冷却期时（param.IgnoreBackoff除外），不会尝试建连，直接返回ServerUnreachableErr。
+// 使用完毕后必须调用Release。
+func (p *sshConnPool) Get(param *OpenExecutorServiceParam) (*pooledSSHConn, *SErr.APIErr) {
+	key := newSSHConnPoolKey(param)
+	p.mu.Lock()
+	if pc, ok := p.conns[key]; ok {
+		if !pc.invalidated && pc.conn.healthy() {
+			pc.refs++
+			pc.lastUsed = time.Now()
+			p.mu.Unlock()
+			return pc, nil
+		}
+		p.removeLocked(pc)
+	}
+	p.mu.Unlock()
+
+	hostPort := hostPortKey(param.Host, param.Port)
+	if !param.IgnoreBackoff {
+		if err := p.reachability.check(hostPort); err != nil {
+			return nil, err
+		}
+	}
+	// 建连比较耗时，不持有锁。
+	conn, osRelease, err := dialLinuxSSHConnection(param)
+	p.reachability.record(hostPort, err)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc, ok := p.conns[key]; ok && !pc.invalidated && pc.conn.healthy() {
+		// 并发建连时，其他请求已经放入了一条连接，那么关闭自己新建的这条。
+		_ = conn.Close()
+		pc.refs++
+		pc.lastUsed = time.Now()
+		return pc, nil
+	}
+	limiter, ok := p.sessionLimiters[hostPort]
+	if !ok {
+		limiter = make(chan struct{}, p.maxSessionsPerHost)
+		p.sessionLimiters[hostPort] = limiter
+	}
+	conn.sessionLimiter = limiter
+	jumpHostPorts := make([]string, 0, len(param.JumpHops))
+	for _, hop := range param.JumpHops {
+		jumpHostPorts = append(jumpHostPorts, hostPortKey(hop.Host, hop.Port))
+	}
+	pc := &pooledSSHConn{
+		key:           key,
+		hostPort:      hostPort,
+		jumpHostPorts: jumpHostPorts,
+		conn:          conn,
+		osRelease:     osRelease,
+		refs:          1,
+		lastUsed:      time.Now(),
+	}
+	p.conns[key] = pc
+	return pc, nil
+}
+
+// Release 归还连接。已经被置为失效的连接在没有人使用后关闭。
+func (p *sshConnPool) Release(pc *pooledSSHConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc.refs--
+	pc.lastUsed = time.Now()
+	if pc.refs <= 0 && (pc.invalidated || !pc.conn.healthy()) {
+		p.removeLocked(pc)
+	}
+}
+
+// Invalidate 使某个服务器的全部连接，以及经过该服务器跳转的全部连接失效。用于服务器的认证信息被修改或者服务器被删除时。
+// 正在使用中的连接会在归还后关闭。该服务器的连接状态也被清除，下次使用时会立即尝试建连。
+func (p *sshConnPool) Invalidate(host string, port uint) {
+	hostPort := hostPortKey(host, port)
+	p.reachability.reset(hostPort)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pc := range p.conns {
+		if !pc.via(hostPort) {
+			continue
+		}
+		pc.invalidated = true
+		if pc.refs <= 0 {
+			p.removeLocked(pc)
+		}
+	}
+}
+
+// via 该连接的目标服务器或者经过的某个跳板机是否为hostPort。
+func (pc *pooledSSHConn) via(hostPort string) bool {
+	if pc.hostPort == hostPort {
+		return true
+	}
+	for _, jump := range pc.jumpHostPorts {
+		if jump == hostPort {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLocked 从池中移除并关闭连接。调用者需要持有锁。
+func (p *sshConnPool) removeLocked(pc *pooledSSHConn) {
+	if cur, ok := p.conns[pc.key]; ok && cur == pc {
+		delete(p.conns, pc.key)
+	}
+	if pc.refs > 0 {
+		// 仍然有人在使用，等Release时再关闭。
+		pc.invalidated = true
+		return
+	}
+	go func() {
+		err := pc.conn.Close()
+		if err != nil {
+			log.Printf("sshConnPool close connection failed, conn=[%s], err=[%v]", pc.conn, err)
+		}
+	}()
+}
+
+// maintain 定期淘汰空闲连接，并对其余的空闲连接做keepalive检查。
+func (p *sshConnPool) maintain() {
+	ticker := time.NewTicker(p.keepaliveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.evictIdle()
+		p.keepalive()
+	}
+}
+
+func (p *sshConnPool) evictIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, pc := range p.conns {
+		if pc.refs <= 0 && now.Sub(pc.lastUsed) > p.idleTimeout {
+			log.Printf("sshConnPool evict idle connection, conn=[%s]", pc.conn)
+			p.removeLocked(pc)
+		}
+	}
+}
+
+func (p *sshConnPool) keepalive() {
+	p.mu.Lock()
+	idles := make([]*pooledSSHConn, 0, len(p.conns))
+	for _, pc := range p.conns {
+		if pc.refs <= 0 {
+			idles = append(idles, pc)
+		}
+	}
+	p.mu.Unlock()
+	for _, pc := range idles {
+		if pc.conn.sendKeepalive(sshKeepaliveTimeout) {
+			continue
+		}
+		log.Printf("sshConnPool keepalive failed, conn=[%s]", pc.conn)
+		p.mu.Lock()
+		pc.invalidated = true
+		if pc.refs <= 0 {
+			p.removeLocked(pc)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// InvalidateConnections 使连接池中某个服务器的全部连接失效。
+func InvalidateConnections(host string, port uint) {
+	getSSHConnPool().Invalidate(host, port)
+}
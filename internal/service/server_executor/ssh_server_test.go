@@ -0,0 +1,763 @@
+package server_executor
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer 测试使用的进程内SSH服务器，不需要任何外部机器。
+// 它使用密码或者公钥认证，模拟sudo -S读取密码的过程，并按脚本名回答cmds_scripts中的脚本：
+// 固定输出的脚本（os_info、top、lscpu等）从testdata/ssh_server下的同名文件读取，账户与目录相关的脚本由fakeLinuxMachine模拟。
+// 还可以注入失败：拒绝认证、在执行某个脚本时断开连接、让某个脚本以非0的退出码结束。
+type testSSHServer struct {
+	t        *testing.T
+	listener net.Listener
+	config   *ssh.ServerConfig
+	matchers []*scriptMatcher
+
+	Host               string
+	Port               uint
+	Account            string
+	Password           string
+	HostKeyFingerprint string
+
+	mu sync.Mutex
+	// sudoPassword sudo使用的密码，默认与登录密码相同。
+	sudoPassword string
+	// sudoNoPassword 模拟NOPASSWD的sudo用户，此时sudo不需要密码。
+	sudoNoPassword bool
+	rejectAuth     bool
+	authorizedKeys map[string]bool
+	dropOn         map[string]bool
+	failures       map[string]*testSSHFailure
+	machine        *fakeLinuxMachine
+	// executed 按顺序记录执行过的脚本名。
+	executed []string
+	conns    []*ssh.ServerConn
+}
+
+// testSSHFailure 让某个脚本以ExitStatus退出，并输出Stderr。
+type testSSHFailure struct {
+	ExitStatus int
+	Stderr     string
+}
+
+// scriptMatcher 将渲染后的命令还原为脚本名以及参数。
+type scriptMatcher struct {
+	name   string
+	params []string
+	reg    *regexp.Regexp
+}
+
+const testSSHAccount = "admin"
+const testSSHPassword = "admin'pwd"
+
+// newTestSSHServer 在127.0.0.1的随机端口上启动一个服务器，测试结束时自动关闭。
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSSHServer{
+		t:                  t,
+		listener:           listener,
+		matchers:           newScriptMatchers(t),
+		Host:               "127.0.0.1",
+		Port:               uint(listener.Addr().(*net.TCPAddr).Port),
+		Account:            testSSHAccount,
+		Password:           testSSHPassword,
+		HostKeyFingerprint: ssh.FingerprintSHA256(signer.PublicKey()),
+		sudoPassword:       testSSHPassword,
+		authorizedKeys:     make(map[string]bool),
+		dropOn:             make(map[string]bool),
+		failures:           make(map[string]*testSSHFailure),
+		machine:            newFakeLinuxMachine(t),
+	}
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.rejectAuth || conn.User() != s.Account || string(password) != s.Password {
+				return nil, fmt.Errorf("password rejected for %s", conn.User())
+			}
+			return nil, nil
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.rejectAuth || conn.User() != s.Account || !s.authorizedKeys[string(key.Marshal())] {
+				return nil, fmt.Errorf("public key rejected for %s", conn.User())
+			}
+			return nil, nil
+		},
+	}
+	s.config.AddHostKey(signer)
+	go s.serve()
+	t.Cleanup(func() {
+		// 连接池中的连接以Host与Port为key，每个测试的服务器端口不同，但仍然需要关闭这些连接。
+		InvalidateConnections(s.Host, s.Port)
+		s.Close()
+	})
+	return s
+}
+
+// newScriptMatchers 为每个脚本生成匹配渲染后命令的正则，参数的位置匹配shellQuote的输出。
+func newScriptMatchers(t *testing.T) []*scriptMatcher {
+	infos, err := ListCmdScripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	quotedReg := `('(?:[^']|'"'"')*')`
+	matchers := make([]*scriptMatcher, 0, len(infos))
+	for _, info := range infos {
+		tmpl, err := loadCmdTemplate([]string{info.Dir}, info.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &scriptMatcher{name: info.Name}
+		expr := &strings.Builder{}
+		last := 0
+		for _, loc := range tmpl.placeholders() {
+			expr.WriteString(regexp.QuoteMeta(tmpl.body[last:loc[0]]))
+			expr.WriteString(quotedReg)
+			m.params = append(m.params, tmpl.body[loc[2]:loc[3]])
+			last = loc[1]
+		}
+		expr.WriteString(regexp.QuoteMeta(tmpl.body[last:]))
+		m.reg = regexp.MustCompile("^" + expr.String() + "$")
+		matchers = append(matchers, m)
+	}
+	return matchers
+}
+
+// match 还原出脚本名以及参数，不是脚本时返回false。
+func (s *testSSHServer) match(cmd string) (string, CmdArgs, bool) {
+	for _, m := range s.matchers {
+		sub := m.reg.FindStringSubmatch(cmd)
+		if sub == nil {
+			continue
+		}
+		args := make(CmdArgs, len(m.params))
+		for i, name := range m.params {
+			quoted := sub[i+1]
+			args[name] = strings.ReplaceAll(quoted[1:len(quoted)-1], `'"'"'`, `'`)
+		}
+		return m.name, args, true
+	}
+	return "", nil, false
+}
+
+func (s *testSSHServer) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// SetSudoPassword 使sudo的密码与登录密码不同。
+func (s *testSSHServer) SetSudoPassword(pwd string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sudoPassword = pwd
+}
+
+// SetSudoNoPassword 模拟NOPASSWD的sudo用户。
+func (s *testSSHServer) SetSudoNoPassword(noPassword bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sudoNoPassword = noPassword
+}
+
+// AuthorizeNewKey 生成一个新的私钥并允许它登录，返回PEM格式的私钥。
+func (s *testSSHServer) AuthorizeNewKey() string {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorizedKeys[string(signer.PublicKey().Marshal())] = true
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// RejectAuth 拒绝之后的全部认证。
+func (s *testSSHServer) RejectAuth(reject bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectAuth = reject
+}
+
+// DropOn 在收到名为script的脚本时直接断开该连接，不返回退出码。
+func (s *testSSHServer) DropOn(script string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropOn[script] = true
+}
+
+// Fail 使名为script的脚本以exitStatus退出。
+func (s *testSSHServer) Fail(script string, exitStatus int, stderr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[script] = &testSSHFailure{ExitStatus: exitStatus, Stderr: stderr}
+}
+
+// SetFixture 替换固定输出的脚本的输出，例如通过os_info模拟其它发行版。
+func (s *testSSHServer) SetFixture(script, output string) {
+	s.machine.mu.Lock()
+	defer s.machine.mu.Unlock()
+	s.machine.fixtures[script] = output
+}
+
+// Executed 返回执行过的脚本名。
+func (s *testSSHServer) Executed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.executed...)
+}
+
+// OpenParam 使用该服务器的地址与管理员账户生成建连参数。
+func (s *testSSHServer) OpenParam() *OpenExecutorServiceParam {
+	return &OpenExecutorServiceParam{
+		Host:             s.Host,
+		Port:             s.Port,
+		OSType:           daModels.OSTypeLinux,
+		AdminAccountName: s.Account,
+		AdminAccountPwd:  s.Password,
+	}
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(netConn)
+	}
+}
+
+func (s *testSSHServer) handleConn(netConn net.Conn) {
+	conn, chans, reqs, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		_ = netConn.Close()
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+	// keepalive@openssh.com之类的全局请求一律回复失败，客户端只关心是否有回复。
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.handleSession(conn, newChannel)
+		case "direct-tcpip":
+			go s.handleDirectTCPIP(newChannel)
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+// handleDirectTCPIP 转发到目标地址，使该服务器可以作为跳板机使用。
+func (s *testSSHServer) handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		_ = target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		_, _ = io.Copy(target, channel)
+		_ = target.Close()
+	}()
+	_, _ = io.Copy(channel, target)
+	_ = channel.Close()
+}
+
+func (s *testSSHServer) handleSession(conn *ssh.ServerConn, newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	pty := &testPTY{}
+	for req := range reqs {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			exitStatus, ok := s.exec(conn, channel, payload.Command)
+			if !ok {
+				return
+			}
+			_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(exitStatus)}))
+			return
+		case "env":
+			_ = req.Reply(true, nil)
+		case "pty-req":
+			var payload struct {
+				Term                      string
+				Cols, Rows, Width, Height uint32
+				Modes                     string
+			}
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			pty.resize(payload.Cols, payload.Rows)
+			_ = req.Reply(true, nil)
+		case "window-change":
+			var payload struct{ Cols, Rows, Width, Height uint32 }
+			if err := ssh.Unmarshal(req.Payload, &payload); err == nil {
+				pty.resize(payload.Cols, payload.Rows)
+			}
+		case "shell":
+			_ = req.Reply(true, nil)
+			go func() {
+				exitStatus := pty.shell(channel)
+				_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(exitStatus)}))
+				_ = channel.Close()
+			}()
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// testPTY 模拟分配了PTY的交互式shell：回显输入，按行执行size、echo与exit三个命令。
+type testPTY struct {
+	mu         sync.Mutex
+	cols, rows uint32
+}
+
+func (p *testPTY) resize(cols, rows uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cols, p.rows = cols, rows
+}
+
+// shell 直到收到exit命令或者输入结束，返回shell的退出码。与真实的终端一样，回车为\r，输出的换行为\r\n。
+func (p *testPTY) shell(channel ssh.Channel) int {
+	_, _ = io.WriteString(channel, "$ ")
+	reader := bufio.NewReader(channel)
+	line := ""
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0
+		}
+		if b != '\r' && b != '\n' {
+			line += string(b)
+			_, _ = channel.Write([]byte{b})
+			continue
+		}
+		_, _ = io.WriteString(channel, "\r\n")
+		switch fields := strings.Fields(line); {
+		case len(fields) == 0:
+		case fields[0] == "exit":
+			status := 0
+			if len(fields) > 1 {
+				status, _ = strconv.Atoi(fields[1])
+			}
+			return status
+		case fields[0] == "size":
+			p.mu.Lock()
+			_, _ = fmt.Fprintf(channel, "%dx%d\r\n", p.cols, p.rows)
+			p.mu.Unlock()
+		case fields[0] == "echo":
+			_, _ = io.WriteString(channel, strings.Join(fields[1:], " ")+"\r\n")
+		default:
+			_, _ = fmt.Fprintf(channel, "bash: %s: command not found\r\n", fields[0])
+		}
+		line = ""
+		_, _ = io.WriteString(channel, "$ ")
+	}
+}
+
+// exec 执行一条命令，返回退出码。连接被主动断开时返回false。
+func (s *testSSHServer) exec(conn *ssh.ServerConn, channel ssh.Channel, cmd string) (int, bool) {
+	withSudo := strings.HasPrefix(cmd, sudoPrelude)
+	cmd = strings.TrimPrefix(cmd, sudoPrelude)
+	if sub := collectMarkerReg.FindStringSubmatch(cmd); sub != nil {
+		return s.execCollect(conn, channel, cmd, sub[1], withSudo)
+	}
+	script, args, ok := s.match(cmd)
+	if !ok {
+		// 不是脚本的命令（例如SendCommands直接发送的命令），以命令本身作为脚本名。
+		script, args = cmd, nil
+	}
+
+	s.mu.Lock()
+	s.executed = append(s.executed, script)
+	drop := s.dropOn[script]
+	failure := s.failures[script]
+	sudoPassword, sudoNoPassword := s.sudoPassword, s.sudoNoPassword
+	s.mu.Unlock()
+
+	if drop {
+		_ = conn.Close()
+		return 0, false
+	}
+	if script == sftpServerScript {
+		return s.serveSFTP(channel, sudoPassword, sudoNoPassword), true
+	}
+	if !s.checkSudo(channel, cmd, withSudo) {
+		return 1, true
+	}
+	if failure != nil {
+		_, _ = io.WriteString(channel.Stderr(), failure.Stderr)
+		return failure.ExitStatus, true
+	}
+	stdout, stderr, exitStatus := s.machine.run(script, args)
+	_, _ = io.WriteString(channel, stdout)
+	_, _ = io.WriteString(channel.Stderr(), stderr)
+	return exitStatus, true
+}
+
+// collectMarkerReg 与collectSectionReg从buildCollectCmd生成的组合命令中还原出分隔符以及每个脚本的命令。
+var collectMarkerReg = regexp.MustCompile(`^__ss_m='([^']*)';`)
+var collectSectionReg = regexp.MustCompile(`\| timeout [0-9]+ sh -c '((?:[^']|'"'"')*)' 2>"\$__ss_err"\n`)
+
+// execCollect 与真实的shell一样执行组合命令：只读取一次sudo的密码，之后逐个执行其中的脚本，并按buildCollectCmd的格式输出。
+// 执行过的脚本按顺序记录为collect_info以及每个脚本的脚本名，注入的断开连接与失败对每个脚本分别生效。
+func (s *testSSHServer) execCollect(conn *ssh.ServerConn, channel ssh.Channel, cmd string, marker string, withSudo bool) (int, bool) {
+	s.mu.Lock()
+	s.executed = append(s.executed, collectScriptName)
+	s.mu.Unlock()
+	if !s.checkSudo(channel, cmd, withSudo) {
+		return 1, true
+	}
+	now := time.Now().UnixNano()
+	for i, sub := range collectSectionReg.FindAllStringSubmatch(cmd, -1) {
+		scriptCmd := strings.TrimPrefix(strings.ReplaceAll(sub[1], `'"'"'`, `'`), sudoPrelude)
+		script, args, ok := s.match(scriptCmd)
+		if !ok {
+			script, args = scriptCmd, nil
+		}
+		s.mu.Lock()
+		s.executed = append(s.executed, script)
+		drop := s.dropOn[script]
+		failure := s.failures[script]
+		s.mu.Unlock()
+
+		_, _ = fmt.Fprintf(channel, "\n%s begin %d %d\n", marker, i, now)
+		if drop {
+			_ = conn.Close()
+			return 0, false
+		}
+		stdout, stderr, exitStatus := "", "", 0
+		if failure != nil {
+			stderr, exitStatus = failure.Stderr, failure.ExitStatus
+		} else {
+			stdout, stderr, exitStatus = s.machine.run(script, args)
+		}
+		// 每个脚本固定耗时1ms。
+		now += int64(time.Millisecond)
+		_, _ = fmt.Fprintf(channel, "%s\n%s end %d %d\n%s", stdout, marker, exitStatus, now, stderr)
+	}
+	_, _ = fmt.Fprintf(channel, "\n%s done\n", marker)
+	return 0, true
+}
+
+// checkSudo 模拟sudoPrelude的sudo：需要密码时从stdin读取一行作为密码。sudo无法使用时输出与sudo相同的错误并返回false。
+func (s *testSSHServer) checkSudo(channel ssh.Channel, cmd string, withSudo bool) bool {
+	s.mu.Lock()
+	sudoPassword, sudoNoPassword := s.sudoPassword, s.sudoNoPassword
+	s.mu.Unlock()
+	if !withSudo && !sudoNoPassword && strings.Contains(cmd, "sudo ") {
+		// 没有sudoPrelude时sudo只能从终端读取密码，而执行命令时并没有分配PTY。
+		_, _ = io.WriteString(channel.Stderr(), "sudo: a terminal is required to read the password; either use the -S option to read from standard input or configure an askpass helper\n")
+		return false
+	}
+	if withSudo && !sudoNoPassword {
+		// 与sudoPrelude一样，从stdin读取一行作为sudo的密码。
+		line, err := bufio.NewReader(channel).ReadString('\n')
+		if err != nil && line == "" {
+			_, _ = io.WriteString(channel.Stderr(), "sudo: no password was provided\nsudo: a password is required\n")
+			return false
+		}
+		if strings.TrimSuffix(line, "\n") != sudoPassword {
+			_, _ = io.WriteString(channel.Stderr(), "sudo: 1 incorrect password attempt\n")
+			return false
+		}
+	}
+	return true
+}
+
+// serveSFTP 与sftp_server脚本一样，先从stdin读取一行作为sudo的密码，之后在channel上提供本机文件系统的SFTP服务。
+// 密码逐字节读取，避免读走之后的SFTP数据。
+func (s *testSSHServer) serveSFTP(channel ssh.Channel, sudoPassword string, sudoNoPassword bool) int {
+	line := make([]byte, 0, len(sudoPassword)+1)
+	b := make([]byte, 1)
+	for {
+		if _, err := channel.Read(b); err != nil {
+			return 1
+		}
+		if b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
+	}
+	if !sudoNoPassword && string(line) != sudoPassword {
+		_, _ = io.WriteString(channel.Stderr(), "sudo: 1 incorrect password attempt\n")
+		return 1
+	}
+	server, err := sftp.NewServer(channel)
+	if err != nil {
+		_, _ = io.WriteString(channel.Stderr(), err.Error())
+		return 1
+	}
+	if err := server.Serve(); err != nil && err != io.EOF {
+		return 1
+	}
+	return 0
+}
+
+// fakeLinuxMachine 模拟服务器上的账户、sudoers以及目录，固定输出的脚本从fixture文件读取。
+type fakeLinuxMachine struct {
+	mu       sync.Mutex
+	accounts map[string]*fakeLinuxAccount
+	dirs     map[string]bool
+	sudoers  string
+	fixtures map[string]string
+}
+
+type fakeLinuxAccount struct {
+	Name string
+	UID  int
+	GID  int
+	Home string
+}
+
+func newFakeLinuxMachine(t *testing.T) *fakeLinuxMachine {
+	m := &fakeLinuxMachine{
+		accounts: make(map[string]*fakeLinuxAccount),
+		dirs:     map[string]bool{"/": true, "/home": true, "/root": true},
+		fixtures: make(map[string]string),
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "ssh_server", "*"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("load ssh server fixtures failed, err=%v", err)
+	}
+	for _, file := range files {
+		bs, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.fixtures[filepath.Base(file)] = string(bs)
+	}
+	// 初始的账户与sudoers来自fixture，之后由账户相关的脚本修改。
+	for _, line := range strings.Split(m.fixtures["get_account_list"], "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) != 3 {
+			continue
+		}
+		uid, _ := strconv.Atoi(fields[1])
+		gid, _ := strconv.Atoi(fields[2])
+		home := "/home/" + fields[0]
+		if fields[0] == "root" {
+			home = "/root"
+		}
+		m.accounts[fields[0]] = &fakeLinuxAccount{Name: fields[0], UID: uid, GID: gid, Home: home}
+		m.dirs[home] = true
+	}
+	m.sudoers = m.fixtures["cat_sudoers"]
+	return m
+}
+
+func (m *fakeLinuxMachine) run(script string, args CmdArgs) (string, string, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch script {
+	case "sudo_privilege":
+		return "Test Sudo\n", "", 0
+	case "get_account_list":
+		accounts := make([]*fakeLinuxAccount, 0, len(m.accounts))
+		for _, account := range m.accounts {
+			accounts = append(accounts, account)
+		}
+		sort.Slice(accounts, func(i, j int) bool { return accounts[i].UID < accounts[j].UID })
+		out := &strings.Builder{}
+		for _, account := range accounts {
+			fmt.Fprintf(out, "%s|%d|%d\n", account.Name, account.UID, account.GID)
+		}
+		return out.String(), "", 0
+	case "cat_sudoers":
+		return m.sudoers, "", 0
+	case "add_sudoers":
+		m.sudoers += fmt.Sprintf("%s ALL=(ALL:ALL) ALL\n", args["account_name"])
+		return "", "", 0
+	case "user_add", "user_add_with_openssl_pwd":
+		name := args["account_name"]
+		if _, ok := m.accounts[name]; ok {
+			return "", fmt.Sprintf("useradd: user '%s' already exists\n", name), 9
+		}
+		uid := 1000
+		for _, account := range m.accounts {
+			if account.UID >= uid {
+				uid = account.UID + 1
+			}
+		}
+		account := &fakeLinuxAccount{Name: name, UID: uid, GID: uid, Home: "/home/" + name}
+		m.accounts[name] = account
+		stderr := ""
+		if m.dirs[account.Home] {
+			stderr = "useradd: warning: the home directory already exists.\n"
+		}
+		m.dirs[account.Home] = true
+		return "", stderr, 0
+	case "user_del":
+		name := args["account_name"]
+		if _, ok := m.accounts[name]; !ok {
+			return "", fmt.Sprintf("userdel: user '%s' does not exist\n", name), 6
+		}
+		delete(m.accounts, name)
+		return "", "", 0
+	case "get_user_home_dir":
+		if account, ok := m.accounts[args["account_name"]]; ok {
+			return account.Home + "\n", "", 0
+		}
+		return "", "", 0
+	case "path_exists", "is_dir":
+		p := args["path"]
+		if script == "is_dir" {
+			p = args["dir_path"]
+		}
+		if m.dirs[path.Clean(p)] {
+			return "1\n", "", 0
+		}
+		return "0\n", "", 0
+	case "is_file":
+		// 只模拟了目录，不存在任何文件。
+		return "0\n", "", 0
+	case "dir_empty":
+		p := path.Clean(args["dir_path"])
+		for dir := range m.dirs {
+			if strings.HasPrefix(dir, p+"/") {
+				return "0\n", "", 0
+			}
+		}
+		return "1\n", "", 0
+	case "mkdir":
+		p := path.Clean(args["dir_path"])
+		if m.dirs[p] {
+			return "", fmt.Sprintf("mkdir: cannot create directory '%s': File exists\n", p), 1
+		}
+		if !m.dirs[path.Dir(p)] {
+			return "", fmt.Sprintf("mkdir: cannot create directory '%s': No such file or directory\n", p), 1
+		}
+		m.dirs[p] = true
+		return "", "", 0
+	case "mv", "mv_force":
+		src, dst := path.Clean(args["src"]), path.Clean(args["dst"])
+		if !m.dirs[src] {
+			return "", fmt.Sprintf("mv: cannot stat '%s': No such file or directory\n", src), 1
+		}
+		// 与mv相同，目标是已经存在的目录时移动到它的里面。
+		if m.dirs[dst] {
+			dst = path.Join(dst, path.Base(src))
+		}
+		for dir := range m.dirs {
+			if dir == src || strings.HasPrefix(dir, src+"/") {
+				delete(m.dirs, dir)
+				m.dirs[dst+strings.TrimPrefix(dir, src)] = true
+			}
+		}
+		return "", "", 0
+	case "backup_dir_list":
+		root := path.Clean(args["backup_root"])
+		m.dirs[root] = true
+		names := make([]string, 0, 4)
+		for dir := range m.dirs {
+			if path.Dir(dir) == root && strings.HasSuffix(dir, ".backup") {
+				names = append(names, path.Base(dir))
+			}
+		}
+		sort.Strings(names)
+		out := &strings.Builder{}
+		for _, name := range names {
+			fmt.Fprintf(out, "d|%s\n", name)
+		}
+		return out.String(), "", 0
+	case "run_script":
+		// 并不真正执行，而是输出解码后的脚本，测试据此检查渲染的结果。
+		content, err := base64.StdEncoding.DecodeString(args["script"])
+		if err != nil {
+			return "", "base64: invalid input\n", 1
+		}
+		return string(content), "", 0
+	}
+	if out, ok := m.fixtures[script]; ok {
+		return out, "", 0
+	}
+	return "", fmt.Sprintf("sh: 1: %s: not found\n", script), 127
+}
+
+// dirExists 检查模拟的目录是否存在。
+func (m *fakeLinuxMachine) dirExists(p string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dirs[p]
+}
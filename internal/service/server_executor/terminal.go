@@ -0,0 +1,166 @@
+package server_executor
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"log"
+	"sync"
+)
+
+const (
+	// TerminalType 终端类型，与前端使用的xterm.js一致。
+	TerminalType          = "xterm-256color"
+	defaultTerminalCols   = 80
+	defaultTerminalRows   = 24
+	maxTerminalDimensions = 1000
+)
+
+// Terminal 一个交互式的终端：在服务器上分配PTY并启动登录shell。
+// 它使用单独建立的SSH连接，而不是连接池中的连接，因为它可能持续很久，并且关闭时需要连同连接一起关闭。
+type Terminal struct {
+	conn    *LinuxSSHConnection
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+
+	closeOnce sync.Once
+	waitOnce  sync.Once
+	exit      int
+	exitErr   *SErr.APIErr
+}
+
+// TerminalSize 将前端请求的终端大小限制在合理的范围内，为0时使用默认大小，返回实际使用的列数与行数。
+func TerminalSize(cols, rows int) (int, int) {
+	if cols <= 0 {
+		cols = defaultTerminalCols
+	}
+	if rows <= 0 {
+		rows = defaultTerminalRows
+	}
+	if cols > maxTerminalDimensions {
+		cols = maxTerminalDimensions
+	}
+	if rows > maxTerminalDimensions {
+		rows = maxTerminalDimensions
+	}
+	return cols, rows
+}
+
+// OpenTerminal 使用管理员账户登录服务器，分配一个cols列、rows行的PTY并启动登录shell。
+// 只支持通过SSH管理的服务器。
+func OpenTerminal(param *OpenExecutorServiceParam, cols, rows int) (*Terminal, *SErr.APIErr) {
+	if param.OSType != daModels.OSTypeLinux {
+		return nil, SErr.InvalidParamErr.CustomMessageF("只能在通过SSH管理的服务器上打开终端！服务器的类型为：%s", param.OSType)
+	}
+	conn, err := openLinuxSSHConnection(param.Host, param.Port, param.AdminAccountName, param.sshAuth(), param.HostKeyFingerprint, param.JumpHops)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newTerminal(conn, cols, rows)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func newTerminal(conn *LinuxSSHConnection, cols, rows int) (*Terminal, *SErr.APIErr) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, SErr.SSHConnectionErr.CustomMessageF("打开终端时，创建SSH session失败！错误信息为：%s", err.Error())
+	}
+	fail := func(step string, err error) (*Terminal, *SErr.APIErr) {
+		_ = session.Close()
+		return nil, SErr.SSHConnectionErr.CustomMessageF("打开终端时，%s失败！错误信息为：%s", step, err.Error())
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fail("获取stdin", err)
+	}
+	// 分配了PTY之后，stderr也会写入终端，服务器只通过stdout返回输出。
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fail("获取stdout", err)
+	}
+	cols, rows = TerminalSize(cols, rows)
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty(TerminalType, rows, cols, modes); err != nil {
+		return fail("分配PTY", err)
+	}
+	if err := session.Shell(); err != nil {
+		return fail("启动shell", err)
+	}
+	log.Printf("Terminal opened, conn=[%s], cols=[%d], rows=[%d]", conn, cols, rows)
+	return &Terminal{
+		conn:    conn,
+		session: session,
+		stdin:   stdin,
+		stdout:  stdout,
+	}, nil
+}
+
+// HostKeyFingerprint 返回建立连接时服务器提供的host key指纹，用于没有记录指纹的服务器在第一次连接后记录它。
+func (t *Terminal) HostKeyFingerprint() string {
+	return t.conn.HostKeyFingerprint
+}
+
+func (t *Terminal) String() string {
+	return fmt.Sprintf("Terminal=[%s]", t.conn)
+}
+
+// Read 读取终端的输出，shell退出后返回io.EOF。
+func (t *Terminal) Read(p []byte) (int, error) {
+	return t.stdout.Read(p)
+}
+
+// Write 将用户的输入（包括控制字符）写入终端。
+func (t *Terminal) Write(p []byte) (int, error) {
+	return t.stdin.Write(p)
+}
+
+// Resize 修改终端的大小。
+func (t *Terminal) Resize(cols, rows int) *SErr.APIErr {
+	cols, rows = TerminalSize(cols, rows)
+	if err := t.session.WindowChange(rows, cols); err != nil {
+		return SErr.SSHConnectionErr.CustomMessageF("修改终端大小失败！错误信息为：%s", err.Error())
+	}
+	return nil
+}
+
+// Wait 等待shell退出，返回它的退出码。shell没有正常退出时（例如连接断开或者终端被关闭）返回-1以及对应的错误。
+// 可以多次调用，返回相同的结果。
+func (t *Terminal) Wait() (int, *SErr.APIErr) {
+	t.waitOnce.Do(func() {
+		err := t.session.Wait()
+		if err == nil {
+			return
+		}
+		exitErr := &ssh.ExitError{}
+		if errors.As(err, &exitErr) {
+			t.exit = exitErr.ExitStatus()
+			return
+		}
+		t.exit = -1
+		t.exitErr = SErr.SSHConnectionErr.CustomMessageF("终端没有正常退出！错误信息为：%s", err.Error())
+	})
+	return t.exit, t.exitErr
+}
+
+// Close 关闭终端以及它使用的SSH连接，shell仍在运行时会被终止。
+func (t *Terminal) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		_ = t.session.Close()
+		err = t.conn.Close()
+		log.Printf("Terminal closed, conn=[%s]", t.conn)
+	})
+	return err
+}
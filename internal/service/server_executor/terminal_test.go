@@ -0,0 +1,91 @@
+package server_executor
+
+import (
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readUntil 读取终端的输出，直到出现want。
+func readUntil(t *testing.T, r *bufio.Reader, want string) string {
+	t.Helper()
+	out := &strings.Builder{}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q, got %q", want, out.String())
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatalf("read failed after %q: %v", out.String(), err)
+		}
+		out.WriteByte(b)
+	}
+	return out.String()
+}
+
+func TestTerminal(t *testing.T) {
+	server := newTestSSHServer(t)
+	term, err := OpenTerminal(server.OpenParam(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+	if term.HostKeyFingerprint() != server.HostKeyFingerprint {
+		t.Fatalf("unexpected host key fingerprint %s", term.HostKeyFingerprint())
+	}
+	r := bufio.NewReader(term)
+	readUntil(t, r, "$ ")
+
+	// 没有指定大小时使用默认大小，之后可以修改。
+	_, _ = io.WriteString(term, "size\r")
+	readUntil(t, r, "80x24\r\n$ ")
+	if err := term.Resize(132, 43); err != nil {
+		t.Fatal(err)
+	}
+	// window-change不需要回复，服务器可能在处理它之前就执行了下一条命令。
+	for i := 0; ; i++ {
+		_, _ = io.WriteString(term, "size\r")
+		if strings.Contains(readUntil(t, r, "\r\n$ "), "132x43") {
+			break
+		}
+		if i == 10 {
+			t.Fatal("terminal was not resized")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, _ = io.WriteString(term, "echo hello\r")
+	readUntil(t, r, "hello\r\n$ ")
+	_, _ = io.WriteString(term, "exit 3\r")
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := term.Wait(); status != 3 || err != nil {
+		t.Fatalf("unexpected exit %d, err %v", status, err)
+	}
+}
+
+func TestTerminalClose(t *testing.T) {
+	server := newTestSSHServer(t)
+	term, err := OpenTerminal(server.OpenParam(), 100, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, bufio.NewReader(term), "$ ")
+	// 前端断开时直接关闭终端，shell没有正常退出。
+	_ = term.Close()
+	if status, err := term.Wait(); status != -1 || err == nil {
+		t.Fatalf("unexpected exit %d, err %v", status, err)
+	}
+
+	param := server.OpenParam()
+	param.OSType = daModels.OSTypeLinuxLocal
+	if _, err := OpenTerminal(param, 0, 0); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unexpected err %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package server_executor
+
+import (
+	"ServerServing/config"
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transcript 录制下来的一次ExecutorService会话，包含在服务器上执行过的每个脚本的名称、参数以及执行结果。
+// 回放时不需要真实的服务器，上层仍然使用与真实连接相同的脚本渲染与输出解析逻辑。
+type Transcript struct {
+	OSType  LinuxOSType        `json:"os_type"`
+	Host    string             `json:"host"`
+	Port    uint               `json:"port"`
+	Entries []*TranscriptEntry `json:"entries"`
+	// OSRelease 录制时解析的/etc/os-release，旧的录制记录中没有。
+	OSRelease *OSRelease `json:"os_release,omitempty"`
+}
+
+// TranscriptEntry 一次脚本调用。Args中敏感参数（例如密码）的值已经被隐藏。
+// ErrCode 为执行失败时返回的错误码，成功时为0。
+type TranscriptEntry struct {
+	Script  string                         `json:"script"`
+	Args    CmdArgs                        `json:"args,omitempty"`
+	Result  *internal_models.CommandResult `json:"result"`
+	ErrCode int                            `json:"err_code,omitempty"`
+}
+
+// key 回放时按脚本名与参数查找录制的结果。
+func (e *TranscriptEntry) key() string {
+	return transcriptKey(e.Script, e.Args)
+}
+
+func transcriptKey(script string, args CmdArgs) string {
+	if len(args) == 0 {
+		return script
+	}
+	// json.Marshal会对map的key排序，所以相同的参数总是得到相同的key。
+	bs, _ := json.Marshal(args)
+	return script + string(bs)
+}
+
+// LoadTranscript 从文件中加载录制记录。
+func LoadTranscript(path string) (*Transcript, *SErr.APIErr) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("读取回放记录%s失败！错误信息为：%s", path, err.Error())
+	}
+	transcript := &Transcript{}
+	if err := json.Unmarshal(bs, transcript); err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("解析回放记录%s失败！错误信息为：%s", path, err.Error())
+	}
+	if !isSupportedLinuxOSType(transcript.OSType) {
+		return nil, SErr.InternalErr.CustomMessageF("回放记录%s的操作系统类型%s不受支持！", path, transcript.OSType)
+	}
+	for i, entry := range transcript.Entries {
+		if entry.Script == "" || entry.Result == nil {
+			return nil, SErr.InternalErr.CustomMessageF("回放记录%s的第%d条记录缺少脚本名或者执行结果！", path, i+1)
+		}
+	}
+	return transcript, nil
+}
+
+// recordingRunner 将每次脚本调用的名称、参数与结果录制下来，命令本身交给runner执行。
+type recordingRunner struct {
+	runner commandRunner
+
+	mu         sync.Mutex
+	transcript *Transcript
+}
+
+func newRecordingRunner(runner commandRunner, osType LinuxOSType, Host string, Port uint) *recordingRunner {
+	return &recordingRunner{
+		runner: runner,
+		transcript: &Transcript{
+			OSType:  osType,
+			Host:    Host,
+			Port:    Port,
+			Entries: make([]*TranscriptEntry, 0),
+		},
+	}
+}
+
+func (r *recordingRunner) String() string {
+	return fmt.Sprintf("recordingRunner=[%s]", r.runner)
+}
+
+func (r *recordingRunner) Close() error {
+	return r.runner.Close()
+}
+
+func (r *recordingRunner) runScript(ctx context.Context, script *renderedScript) (*internal_models.CommandResult, *SErr.APIErr) {
+	result, err := r.runner.runCommand(ctx, nil, script.Name, script.Cmd, true)
+	r.record(script.Name, script.Args, result, err)
+	return result, err
+}
+
+// runScripts 每个脚本分别录制，所以录制记录与逐个执行时相同，也可以被逐个回放。
+func (r *recordingRunner) runScripts(ctx context.Context, scripts []*renderedScript) ([]*internal_models.CommandResult, []*SErr.APIErr) {
+	results, errs := runScriptsWith(ctx, r.runner, scripts)
+	for i, script := range scripts {
+		r.record(script.Name, script.Args, results[i], errs[i])
+	}
+	return results, errs
+}
+
+// runCommand 不是通过脚本执行的命令没有参数，以desc作为脚本名录制。
+func (r *recordingRunner) runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr) {
+	result, err := r.runner.runCommand(ctx, envs, desc, cmd, withSudo)
+	r.record(desc, nil, result, err)
+	return result, err
+}
+
+func (r *recordingRunner) record(script string, args CmdArgs, result *internal_models.CommandResult, err *SErr.APIErr) {
+	entry := &TranscriptEntry{
+		Script: script,
+		Args:   args,
+		Result: result,
+	}
+	if err != nil {
+		entry.ErrCode = err.Code
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transcript.Entries = append(r.transcript.Entries, entry)
+}
+
+// save 将录制记录写入dir下的一个新文件中，没有执行过任何脚本时不写入。
+func (r *recordingRunner) save(dir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.transcript.Entries) == 0 {
+		return nil
+	}
+	bs, err := json.MarshalIndent(r.transcript, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	host := strings.NewReplacer(":", "_", "/", "_").Replace(r.transcript.Host)
+	name := fmt.Sprintf("%s_%d_%s_%d.json", host, r.transcript.Port, r.transcript.OSType, time.Now().UnixNano())
+	// 录制记录中包含服务器的输出，例如账户列表，只允许当前用户读取。
+	return ioutil.WriteFile(filepath.Join(dir, name), bs, 0600)
+}
+
+// recordTranscriptIfConfigured 配置了transcript_record_dir时，录制template执行的每个脚本，并在Close时写入文件。
+func recordTranscriptIfConfigured(template *LinuxSSHExecutorServiceTemplate) {
+	conf := config.GetConfig()
+	if conf == nil || conf.TranscriptRecordDir == "" {
+		return
+	}
+	dir := conf.TranscriptRecordDir
+	recorder := newRecordingRunner(template.runner, template.OSType, template.Host, template.Port)
+	recorder.transcript.OSRelease = template.osRelease
+	template.runner = recorder
+	release := template.release
+	template.release = func() {
+		if err := recorder.save(dir); err != nil {
+			log.Printf("recordTranscriptIfConfigured save transcript failed, template=[%s], err=[%v]", template, err)
+		}
+		if release != nil {
+			release()
+			return
+		}
+		_ = recorder.runner.Close()
+	}
+}
+
+// replayRunner 按脚本名与参数回放录制下来的结果，不会执行任何命令。
+// 同一个脚本以相同的参数被录制了多次时按录制的顺序依次返回，用完之后一直返回最后一次的结果。
+type replayRunner struct {
+	transcript *Transcript
+
+	mu      sync.Mutex
+	entries map[string][]*TranscriptEntry
+	cursors map[string]int
+}
+
+func newReplayRunner(transcript *Transcript) *replayRunner {
+	entries := make(map[string][]*TranscriptEntry)
+	for _, entry := range transcript.Entries {
+		entries[entry.key()] = append(entries[entry.key()], entry)
+	}
+	return &replayRunner{
+		transcript: transcript,
+		entries:    entries,
+		cursors:    make(map[string]int),
+	}
+}
+
+func (r *replayRunner) String() string {
+	return fmt.Sprintf("replayRunner=[%s, %s]", net.JoinHostPort(r.transcript.Host, strconv.Itoa(int(r.transcript.Port))), r.transcript.OSType)
+}
+
+func (r *replayRunner) Close() error {
+	return nil
+}
+
+func (r *replayRunner) runScript(ctx context.Context, script *renderedScript) (*internal_models.CommandResult, *SErr.APIErr) {
+	return r.replay(ctx, script.Name, script.Args)
+}
+
+// runScripts 逐个回放，录制记录中没有的脚本只有它自己失败。
+func (r *replayRunner) runScripts(ctx context.Context, scripts []*renderedScript) ([]*internal_models.CommandResult, []*SErr.APIErr) {
+	results := make([]*internal_models.CommandResult, len(scripts))
+	errs := make([]*SErr.APIErr, len(scripts))
+	for i, script := range scripts {
+		results[i], errs[i] = r.replay(ctx, script.Name, script.Args)
+	}
+	return results, errs
+}
+
+func (r *replayRunner) runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr) {
+	return r.replay(ctx, desc, nil)
+}
+
+func (r *replayRunner) replay(ctx context.Context, script string, args CmdArgs) (*internal_models.CommandResult, *SErr.APIErr) {
+	if ctx.Err() != nil {
+		err := commandContextErr(ctx, script)
+		return &internal_models.CommandResult{Script: script, ExitStatus: -1, Error: err.Message}, err
+	}
+	key := transcriptKey(script, args)
+	r.mu.Lock()
+	entries := r.entries[key]
+	cursor := r.cursors[key]
+	if cursor < len(entries)-1 {
+		r.cursors[key] = cursor + 1
+	}
+	r.mu.Unlock()
+	if len(entries) == 0 {
+		argsBytes, _ := json.Marshal(args)
+		err := SErr.InternalErr.CustomMessageF("回放记录中没有脚本%s的执行结果！参数为：%s", script, argsBytes)
+		return &internal_models.CommandResult{Script: script, ExitStatus: -1, Error: err.Message}, err
+	}
+	entry := entries[cursor]
+	result := *entry.Result
+	result.Script = script
+	emitOutput(ctx, script, result.Stdout, result.Stderr)
+	if entry.ErrCode == 0 {
+		return &result, nil
+	}
+	return &result, replayErr(entry.ErrCode, result.Error)
+}
+
+// replayErr 还原录制时返回的错误。
+func replayErr(code int, message string) *SErr.APIErr {
+	for _, err := range []*SErr.APIErr{SErr.CommandFailedErr, SErr.CommandTimeoutErr, SErr.CommandCanceledErr, SErr.HostKeyMismatchErr} {
+		if err.Code == code {
+			return err.CustomMessage(message)
+		}
+	}
+	return SErr.InternalErr.CustomMessage(message)
+}
+
+// OpenReplayExecutorService 获取一个回放transcript的Executor服务实例，它不会连接任何服务器。
+func OpenReplayExecutorService(transcript *Transcript) ExecutorService {
+	template := newLinuxExecutorServiceTemplate(transcript.OSType, transcript.Host, transcript.Port, "", "", newReplayRunner(transcript))
+	template.osRelease = transcript.OSRelease
+	return newLinuxExecutorService(template)
+}
+
+// UseReplayTranscripts 替换OpenExecutorService，使它对transcripts中的服务器（按Host与Port匹配）回放录制下来的结果，而不建立真实的连接，
+// 其它服务器仍然使用原来的实现。用于在没有真实服务器的环境（例如CI）中测试上层服务，返回的函数用于恢复原状。
+func UseReplayTranscripts(transcripts ...*Transcript) func() {
+	m := make(map[string]*Transcript, len(transcripts))
+	for _, transcript := range transcripts {
+		m[net.JoinHostPort(transcript.Host, strconv.Itoa(int(transcript.Port)))] = transcript
+	}
+	previous := OpenExecutorService
+	OpenExecutorService = func(param *OpenExecutorServiceParam) (ExecutorService, *SErr.APIErr) {
+		if transcript, ok := m[net.JoinHostPort(param.Host, strconv.Itoa(int(param.Port)))]; ok {
+			return OpenReplayExecutorService(transcript), nil
+		}
+		return previous(param)
+	}
+	return func() {
+		OpenExecutorService = previous
+	}
+}
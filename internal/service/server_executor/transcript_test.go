@@ -0,0 +1,127 @@
+package server_executor
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner 按脚本名返回预设的标准输出，并记录实际执行的命令。
+type fakeRunner struct {
+	outputs map[string]string
+	cmds    []string
+}
+
+func (r *fakeRunner) String() string {
+	return "fakeRunner"
+}
+
+func (r *fakeRunner) Close() error {
+	return nil
+}
+
+func (r *fakeRunner) runCommand(ctx context.Context, envs map[string]string, desc string, cmd string, withSudo bool) (*internal_models.CommandResult, *SErr.APIErr) {
+	r.cmds = append(r.cmds, cmd)
+	output, ok := r.outputs[desc]
+	if !ok {
+		result := &internal_models.CommandResult{Script: desc, Stderr: "command not found\n", ExitStatus: 127}
+		err := commandFailedErr(result)
+		result.Error = err.Message
+		return result, err
+	}
+	return &internal_models.CommandResult{Script: desc, Stdout: output}, nil
+}
+
+func TestTranscriptRecordReplay(t *testing.T) {
+	inner := &fakeRunner{outputs: map[string]string{
+		"get_account_list":          "root|0|0\nalice|1001|1001\n",
+		"user_add_with_openssl_pwd": "",
+		"cat_sudoers":               "root\tALL=(ALL:ALL) ALL\n",
+		"add_sudoers":               "",
+	}}
+	recorder := newRecordingRunner(inner, Ubuntu, "192.0.2.10", 22)
+	recording := newLinuxExecutorService(newLinuxExecutorServiceTemplate(Ubuntu, "192.0.2.10", 22, "admin", "", recorder))
+	ctx := context.Background()
+	if _, err := recording.AddAccount(ctx, "bob", "s3cret'pwd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recording.GetAccountList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recording.GetGPUUsages(ctx); err == nil || err.Code != SErr.CodeCommandFailed {
+		t.Fatalf("unexpected err %+v", err)
+	}
+	if !strings.Contains(inner.cmds[0], "s3cret") {
+		t.Fatalf("the real command should contain the password, cmd=%s", inner.cmds[0])
+	}
+
+	dir := t.TempDir()
+	if err := recorder.save(dir); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("unexpected transcript files %v", files)
+	}
+	bs, _ := ioutil.ReadFile(files[0])
+	if strings.Contains(string(bs), "s3cret") || !strings.Contains(string(bs), redactedArgValue) {
+		t.Fatalf("password should be redacted in the transcript: %s", bs)
+	}
+	transcript, err := LoadTranscript(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaying := OpenReplayExecutorService(transcript)
+	defer replaying.Close()
+	// 回放时密码不同也能匹配，因为记录中的密码已经被隐藏。
+	if _, err := replaying.AddAccount(ctx, "bob", "another"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := replaying.GetAccountList(ctx)
+	if err != nil || len(resp.Accounts) != 2 || resp.Accounts[1].Name != "alice" || resp.Accounts[1].Host != "192.0.2.10" {
+		t.Fatalf("unexpected resp %+v, err %+v", resp, err)
+	}
+	gpuResp, err := replaying.GetGPUUsages(ctx)
+	if err == nil || err.Code != SErr.CodeCommandFailed || len(gpuResp.Results) != 1 || gpuResp.Results[0].ExitStatus != 127 {
+		t.Fatalf("unexpected resp %+v, err %+v", gpuResp, err)
+	}
+	// 录制中没有的参数。
+	if _, err := replaying.AddAccount(ctx, "carol", "another"); err == nil || !strings.Contains(err.Message, "carol") {
+		t.Fatalf("unexpected err %+v", err)
+	}
+}
+
+func TestReplayRunnerSequence(t *testing.T) {
+	transcript := &Transcript{OSType: CentOS, Host: "192.0.2.20", Port: 22, Entries: []*TranscriptEntry{
+		{Script: "path_exists", Args: CmdArgs{"path": "/backup"}, Result: &internal_models.CommandResult{Stdout: "0\n"}},
+		{Script: "path_exists", Args: CmdArgs{"path": "/backup"}, Result: &internal_models.CommandResult{Stdout: "1\n"}},
+	}}
+	es := OpenReplayExecutorService(transcript)
+	defer es.Close()
+	for _, want := range []bool{false, true, true} {
+		resp, err := es.PathExists(context.Background(), "/backup")
+		if err != nil || resp.Exists != want {
+			t.Fatalf("unexpected resp %+v, err %+v, want %v", resp, err, want)
+		}
+	}
+
+	restore := UseReplayTranscripts(transcript)
+	opened, err := OpenExecutorService(&OpenExecutorServiceParam{Host: "192.0.2.20", Port: 22})
+	restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opened.Close()
+	if _, ok := opened.(*CentOSSSHExecutorService); !ok {
+		t.Fatalf("unexpected executor %s", opened)
+	}
+	if reflect.ValueOf(OpenExecutorService).Pointer() != reflect.ValueOf(openExecutorService).Pointer() {
+		t.Fatal("OpenExecutorService should be restored")
+	}
+}
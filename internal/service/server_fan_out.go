@@ -0,0 +1,254 @@
+package service
+
+import (
+	"ServerServing/config"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/dal"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"ServerServing/util"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFanOutParallelism = 8
+	maxFanOutParallelism     = 32
+	defaultFanOutHostTimeout = 60 * time.Second
+	maxFanOutHostTimeout     = 10 * time.Minute
+	// maxFanOutServers 一次最多在多少台服务器上执行。
+	maxFanOutServers = 1000
+	// fanOutCommandName 直接执行的命令使用的脚本名，可以在配置文件中为它单独配置超时时间。
+	fanOutCommandName = "command"
+)
+
+// fanOutLimits 获取同时执行的服务器数量与每台服务器的超时时间。请求中没有指定时使用配置的默认值，超过配置的上限时返回错误。
+func fanOutLimits(parallelism, timeoutSeconds int) (int, time.Duration, *SErr.APIErr) {
+	defaultParallelism, maxParallelism := defaultFanOutParallelism, maxFanOutParallelism
+	defaultTimeout, maxTimeout := defaultFanOutHostTimeout, maxFanOutHostTimeout
+	if conf := config.GetConfig(); conf != nil && conf.FanOutConfig != nil {
+		c := conf.FanOutConfig
+		if c.DefaultParallelism > 0 {
+			defaultParallelism = c.DefaultParallelism
+		}
+		if c.MaxParallelism > 0 {
+			maxParallelism = c.MaxParallelism
+		}
+		if c.DefaultHostTimeoutSeconds > 0 {
+			defaultTimeout = time.Duration(c.DefaultHostTimeoutSeconds) * time.Second
+		}
+		if c.MaxHostTimeoutSeconds > 0 {
+			maxTimeout = time.Duration(c.MaxHostTimeoutSeconds) * time.Second
+		}
+	}
+	if parallelism < 0 || parallelism > maxParallelism {
+		return 0, 0, SErr.InvalidParamErr.CustomMessageF("同时执行的服务器数量需要在1到%d之间！", maxParallelism)
+	}
+	if parallelism == 0 {
+		parallelism = defaultParallelism
+	}
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	if timeoutSeconds < 0 || timeout > maxTimeout {
+		return 0, 0, SErr.InvalidParamErr.CustomMessageF("每台服务器的超时时间需要在1到%d秒之间！", int(maxTimeout.Seconds()))
+	}
+	if timeoutSeconds == 0 {
+		timeout = defaultTimeout
+	}
+	return parallelism, timeout, nil
+}
+
+// FanOut 在多台服务器上同时执行同一个命令或者脚本目录中的脚本，并按服务器以及按相同的结果汇总。
+// 某台服务器连接失败或者执行失败不影响其它服务器，失败原因在该服务器的结果中。
+// 执行脚本目录中的脚本时，不在脚本允许范围内的服务器不会执行，每台执行了的服务器都会保存执行记录。
+func (s *ServersService) FanOut(c *gin.Context, userID int, isAdmin bool, req *internal_models.ServerFanOutRequest) (*internal_models.ServerFanOutResponse, *SErr.APIErr) {
+	hasCommand := strings.TrimSpace(req.Command) != ""
+	if hasCommand == (req.ScriptID != 0) {
+		return nil, SErr.InvalidParamErr.CustomMessage("需要且只能指定要执行的命令或者脚本中的一个！")
+	}
+	var script *daModels.Script
+	var err *SErr.APIErr
+	if hasCommand {
+		if !isAdmin {
+			return nil, SErr.AdminOnlyActionErr
+		}
+	} else {
+		script, err = GetScriptsService().runnableScript(req.ScriptID, isAdmin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	parallelism, timeout, err := fanOutLimits(req.Parallelism, req.TimeoutSeconds)
+	if err != nil {
+		return nil, err
+	}
+	servers, err := s.fanOutServers(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := requestContext(c)
+	results := make([]*internal_models.ServerFanOutHostResult, len(servers))
+	sem := make(chan struct{}, parallelism)
+	wg := &sync.WaitGroup{}
+	for i, daServer := range servers {
+		i, serverBasic := i, s.packServer(daServer)
+		util.GoWithWG(wg, func() {
+			select {
+			case sem <- struct{}{}:
+				defer func() {
+					<-sem
+				}()
+				results[i] = s.fanOutOne(ctx, c, userID, script, req, serverBasic, timeout)
+			case <-ctx.Done():
+				results[i] = newFanOutHostResult(serverBasic, &internal_models.CommandResult{
+					Script:     fanOutCommandName,
+					ExitStatus: -1,
+					Error:      SErr.CommandCanceledErr.Message,
+				})
+			}
+		})
+	}
+	wg.Wait()
+	return aggregateFanOut(results), nil
+}
+
+// fanOutServers 获取请求中明确指定的，或者按关键字搜索到的服务器。
+func (s *ServersService) fanOutServers(req *internal_models.ServerFanOutRequest) ([]*daModels.Server, *SErr.APIErr) {
+	if (len(req.Servers) == 0) == (req.Keyword == nil) {
+		return nil, SErr.InvalidParamErr.CustomMessage("需要且只能通过servers或者keyword中的一个指定服务器！")
+	}
+	serverDal := dal.GetServerDal()
+	if req.Keyword != nil {
+		var servers []*daModels.Server
+		var total uint
+		var err *SErr.APIErr
+		if *req.Keyword == "" {
+			servers, total, err = serverDal.List(0, maxFanOutServers, false)
+		} else {
+			servers, total, err = serverDal.Search(0, maxFanOutServers, *req.Keyword, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if total > maxFanOutServers {
+			return nil, SErr.InvalidParamErr.CustomMessageF("匹配的服务器有%d台，一次最多在%d台服务器上执行！", total, maxFanOutServers)
+		}
+		return servers, nil
+	}
+	if len(req.Servers) > maxFanOutServers {
+		return nil, SErr.InvalidParamErr.CustomMessageF("一次最多在%d台服务器上执行！", maxFanOutServers)
+	}
+	servers := make([]*daModels.Server, 0, len(req.Servers))
+	seen := make(map[string]bool)
+	for _, target := range req.Servers {
+		addr := net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port)))
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		server, err := serverDal.Get(target.Host, target.Port, false)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+// fanOutOne 在一台服务器上执行，超时时间为timeout。
+func (s *ServersService) fanOutOne(ctx context.Context, c *gin.Context, userID int, script *daModels.Script, req *internal_models.ServerFanOutRequest, serverBasic *internal_models.ServerBasic, timeout time.Duration) *internal_models.ServerFanOutHostResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if script != nil {
+		scriptsSvc := GetScriptsService()
+		if !scriptsSvc.allowedOn(script, serverBasic.Host, serverBasic.Port) {
+			return newFanOutHostResult(serverBasic, &internal_models.CommandResult{
+				Script:     scriptCmdName(script.Name),
+				ExitStatus: -1,
+				Error:      scriptsSvc.notAllowedErr(script, serverBasic.Host, serverBasic.Port).Message,
+			})
+		}
+		run, err := scriptsSvc.runOn(ctx, c, userID, script, serverBasic, server_executor.CmdArgs(req.Args))
+		if err != nil {
+			return newFanOutHostResult(serverBasic, &internal_models.CommandResult{
+				Script:     scriptCmdName(script.Name),
+				ExitStatus: -1,
+				Error:      err.Message,
+			})
+		}
+		res := newFanOutHostResult(serverBasic, scriptsSvc.packRun(run).Result)
+		res.ScriptRunID = run.ID
+		return res
+	}
+
+	result := &internal_models.CommandResult{
+		Script:     fanOutCommandName,
+		ExitStatus: -1,
+	}
+	err := s.withConnectionByServer(c, serverBasic, func(es server_executor.ExecutorService) *SErr.APIErr {
+		resp, err := es.RunCommand(ctx, fanOutCommandName, req.Command)
+		if len(resp.Results) > 0 {
+			result = resp.Results[len(resp.Results)-1]
+		}
+		return err
+	})
+	if err != nil && result.Error == "" {
+		result.Error = err.Message
+	}
+	return newFanOutHostResult(serverBasic, result)
+}
+
+func newFanOutHostResult(serverBasic *internal_models.ServerBasic, result *internal_models.CommandResult) *internal_models.ServerFanOutHostResult {
+	return &internal_models.ServerFanOutHostResult{
+		Host:      serverBasic.Host,
+		Port:      serverBasic.Port,
+		Name:      serverBasic.Name,
+		Succeeded: result.Succeeded(),
+		Result:    result,
+	}
+}
+
+// aggregateFanOut 统计成功与失败的数量，并把结果完全相同的服务器归为一组。
+func aggregateFanOut(results []*internal_models.ServerFanOutHostResult) *internal_models.ServerFanOutResponse {
+	resp := &internal_models.ServerFanOutResponse{
+		Total:  len(results),
+		Hosts:  results,
+		Groups: make([]*internal_models.ServerFanOutGroup, 0),
+	}
+	groups := make(map[string]*internal_models.ServerFanOutGroup)
+	for _, res := range results {
+		if res.Succeeded {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+		r := res.Result
+		key := fmt.Sprintf("%d\x00%s\x00%s\x00%s", r.ExitStatus, r.Stdout, r.Stderr, r.Error)
+		group, ok := groups[key]
+		if !ok {
+			group = &internal_models.ServerFanOutGroup{
+				Succeeded:  res.Succeeded,
+				ExitStatus: r.ExitStatus,
+				Stdout:     r.Stdout,
+				Stderr:     r.Stderr,
+				Error:      r.Error,
+				Hosts:      make([]string, 0, 1),
+			}
+			groups[key] = group
+			resp.Groups = append(resp.Groups, group)
+		}
+		group.Hosts = append(group.Hosts, net.JoinHostPort(res.Host, strconv.Itoa(int(res.Port))))
+	}
+	sort.SliceStable(resp.Groups, func(i, j int) bool {
+		return len(resp.Groups[i].Hosts) > len(resp.Groups[j].Hosts)
+	})
+	return resp
+}
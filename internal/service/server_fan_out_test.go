@@ -0,0 +1,137 @@
+package service
+
+import (
+	"ServerServing/da/mysql"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServersService_FanOutReplay(t *testing.T) {
+	servers := initReplayEnv(t)
+	ubuntu, centos := servers[0].transcript, servers[1].transcript
+	// 另一台与ubuntu_20_04相同的服务器，用于检查相同的结果被归为一组。
+	twin := *ubuntu
+	twin.Port = ubuntu.Port + 1
+	if res := mysql.GetDB().Create(&daModels.Server{Name: "ubuntu_twin", Host: twin.Host, Port: twin.Port, AdminAccountName: "admin", AdminAccountPwd: "admin_pwd", OSType: daModels.OSTypeLinux}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	cmd := "nvidia-smi --query-gpu=driver_version --format=csv,noheader"
+	driver := &server_executor.TranscriptEntry{
+		Script: "command",
+		Args:   server_executor.CmdArgs{"command": cmd},
+		Result: &internal_models.CommandResult{Stdout: "470.82.01\n470.82.01\n"},
+	}
+	ubuntu.Entries = append(ubuntu.Entries, driver)
+	twin.Entries = append(append([]*server_executor.TranscriptEntry{}, twin.Entries...), driver)
+	centos.Entries = append(centos.Entries, &server_executor.TranscriptEntry{
+		Script:  "command",
+		Args:    server_executor.CmdArgs{"command": cmd},
+		Result:  &internal_models.CommandResult{Stderr: "bash: nvidia-smi: command not found\n", ExitStatus: 127, Error: "exit status 127"},
+		ErrCode: SErr.CodeCommandFailed,
+	})
+	t.Cleanup(server_executor.UseReplayTranscripts(ubuntu, centos, &twin))
+
+	svc := GetServersService()
+	keyword := ""
+	if _, err := svc.FanOut(nil, 2, false, &internal_models.ServerFanOutRequest{Keyword: &keyword, Command: cmd}); err != SErr.AdminOnlyActionErr {
+		t.Fatalf("unexpected err %v", err)
+	}
+	if _, err := svc.FanOut(nil, 1, true, &internal_models.ServerFanOutRequest{Keyword: &keyword, Command: cmd, Parallelism: 1000}); err == nil || err.Code != SErr.InvalidParamErr.Code {
+		t.Fatalf("unexpected err %v", err)
+	}
+	resp, err := svc.FanOut(nil, 1, true, &internal_models.ServerFanOutRequest{Keyword: &keyword, Command: cmd, Parallelism: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 3 || resp.Succeeded != 2 || resp.Failed != 1 || len(resp.Groups) != 2 {
+		t.Fatalf("unexpected resp %+v", resp)
+	}
+	if group := resp.Groups[0]; !group.Succeeded || group.Stdout != "470.82.01\n470.82.01\n" || len(group.Hosts) != 2 {
+		t.Fatalf("unexpected group %+v", group)
+	}
+	centosAddr := net.JoinHostPort(centos.Host, strconv.Itoa(int(centos.Port)))
+	if group := resp.Groups[1]; group.Succeeded || group.ExitStatus != 127 || len(group.Hosts) != 1 || group.Hosts[0] != centosAddr {
+		t.Fatalf("unexpected group %+v", group)
+	}
+
+	// 流式执行时，每台服务器的每一行输出都带着该服务器的地址推送。
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/servers/fan_out/stream", nil)
+	mu := sync.Mutex{}
+	lines := make(map[string][]string)
+	StreamOutput(c, func(line *internal_models.CommandOutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		addr := net.JoinHostPort(line.Host, strconv.Itoa(int(line.Port)))
+		lines[addr] = append(lines[addr], line.Stream+":"+line.Line)
+	})
+	if _, err := svc.FanOut(c, 1, true, &internal_models.ServerFanOutRequest{Keyword: &keyword, Command: cmd}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		net.JoinHostPort(ubuntu.Host, strconv.Itoa(int(ubuntu.Port))): {"stdout:470.82.01", "stdout:470.82.01"},
+		net.JoinHostPort(twin.Host, strconv.Itoa(int(twin.Port))):     {"stdout:470.82.01", "stdout:470.82.01"},
+		centosAddr: {"stderr:bash: nvidia-smi: command not found"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+
+	// 执行脚本目录中的脚本时，不允许的服务器不会执行，执行了的服务器都保存了执行记录。
+	ubuntu.Entries = append(ubuntu.Entries, &server_executor.TranscriptEntry{
+		Script: "script:driver_version",
+		Result: &internal_models.CommandResult{Stdout: "470.82.01\n"},
+	})
+	script, err := GetScriptsService().Create(nil, 1, &internal_models.ScriptsCreateRequest{
+		Name:           "driver_version",
+		Body:           "nvidia-smi --query-gpu=driver_version --format=csv,noheader | head -n 1",
+		AllowedServers: []string{net.JoinHostPort(ubuntu.Host, strconv.Itoa(int(ubuntu.Port)))},
+		RequiredRole:   daModels.ScriptRoleUser,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = svc.FanOut(nil, 2, false, &internal_models.ServerFanOutRequest{
+		Servers:  []*internal_models.ServerFanOutTarget{{Host: ubuntu.Host, Port: ubuntu.Port}, {Host: centos.Host, Port: centos.Port}},
+		ScriptID: script.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 2 || resp.Succeeded != 1 || resp.Hosts[0].ScriptRunID == 0 || resp.Hosts[0].Result.Stdout != "470.82.01\n" {
+		t.Fatalf("unexpected resp %+v", resp.Hosts[0])
+	}
+	if resp.Hosts[1].Succeeded || resp.Hosts[1].ScriptRunID != 0 {
+		t.Fatalf("unexpected host result %+v", resp.Hosts[1])
+	}
+	if _, total, err := GetScriptsService().Runs(nil, 2, false, &internal_models.ScriptRunsInfosRequest{Size: 10}); err != nil || total != 1 {
+		t.Fatalf("unexpected run count %d, err=%v", total, err)
+	}
+}
+
+func TestFanOutLimits(t *testing.T) {
+	parallelism, timeout, err := fanOutLimits(0, 0)
+	if err != nil || parallelism != defaultFanOutParallelism || timeout != defaultFanOutHostTimeout {
+		t.Fatalf("unexpected limits %d, %s, err=%v", parallelism, timeout, err)
+	}
+	parallelism, timeout, err = fanOutLimits(4, 30)
+	if err != nil || parallelism != 4 || timeout != 30*time.Second {
+		t.Fatalf("unexpected limits %d, %s, err=%v", parallelism, timeout, err)
+	}
+	for _, c := range [][2]int{{-1, 0}, {maxFanOutParallelism + 1, 0}, {0, -1}, {0, int(maxFanOutHostTimeout.Seconds()) + 1}} {
+		if _, _, err := fanOutLimits(c[0], c[1]); err == nil {
+			t.Fatalf("limits %v should be rejected", c)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+)
+
+// defaultUploadPerm 上传文件时没有指定权限时使用的权限。
+const defaultUploadPerm = "0644"
+
+type ServerFilesService struct{}
+
+func GetServerFilesService() *ServerFilesService {
+	return &ServerFilesService{}
+}
+
+// ListDir 列出服务器上某个文件夹中的文件，并根据服务器上的账户列表填入每个文件所有者的账户名。
+func (s *ServerFilesService) ListDir(c *gin.Context, Host string, Port uint, dirPath string) ([]*internal_models.ServerFileInfo, *SErr.APIErr) {
+	var files []*internal_models.ServerFileInfo
+	err := GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		resp, err := es.ListDir(requestContext(c), dirPath)
+		if err != nil {
+			return err
+		}
+		files = resp.Files
+		s.fillOwners(c, es, files...)
+		return nil
+	})
+	return files, err
+}
+
+// Stat 获取服务器上某个文件或文件夹的信息。
+func (s *ServerFilesService) Stat(c *gin.Context, Host string, Port uint, filePath string) (*internal_models.ServerFileInfo, *SErr.APIErr) {
+	var file *internal_models.ServerFileInfo
+	err := GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		resp, err := es.Stat(requestContext(c), filePath)
+		if err != nil {
+			return err
+		}
+		file = resp.File
+		s.fillOwners(c, es, file)
+		return nil
+	})
+	return file, err
+}
+
+// fillOwners 获取账户列表失败时只打印日志，文件的所有者留空。
+func (s *ServerFilesService) fillOwners(c *gin.Context, es server_executor.ExecutorService, files ...*internal_models.ServerFileInfo) {
+	resp, err := es.GetAccountList(requestContext(c))
+	if err != nil {
+		log.Printf("ServerFilesService fillOwners GetAccountList failed, es=[%s], err=[%s]", es, err)
+		return
+	}
+	names := make(map[uint]string, len(resp.Accounts))
+	for _, account := range resp.Accounts {
+		names[account.UID] = account.Name
+	}
+	for _, file := range files {
+		file.Owner = names[file.UID]
+	}
+}
+
+// Download 将服务器上的文件写入target返回的writer。
+func (s *ServerFilesService) Download(c *gin.Context, Host string, Port uint, filePath string, target server_executor.DownloadTarget) *SErr.APIErr {
+	return GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		resp, err := es.Download(requestContext(c), filePath, target)
+		log.Printf("ServerFilesService Download, es=[%s], path=[%s], transferred=[%d], err=[%v]", es, filePath, resp.Transferred, err)
+		return err
+	})
+}
+
+// Upload 将content上传到服务器上的filePath，已经存在的文件会被替换。perm为空时使用defaultUploadPerm。
+func (s *ServerFilesService) Upload(c *gin.Context, Host string, Port uint, filePath string, perm string, content io.Reader) (*internal_models.ServerFileInfo, *SErr.APIErr) {
+	if perm == "" {
+		perm = defaultUploadPerm
+	}
+	mode, err := server_executor.ParseFilePerm(perm)
+	if err != nil {
+		return nil, err
+	}
+	var file *internal_models.ServerFileInfo
+	err = GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		resp, err := es.Upload(requestContext(c), filePath, content, mode)
+		if err != nil {
+			return err
+		}
+		file = resp.File
+		s.fillOwners(c, es, file)
+		return nil
+	})
+	return file, err
+}
+
+// Chmod 修改服务器上文件或文件夹的权限，perm为八进制的权限位。
+func (s *ServerFilesService) Chmod(c *gin.Context, Host string, Port uint, filePath string, perm string) *SErr.APIErr {
+	mode, err := server_executor.ParseFilePerm(perm)
+	if err != nil {
+		return err
+	}
+	return GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		_, err := es.Chmod(requestContext(c), filePath, mode)
+		return err
+	})
+}
+
+// Chown 将服务器上文件或文件夹的所有者修改为名为owner的账户，组为该账户的主组。
+func (s *ServerFilesService) Chown(c *gin.Context, Host string, Port uint, filePath string, owner string) *SErr.APIErr {
+	return GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		accountsResp, err := es.GetAccountList(requestContext(c))
+		if err != nil {
+			return err
+		}
+		for _, account := range accountsResp.Accounts {
+			if account.Name == owner {
+				_, err := es.Chown(requestContext(c), filePath, account.UID, account.GID)
+				return err
+			}
+		}
+		return SErr.InvalidParamErr.CustomMessageF("服务器上不存在该账户！账户名为：%s", owner)
+	})
+}
+
+// Remove 删除服务器上的文件或文件夹，recursive为true时删除整个文件夹。
+func (s *ServerFilesService) Remove(c *gin.Context, Host string, Port uint, filePath string, recursive bool) *SErr.APIErr {
+	return GetServersService().withConnectionByHostPort(c, Host, Port, func(es server_executor.ExecutorService) *SErr.APIErr {
+		_, err := es.Remove(requestContext(c), filePath, recursive)
+		return err
+	})
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"regexp"
+
+	"ServerServing/da/mysql"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/dal"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"gorm.io/driver/sqlite"
+	"path/filepath"
+	"testing"
+)
+
+// 以下测试使用sqlite代替MySQL，并回放testdata/transcripts中录制下来的Ubuntu与CentOS服务器的输出，不需要任何真实的环境。
+// 回放记录可以通过在配置文件中设置transcript_record_dir来录制。
+
+const replayAccountPwd = "Passw0rd!"
+
+type replayServer struct {
+	name       string
+	transcript *server_executor.Transcript
+}
+
+func initReplayEnv(t *testing.T) []*replayServer {
+	t.Helper()
+	mysql.InitWithDialector(sqlite.Open(filepath.Join(t.TempDir(), "server_serving.db")))
+	db := mysql.GetDB()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.ConnPool = &sqliteRowValuesPool{DB: sqlDB}
+	db.Statement.ConnPool = db.ConnPool
+	servers := make([]*replayServer, 0, 2)
+	transcripts := make([]*server_executor.Transcript, 0, 2)
+	for _, name := range []string{"ubuntu_20_04", "centos_7"} {
+		transcript, err := server_executor.LoadTranscript(filepath.Join("testdata", "transcripts", name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := mysql.GetDB().Create(&daModels.Server{
+			Name:             name,
+			Host:             transcript.Host,
+			Port:             transcript.Port,
+			AdminAccountName: "admin",
+			AdminAccountPwd:  "admin_pwd",
+			OSType:           daModels.OSTypeLinux,
+		})
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		servers = append(servers, &replayServer{name: name, transcript: transcript})
+		transcripts = append(transcripts, transcript)
+	}
+	t.Cleanup(server_executor.UseReplayTranscripts(transcripts...))
+	return servers
+}
+
+// sqliteRowValuesPool 预加载账户时，gorm会对联合主键生成(host, port) IN ((...))，sqlite只支持(host, port) IN (VALUES (...))。
+var sqliteRowValuesInReg = regexp.MustCompile(`\) IN \(\(`)
+
+type sqliteRowValuesPool struct {
+	*sql.DB
+}
+
+func (p *sqliteRowValuesPool) rewrite(query string) string {
+	return sqliteRowValuesInReg.ReplaceAllString(query, ") IN (VALUES (")
+}
+
+func (p *sqliteRowValuesPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.DB.PrepareContext(ctx, p.rewrite(query))
+}
+
+func (p *sqliteRowValuesPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.DB.ExecContext(ctx, p.rewrite(query), args...)
+}
+
+func (p *sqliteRowValuesPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.DB.QueryContext(ctx, p.rewrite(query), args...)
+}
+
+func (p *sqliteRowValuesPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.DB.QueryRowContext(ctx, p.rewrite(query), args...)
+}
+
+func TestServersService_InfoReplay(t *testing.T) {
+	servers := initReplayEnv(t)
+	expects := map[string]struct {
+		cores        int
+		gpus         int
+		memTotal     string
+		gpuUsageFail bool
+		// backupRootOn 存放备份文件夹的文件系统的挂载点。
+		backupRootOn string
+		prettyName   string
+		idLike       string
+	}{
+		// Ubuntu 20.04的top以MiB为单位输出带小数的内存，需要回退到/proc/meminfo。
+		"ubuntu_20_04": {cores: 40, gpus: 2, memTotal: "128584MB", gpuUsageFail: false, backupRootOn: "/backup", prettyName: "Ubuntu 20.04.3 LTS", idLike: "debian"},
+		// 这台CentOS服务器没有安装nvidia-smi。它的回放记录中没有os_release，不会更新数据库中的发行版信息。
+		"centos_7": {cores: 8, gpus: 1, memTotal: "15884MB", gpuUsageFail: true, backupRootOn: "/", prettyName: "", idLike: ""},
+	}
+	svc := GetServersService()
+	for _, server := range servers {
+		server := server
+		expect := expects[server.name]
+		t.Run(server.name, func(t *testing.T) {
+			host, port := server.transcript.Host, server.transcript.Port
+			// dave只存在于数据库中，可能已经在服务器上被删除了。
+			if err := dal.GetAccountDal().Upsert([]*daModels.Account{{Name: "dave", Pwd: replayAccountPwd, Host: host, Port: port}}); err != nil {
+				t.Fatal(err)
+			}
+			res, err := svc.Info(nil, host, port, &internal_models.LoadServerDetailArg{
+				WithHardwareInfo:         true,
+				WithAccounts:             true,
+				WithRemoteAccessUsages:   true,
+				WithGPUUsages:            true,
+				WithCPUMemProcessesUsage: true,
+				WithFilesystemUsages:     true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Basic.OSPrettyName != expect.prettyName {
+				t.Fatalf("unexpected os pretty name %q", res.Basic.OSPrettyName)
+			}
+			if daServer, err := dal.GetServerDal().Get(host, port, false); err != nil || daServer.OSPrettyName != expect.prettyName || daServer.OSReleaseIDLike != expect.idLike {
+				t.Fatalf("unexpected server %+v, err=%v", daServer, err)
+			}
+			cpu := res.HardwareInfo.CPUHardwareInfo
+			if cpu.FailedInfo != nil || cpu.Info.Cores == nil || *cpu.Info.Cores != expect.cores {
+				t.Fatalf("unexpected cpu info %+v", cpu.Info)
+			}
+			if gpus := res.HardwareInfo.GPUHardwareInfos.Infos; len(gpus) != expect.gpus {
+				t.Fatalf("unexpected gpus %+v", gpus)
+			}
+			usage := res.CPUMemProcessesUsageInfo
+			if usage.FailedInfo != nil || usage.CPUMemUsage.MemTotal == nil || *usage.CPUMemUsage.MemTotal != expect.memTotal || len(usage.ProcessInfos) == 0 {
+				t.Fatalf("unexpected cpu mem usage %+v", usage)
+			}
+			// CPU使用率来自两次采样/proc/stat，每个核各有一条。
+			if cpuStat := usage.CPUMemUsage; cpuStat.CPUStat == nil || len(cpuStat.CPUStats) != expect.cores || cpuStat.LoadAverage == nil || cpuStat.UptimeSeconds == nil ||
+				*cpuStat.UserProcCPUUsage != cpuStat.CPUStat.User {
+				t.Fatalf("unexpected cpu stat %+v", cpuStat)
+			}
+			if remote := res.RemoteAccessingUsageInfo; remote.FailedInfo != nil || len(remote.Infos) == 0 {
+				t.Fatalf("unexpected remote access infos %+v", remote)
+			}
+			gpuUsage := res.GPUUsageInfo
+			if (gpuUsage.FailedInfo != nil) != expect.gpuUsageFail {
+				t.Fatalf("unexpected gpu usage failed info %+v", gpuUsage.FailedInfo)
+			}
+			if expect.gpuUsageFail && (gpuUsage.FailedInfo.FailedCommand == nil || gpuUsage.FailedInfo.FailedCommand.ExitStatus != 1) {
+				t.Fatalf("unexpected gpu usage failed command %+v", gpuUsage.FailedInfo.FailedCommand)
+			}
+			// 每块NVIDIA GPU的使用情况按总线地址填入硬件信息中。
+			for _, gpu := range res.HardwareInfo.GPUHardwareInfos.Infos {
+				if (gpu.Usage != nil) == expect.gpuUsageFail {
+					t.Fatalf("unexpected gpu %+v", gpu)
+				}
+			}
+			if !expect.gpuUsageFail && (len(gpuUsage.GPUs) != expect.gpus || *gpuUsage.GPUs[0].UtilizationPercent != 97) {
+				t.Fatalf("unexpected gpu usages %+v", gpuUsage.GPUs)
+			}
+			// 进程按账户汇总，占用显存最多的账户排在最前面。
+			if len(res.AccountUsages) == 0 {
+				t.Fatal("account usages should be loaded")
+			}
+			if top := res.AccountUsages[0]; !expect.gpuUsageFail && (top.AccountName != "alice" || top.GPUMemoryMiB != 20109 || len(top.GPUIndexes) != 1 || top.CPUUsage < 100) {
+				t.Fatalf("unexpected account usage %+v", top)
+			}
+			// 伪文件系统不展示，存放/、/home以及备份文件夹的文件系统被标出。
+			fsUsage := res.FilesystemUsageInfo
+			if fsUsage.FailedInfo != nil || len(fsUsage.Filesystems) == 0 {
+				t.Fatalf("unexpected filesystem usages %+v", fsUsage)
+			}
+			highlighted := make(map[string]string)
+			for _, fs := range fsUsage.Filesystems {
+				if fs.FSType == "tmpfs" || fs.FSType == "devtmpfs" {
+					t.Fatalf("unexpected filesystem %+v", fs)
+				}
+				for _, highlight := range fs.Highlights {
+					highlighted[highlight] = fs.MountPoint
+				}
+			}
+			if highlighted[internal_models.ServerFilesystemHighlightRoot] != "/" || highlighted[internal_models.ServerFilesystemHighlightHome] != "/home" ||
+				highlighted[internal_models.ServerFilesystemHighlightBackupRoot] != expect.backupRootOn {
+				t.Fatalf("unexpected highlighted filesystems %+v", highlighted)
+			}
+
+			accounts := make(map[string]*internal_models.ServerAccount)
+			for _, account := range res.AccountInfos.Accounts {
+				accounts[account.Name] = account
+			}
+			if len(accounts) != 4 || accounts["alice"] == nil || accounts["alice"].UID != 1001 || !accounts["dave"].NotExistsInServer {
+				t.Fatalf("unexpected accounts %+v", res.AccountInfos.Accounts)
+			}
+			// 服务器上存在但是数据库中没有的账户会被补充到数据库中。
+			count, err := dal.GetAccountDal().Count(host, port)
+			if err != nil || count != 4 {
+				t.Fatalf("unexpected account count %d, err=%v", count, err)
+			}
+		})
+	}
+}
+
+func TestServersService_AccountsReplay(t *testing.T) {
+	servers := initReplayEnv(t)
+	svc := GetServersService()
+	for _, server := range servers {
+		server := server
+		t.Run(server.name, func(t *testing.T) {
+			host, port := server.transcript.Host, server.transcript.Port
+			if err := svc.AddAccount(nil, host, port, "bob", replayAccountPwd); err != nil {
+				t.Fatal(err)
+			}
+			count, err := dal.GetAccountDal().Count(host, port)
+			if err != nil || count != 1 {
+				t.Fatalf("unexpected account count %d, err=%v", count, err)
+			}
+			if err := svc.AddAccount(nil, host, port, "alice", replayAccountPwd); err != SErr.CreateAccountNameAlreadyExists {
+				t.Fatalf("unexpected err %v", err)
+			}
+
+			backupDir, err := svc.DeleteAccount(nil, host, port, "alice", true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if backupDir != "/backup/alice.backup" {
+				t.Fatalf("unexpected backup dir %s", backupDir)
+			}
+			if _, err := svc.DeleteAccount(nil, host, port, "nobody", false); err != SErr.DeleteAccountNameNotExists {
+				t.Fatalf("unexpected err %v", err)
+			}
+
+			if err := svc.RecoverAccount(nil, host, port, "carol", replayAccountPwd, true); err != nil {
+				t.Fatal(err)
+			}
+			// 回放记录中没有为dave添加账户的记录，找不到记录时回放会返回错误，而不是当作执行成功。
+			if err := svc.RecoverAccount(nil, host, port, "dave", replayAccountPwd, true); err == nil {
+				t.Fatal("RecoverAccount should fail without a transcript entry")
+			}
+		})
+	}
+}
+
+func TestServersService_InfosKeepsUnreachableServers(t *testing.T) {
+	servers := initReplayEnv(t)
+	// 监听之后立即关闭，连接该端口会被拒绝。
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := uint(listener.Addr().(*net.TCPAddr).Port)
+	_ = listener.Close()
+	if res := mysql.GetDB().Create(&daModels.Server{
+		Name:             "down",
+		Host:             "127.0.0.1",
+		Port:             port,
+		AdminAccountName: "admin",
+		AdminAccountPwd:  "admin_pwd",
+		OSType:           daModels.OSTypeLinux,
+	}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	for round := 1; round <= 3; round++ {
+		infos, total, err := GetServersService().Infos(nil, 0, 10, &internal_models.LoadServerDetailArg{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 3 || len(infos) != 3 {
+			t.Fatalf("unexpected infos %+v, total=%d", infos, total)
+		}
+		byName := make(map[string]*internal_models.ServerInfo)
+		for _, info := range infos {
+			byName[info.Basic.Name] = info
+		}
+		for _, server := range servers {
+			if info := byName[server.name]; info == nil || info.Status.Status != string(server_executor.ServerStatusOnline) || info.AccessFailedInfo != nil {
+				t.Fatalf("unexpected info %+v", info)
+			}
+		}
+		down := byName["down"]
+		if down == nil || down.AccessFailedInfo == nil || down.Status.Status != string(server_executor.ServerStatusUnreachable) || down.Status.Since == 0 {
+			t.Fatalf("unexpected info %+v, status %+v", down.Basic, down.Status)
+		}
+		// 连续失败之后进入冷却期，之后的请求不再尝试连接，失败次数不再增加。
+		if round >= 2 && (down.Status.RetryAt == 0 || down.Status.ConsecutiveFailures != 2) {
+			t.Fatalf("unexpected status %+v in round %d", down.Status, round)
+		}
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"ServerServing/da/mysql"
 	"ServerServing/internal/internal_models"
 	"ServerServing/util"
+	"ServerServing/util/testutil"
 	"testing"
 )
 
 func initEnv(t *testing.T) {
-	config.InitConfigWithFile("/Users/purchaser/go/src/ServerServing/config.yml", "dev")
+	// 离线测试见server_replay_test.go。
+	configPath := testutil.LiveConfigPath(t, "真实MySQL与服务器")
+	config.InitConfigWithFile(configPath, "dev")
 	mysql.InitMySQL()
 }
 
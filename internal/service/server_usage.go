@@ -0,0 +1,121 @@
+package service
+
+import (
+	"ServerServing/internal/internal_models"
+	"sort"
+)
+
+// loadAccountUsages 将正在使用GPU的进程填入进程信息中，并按账户汇总进程的CPU、内存、GPU占用。
+// 需要在loadCPUMemProcessesUsageInfo与loadGPUUsages之后调用，两者都没有加载（或者都失败）时不做任何事。
+func (s *ServersService) loadAccountUsages(serverInfo *internal_models.ServerInfo) {
+	var processes []*internal_models.ServerProcessInfo
+	if info := serverInfo.CPUMemProcessesUsageInfo; info != nil && info.FailedInfo == nil {
+		processes = info.ProcessInfos
+	}
+	var gpuProcesses []*internal_models.ServerGPUProcess
+	if info := serverInfo.GPUUsageInfo; info != nil && info.FailedInfo == nil {
+		gpuProcesses = info.Processes
+	}
+	if processes == nil && gpuProcesses == nil {
+		return
+	}
+
+	byPID := make(map[uint]*internal_models.ServerProcessInfo, len(processes))
+	for _, process := range processes {
+		if process.PID == nil {
+			continue
+		}
+		byPID[*process.PID] = process
+		// 可能已经使用agent的数据加载过一次，重新计算。
+		process.GPUIndexes = nil
+		process.GPUMemoryMiB = nil
+	}
+	usages := make(map[string]*internal_models.ServerAccountUsage)
+	usageOf := func(accountName string) *internal_models.ServerAccountUsage {
+		usage, ok := usages[accountName]
+		if !ok {
+			usage = &internal_models.ServerAccountUsage{AccountName: accountName, GPUIndexes: make([]int, 0)}
+			usages[accountName] = usage
+		}
+		return usage
+	}
+
+	// top输出的用户名过长时会被截断，ps输出的是完整的用户名，所以使用GPU的进程以ps的结果为准。
+	gpuPIDs := make(map[uint]bool)
+	for _, gpuProcess := range gpuProcesses {
+		process := byPID[gpuProcess.PID]
+		owner := gpuProcess.OwnerAccountName
+		if process != nil {
+			if owner != nil {
+				process.OwnerAccountName = owner
+			} else {
+				owner = process.OwnerAccountName
+			}
+			process.CommandLine = gpuProcess.CommandLine
+			if gpuProcess.GPUIndex != nil {
+				process.GPUIndexes = appendIndex(process.GPUIndexes, *gpuProcess.GPUIndex)
+			}
+			if gpuProcess.UsedMemoryMiB != nil {
+				used := *gpuProcess.UsedMemoryMiB
+				if process.GPUMemoryMiB != nil {
+					used += *process.GPUMemoryMiB
+				}
+				process.GPUMemoryMiB = &used
+			}
+		}
+		if owner == nil {
+			continue
+		}
+		usage := usageOf(*owner)
+		if gpuProcess.GPUIndex != nil {
+			usage.GPUIndexes = appendIndex(usage.GPUIndexes, *gpuProcess.GPUIndex)
+		}
+		if gpuProcess.UsedMemoryMiB != nil {
+			usage.GPUMemoryMiB += *gpuProcess.UsedMemoryMiB
+		}
+		// 没有进程信息时，以使用GPU的进程计数。
+		if process == nil && !gpuPIDs[gpuProcess.PID] {
+			usage.Processes++
+		}
+		gpuPIDs[gpuProcess.PID] = true
+	}
+	for _, process := range processes {
+		if process.OwnerAccountName == nil {
+			continue
+		}
+		usage := usageOf(*process.OwnerAccountName)
+		usage.Processes++
+		if process.CPUUsage != nil {
+			usage.CPUUsage += *process.CPUUsage
+		}
+		if process.MemUsage != nil {
+			usage.MemUsage += *process.MemUsage
+		}
+	}
+
+	serverInfo.AccountUsages = make([]*internal_models.ServerAccountUsage, 0, len(usages))
+	for _, usage := range usages {
+		sort.Ints(usage.GPUIndexes)
+		serverInfo.AccountUsages = append(serverInfo.AccountUsages, usage)
+	}
+	sort.Slice(serverInfo.AccountUsages, func(i, j int) bool {
+		a, b := serverInfo.AccountUsages[i], serverInfo.AccountUsages[j]
+		if a.GPUMemoryMiB != b.GPUMemoryMiB {
+			return a.GPUMemoryMiB > b.GPUMemoryMiB
+		}
+		if a.CPUUsage != b.CPUUsage {
+			return a.CPUUsage > b.CPUUsage
+		}
+		return a.AccountName < b.AccountName
+	})
+}
+
+// appendIndex 将GPU编号加入indexes中，已经存在时不重复加入。
+func appendIndex(indexes []int, index int) []int {
+	for _, i := range indexes {
+		if i == index {
+			return indexes
+		}
+	}
+	return append(indexes, index)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"ServerServing/internal/internal_models"
+	"testing"
+)
+
+func TestServersService_loadAccountUsages(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+	pid := func(p uint) *uint { return &p }
+	idx := func(i int) *int { return &i }
+	newServerInfo := func() *internal_models.ServerInfo {
+		return &internal_models.ServerInfo{
+			CPUMemProcessesUsageInfo: &internal_models.ServerCPUMemProcessesUsageInfo{
+				ServerInfoCommon: &internal_models.ServerInfoCommon{},
+				ProcessInfos: []*internal_models.ServerProcessInfo{
+					{PID: pid(1), Command: str("systemd"), OwnerAccountName: str("root"), CPUUsage: num(0.5), MemUsage: num(0.1)},
+					{PID: pid(100), Command: str("python"), OwnerAccountName: str("alice"), CPUUsage: num(100), MemUsage: num(4.9)},
+					// top输出的用户名被截断。
+					{PID: pid(200), Command: str("torchrun"), OwnerAccountName: str("experim+"), CPUUsage: num(180), MemUsage: num(12)},
+					{PID: pid(201), Command: str("bash"), OwnerAccountName: str("alice"), CPUUsage: num(0), MemUsage: num(0.1)},
+				},
+			},
+			GPUUsageInfo: &internal_models.ServerGPUUsageInfo{
+				ServerInfoCommon: &internal_models.ServerInfoCommon{},
+				Processes: []*internal_models.ServerGPUProcess{
+					{PID: 100, GPUIndex: idx(0), UsedMemoryMiB: num(20109), OwnerAccountName: str("alice"), CommandLine: str("python train.py")},
+					{PID: 200, GPUIndex: idx(1), UsedMemoryMiB: num(1520), OwnerAccountName: str("experiment_runner"), CommandLine: str("torchrun ddp.py")},
+					{PID: 200, GPUIndex: idx(0), UsedMemoryMiB: num(3050), OwnerAccountName: str("experiment_runner"), CommandLine: str("torchrun ddp.py")},
+					// 查询之后已经退出的进程，无法知道它的所有者。
+					{PID: 300, GPUIndex: idx(1), UsedMemoryMiB: num(10)},
+				},
+			},
+		}
+	}
+
+	svc := GetServersService()
+	serverInfo := newServerInfo()
+	svc.loadAccountUsages(serverInfo)
+	// 使用agent的数据之后再次加载时，结果相同。
+	svc.loadAccountUsages(serverInfo)
+	usages := serverInfo.AccountUsages
+	if len(usages) != 3 || usages[0].AccountName != "alice" || usages[1].AccountName != "experiment_runner" || usages[2].AccountName != "root" {
+		t.Fatalf("unexpected usages %+v", usages)
+	}
+	if u := usages[1]; u.GPUMemoryMiB != 4570 || len(u.GPUIndexes) != 2 || u.GPUIndexes[0] != 0 || u.Processes != 1 || u.CPUUsage != 180 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+	if u := usages[0]; u.GPUMemoryMiB != 20109 || u.Processes != 2 || u.CPUUsage != 100 || u.MemUsage != 5 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+	if u := usages[2]; u.GPUMemoryMiB != 0 || len(u.GPUIndexes) != 0 || u.Processes != 1 {
+		t.Fatalf("unexpected usage %+v", u)
+	}
+	process := serverInfo.CPUMemProcessesUsageInfo.ProcessInfos[2]
+	if *process.OwnerAccountName != "experiment_runner" || *process.CommandLine != "torchrun ddp.py" || *process.GPUMemoryMiB != 4570 || len(process.GPUIndexes) != 2 {
+		t.Fatalf("unexpected process %+v", process)
+	}
+	if process := serverInfo.CPUMemProcessesUsageInfo.ProcessInfos[0]; process.GPUMemoryMiB != nil || process.CommandLine != nil {
+		t.Fatalf("unexpected process %+v", process)
+	}
+
+	// 只加载了GPU的使用情况时，以使用GPU的进程计数。
+	serverInfo = newServerInfo()
+	serverInfo.CPUMemProcessesUsageInfo = nil
+	svc.loadAccountUsages(serverInfo)
+	if usages := serverInfo.AccountUsages; len(usages) != 2 || usages[1].Processes != 1 || usages[1].CPUUsage != 0 {
+		t.Fatalf("unexpected usages %+v", usages)
+	}
+
+	// 加载失败时不汇总。
+	serverInfo = newServerInfo()
+	serverInfo.CPUMemProcessesUsageInfo.FailedInfo = &internal_models.ServerInfoLoadingFailedInfo{}
+	serverInfo.GPUUsageInfo = nil
+	svc.loadAccountUsages(serverInfo)
+	if serverInfo.AccountUsages != nil {
+		t.Fatalf("unexpected usages %+v", serverInfo.AccountUsages)
+	}
+}
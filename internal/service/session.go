@@ -19,6 +19,9 @@ func GetSessionsService() *SessionsService {
 
 const tokenKey = "X-Token"
 
+// tokenQueryKey 浏览器的WebSocket无法设置请求头，WebSocket请求通过该查询参数传递token。
+const tokenQueryKey = "x_token"
+
 func (*SessionsService) GenToken(user *daModels.User) string {
 	return base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(int(user.ID))))
 }
@@ -57,6 +60,9 @@ func (s *SessionsService) Create(c *gin.Context, name, pwd string) (string, *SEr
 
 func (s *SessionsService) GetUserID(c *gin.Context) (int, *SErr.APIErr) {
 	tokenHeader := c.GetHeader(tokenKey)
+	if tokenHeader == "" && c.IsWebsocket() {
+		tokenHeader = c.Query(tokenQueryKey)
+	}
 	uid, err := s.ParseToken(tokenHeader)
 	if err != nil {
 		return 0, err
@@ -89,6 +95,19 @@ func (s *SessionsService) LoggedInAndIsAdmin(c *gin.Context) (int, *SErr.APIErr)
 	return userID, nil
 }
 
+// LoggedInUser 返回当前登录的用户ID以及该用户是否为管理员。
+func (s *SessionsService) LoggedInUser(c *gin.Context) (int, bool, *SErr.APIErr) {
+	userID, err := s.GetUserID(c)
+	if err != nil {
+		return 0, false, SErr.NeedLoginErr
+	}
+	isAdmin, err := GetUsersService().IsAdmin(c, userID)
+	if err != nil {
+		return 0, false, err
+	}
+	return userID, isAdmin, nil
+}
+
 func (*SessionsService) Destroy(c *gin.Context) {
 	session := sessions.Default(c)
 	session.Clear()
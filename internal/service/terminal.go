@@ -0,0 +1,274 @@
+package service
+
+import (
+	"ServerServing/config"
+	daModels "ServerServing/da/mysql/da_models"
+	SErr "ServerServing/err"
+	"ServerServing/internal/dal"
+	"ServerServing/internal/internal_models"
+	"ServerServing/internal/service/server_executor"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTerminalRecordingDir = "terminal_recordings"
+	// terminalExitWait 终端的输出结束后，等待shell退出码的时间，超过后直接关闭终端。
+	terminalExitWait = 5 * time.Second
+)
+
+type TerminalsService struct{}
+
+func GetTerminalsService() *TerminalsService {
+	return &TerminalsService{}
+}
+
+// terminalConn 终端的底层实现，即server_executor.Terminal。
+type terminalConn interface {
+	io.ReadWriter
+	Resize(cols, rows int) *SErr.APIErr
+	Wait() (int, *SErr.APIErr)
+	Close() error
+}
+
+// TerminalSession 一个正在进行的终端会话。读取到的终端输出会同时被录制下来。
+type TerminalSession struct {
+	ID uint
+
+	term       terminalConn
+	record     *daModels.TerminalSession
+	recording  *os.File
+	recorder   *asciicastRecorder
+	finishOnce sync.Once
+	exit       *internal_models.TerminalExitMessage
+}
+
+func terminalRecordingDir() string {
+	if conf := config.GetConfig(); conf != nil && conf.TerminalRecordingDir != "" {
+		return conf.TerminalRecordingDir
+	}
+	return defaultTerminalRecordingDir
+}
+
+// Open 使用服务器的管理员账户登录并打开一个终端，同时开始录制。
+func (s *TerminalsService) Open(c *gin.Context, userID int, Host string, Port uint, cols, rows int) (*TerminalSession, *SErr.APIErr) {
+	serversSvc := GetServersService()
+	serverBasic, _, err := serversSvc.basicInfo(c, Host, Port)
+	if err != nil {
+		return nil, err
+	}
+	param, err := serversSvc.openParamOf(serverBasic)
+	if err != nil {
+		return nil, err
+	}
+	term, err := server_executor.OpenTerminal(param, cols, rows)
+	if err != nil {
+		return nil, err.CustomMessage(fmt.Sprintf("在服务器上打开终端失败！错误信息为：%s", err.Message))
+	}
+	// 与其它连接一样，没有记录host key指纹的服务器在第一次连接后记录它。
+	serversSvc.pinHostKeyIfAbsent(serverBasic, term)
+	cols, rows = server_executor.TerminalSize(cols, rows)
+	session, err := s.start(terminalRecordingDir(), userID, serverBasic.Host, serverBasic.Port, serverBasic.AdminAccountName, term, cols, rows)
+	if err != nil {
+		_ = term.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// start 在dir下创建录像文件并保存终端会话。
+func (s *TerminalsService) start(dir string, userID int, Host string, Port uint, account string, term terminalConn, cols, rows int) (*TerminalSession, *SErr.APIErr) {
+	startedAt := time.Now()
+	// 录像中包含终端的全部输出，只允许当前用户读取。
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("创建终端录像目录失败！出错信息为：%s", err.Error())
+	}
+	host := strings.NewReplacer(":", "_", "/", "_").Replace(Host)
+	path := filepath.Join(dir, fmt.Sprintf("%s_%d_%d.cast", host, Port, startedAt.UnixNano()))
+	recording, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, SErr.InternalErr.CustomMessageF("创建终端录像文件失败！出错信息为：%s", err.Error())
+	}
+	recorder, err := newAsciicastRecorder(recording, startedAt, &asciicastHeader{
+		Width:  cols,
+		Height: rows,
+		Title:  fmt.Sprintf("%s@%s:%d", account, Host, Port),
+		Env:    map[string]string{"TERM": server_executor.TerminalType},
+	})
+	if err != nil {
+		_ = recording.Close()
+		return nil, SErr.InternalErr.CustomMessageF("写入终端录像失败！出错信息为：%s", err.Error())
+	}
+	record := &daModels.TerminalSession{
+		UserID:        uint(userID),
+		Host:          Host,
+		Port:          Port,
+		Account:       account,
+		Cols:          cols,
+		Rows:          rows,
+		StartedAt:     startedAt,
+		ExitStatus:    -1,
+		RecordingPath: path,
+	}
+	if err := dal.GetTerminalSessionDal().Create(record); err != nil {
+		_ = recording.Close()
+		return nil, err
+	}
+	log.Printf("TerminalsService start session, ID=[%d], userID=[%d], Host=[%s], Port=[%d], recording=[%s]", record.ID, userID, Host, Port, path)
+	return &TerminalSession{
+		ID:        record.ID,
+		term:      term,
+		record:    record,
+		recording: recording,
+		recorder:  recorder,
+	}, nil
+}
+
+// Read 读取终端的输出并录制，shell退出后返回io.EOF。
+func (t *TerminalSession) Read(p []byte) (int, error) {
+	n, err := t.term.Read(p)
+	if n > 0 {
+		t.recorder.Output(p[:n])
+	}
+	return n, err
+}
+
+// Write 将用户的输入写入终端，输入不会被录制。
+func (t *TerminalSession) Write(p []byte) (int, error) {
+	return t.term.Write(p)
+}
+
+// Resize 修改终端的大小，并录制这次变化。
+func (t *TerminalSession) Resize(cols, rows int) *SErr.APIErr {
+	if err := t.term.Resize(cols, rows); err != nil {
+		return err
+	}
+	cols, rows = server_executor.TerminalSize(cols, rows)
+	t.recorder.Resize(cols, rows)
+	return nil
+}
+
+// Close 在前端断开时直接关闭终端，shell会被终止。之后仍然需要调用Finish。
+func (t *TerminalSession) Close() {
+	_ = t.term.Close()
+}
+
+// Finish 等待shell退出（最多terminalExitWait）后关闭终端，保存退出码与录像，返回发送给前端的最后一条消息。
+// 可以多次调用，返回相同的结果。
+func (t *TerminalSession) Finish() *internal_models.TerminalExitMessage {
+	t.finishOnce.Do(func() {
+		var exitStatus int
+		var exitErr *SErr.APIErr
+		exited := make(chan struct{})
+		go func() {
+			exitStatus, exitErr = t.term.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(terminalExitWait):
+			_ = t.term.Close()
+			<-exited
+		}
+		_ = t.term.Close()
+
+		endedAt := time.Now()
+		record := t.record
+		record.EndedAt = &endedAt
+		record.DurationMs = endedAt.Sub(record.StartedAt).Milliseconds()
+		record.ExitStatus = exitStatus
+		if exitErr != nil {
+			record.Error = exitErr.Message
+		}
+		if err := t.recorder.Close(); err != nil {
+			log.Printf("TerminalSession write recording failed, ID=[%d], err=[%v]", record.ID, err)
+		}
+		if info, err := t.recording.Stat(); err == nil {
+			record.RecordingSize = info.Size()
+		}
+		_ = t.recording.Close()
+		if err := dal.GetTerminalSessionDal().Finish(record); err != nil {
+			log.Printf("TerminalSession Finish failed, ID=[%d], err=[%s]", record.ID, err.Message)
+		}
+		log.Printf("TerminalsService finish session, ID=[%d], exitStatus=[%d], durationMs=[%d]", record.ID, exitStatus, record.DurationMs)
+		t.exit = &internal_models.TerminalExitMessage{
+			Type:       internal_models.TerminalMessageExit,
+			SessionID:  record.ID,
+			ExitStatus: record.ExitStatus,
+			Error:      record.Error,
+		}
+	})
+	return t.exit
+}
+
+// Sessions 查询终端会话列表。
+func (s *TerminalsService) Sessions(c *gin.Context, req *internal_models.TerminalSessionsInfosRequest) ([]*internal_models.TerminalSession, uint, *SErr.APIErr) {
+	cond := &daModels.TerminalSession{}
+	if req.UserID != nil {
+		cond.UserID = *req.UserID
+	}
+	if req.Host != nil {
+		cond.Host = *req.Host
+	}
+	if req.Port != nil {
+		cond.Port = *req.Port
+	}
+	sessions, totalCount, err := dal.GetTerminalSessionDal().List(cond, req.From, req.Size)
+	if err != nil {
+		return nil, 0, err
+	}
+	res := make([]*internal_models.TerminalSession, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, s.packSession(session))
+	}
+	return res, totalCount, nil
+}
+
+// SessionInfo 获取一个终端会话。
+func (s *TerminalsService) SessionInfo(c *gin.Context, ID uint) (*internal_models.TerminalSession, *SErr.APIErr) {
+	session, err := dal.GetTerminalSessionDal().Get(ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.packSession(session), nil
+}
+
+// RecordingPath 获取终端会话的录像文件的路径。
+func (s *TerminalsService) RecordingPath(c *gin.Context, ID uint) (string, *SErr.APIErr) {
+	session, err := dal.GetTerminalSessionDal().Get(ID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(session.RecordingPath); err != nil {
+		return "", SErr.InvalidParamErr.CustomMessageF("该终端会话的录像文件不存在！ID=[%d]，出错信息为：%s", ID, err.Error())
+	}
+	return session.RecordingPath, nil
+}
+
+func (s *TerminalsService) packSession(session *daModels.TerminalSession) *internal_models.TerminalSession {
+	res := &internal_models.TerminalSession{
+		ID:            session.ID,
+		UserID:        session.UserID,
+		Host:          session.Host,
+		Port:          session.Port,
+		Account:       session.Account,
+		Cols:          session.Cols,
+		Rows:          session.Rows,
+		StartedAt:     session.StartedAt.Unix(),
+		DurationMs:    session.DurationMs,
+		ExitStatus:    session.ExitStatus,
+		Error:         session.Error,
+		RecordingSize: session.RecordingSize,
+	}
+	if session.EndedAt != nil {
+		res.EndedAt = session.EndedAt.Unix()
+	}
+	return res
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	SErr "ServerServing/err"
+	"ServerServing/internal/internal_models"
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeTerminal 依次输出outputs中的内容，之后以exitStatus退出。
+type fakeTerminal struct {
+	outputs    []string
+	input      strings.Builder
+	exitStatus int
+	closed     bool
+}
+
+func (f *fakeTerminal) Read(p []byte) (int, error) {
+	if len(f.outputs) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, f.outputs[0])
+	f.outputs[0] = f.outputs[0][n:]
+	if f.outputs[0] == "" {
+		f.outputs = f.outputs[1:]
+	}
+	return n, nil
+}
+
+func (f *fakeTerminal) Write(p []byte) (int, error) {
+	return f.input.Write(p)
+}
+
+func (f *fakeTerminal) Resize(cols, rows int) *SErr.APIErr {
+	return nil
+}
+
+func (f *fakeTerminal) Wait() (int, *SErr.APIErr) {
+	return f.exitStatus, nil
+}
+
+func (f *fakeTerminal) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestTerminalSessionRecording(t *testing.T) {
+	initReplayEnv(t)
+	svc := GetTerminalsService()
+	// “终端”的UTF-8编码被拆到了两次输出中，录像中仍然应该是完整的字符。
+	term := &fakeTerminal{
+		outputs:    []string{"$ ", "password: ", "\r\n$ echo \xe7\xbb\x88", "\xe7\xab\xaf\r\n", "exit\r\n"},
+		exitStatus: 0,
+	}
+	session, err := svc.start(t.TempDir(), 1, "10.0.0.1", 22, "admin", term, 100, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	for i := 0; i < 2; i++ {
+		_, _ = session.Read(buf)
+	}
+	// 输入的密码不会出现在录像中。
+	_, _ = session.Write([]byte("s3cret\r"))
+	if err := session.Resize(120, 40); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := session.Read(buf); err != nil {
+			break
+		}
+	}
+	exit := session.Finish()
+	if exit.ExitStatus != 0 || exit.Error != "" || exit.SessionID != session.ID || !term.closed || term.input.String() != "s3cret\r" {
+		t.Fatalf("unexpected exit %+v", exit)
+	}
+	if again := session.Finish(); again != exit {
+		t.Fatalf("Finish should return the same result")
+	}
+
+	info, err := svc.SessionInfo(nil, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Host != "10.0.0.1" || info.Cols != 100 || info.Rows != 30 || info.EndedAt == 0 || info.RecordingSize == 0 {
+		t.Fatalf("unexpected session %+v", info)
+	}
+	path, err := svc.RecordingPath(nil, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, e := os.Open(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Scan()
+	header := &asciicastHeader{}
+	if err := json.Unmarshal(scanner.Bytes(), header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 100 || header.Height != 30 || header.Title != "admin@10.0.0.1:22" {
+		t.Fatalf("unexpected header %+v", header)
+	}
+	var types, output []string
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, event[1].(string))
+		if event[1] == asciicastEventOutput {
+			output = append(output, event[2].(string))
+		}
+	}
+	if strings.Join(types, ",") != "o,o,r,o,o,o" {
+		t.Fatalf("unexpected event types %v", types)
+	}
+	recorded := strings.Join(output, "")
+	if recorded != "$ password: \r\n$ echo 终端\r\nexit\r\n" || strings.Contains(recorded, "s3cret") {
+		t.Fatalf("unexpected output %q", recorded)
+	}
+
+	sessions, total, err := svc.Sessions(nil, &internal_models.TerminalSessionsInfosRequest{Size: 10})
+	if err != nil || total != 1 || sessions[0].ID != session.ID {
+		t.Fatalf("unexpected sessions %v, total %d, err %v", sessions, total, err)
+	}
+	host := "10.0.0.2"
+	if _, total, err := svc.Sessions(nil, &internal_models.TerminalSessionsInfosRequest{Host: &host, Size: 10}); err != nil || total != 0 {
+		t.Fatalf("unexpected total %d, err %v", total, err)
+	}
+}
+
+func TestSplitIncompleteUTF8(t *testing.T) {
+	for _, c := range []struct {
+		in, complete, rest string
+	}{
+		{"abc", "abc", ""},
+		{"a\xe7\xbb", "a", "\xe7\xbb"},
+		{"a\xe7\xbb\x88", "a\xe7\xbb\x88", ""},
+		{"\xf0\x9f\x98", "", "\xf0\x9f\x98"},
+		// 本身就不合法的字节不会被一直保留。
+		{"a\x88", "a\x88", ""},
+	} {
+		complete, rest := splitIncompleteUTF8([]byte(c.in))
+		if string(complete) != c.complete || string(rest) != c.rest {
+			t.Fatalf("splitIncompleteUTF8(%q) = %q, %q", c.in, complete, rest)
+		}
+	}
+}
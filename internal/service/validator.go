@@ -1,6 +1,9 @@
 package service
 
-import "regexp"
+import (
+	"ServerServing/internal/service/server_executor"
+	"regexp"
+)
 
 type Validator struct{}
 
@@ -10,3 +13,8 @@ func (v Validator) ValidateAccountPassword(pwd string) bool {
 	reg := regexp.MustCompile(`^[a-zA-Z][0-9a-zA-Z~!@#$%^&*?]{5,14}$`)
 	return reg.MatchString(pwd)
 }
+
+// ValidateAccountName 账户名会被拼接到服务器上执行的命令中，需要符合严格的格式。
+func (v Validator) ValidateAccountName(name string) bool {
+	return server_executor.IsValidAccountName(name)
+}
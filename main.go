@@ -5,10 +5,13 @@ import (
 	"ServerServing/config"
 	"ServerServing/da/mysql"
 	_ "ServerServing/docs"
+	"ServerServing/internal/service"
+	"ServerServing/internal/service/server_executor"
 	"ServerServing/middlewares"
 	_ "database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"log"
 )
 
 // @title ServerServing Web API
@@ -22,7 +25,11 @@ import (
 // @BasePath
 func main() {
 	config.InitConfig()
+	if err := server_executor.InitCmdScripts(); err != nil {
+		log.Fatalf("InitCmdScripts failed, err=[%s]", err.Message)
+	}
 	mysql.InitMySQL()
+	server_executor.UseCommandAuditor(service.GetCommandAuditsService().Audit)
 	r := gin.Default()
 	registerMiddleware(r)
 	api.Register(r)
@@ -0,0 +1,20 @@
+// Package testutil 测试中共用的辅助函数，只应该被_test.go引用。
+package testutil
+
+import (
+	"os"
+	"testing"
+)
+
+// LiveConfigEnv 真实环境的配置文件路径。需要连接真实的MySQL或者服务器的测试在没有设置时跳过。
+const LiveConfigEnv = "SERVER_SERVING_LIVE_CONFIG"
+
+// LiveConfigPath 返回LiveConfigEnv配置的路径，没有设置时跳过当前测试，requires说明该测试需要连接的真实环境。
+func LiveConfigPath(t testing.TB, requires string) string {
+	t.Helper()
+	configPath := os.Getenv(LiveConfigEnv)
+	if configPath == "" {
+		t.Skipf("没有设置%s，跳过需要%s的测试。", LiveConfigEnv, requires)
+	}
+	return configPath
+}